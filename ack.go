@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// issueID fingerprints an issue of an object, e.g. "volume/pvc-1234" and "Scheduled: ...",
+// so the same issue keeps its ID across refreshes and restarts
+func issueID(object, issue string) string {
+	sum := sha256.Sum256([]byte(object + "\n" + issue))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// acknowledged returns true if the issue was silenced with "lhmon4 ack" and the acknowledgement
+// has not expired. Issues are never hidden because the state cannot be read.
+func acknowledged(id string) bool {
+	state, err := freshState()
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(state.Acks[id])
+}
+
+// formatIssue renders the issue text of a report row, dimmed when the issue is acknowledged and
+// tagged when it occurs during a maintenance window
+func formatIssue(text, id, color string) string {
+	if window, ok := inMaintenance(time.Now()); ok {
+		text += fmt.Sprintf(" (in maintenance: %s)", window)
+	}
+	if acknowledged(id) {
+		return colorize(text+" (acknowledged)", Dim)
+	}
+	return colorize(text, color)
+}
+
+// runAck implements the "lhmon4 ack <issue-id>..." subcommand
+func runAck(args []string) {
+	fs := flag.NewFlagSet("ack", flag.ExitOnError)
+	duration := fs.String("for", "24h", "how long the issues stay silenced, e.g. 24h or 7d")
+	maintenance := fs.String("silence", "", "start an ad hoc maintenance window of this length suppressing all alerts, e.g. 2h (optional)")
+	registerFormatFlags(fs)
+	fs.StringVar(&stateFile, "state-file", "", "file recording state between runs (default in the user cache directory)")
+	parseFlags(fs, args)
+
+	if fs.NArg() == 0 && *maintenance == "" {
+		fmt.Printf("Usage: %s ack [flags] <issue-id>...\n", commandName)
+		fmt.Printf("       %s ack --silence <duration>\n", commandName)
+		os.Exit(1)
+	}
+
+	silence, err := parseAge(*duration)
+	if err != nil || silence <= 0 {
+		fmt.Printf("Error: invalid --for %q\n", *duration)
+		os.Exit(1)
+	}
+	maintenanceLength, err := parseAge(*maintenance)
+	if err != nil {
+		fmt.Printf("Error: invalid --silence %q\n", *maintenance)
+		os.Exit(1)
+	}
+
+	state, err := sharedState()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Drop expired acknowledgements while the state is being rewritten anyway
+	now := time.Now()
+	for id, until := range state.Acks {
+		if !now.Before(until) {
+			delete(state.Acks, id)
+		}
+	}
+
+	until := now.Add(silence)
+	for _, id := range fs.Args() {
+		state.Acks[id] = until
+	}
+	if maintenanceLength > 0 {
+		state.MaintenanceUntil = now.Add(maintenanceLength)
+	}
+
+	if err := state.save(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, id := range fs.Args() {
+		fmt.Printf("Acknowledged %s until %s\n", id, formatTimestamp(until))
+	}
+	if maintenanceLength > 0 {
+		fmt.Printf("Alerts silenced until %s\n", formatTimestamp(state.MaintenanceUntil))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// actionFlags holds the flags shared by every command that modifies the cluster
+type actionFlags struct {
+	dryRun *bool
+	yes    *bool
+	audit  auditFlags
+}
+
+// registerActionFlags registers the dry-run, confirmation and audit flags on the flag set of a mutating command
+func registerActionFlags(fs *flag.FlagSet) actionFlags {
+	var af actionFlags
+	af.dryRun = fs.Bool("dry-run", false, "print the changes that would be made to the cluster without making them")
+	af.yes = fs.Bool("yes", false, "do not ask for confirmation before changing the cluster")
+	af.audit = registerAuditFlags(fs)
+	return af
+}
+
+// plannedChange is one change a command is about to make to the cluster
+type plannedChange struct {
+	Verb       string // create, patch or delete
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string // generateName prefix for objects named by the API server
+	Diff       string
+}
+
+// createChange returns the planned creation of an object, with its spec as the diff
+func createChange(obj *unstructured.Unstructured) plannedChange {
+	name := obj.GetName()
+	if name == "" {
+		name = obj.GetGenerateName()
+	}
+	spec, _ := json.Marshal(obj.Object["spec"])
+	return plannedChange{
+		Verb:       "create",
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       name,
+		Diff:       string(spec),
+	}
+}
+
+// action applies the confirmed changes of one command, recording each of them in the audit log
+type action struct {
+	audit *auditLog
+}
+
+// changeVerbColors colors the verbs of the change preview
+var changeVerbColors = map[string]string{
+	"create": Green,
+	"patch":  Yellow,
+	"delete": Red,
+}
+
+// printChanges prints the changes a command is about to make, with the diff of each object
+func printChanges(changes []plannedChange) {
+	fmt.Println(colorize("The following changes will be made to the cluster:", Bold))
+	for _, change := range changes {
+		object := change.Kind + " " + change.Name
+		if change.Namespace != "" {
+			object = change.Kind + " " + change.Namespace + "/" + change.Name
+		}
+		fmt.Printf("  %s %s\n", colorize(fmt.Sprintf("%-6s", change.Verb), changeVerbColors[change.Verb]+Bold), object)
+		if change.Diff != "" {
+			fmt.Printf("         %s\n", colorize(change.Diff, Cyan))
+		}
+	}
+	fmt.Println()
+}
+
+// prepare previews the changes of a command and asks for confirmation unless --yes is given. It
+// returns a nil action when nothing must be changed, with --dry-run or when the user declines.
+// Read-only mode is checked after the preview, so --dry-run works with the default read-only client.
+func (af actionFlags) prepare(name string, clientOpts clientFlags, clientset *kubernetes.Clientset, changes []plannedChange) (*action, error) {
+	printChanges(changes)
+	if *af.dryRun {
+		fmt.Println("Dry run, nothing changed")
+		return nil, nil
+	}
+	if err := clientOpts.requireWritable(name); err != nil {
+		return nil, err
+	}
+
+	if !*af.yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("%s needs confirmation, run it with --yes when stdin is not a terminal", name)
+		}
+		fmt.Printf("Apply %d change(s)? [y/N] ", len(changes))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Aborted, nothing changed")
+			return nil, nil
+		}
+	}
+
+	audit, err := af.audit.open(clientOpts, clientset)
+	if err != nil {
+		return nil, err
+	}
+	return &action{audit: audit}, nil
+}
+
+// apply makes one planned change and records it in the audit log. The function returns the name of
+// the changed object, which differs from the planned name for objects named by the API server.
+func (a *action) apply(change plannedChange, fn func() (string, error)) error {
+	name, err := fn()
+	if err == nil && name != "" {
+		change.Name = name
+	}
+	a.audit.record(auditEntry{
+		Verb:       change.Verb,
+		APIVersion: change.APIVersion,
+		Kind:       change.Kind,
+		Namespace:  change.Namespace,
+		Name:       change.Name,
+		Diff:       change.Diff,
+	}, err)
+	return err
+}
+
+// close closes the audit log of the action
+func (a *action) close() {
+	a.audit.close()
+}
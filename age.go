@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ageRange restricts the listed resources by the time since their creation
+type ageRange struct {
+	olderThan time.Duration // 0 disables the lower bound
+	newerThan time.Duration // 0 disables the upper bound
+}
+
+// ageFilter is the age range set with --older-than and --newer-than
+var ageFilter ageRange
+
+// matches returns true if a resource created at the given time falls within the range.
+// Resources without a known creation time always match.
+func (r ageRange) matches(created time.Time) bool {
+	if created.IsZero() {
+		return true
+	}
+	age := time.Since(created)
+	if r.olderThan > 0 && age < r.olderThan {
+		return false
+	}
+	if r.newerThan > 0 && age > r.newerThan {
+		return false
+	}
+	return true
+}
+
+// parseAge parses a duration like "90m", "36h" or "7d12h"; time.ParseDuration has no day unit
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	var days time.Duration
+	if i := strings.Index(value, "d"); i >= 0 {
+		n, err := strconv.Atoi(value[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		value = value[i+1:]
+		if value == "" {
+			return days, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return days + d, nil
+}
+
+// formatAge renders the time since creation with the two most significant units, e.g. 3d4h or 5m10s,
+// or the creation time with absolute --timestamps
+func formatAge(created time.Time) string {
+	if created.IsZero() {
+		return "-"
+	}
+	if absoluteTimestamps() {
+		return formatTimestamp(created)
+	}
+
+	return formatDuration(time.Since(created))
+}
+
+// formatDuration renders a duration with the two most significant units, e.g. 3d4h or 5m10s
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+
+	switch {
+	case days >= 365:
+		return fmt.Sprintf("%dy%dd", days/365, days%365)
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// alertsPath is the path Alertmanager webhooks are received on
+const alertsPath = "/alerts"
+
+// alertmanagerPayload is the body of an Alertmanager webhook notification
+type alertmanagerPayload struct {
+	Alerts []alertmanagerAlert `json:"alerts"`
+}
+
+// alertmanagerAlert is one alert of a webhook notification
+type alertmanagerAlert struct {
+	Status      string            `json:"status"` // firing or resolved
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// externalAlert is an alert received from Alertmanager, mapped onto the issue model
+type externalAlert struct {
+	Object   string
+	Issue    string
+	Severity severity
+	Resolved bool
+}
+
+// alertObject returns the object an alert is about. Longhorn's own metrics label volumes and nodes,
+// alerts without either are kept under their alert name.
+func alertObject(labels map[string]string) string {
+	if volume := labels["volume"]; volume != "" {
+		return "volume/" + volume
+	}
+	if node := labels["node"]; node != "" {
+		return "node/" + node
+	}
+	return "alert/" + labels["alertname"]
+}
+
+// alertIssue returns the issue text of an alert. It only uses labels, annotations may contain
+// values that change between the firing and the resolved notification.
+func alertIssue(labels map[string]string) string {
+	issue := "alert " + labels["alertname"]
+	if disk := labels["disk"]; disk != "" {
+		issue += " on disk " + disk
+	}
+	return issue
+}
+
+// alertSeverity maps the severity label of an alert, alerts without a known one are warnings
+func alertSeverity(labels map[string]string) severity {
+	if s, err := parseSeverity(labels["severity"]); err == nil && s != severityNone {
+		return s
+	}
+	return severityWarning
+}
+
+// externalAlerts maps the alerts of a webhook notification onto the issue model
+func externalAlerts(payload alertmanagerPayload) []externalAlert {
+	alerts := make([]externalAlert, 0, len(payload.Alerts))
+	for _, alert := range payload.Alerts {
+		alerts = append(alerts, externalAlert{
+			Object:   alertObject(alert.Labels),
+			Issue:    alertIssue(alert.Labels),
+			Severity: alertSeverity(alert.Labels),
+			Resolved: strings.EqualFold(alert.Status, "resolved"),
+		})
+	}
+	return alerts
+}
+
+// serveAlerts receives Alertmanager webhooks and passes their alerts on until the server fails
+func serveAlerts(listen string, alerts chan<- externalAlert) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(alertsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload alertmanagerPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("invalid Alertmanager webhook: %v", err), http.StatusBadRequest)
+			return
+		}
+		for _, alert := range externalAlerts(payload) {
+			alerts <- alert
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if err := http.ListenAndServe(listen, mux); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// attachmentHistoryLength is the number of previous attachments shown per volume
+const attachmentHistoryLength = 3
+
+// attachedEventPattern extracts the node from Longhorn "volume X has been attached to node" events
+var attachedEventPattern = regexp.MustCompile(`attached to (?:node )?(\S+)`)
+
+// AttachmentRecord is a single observed attachment of a volume to a node
+type AttachmentRecord struct {
+	Node   string
+	At     time.Time
+	Source string // Longhorn event, or the type of the attachment ticket
+}
+
+// AttachmentHistoryInfo is the reconstructed attachment history of a volume, most recent first
+type AttachmentHistoryInfo struct {
+	VolumeName  string
+	CurrentNode string
+	Attachments []AttachmentRecord
+	Nodes       int // Distinct nodes in the history
+}
+
+// getAttachmentHistory reconstructs recent attachments from the Attached events Longhorn records on
+// volumes and from the satisfied tickets of Longhorn VolumeAttachments. Events expire after an hour
+// by default, so the history is short.
+func getAttachmentHistory(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource) ([]AttachmentHistoryInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	records := make(map[string][]AttachmentRecord) // volume -> attachments
+
+	events, err := listEvents(clientset, namespace, "involvedObject.kind=Volume,reason=Attached")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume events: %v", err)
+	}
+	for _, event := range events.Items {
+		match := attachedEventPattern.FindStringSubmatch(event.Message)
+		if match == nil {
+			continue
+		}
+		records[event.InvolvedObject.Name] = append(records[event.InvolvedObject.Name], AttachmentRecord{
+			Node:   strings.TrimSuffix(match[1], "."),
+			At:     eventTime(event),
+			Source: "event",
+		})
+	}
+
+	// VolumeAttachments only exist since Longhorn 1.5
+	attachments, err := listAll(dynClient, longhornGVR(longhornAttachments), namespace)
+	if err == nil {
+		for _, attachment := range attachments.Items {
+			volumeName, _, _ := unstructured.NestedString(attachment.Object, "spec", "volume")
+			tickets, _, _ := unstructured.NestedMap(attachment.Object, "spec", "attachmentTickets")
+			for id, t := range tickets {
+				ticket, ok := t.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				nodeID, _ := ticket["nodeID"].(string)
+				ticketType, _ := ticket["type"].(string)
+				satisfiedAt, ok := ticketSatisfiedAt(attachment, id)
+				if nodeID == "" || !ok {
+					continue
+				}
+				records[volumeName] = append(records[volumeName], AttachmentRecord{
+					Node:   nodeID,
+					At:     satisfiedAt,
+					Source: ticketType,
+				})
+			}
+		}
+	}
+
+	var histories []AttachmentHistoryInfo
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+		volumeRecords := records[volumeName]
+		if len(volumeRecords) == 0 {
+			continue
+		}
+
+		sort.Slice(volumeRecords, func(i, j int) bool {
+			return volumeRecords[i].At.After(volumeRecords[j].At)
+		})
+
+		// An attachment seen both as an event and as a ticket is shown once
+		history := AttachmentHistoryInfo{VolumeName: volumeName}
+		history.CurrentNode, _, _ = unstructured.NestedString(volume.Object, "status", "currentNodeID")
+		nodes := make(map[string]bool)
+		for _, record := range volumeRecords {
+			nodes[record.Node] = true
+			if n := len(history.Attachments); n > 0 {
+				last := history.Attachments[n-1]
+				if last.Node == record.Node && last.At.Sub(record.At) < time.Minute {
+					continue
+				}
+			}
+			history.Attachments = append(history.Attachments, record)
+		}
+		history.Nodes = len(nodes)
+		if len(history.Attachments) > attachmentHistoryLength {
+			history.Attachments = history.Attachments[:attachmentHistoryLength]
+		}
+
+		histories = append(histories, history)
+	}
+
+	// Volumes bouncing between the most nodes first
+	sort.Slice(histories, func(i, j int) bool {
+		if histories[i].Nodes != histories[j].Nodes {
+			return histories[i].Nodes > histories[j].Nodes
+		}
+		return histories[i].VolumeName < histories[j].VolumeName
+	})
+
+	return histories, nil
+}
+
+// eventTime returns the time an event last occurred
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
+// ticketSatisfiedAt returns when an attachment ticket became satisfied
+func ticketSatisfiedAt(attachment unstructured.Unstructured, ticketID string) (time.Time, bool) {
+	conditions, _, _ := unstructured.NestedSlice(attachment.Object, "status", "attachmentTicketStatuses", ticketID, "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _ := condition["type"].(string)
+		status, _ := condition["status"].(string)
+		transition, _ := condition["lastTransitionTime"].(string)
+		if condType != "Satisfied" || status != "True" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, transition)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return at, true
+	}
+	return time.Time{}, false
+}
+
+// printAttachmentHistory prints the previous attachments of every volume with a known history
+func printAttachmentHistory(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource) {
+	histories, err := getAttachmentHistory(dynClient, clientset, namespace, volumesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "ATTACHMENT HISTORY",
+		Description: fmt.Sprintf("Last %d attachments per volume from events and attachment tickets", attachmentHistoryLength),
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tCURRENT NODE\tNODES\tPREVIOUS ATTACHMENTS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tCURRENT NODE\tNODES\tPREVIOUS ATTACHMENTS")
+	}
+
+	fmt.Fprintln(w, "──────\t────────────\t─────\t────────────────────")
+
+	for _, history := range histories {
+		currentNode := history.CurrentNode
+		if currentNode == "" {
+			currentNode = "detached"
+		}
+
+		// Workloads moving between nodes are highlighted
+		nodesColor := Green
+		if history.Nodes > 1 {
+			nodesColor = Yellow
+		}
+
+		var attachments []string
+		for _, record := range history.Attachments {
+			attachments = append(attachments, fmt.Sprintf("%s (%s, %s)", record.Node, formatWhen(record.At), record.Source))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			history.VolumeName,
+			colorize(currentNode, Cyan),
+			colorize(fmt.Sprint(history.Nodes), nodesColor),
+			strings.Join(attachments, ", "),
+		)
+	}
+
+	if len(histories) == 0 {
+		fmt.Fprintln(w, "No recent attachments found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// auditFlags holds the flags of the commands that modify the cluster
+type auditFlags struct {
+	path   *string
+	events *bool
+}
+
+// registerAuditFlags registers the audit trail flags on the flag set of a mutating command
+func registerAuditFlags(fs *flag.FlagSet) auditFlags {
+	var af auditFlags
+	af.path = fs.String("audit-log", "", "append-only JSON lines file recording every change made to the cluster (default audit.log in the user cache directory)")
+	af.events = fs.Bool("audit-events", false, "also record every change as a Kubernetes Event on the changed object")
+	return af
+}
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`     // Local user running lhmon4
+	KubeUser   string    `json:"kubeUser"` // User of the current kubeconfig context
+	Command    string    `json:"command"`
+	Verb       string    `json:"verb"` // create, patch or delete
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	Diff       string    `json:"diff,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditLog records the changes made by one command
+type auditLog struct {
+	file      *os.File
+	clientset *kubernetes.Clientset // nil unless changes are recorded as Events
+	user      string
+	kubeUser  string
+	command   string
+}
+
+// auditPath returns the path of the audit log, next to the state file by default
+func auditPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %v", err)
+	}
+	return filepath.Join(dir, "lhmon4", "audit.log"), nil
+}
+
+// kubeconfigUser returns the user of the kubeconfig context in use, empty when unknown
+func kubeconfigUser(clientOpts clientFlags) string {
+	config, err := clientOpts.clientConfig().RawConfig()
+	if err != nil {
+		return ""
+	}
+	contextName := config.CurrentContext
+	if *clientOpts.context != "" {
+		contextName = *clientOpts.context
+	}
+	if current, found := config.Contexts[contextName]; found {
+		return current.AuthInfo
+	}
+	return ""
+}
+
+// open opens the audit log before anything is changed, so changes are never made unrecorded
+func (af auditFlags) open(clientOpts clientFlags, clientset *kubernetes.Clientset) (*auditLog, error) {
+	path, err := auditPath(*af.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+
+	a := &auditLog{file: file, kubeUser: kubeconfigUser(clientOpts), command: strings.Join(os.Args, " ")}
+	if current, err := user.Current(); err == nil {
+		a.user = current.Username
+	}
+	if *af.events {
+		a.clientset = clientset
+	}
+	return a, nil
+}
+
+// record appends a change to the audit log and, with --audit-events, creates an Event on the
+// changed object. Failing to record is reported but does not undo the change.
+func (a *auditLog) record(entry auditEntry, err error) {
+	entry.Time = time.Now().UTC()
+	entry.User = a.user
+	entry.KubeUser = a.kubeUser
+	entry.Command = a.command
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	line, _ := json.Marshal(entry)
+	if _, writeErr := a.file.Write(append(line, '\n')); writeErr != nil {
+		fmt.Printf("Error: failed to write audit log: %v\n", writeErr)
+	}
+
+	if a.clientset == nil || err != nil {
+		return
+	}
+	who := a.user
+	if a.kubeUser != "" {
+		who += " (" + a.kubeUser + ")"
+	}
+	message := fmt.Sprintf("lhmon4: %s by %s", entry.Verb, who)
+	if entry.Diff != "" {
+		message += ": " + entry.Diff
+	}
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "lhmon4-", Namespace: entry.Namespace},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: entry.APIVersion,
+			Kind:       entry.Kind,
+			Namespace:  entry.Namespace,
+			Name:       entry.Name,
+		},
+		Reason:         "Lhmon4Change",
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "lhmon4"},
+		FirstTimestamp: metav1.NewTime(entry.Time),
+		LastTimestamp:  metav1.NewTime(entry.Time),
+		Count:          1,
+	}
+	if _, err := a.clientset.CoreV1().Events(entry.Namespace).Create(context.TODO(), event, metav1.CreateOptions{}); err != nil {
+		fmt.Printf("Error: failed to create audit event: %v\n", err)
+	}
+}
+
+// close closes the audit log
+func (a *auditLog) close() {
+	a.file.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// exitPolicyViolation is the exit status of a run that found availability policy violations
+const exitPolicyViolation = 3
+
+// availabilityPolicy requires the matching volumes to keep a minimum number of healthy replicas.
+// A policy without storage classes or labels applies to every volume.
+type availabilityPolicy struct {
+	Name               string            `json:"name"`
+	StorageClasses     []string          `json:"storageClasses,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"` // Labels of the Longhorn volume
+	MinHealthyReplicas int               `json:"minHealthyReplicas"`
+}
+
+// matches returns true if the policy applies to a volume with the given storage class and labels
+func (p availabilityPolicy) matches(storageClass string, labels map[string]string) bool {
+	if len(p.StorageClasses) > 0 && !contains(p.StorageClasses, storageClass) {
+		return false
+	}
+	for key, value := range p.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// AvailabilityViolation describes a volume with fewer healthy replicas than its policy requires
+type AvailabilityViolation struct {
+	VolumeName   string
+	StorageClass string
+	Policy       string
+	Healthy      int
+	Required     int
+}
+
+// checkAvailability compares the healthy replica count of every attached volume against the
+// strictest matching policy. Detached volumes run no replicas and are skipped.
+func checkAvailability(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo, policies []availabilityPolicy) ([]AvailabilityViolation, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	healthyReplicas := make(map[string]int)
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if state == "running" && failedAt == "" {
+			healthyReplicas[volumeName]++
+		}
+	}
+
+	var violations []AvailabilityViolation
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		if state != "attached" {
+			continue
+		}
+
+		storageClass := pvInfoMap[volumeName].StorageClass
+		labels := volume.GetLabels()
+
+		// The policy requiring the most replicas wins
+		var strictest *availabilityPolicy
+		for i, policy := range policies {
+			if policy.matches(storageClass, labels) && (strictest == nil || policy.MinHealthyReplicas > strictest.MinHealthyReplicas) {
+				strictest = &policies[i]
+			}
+		}
+		if strictest == nil || healthyReplicas[volumeName] >= strictest.MinHealthyReplicas {
+			continue
+		}
+
+		violations = append(violations, AvailabilityViolation{
+			VolumeName:   volumeName,
+			StorageClass: storageClass,
+			Policy:       strictest.Name,
+			Healthy:      healthyReplicas[volumeName],
+			Required:     strictest.MinHealthyReplicas,
+		})
+	}
+
+	// Volumes furthest below their policy first
+	sort.Slice(violations, func(i, j int) bool {
+		gapI := violations[i].Required - violations[i].Healthy
+		gapJ := violations[j].Required - violations[j].Healthy
+		if gapI != gapJ {
+			return gapI > gapJ
+		}
+		return violations[i].VolumeName < violations[j].VolumeName
+	})
+
+	return violations, nil
+}
+
+// printAvailabilityPolicy prints the volumes violating an availability policy and returns how many there are
+func printAvailabilityPolicy(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo, policies []availabilityPolicy) int {
+	violations, err := checkAvailability(dynClient, namespace, volumesGVR, pvInfoMap, policies)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 0
+	}
+
+	var names []string
+	for _, policy := range policies {
+		names = append(names, policy.Name)
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "AVAILABILITY POLICY",
+		Description: "Volumes with fewer healthy replicas than required by: " + strings.Join(names, ", "),
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSTORAGE CLASS\tPOLICY\tHEALTHY\tREQUIRED%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSTORAGE CLASS\tPOLICY\tHEALTHY\tREQUIRED")
+	}
+
+	fmt.Fprintln(w, "──────\t─────────────\t──────\t───────\t────────")
+
+	for _, violation := range violations {
+		storageClass := violation.StorageClass
+		if storageClass == "" {
+			storageClass = "-"
+		}
+
+		healthyColor := Yellow
+		if violation.Healthy == 0 {
+			healthyColor = Red
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n",
+			violation.VolumeName,
+			storageClass,
+			withRunbook(violation.Policy, "availability"),
+			colorize(fmt.Sprintf("%d", violation.Healthy), healthyColor),
+			violation.Required,
+		)
+	}
+
+	if len(violations) == 0 {
+		fmt.Fprintln(w, "All volumes meet their availability policy")
+	}
+
+	w.Flush()
+
+	return len(violations)
+}
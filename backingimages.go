@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// BackingImageIssue describes a disk hosting replicas of a backing-image-based volume without a ready copy of the image
+type BackingImageIssue struct {
+	BackingImage string
+	NodeName     string
+	DiskUUID     string
+	Replicas     []string
+	State        string // State of the image copy on the disk, "missing" when there is none
+	Message      string
+}
+
+// checkBackingImagePlacement verifies every disk hosting a replica of a backing-image-based volume
+// has the backing image downloaded and ready
+func checkBackingImagePlacement(dynClient dynamic.Interface, namespace string) ([]BackingImageIssue, error) {
+	images, err := listAll(dynClient, longhornGVR(longhornBackingImages), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn backing images: %v", err)
+	}
+
+	// Image name -> disk UUID -> status of the copy on that disk
+	imageDisks := make(map[string]map[string]map[string]interface{})
+	for _, image := range images.Items {
+		statuses, _, _ := unstructured.NestedMap(image.Object, "status", "diskFileStatusMap")
+		disks := make(map[string]map[string]interface{})
+		for diskUUID, s := range statuses {
+			if status, ok := s.(map[string]interface{}); ok {
+				disks[diskUUID] = status
+			}
+		}
+		imageDisks[image.GetName()] = disks
+	}
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	issuesByDisk := make(map[string]*BackingImageIssue) // image/disk -> issue
+	for _, replica := range replicas.Items {
+		imageName, _, _ := unstructured.NestedString(replica.Object, "spec", "backingImage")
+		diskUUID, _, _ := unstructured.NestedString(replica.Object, "spec", "diskID")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		if imageName == "" || diskUUID == "" {
+			continue
+		}
+
+		state, message := "missing", "backing image has no copy on this disk"
+		if disks, found := imageDisks[imageName]; !found {
+			message = "backing image does not exist"
+		} else if status, found := disks[diskUUID]; found {
+			state, _ = status["state"].(string)
+			message, _ = status["message"].(string)
+		}
+		if state == "ready" {
+			continue
+		}
+
+		key := imageName + "/" + diskUUID
+		issue, found := issuesByDisk[key]
+		if !found {
+			issue = &BackingImageIssue{
+				BackingImage: imageName,
+				NodeName:     nodeID,
+				DiskUUID:     diskUUID,
+				State:        state,
+				Message:      message,
+			}
+			issuesByDisk[key] = issue
+		}
+		issue.Replicas = append(issue.Replicas, replica.GetName())
+	}
+
+	var issues []BackingImageIssue
+	for _, issue := range issuesByDisk {
+		sort.Strings(issue.Replicas)
+		issues = append(issues, *issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].BackingImage != issues[j].BackingImage {
+			return issues[i].BackingImage < issues[j].BackingImage
+		}
+		if issues[i].NodeName != issues[j].NodeName {
+			return issues[i].NodeName < issues[j].NodeName
+		}
+		return issues[i].DiskUUID < issues[j].DiskUUID
+	})
+
+	return issues, nil
+}
+
+// printBackingImagePlacement prints disks hosting replicas whose backing image copy is missing or not ready
+func printBackingImagePlacement(dynClient dynamic.Interface, namespace string) {
+	issues, err := checkBackingImagePlacement(dynClient, namespace)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "BACKING IMAGE PLACEMENT",
+		Description: "Disks hosting replicas without a ready copy of their backing image",
+		Color:       Yellow,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sBACKING IMAGE\tNODE\tDISK UUID\tREPLICAS\tSTATE\tMESSAGE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "BACKING IMAGE\tNODE\tDISK UUID\tREPLICAS\tSTATE\tMESSAGE")
+	}
+
+	fmt.Fprintln(w, "─────────────\t────\t─────────\t────────\t─────\t───────")
+
+	for _, issue := range issues {
+		stateColor := Yellow
+		if issue.State == "missing" || issue.State == "failed" {
+			stateColor = Red
+		}
+		message := issue.Message
+		if message == "" {
+			message = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			issue.BackingImage,
+			issue.NodeName,
+			issue.DiskUUID,
+			len(issue.Replicas),
+			colorize(issue.State, stateColor),
+			message,
+		)
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "All replicas have their backing image ready on their disk")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// backupPollInterval is how often the snapshot and backup of "backup create" are checked
+const backupPollInterval = 2 * time.Second
+
+// Final states of a Longhorn backup
+const (
+	backupCompleted = "Completed"
+	backupError     = "Error"
+)
+
+// runBackup implements the "lhmon4 backup" subcommand
+func runBackup(args []string) {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Printf("Usage: %s backup create <volume> [--wait] [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("backup create", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	wait := fs.Bool("wait", false, "wait until the backup is completed, printing its progress")
+	timeout := fs.Duration("timeout", time.Hour, "maximum time to wait for the snapshot and, with --wait, the backup")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	actionOpts := registerActionFlags(fs)
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() != 1 {
+		fmt.Printf("Usage: %s backup create <volume> [--wait] [flags]\n", commandName)
+		os.Exit(2)
+	}
+	volumeName := fs.Arg(0)
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	namespace := *clientOpts.namespace
+	if err := checkBackupVolume(dynClient, namespace, volumeName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The name of the snapshot is only known once it is created
+	snapshot := backupSnapshotObject(namespace, volumeName)
+	changes := []plannedChange{createChange(snapshot), createChange(backupObject(namespace, volumeName, snapshot.GetGenerateName()))}
+	run, err := actionOpts.prepare("backup create", clientOpts, clientset, changes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if run == nil {
+		return
+	}
+
+	deadline := time.Now().Add(*timeout)
+	backupName, err := createBackup(dynClient, namespace, volumeName, deadline, run)
+	run.close()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created backup %s of volume %s\n", colorize(backupName, Blue), volumeName)
+
+	if !*wait {
+		return
+	}
+	if err := waitForBackup(dynClient, namespace, backupName, deadline); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(colorize(fmt.Sprintf("Backup %s completed", backupName), Green+Bold))
+}
+
+// checkBackupVolume fails unless the volume is attached, snapshots of detached volumes cannot be taken
+func checkBackupVolume(dynClient dynamic.Interface, namespace, volumeName string) error {
+	volume, err := dynClient.Resource(longhornGVR(longhornVolumes)).Namespace(namespace).Get(context.TODO(), volumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get Longhorn volume %s: %v", volumeName, err)
+	}
+	if state, _, _ := unstructured.NestedString(volume.Object, "status", "state"); state != "attached" {
+		return fmt.Errorf("volume %s is %s, snapshots and backups need an attached volume", volumeName, state)
+	}
+	return nil
+}
+
+// backupSnapshotObject returns the snapshot taken of a volume to back it up
+func backupSnapshotObject(namespace, volumeName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": longhornGroup + "/" + longhornVersion,
+		"kind":       "Snapshot",
+		"metadata": map[string]interface{}{
+			"generateName": "lhmon4-",
+			"namespace":    namespace,
+		},
+		"spec": map[string]interface{}{
+			"volume":         volumeName,
+			"createSnapshot": true,
+		},
+	}}
+}
+
+// backupObject returns the backup of a snapshot. The backup volume is found through the label, like
+// the backups created by Longhorn itself.
+func backupObject(namespace, volumeName, snapshotName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": longhornGroup + "/" + longhornVersion,
+		"kind":       "Backup",
+		"metadata": map[string]interface{}{
+			"generateName": "backup-",
+			"namespace":    namespace,
+			"labels":       map[string]interface{}{"backup-volume": volumeName},
+		},
+		"spec": map[string]interface{}{
+			"snapshotName": snapshotName,
+		},
+	}}
+}
+
+// createBackup takes a snapshot of an attached volume and backs it up, the same steps as the
+// backup button of the Longhorn UI. The backup is created once the snapshot is ready.
+func createBackup(dynClient dynamic.Interface, namespace, volumeName string, deadline time.Time, run *action) (string, error) {
+	snapshot := backupSnapshotObject(namespace, volumeName)
+	snapshots := dynClient.Resource(longhornGVR(longhornSnapshots)).Namespace(namespace)
+	var snapshotName string
+	err := run.apply(createChange(snapshot), func() (string, error) {
+		created, err := snapshots.Create(context.TODO(), snapshot, metav1.CreateOptions{})
+		if err != nil {
+			return "", err
+		}
+		snapshotName = created.GetName()
+		return snapshotName, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot of volume %s: %v", volumeName, err)
+	}
+	fmt.Printf("Created snapshot %s, waiting until it is ready\n", snapshotName)
+
+	for {
+		current, err := snapshots.Get(context.TODO(), snapshotName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get Longhorn snapshot %s: %v", snapshotName, err)
+		}
+		if message, _, _ := unstructured.NestedString(current.Object, "status", "error"); message != "" {
+			return "", fmt.Errorf("snapshot %s failed: %s", snapshotName, message)
+		}
+		if ready, _, _ := unstructured.NestedBool(current.Object, "status", "readyToUse"); ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("snapshot %s was not ready in time", snapshotName)
+		}
+		time.Sleep(backupPollInterval)
+	}
+
+	backup := backupObject(namespace, volumeName, snapshotName)
+	var backupName string
+	err = run.apply(createChange(backup), func() (string, error) {
+		created, err := dynClient.Resource(longhornGVR(longhornBackups)).Namespace(namespace).Create(context.TODO(), backup, metav1.CreateOptions{})
+		if err != nil {
+			return "", err
+		}
+		backupName = created.GetName()
+		return backupName, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup of snapshot %s: %v", snapshotName, err)
+	}
+	return backupName, nil
+}
+
+// waitForBackup prints the progress of a backup until it completes, fails or the deadline passes
+func waitForBackup(dynClient dynamic.Interface, namespace, backupName string, deadline time.Time) error {
+	backups := dynClient.Resource(longhornGVR(longhornBackups)).Namespace(namespace)
+	lastState, lastProgress := "", int64(-1)
+	for {
+		current, err := backups.Get(context.TODO(), backupName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get Longhorn backup %s: %v", backupName, err)
+		}
+		state, _, _ := unstructured.NestedString(current.Object, "status", "state")
+		progress, _, _ := unstructured.NestedInt64(current.Object, "status", "progress")
+
+		switch state {
+		case backupCompleted:
+			return nil
+		case backupError:
+			message, _, _ := unstructured.NestedString(current.Object, "status", "error")
+			return fmt.Errorf("backup %s failed: %s", backupName, message)
+		}
+
+		if state != lastState || progress != lastProgress {
+			shown := state
+			if shown == "" {
+				shown = "Pending"
+			}
+			fmt.Printf("%s  %s %d%%\n", formatTimestamp(time.Now()), shown, progress)
+			lastState, lastProgress = state, progress
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("backup %s did not complete in time, it keeps running in the cluster", backupName)
+		}
+		time.Sleep(backupPollInterval)
+	}
+}
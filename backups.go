@@ -0,0 +1,265 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// BackupInfo stores information about a single Longhorn backup
+type BackupInfo struct {
+	Name          string
+	VolumeName    string
+	SnapshotName  string
+	State         string
+	CreatedAt     string
+	Size          ByteSize // Size of the backed up snapshot
+	Uploaded      ByteSize // Data newly uploaded to the backup store by this backup
+	HasUploadSize bool     // False for Longhorn versions that do not report uploaded data
+}
+
+// BackupVolumeInfo stores a backup volume and its backups
+type BackupVolumeInfo struct {
+	Name         string
+	LastBackup   string
+	LastBackupAt string
+	Backups      []BackupInfo
+	Consumption  ByteSize // Backup store space used by all backups of the volume
+	Estimated    bool     // True when consumption is an upper bound based on snapshot sizes
+}
+
+// runBackups implements the "lhmon4 backups" subcommand
+func runBackups(args []string) {
+	fs := flag.NewFlagSet("backups", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	volumeName := fs.String("volume", "", "only show backups of this volume")
+	summary := fs.Bool("summary", false, "only print the per-volume consumption summary")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	backupVolumes, err := getBackupInventory(dynClient, *clientOpts.namespace, *volumeName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	if !*summary {
+		printBackupInventory(backupVolumes)
+	}
+	printBackupConsumption(backupVolumes)
+}
+
+// getBackupInventory collects all backup volumes with their backups
+func getBackupInventory(dynClient dynamic.Interface, namespace, filterVolume string) ([]BackupVolumeInfo, error) {
+	backupVolumeList, err := listAll(dynClient, longhornGVR(longhornBackupVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn backup volumes: %v", err)
+	}
+
+	backupList, err := listAll(dynClient, longhornGVR(longhornBackups), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn backups: %v", err)
+	}
+
+	volumes := make(map[string]*BackupVolumeInfo)
+	for _, bv := range backupVolumeList.Items {
+		// Backup volumes are named after the volume, newer versions carry the name in the spec
+		name, _, _ := unstructured.NestedString(bv.Object, "spec", "volumeName")
+		if name == "" {
+			name = bv.GetName()
+		}
+		info := &BackupVolumeInfo{Name: name}
+		info.LastBackup, _, _ = unstructured.NestedString(bv.Object, "status", "lastBackupName")
+		info.LastBackupAt, _, _ = unstructured.NestedString(bv.Object, "status", "lastBackupAt")
+		volumes[name] = info
+	}
+
+	for _, b := range backupList.Items {
+		backup := getBackupInfo(b)
+
+		info, found := volumes[backup.VolumeName]
+		if !found {
+			info = &BackupVolumeInfo{Name: backup.VolumeName}
+			volumes[backup.VolumeName] = info
+		}
+		info.Backups = append(info.Backups, backup)
+	}
+
+	var result []BackupVolumeInfo
+	for name, info := range volumes {
+		if filterVolume != "" && name != filterVolume {
+			continue
+		}
+
+		sort.Slice(info.Backups, func(i, j int) bool {
+			return info.Backups[i].CreatedAt < info.Backups[j].CreatedAt
+		})
+
+		// Backups are incremental, so only newly uploaded data adds to the store consumption
+		for _, backup := range info.Backups {
+			if backup.HasUploadSize {
+				info.Consumption += backup.Uploaded
+			} else {
+				info.Consumption += backup.Size
+				info.Estimated = true
+			}
+		}
+
+		result = append(result, *info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// getBackupInfo extracts backup information from a Backup CR
+func getBackupInfo(b unstructured.Unstructured) BackupInfo {
+	backup := BackupInfo{Name: b.GetName()}
+	backup.VolumeName, _, _ = unstructured.NestedString(b.Object, "status", "volumeName")
+	if backup.VolumeName == "" {
+		backup.VolumeName = b.GetLabels()["backup-volume"]
+	}
+	backup.SnapshotName, _, _ = unstructured.NestedString(b.Object, "spec", "snapshotName")
+	backup.State, _, _ = unstructured.NestedString(b.Object, "status", "state")
+	backup.CreatedAt, _, _ = unstructured.NestedString(b.Object, "status", "backupCreatedAt")
+
+	status, _, _ := unstructured.NestedMap(b.Object, "status")
+	size, _ := longhorn.Float64(status, "size")
+	backup.Size = ByteSize(size)
+	if uploaded, found := longhorn.Float64(status, "newlyUploadDataSize"); found {
+		backup.Uploaded = ByteSize(uploaded)
+		backup.HasUploadSize = true
+	}
+
+	return backup
+}
+
+// printBackupInventory prints every backup grouped by backup volume
+func printBackupInventory(backupVolumes []BackupVolumeInfo) {
+	printSectionHeader(Section{
+		Title:       "BACKUP INVENTORY",
+		Description: "Backups per backup volume",
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tBACKUP\tSNAPSHOT\tSTATE\tCREATED\tSIZE\tUPLOADED%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tBACKUP\tSNAPSHOT\tSTATE\tCREATED\tSIZE\tUPLOADED")
+	}
+
+	fmt.Fprintln(w, "──────\t──────\t────────\t─────\t───────\t────\t────────")
+
+	for _, bv := range backupVolumes {
+		for _, backup := range bv.Backups {
+			stateColor := Green
+			if backup.State == "Error" || backup.State == "Unknown" {
+				stateColor = Red
+			} else if backup.State != "Completed" {
+				stateColor = Yellow
+			}
+
+			uploaded := "-"
+			if backup.HasUploadSize {
+				uploaded = backup.Uploaded.String()
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				colorize(bv.Name, Blue),
+				backup.Name,
+				backup.SnapshotName,
+				colorize(backup.State, stateColor),
+				backup.CreatedAt,
+				backup.Size,
+				uploaded,
+			)
+		}
+	}
+
+	if len(backupVolumes) == 0 {
+		fmt.Fprintln(w, "No backups found")
+	}
+
+	w.Flush()
+}
+
+// printBackupConsumption prints the cumulative backup store consumption per volume, largest first
+func printBackupConsumption(backupVolumes []BackupVolumeInfo) {
+	printSectionHeader(Section{
+		Title:       "BACKUP STORE CONSUMPTION",
+		Description: "Cumulative backup store usage per volume",
+		Color:       Magenta,
+	})
+
+	sorted := make([]BackupVolumeInfo, len(backupVolumes))
+	copy(sorted, backupVolumes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Consumption > sorted[j].Consumption
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tBACKUPS\tLAST BACKUP\tLAST BACKUP AT\tCONSUMPTION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tBACKUPS\tLAST BACKUP\tLAST BACKUP AT\tCONSUMPTION")
+	}
+
+	fmt.Fprintln(w, "──────\t───────\t───────────\t──────────────\t───────────")
+
+	var total ByteSize
+	estimated := false
+	for _, bv := range sorted {
+		consumption := bv.Consumption.String()
+		if bv.Estimated {
+			consumption = "≤ " + consumption
+			estimated = true
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
+			colorize(bv.Name, Blue),
+			len(bv.Backups),
+			bv.LastBackup,
+			bv.LastBackupAt,
+			colorize(consumption, Yellow),
+		)
+		total += bv.Consumption
+	}
+	w.Flush()
+
+	fmt.Printf("\nTotal backup store consumption: %s\n", colorize(total.String(), Bold+Yellow))
+	if estimated {
+		fmt.Println("Values marked ≤ are upper bounds: this Longhorn version does not report uploaded data per backup")
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BackupTargetInfo stores information about a Longhorn backup target
+type BackupTargetInfo struct {
+	Name             string
+	URL              string
+	CredentialSecret string
+	Available        bool
+	Message          string
+	LastSyncedAt     string
+}
+
+// backupTargetCheck is the result of a single backup target check
+type backupTargetCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// credentialKeys lists the secret keys required per backup store type
+var credentialKeys = map[string][]string{
+	"s3":     {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+	"cifs":   {"CIFS_USERNAME", "CIFS_PASSWORD"},
+	"azblob": {"AZBLOB_ACCOUNT_NAME", "AZBLOB_ACCOUNT_KEY"},
+}
+
+// runBackupTarget implements the "lhmon4 backup-target" subcommand
+func runBackupTarget(args []string) {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Printf("Usage: %s backup-target test [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("backup-target test", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	probe := fs.Bool("probe", false, "run a short-lived pod that tests TCP reachability of the backup store")
+	probeImage := fs.String("probe-image", "busybox:1.36", "image used for the reachability probe pod")
+	probeTimeout := fs.Duration("probe-timeout", 60*time.Second, "maximum time to wait for the probe pod")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	actionOpts := registerActionFlags(fs)
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	targets, err := getBackupTargets(dynClient, *clientOpts.namespace)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("No backup target configured")
+		os.Exit(1)
+	}
+
+	// The probe creates and deletes a pod per backup target
+	var run *action
+	if *probe {
+		var changes []plannedChange
+		for _, target := range targets {
+			if target.URL == "" {
+				continue
+			}
+			endpoint, err := backupTargetEndpoint(clientset, *clientOpts.namespace, target)
+			if err != nil {
+				continue
+			}
+			pod := backupProbePod(*clientOpts.namespace, *probeImage, endpoint)
+			changes = append(changes, podChange("create", pod), podChange("delete", pod))
+		}
+		if len(changes) > 0 {
+			if run, err = actionOpts.prepare("backup-target test --probe", clientOpts, clientset, changes); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	failed := false
+	for _, target := range targets {
+		printSectionHeader(Section{
+			Title:       "BACKUP TARGET " + strings.ToUpper(target.Name),
+			Description: target.URL,
+			Color:       Blue,
+		})
+
+		checks := checkBackupTarget(clientset, *clientOpts.namespace, target)
+		if run != nil && target.URL != "" {
+			checks = append(checks, probeBackupTarget(clientset, *clientOpts.namespace, target, *probeImage, *probeTimeout, run))
+		}
+
+		for _, check := range checks {
+			status := colorize("PASS", Green)
+			if !check.Passed {
+				status = colorize("FAIL", Red)
+				failed = true
+			}
+			fmt.Printf("  [%s] %s: %s\n", status, check.Name, check.Detail)
+		}
+	}
+
+	if run != nil {
+		run.close()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// getBackupTargets returns the BackupTarget CRs, falling back to the backup-target settings of older Longhorn versions
+func getBackupTargets(dynClient dynamic.Interface, namespace string) ([]BackupTargetInfo, error) {
+	list, err := listAll(dynClient, longhornGVR(longhornBackupTargets), namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to list backup targets: %v", err)
+	}
+
+	var targets []BackupTargetInfo
+	if list != nil {
+		for _, item := range list.Items {
+			target := BackupTargetInfo{Name: item.GetName()}
+			target.URL, _, _ = unstructured.NestedString(item.Object, "spec", "backupTargetURL")
+			target.CredentialSecret, _, _ = unstructured.NestedString(item.Object, "spec", "credentialSecret")
+			target.Available, _, _ = unstructured.NestedBool(item.Object, "status", "available")
+			target.LastSyncedAt, _, _ = unstructured.NestedString(item.Object, "status", "lastSyncedAt")
+
+			// The Unavailable condition carries the reason the target cannot be used
+			conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+			for _, c := range conditions {
+				condition, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				condType, _ := condition["type"].(string)
+				status, _ := condition["status"].(string)
+				message, _ := condition["message"].(string)
+				if condType == "Unavailable" && status == "True" {
+					target.Message = message
+				}
+			}
+
+			targets = append(targets, target)
+		}
+	}
+
+	if len(targets) == 0 {
+		url, err := getLonghornSetting(dynClient, namespace, "backup-target")
+		if err != nil {
+			return nil, err
+		}
+		if url == "" {
+			return nil, nil
+		}
+		secret, err := getLonghornSetting(dynClient, namespace, "backup-target-credential-secret")
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, BackupTargetInfo{Name: "default", URL: url, CredentialSecret: secret, Available: true})
+	}
+
+	return targets, nil
+}
+
+// checkBackupTarget checks the availability condition and credential secret of a backup target
+func checkBackupTarget(clientset *kubernetes.Clientset, namespace string, target BackupTargetInfo) []backupTargetCheck {
+	var checks []backupTargetCheck
+
+	if target.URL == "" {
+		return append(checks, backupTargetCheck{Name: "URL", Detail: "backupTargetURL is empty, backups are disabled"})
+	}
+
+	scheme := backupTargetScheme(target.URL)
+	if _, err := url.Parse(target.URL); err != nil || scheme == "" {
+		checks = append(checks, backupTargetCheck{Name: "URL", Detail: fmt.Sprintf("cannot parse %q, expected s3://, nfs://, cifs:// or azblob://", target.URL)})
+	} else {
+		checks = append(checks, backupTargetCheck{Name: "URL", Passed: true, Detail: scheme + " backup store"})
+	}
+
+	// Availability as reported by longhorn-manager
+	if target.Available {
+		detail := "Longhorn reports the target as available"
+		if target.LastSyncedAt != "" {
+			detail += ", last synced " + target.LastSyncedAt
+		}
+		checks = append(checks, backupTargetCheck{Name: "Available", Passed: true, Detail: detail})
+	} else {
+		checks = append(checks, backupTargetCheck{Name: "Available", Detail: explainBackupTargetError(target.Message)})
+	}
+
+	// Credential secret presence and required keys
+	required := credentialKeys[scheme]
+	switch {
+	case target.CredentialSecret == "" && len(required) > 0 && scheme != "s3":
+		checks = append(checks, backupTargetCheck{Name: "Credentials", Detail: fmt.Sprintf("%s targets require a credential secret", scheme)})
+	case target.CredentialSecret == "":
+		checks = append(checks, backupTargetCheck{Name: "Credentials", Passed: true, Detail: "no credential secret configured"})
+	default:
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), target.CredentialSecret, metav1.GetOptions{})
+		if err != nil {
+			detail := fmt.Sprintf("cannot read secret %s/%s: %v", namespace, target.CredentialSecret, err)
+			if apierrors.IsNotFound(err) {
+				detail = fmt.Sprintf("secret %s/%s does not exist, create it in the Longhorn namespace", namespace, target.CredentialSecret)
+			}
+			checks = append(checks, backupTargetCheck{Name: "Credentials", Detail: detail})
+			break
+		}
+
+		var missing []string
+		for _, key := range required {
+			if len(secret.Data[key]) == 0 {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			checks = append(checks, backupTargetCheck{Name: "Credentials", Detail: fmt.Sprintf("secret %s is missing keys: %s", target.CredentialSecret, strings.Join(missing, ", "))})
+		} else {
+			checks = append(checks, backupTargetCheck{Name: "Credentials", Passed: true, Detail: fmt.Sprintf("secret %s has the required keys", target.CredentialSecret)})
+		}
+	}
+
+	return checks
+}
+
+// explainBackupTargetError turns common backup store errors into actionable advice
+func explainBackupTargetError(message string) string {
+	if message == "" {
+		return "Longhorn reports the target as unavailable without a reason, check the longhorn-manager logs"
+	}
+
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "accessdenied") || strings.Contains(lower, "forbidden") || strings.Contains(lower, "signaturedoesnotmatch"):
+		return message + " (credentials are rejected, verify the access key and bucket policy)"
+	case strings.Contains(lower, "nosuchbucket"):
+		return message + " (the bucket does not exist or the region is wrong)"
+	case strings.Contains(lower, "no such host") || strings.Contains(lower, "i/o timeout") || strings.Contains(lower, "connection refused"):
+		return message + " (the endpoint is unreachable from the cluster, check DNS, firewalls and proxy settings)"
+	case strings.Contains(lower, "x509") || strings.Contains(lower, "certificate"):
+		return message + " (TLS verification failed, add the CA via AWS_CERT in the credential secret)"
+	case strings.Contains(lower, "mount") || strings.Contains(lower, "permission denied"):
+		return message + " (the share cannot be mounted, check export permissions and NFS/CIFS client packages on the nodes)"
+	}
+	return message
+}
+
+// backupTargetScheme returns the scheme of a backup target URL
+func backupTargetScheme(targetURL string) string {
+	if i := strings.Index(targetURL, "://"); i > 0 {
+		return targetURL[:i]
+	}
+	return ""
+}
+
+// backupTargetEndpoint returns the host:port to test for a backup target URL
+func backupTargetEndpoint(clientset *kubernetes.Clientset, namespace string, target BackupTargetInfo) (string, error) {
+	scheme := backupTargetScheme(target.URL)
+
+	var secretData map[string][]byte
+	if target.CredentialSecret != "" {
+		if secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), target.CredentialSecret, metav1.GetOptions{}); err == nil {
+			secretData = secret.Data
+		}
+	}
+
+	switch scheme {
+	case "s3":
+		// s3://bucket@region/path, with an optional custom endpoint in the secret
+		if endpoint := string(secretData["AWS_ENDPOINTS"]); endpoint != "" {
+			return hostPort(endpoint, "443")
+		}
+		rest := strings.TrimPrefix(target.URL, "s3://")
+		region := "us-east-1"
+		if at := strings.Index(rest, "@"); at >= 0 {
+			region = strings.SplitN(rest[at+1:], "/", 2)[0]
+		}
+		return fmt.Sprintf("s3.%s.amazonaws.com:443", region), nil
+	case "nfs":
+		// nfs://host:/export/path
+		host := strings.TrimPrefix(target.URL, "nfs://")
+		if i := strings.Index(host, ":/"); i >= 0 {
+			host = host[:i]
+		}
+		return net.JoinHostPort(host, "2049"), nil
+	case "cifs":
+		u, err := url.Parse(target.URL)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(u.Hostname(), "445"), nil
+	case "azblob":
+		if endpoint := string(secretData["AZBLOB_ENDPOINT"]); endpoint != "" {
+			return hostPort(endpoint, "443")
+		}
+		return fmt.Sprintf("%s.blob.core.windows.net:443", string(secretData["AZBLOB_ACCOUNT_NAME"])), nil
+	}
+	return "", fmt.Errorf("unsupported backup target scheme %q", scheme)
+}
+
+// hostPort extracts host:port from an endpoint URL, using the default port if none is given
+func hostPort(endpoint, defaultPort string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("cannot parse endpoint %q", endpoint)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// backupProbePod returns the pod testing TCP reachability of a backup store endpoint
+func backupProbePod(namespace, image, endpoint string) *corev1.Pod {
+	host, port, _ := net.SplitHostPort(endpoint)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "lhmon4-backup-probe-",
+			Namespace:    namespace,
+			Labels:       map[string]string{"app.kubernetes.io/name": "lhmon4-backup-probe"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    "probe",
+				Image:   image,
+				Command: []string{"sh", "-c", fmt.Sprintf("nc -z -w 5 %s %s", host, port)},
+			}},
+		},
+	}
+}
+
+// podChange returns the planned creation or deletion of a probe pod, with its container as the diff
+func podChange(verb string, pod *corev1.Pod) plannedChange {
+	change := plannedChange{Verb: verb, APIVersion: "v1", Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}
+	if change.Name == "" {
+		change.Name = pod.GenerateName
+	}
+	if verb == "create" {
+		container, _ := json.Marshal(pod.Spec.Containers[0])
+		change.Diff = string(container)
+	}
+	return change
+}
+
+// probeBackupTarget runs a short-lived pod that tests TCP reachability of the backup store from inside the cluster
+func probeBackupTarget(clientset *kubernetes.Clientset, namespace string, target BackupTargetInfo, image string, timeout time.Duration, run *action) backupTargetCheck {
+	endpoint, err := backupTargetEndpoint(clientset, namespace, target)
+	if err != nil {
+		return backupTargetCheck{Name: "Reachability", Detail: err.Error()}
+	}
+
+	pod := backupProbePod(namespace, image, endpoint)
+	var created *corev1.Pod
+	err = run.apply(podChange("create", pod), func() (string, error) {
+		var err error
+		if created, err = clientset.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			return "", err
+		}
+		return created.Name, nil
+	})
+	if err != nil {
+		return backupTargetCheck{Name: "Reachability", Detail: fmt.Sprintf("cannot create probe pod: %v", err)}
+	}
+	defer run.apply(podChange("delete", created), func() (string, error) {
+		return "", clientset.CoreV1().Pods(namespace).Delete(context.TODO(), created.Name, metav1.DeleteOptions{})
+	})
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		current, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), created.Name, metav1.GetOptions{})
+		if err != nil {
+			return backupTargetCheck{Name: "Reachability", Detail: fmt.Sprintf("cannot read probe pod: %v", err)}
+		}
+
+		switch current.Status.Phase {
+		case corev1.PodSucceeded:
+			return backupTargetCheck{Name: "Reachability", Passed: true, Detail: fmt.Sprintf("%s is reachable from the cluster", endpoint)}
+		case corev1.PodFailed:
+			return backupTargetCheck{Name: "Reachability", Detail: fmt.Sprintf("%s is not reachable from the cluster, check network policies, egress firewalls and proxies", endpoint)}
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	return backupTargetCheck{Name: "Reachability", Detail: fmt.Sprintf("probe pod did not finish within %s (image pull or scheduling problem?)", timeout)}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// benchTarget is a single List call measured by the bench subcommand
+type benchTarget struct {
+	Name string
+	List func() (int, error)
+}
+
+// benchResult holds the samples collected for one bench target
+type benchResult struct {
+	Name      string
+	Objects   int
+	Durations []time.Duration
+	Errors    int
+	LastError error
+}
+
+// runBench implements the "lhmon4 bench" subcommand
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	runs := fs.Int("runs", 5, "number of measurement runs")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *runs < 1 {
+		fmt.Println("Error: --runs must be at least 1")
+		os.Exit(1)
+	}
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	targets := benchTargets(dynClient, clientset, *clientOpts.namespace)
+	results := make([]*benchResult, len(targets))
+	for i, target := range targets {
+		results[i] = &benchResult{Name: target.Name}
+	}
+	collection := &benchResult{Name: "end-to-end collection"}
+
+	for run := 1; run <= *runs; run++ {
+		fmt.Printf("Run %d/%d...\n", run, *runs)
+
+		// Time every List call individually
+		for i, target := range targets {
+			start := time.Now()
+			count, err := target.List()
+			results[i].record(time.Since(start), count, err)
+		}
+
+		// Time the collection performed by a regular run
+		start := time.Now()
+		count, err := benchCollection(dynClient, clientset, *clientOpts.namespace)
+		collection.record(time.Since(start), count, err)
+	}
+
+	printSectionHeader(Section{
+		Title:       "COLLECTION BENCHMARK",
+		Description: fmt.Sprintf("List latencies over %d runs (qps=%g, burst=%d, page-size=%d)", *runs, *clientOpts.qps, *clientOpts.burst, *clientOpts.pageSize),
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sRESOURCE\tOBJECTS\tP50\tP95\tMIN\tMAX\tERRORS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "RESOURCE\tOBJECTS\tP50\tP95\tMIN\tMAX\tERRORS")
+	}
+
+	fmt.Fprintln(w, "────────\t───────\t───\t───\t───\t───\t──────")
+
+	for _, result := range append(results, collection) {
+		result.print(w)
+	}
+	w.Flush()
+
+	// Report the last error per failing resource so RBAC problems are visible
+	for _, result := range append(results, collection) {
+		if result.LastError != nil {
+			fmt.Printf("%s: %v\n", result.Name, result.LastError)
+		}
+	}
+}
+
+// benchTargets returns the List calls performed by lhmon4, one per resource
+func benchTargets(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) []benchTarget {
+	var targets []benchTarget
+
+	for _, resource := range []string{longhornNodes, longhornVolumes, longhornReplicas, longhornEngines, longhornInstances, longhornSettings} {
+		gvr := longhornGVR(resource)
+		targets = append(targets, benchTarget{
+			Name: resource + "." + longhornGroup,
+			List: func() (int, error) {
+				list, err := listAll(dynClient, gvr, namespace)
+				if err != nil {
+					return 0, err
+				}
+				return len(list.Items), nil
+			},
+		})
+	}
+
+	targets = append(targets,
+		benchTarget{
+			Name: "persistentvolumes",
+			List: func() (int, error) {
+				list, err := listPersistentVolumes(clientset)
+				if err != nil {
+					return 0, err
+				}
+				return len(list.Items), nil
+			},
+		},
+		benchTarget{
+			Name: "pods (all namespaces)",
+			List: func() (int, error) {
+				list, err := listPods(clientset, "", "")
+				if err != nil {
+					return 0, err
+				}
+				return len(list.Items), nil
+			},
+		},
+	)
+
+	return targets
+}
+
+// benchCollection performs the collection of a regular table report, the snapshot of the Longhorn
+// resources followed by the PV relationships, and returns the number of objects collected.
+// Resources failing to list are left out like the sections of a report leave them out.
+func benchCollection(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) (int, error) {
+	snapshot := takeSnapshot(dynClient, namespace, reportResources())
+	// The List calls timed individually must reach the API server, not the snapshot
+	defer func() { currentSnapshot = nil }()
+
+	total := 0
+	for _, list := range snapshot.lists {
+		if list != nil {
+			total += len(list.Items)
+		}
+	}
+
+	pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, namespace, longhornGVR(longhornVolumes), "", "")
+	if err != nil {
+		return 0, err
+	}
+	for _, pvInfo := range pvInfoMap {
+		total += 1 + len(pvInfo.ConsumerPods)
+	}
+
+	return total, nil
+}
+
+// record adds a single measurement to the result
+func (r *benchResult) record(d time.Duration, objects int, err error) {
+	if err != nil {
+		r.Errors++
+		r.LastError = err
+		return
+	}
+	r.Durations = append(r.Durations, d)
+	r.Objects = objects
+}
+
+// print writes the result as a table row
+func (r *benchResult) print(w *tableWriter) {
+	if len(r.Durations) == 0 {
+		fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t%s\n", r.Name, colorize(fmt.Sprintf("%d", r.Errors), Red))
+		return
+	}
+
+	sorted := make([]time.Duration, len(r.Durations))
+	copy(sorted, r.Durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	errorsColor := Green
+	if r.Errors > 0 {
+		errorsColor = Red
+	}
+
+	fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+		r.Name,
+		r.Objects,
+		colorize(formatLatency(percentile(sorted, 50)), Green),
+		colorize(formatLatency(percentile(sorted, 95)), Yellow),
+		formatLatency(sorted[0]),
+		formatLatency(sorted[len(sorted)-1]),
+		colorize(fmt.Sprintf("%d", r.Errors), errorsColor),
+	)
+}
+
+// percentile returns the nearest-rank percentile of a sorted slice of durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// formatLatency renders a duration with millisecond precision
+func formatLatency(d time.Duration) string {
+	return formatNumber(float64(d)/float64(time.Millisecond), 1) + "ms"
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// listCache keeps Longhorn resources between watch refreshes, nil outside watch mode
+var listCache *resourceCache
+
+// resourceCache serves repeated List calls from memory, applying watch events received since the
+// previous refresh instead of relisting every resource
+type resourceCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry holds the objects of one resource in one namespace and the watch keeping them current
+type cacheEntry struct {
+	items           map[string]unstructured.Unstructured
+	resourceVersion string
+	watcher         watch.Interface
+}
+
+// newResourceCache creates an empty cache
+func newResourceCache() *resourceCache {
+	return &resourceCache{entries: make(map[string]*cacheEntry)}
+}
+
+// list returns the cached objects of a resource, listing it on first use or when the watch
+// can no longer resume from the last seen resourceVersion
+func (c *resourceCache) list(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := gvr.String() + "/" + namespace
+	entry, found := c.entries[key]
+	if found && !entry.sync(dynClient, gvr, namespace) {
+		entry.stop()
+		delete(c.entries, key)
+		found = false
+	}
+
+	if !found {
+		list, err := listPages(dynClient, gvr, namespace)
+		if err != nil {
+			return nil, err
+		}
+		entry = &cacheEntry{
+			items:           make(map[string]unstructured.Unstructured),
+			resourceVersion: list.GetResourceVersion(),
+		}
+		for _, item := range list.Items {
+			entry.items[item.GetName()] = item
+		}
+		// Without a watch the entry is relisted on the next refresh
+		if entry.watch(dynClient, gvr, namespace) == nil {
+			c.entries[key] = entry
+		}
+		return list, nil
+	}
+
+	result := &unstructured.UnstructuredList{}
+	for _, item := range entry.items {
+		result.Items = append(result.Items, item)
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		return result.Items[i].GetName() < result.Items[j].GetName()
+	})
+	result.SetResourceVersion(entry.resourceVersion)
+	return result, nil
+}
+
+// watch starts watching the resource from the entry's resourceVersion
+func (e *cacheEntry) watch(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) error {
+	watcher, err := dynClient.Resource(gvr).Namespace(namespace).Watch(context.TODO(), metav1.ListOptions{
+		ResourceVersion:     e.resourceVersion,
+		AllowWatchBookmarks: true,
+	})
+	if err != nil {
+		return err
+	}
+	e.watcher = watcher
+	return nil
+}
+
+// sync applies the pending watch events, returning false if the entry must be relisted
+func (e *cacheEntry) sync(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) bool {
+	for {
+		select {
+		case event, ok := <-e.watcher.ResultChan():
+			if !ok {
+				// The API server closes watches periodically, resume from the last bookmark
+				return e.watch(dynClient, gvr, namespace) == nil
+			}
+			if !e.apply(event) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// apply updates the cached objects from a watch event, returning false on watch errors such as
+// an expired resourceVersion
+func (e *cacheEntry) apply(event watch.Event) bool {
+	if event.Type == watch.Error {
+		return false
+	}
+
+	obj, ok := event.Object.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	e.resourceVersion = obj.GetResourceVersion()
+
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		e.items[obj.GetName()] = *obj
+	case watch.Deleted:
+		delete(e.items, obj.GetName())
+	}
+	return true
+}
+
+// stop ends the entry's watch
+func (e *cacheEntry) stop() {
+	if e.watcher != nil {
+		e.watcher.Stop()
+	}
+}
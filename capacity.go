@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Longhorn settings controlling replica scheduling, with their Longhorn defaults
+const (
+	overProvisioningSetting        = "storage-over-provisioning-percentage"
+	minimalAvailableSetting        = "storage-minimal-available-percentage"
+	defaultOverProvisioningPercent = 100
+	defaultMinimalAvailablePercent = 25
+)
+
+// NodeCapacityInfo stores the capacity a node can still accept for new replicas
+type NodeCapacityInfo struct {
+	NodeName         string
+	SchedulableDisks int
+	TotalDisks       int
+	Remaining        ByteSize // Sum of what each schedulable disk can still accept
+	LargestVolume    ByteSize // Largest replica fitting on a single disk
+	Unschedulable    string   // Why the node accepts no replicas, empty otherwise
+	OverProvisioning int
+	MinimalAvailable int
+}
+
+// diskSchedulableSize returns the largest replica a disk can accept. Longhorn only schedules to a
+// disk whose free space stays above the minimal available percentage, a yes or no gate, and then
+// limits the size so the scheduled storage stays within the over-provisioned capacity after the
+// reservation.
+func diskSchedulableSize(maximum, reserved, scheduled, available ByteSize, overProvisioning, minimalAvailable int) ByteSize {
+	if !diskAboveMinimalAvailable(maximum, available, minimalAvailable) {
+		return 0
+	}
+	size := (maximum-reserved)*ByteSize(overProvisioning)/100 - scheduled
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// diskAboveMinimalAvailable returns true if the free space of a disk is above the minimal available
+// percentage, the gate for scheduling any replica to it
+func diskAboveMinimalAvailable(maximum, available ByteSize, minimalAvailable int) bool {
+	return maximum > 0 && available > maximum*ByteSize(minimalAvailable)/100
+}
+
+// getSchedulingSettings returns the over-provisioning and minimal available percentages,
+// falling back to the Longhorn defaults
+func getSchedulingSettings(dynClient dynamic.Interface, namespace string) (overProvisioning, minimalAvailable int, err error) {
+	overProvisioning = defaultOverProvisioningPercent
+	value, err := getLonghornSetting(dynClient, namespace, overProvisioningSetting)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		overProvisioning = n
+	}
+
+	minimalAvailable = defaultMinimalAvailablePercent
+	value, err = getLonghornSetting(dynClient, namespace, minimalAvailableSetting)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		minimalAvailable = n
+	}
+	return overProvisioning, minimalAvailable, nil
+}
+
+// nodeUnschedulable returns why a node accepts no new replicas, or "" when it does
+func nodeUnschedulable(node unstructured.Unstructured) string {
+	allowScheduling, found, _ := unstructured.NestedBool(node.Object, "spec", "allowScheduling")
+	evictionRequested, _, _ := unstructured.NestedBool(node.Object, "spec", "evictionRequested")
+	switch {
+	case found && !allowScheduling:
+		return "scheduling disabled on node"
+	case evictionRequested:
+		return "eviction requested on node"
+	case !conditionTrue(node.Object, "Schedulable"):
+		return "node is not schedulable"
+	}
+	return ""
+}
+
+// diskAcceptsReplicas returns true if a disk is open to new replicas and reported schedulable
+func diskAcceptsReplicas(diskSpec, diskStatus map[string]interface{}) bool {
+	if allow, ok := diskSpec["allowScheduling"].(bool); ok && !allow {
+		return false
+	}
+	if evict, _ := diskSpec["evictionRequested"].(bool); evict {
+		return false
+	}
+	return diskStatus != nil && conditionTrue(diskStatus, "Schedulable")
+}
+
+// getSchedulingCapacity computes the remaining schedulable capacity of every Longhorn node
+func getSchedulingCapacity(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) ([]NodeCapacityInfo, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	overProvisioning, minimalAvailable, err := getSchedulingSettings(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var capacities []NodeCapacityInfo
+	for _, node := range nodes.Items {
+		info := NodeCapacityInfo{
+			NodeName:         node.GetName(),
+			OverProvisioning: overProvisioning,
+			MinimalAvailable: minimalAvailable,
+		}
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		diskStatusMap, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus")
+		info.TotalDisks = len(disksMap)
+
+		info.Unschedulable = nodeUnschedulable(node)
+		if info.Unschedulable != "" {
+			capacities = append(capacities, info)
+			continue
+		}
+
+		for diskName, d := range disksMap {
+			diskSpec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			diskStatus, _ := diskStatusMap[diskName].(map[string]interface{})
+			if !diskAcceptsReplicas(diskSpec, diskStatus) {
+				continue
+			}
+
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+
+			size := diskSchedulableSize(ByteSize(maximum), ByteSize(reserved), ByteSize(scheduled), ByteSize(available), overProvisioning, minimalAvailable)
+			info.SchedulableDisks++
+			info.Remaining += size
+			if size > info.LargestVolume {
+				info.LargestVolume = size
+			}
+		}
+
+		if info.SchedulableDisks == 0 {
+			info.Unschedulable = "no schedulable disks"
+		}
+		capacities = append(capacities, info)
+	}
+
+	sort.Slice(capacities, func(i, j int) bool {
+		return capacities[i].NodeName < capacities[j].NodeName
+	})
+
+	return capacities, nil
+}
+
+// conditionTrue returns true if the status conditions of an object report the condition as True.
+// A missing condition is treated as true, older Longhorn versions do not report every condition.
+func conditionTrue(obj map[string]interface{}, conditionType string) bool {
+	conditions, found, _ := unstructured.NestedSlice(obj, "conditions")
+	if !found {
+		conditions, _, _ = unstructured.NestedSlice(obj, "status", "conditions")
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condType, _ := condition["type"].(string); condType == conditionType {
+			status, _ := condition["status"].(string)
+			return status == "True"
+		}
+	}
+	return true
+}
+
+// printSchedulingCapacity prints the remaining schedulable capacity and largest schedulable volume per node
+func printSchedulingCapacity(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) {
+	capacities, err := getSchedulingCapacity(dynClient, namespace, nodesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	description := "Capacity left for new replicas"
+	if len(capacities) > 0 {
+		description = fmt.Sprintf("Capacity left for new replicas (over-provisioning %d%%, minimal available %d%%)",
+			capacities[0].OverProvisioning, capacities[0].MinimalAvailable)
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "SCHEDULING CAPACITY",
+		Description: description,
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISKS\tREMAINING\tLARGEST VOLUME\tSTATUS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISKS\tREMAINING\tLARGEST VOLUME\tSTATUS")
+	}
+
+	fmt.Fprintln(w, "────\t─────\t─────────\t──────────────\t──────")
+
+	var largest ByteSize
+	for _, capacity := range capacities {
+		status := colorize("Schedulable", Green)
+		if capacity.Unschedulable != "" {
+			status = colorize(capacity.Unschedulable, Red)
+		} else if capacity.LargestVolume == 0 {
+			status = colorize("Full", Red)
+		}
+		if capacity.LargestVolume > largest {
+			largest = capacity.LargestVolume
+		}
+
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\n",
+			capacity.NodeName,
+			capacity.SchedulableDisks,
+			capacity.TotalDisks,
+			capacity.Remaining,
+			capacity.LargestVolume,
+			status,
+		)
+	}
+
+	if len(capacities) == 0 {
+		fmt.Fprintln(w, "No Longhorn nodes found")
+	}
+
+	w.Flush()
+
+	if len(capacities) > 0 {
+		fmt.Printf("Largest volume schedulable on any node: %s\n", colorize(largest.String(), Bold))
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClassDrift is a volume setting that no longer matches the parameter of its StorageClass
+type ClassDrift struct {
+	VolumeName   string
+	StorageClass string
+	Parameter    string
+	ClassValue   string
+	VolumeValue  string
+}
+
+// driftRemediation is the merge patch restoring the StorageClass values of one volume
+type driftRemediation struct {
+	VolumeName string
+	Patch      []byte
+}
+
+// change returns the planned patch of the volume
+func (r driftRemediation) change(namespace string) plannedChange {
+	return plannedChange{
+		Verb:       "patch",
+		APIVersion: longhornGroup + "/" + longhornVersion,
+		Kind:       "Volume",
+		Namespace:  namespace,
+		Name:       r.VolumeName,
+		Diff:       string(r.Patch),
+	}
+}
+
+// command returns the kubectl command applying the patch
+func (r driftRemediation) command(namespace string) string {
+	return fmt.Sprintf("kubectl -n %s patch volumes.longhorn.io %s --type merge -p '%s'", namespace, r.VolumeName, r.Patch)
+}
+
+// normalizeSelector sorts a comma separated selector so the order of tags does not count as drift
+func normalizeSelector(tags []string) string {
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// findStorageClassDrift compares the replica count, selectors and data locality of each volume
+// with the parameters of the StorageClass it was provisioned from. Parameters the StorageClass
+// leaves unset follow the Longhorn defaults and are not compared.
+func findStorageClassDrift(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) ([]ClassDrift, error) {
+	classes, err := clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list StorageClasses: %v", err)
+	}
+	parameters := make(map[string]map[string]string)
+	for _, class := range classes.Items {
+		if class.Provisioner == longhornCSIDriver {
+			parameters[class.Name] = class.Parameters
+		}
+	}
+
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	var drifts []ClassDrift
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+		className := pvInfoMap[volumeName].StorageClass
+		params, found := parameters[className]
+		if !found {
+			continue
+		}
+
+		replicas, _, _ := unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
+		diskSelector, _, _ := unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
+		nodeSelector, _, _ := unstructured.NestedStringSlice(volume.Object, "spec", "nodeSelector")
+		dataLocality, _, _ := unstructured.NestedString(volume.Object, "spec", "dataLocality")
+
+		actual := map[string]string{
+			"numberOfReplicas": strconv.FormatInt(replicas, 10),
+			"diskSelector":     normalizeSelector(diskSelector),
+			"nodeSelector":     normalizeSelector(nodeSelector),
+			"dataLocality":     dataLocality,
+		}
+		for _, parameter := range []string{"numberOfReplicas", "diskSelector", "nodeSelector", "dataLocality"} {
+			declared, set := params[parameter]
+			if !set {
+				continue
+			}
+			if parameter == "diskSelector" || parameter == "nodeSelector" {
+				declared = normalizeSelector(splitTags(declared))
+			}
+			if declared == actual[parameter] {
+				continue
+			}
+			drifts = append(drifts, ClassDrift{
+				VolumeName:   volumeName,
+				StorageClass: className,
+				Parameter:    parameter,
+				ClassValue:   declared,
+				VolumeValue:  actual[parameter],
+			})
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].VolumeName < drifts[j].VolumeName
+	})
+
+	return drifts, nil
+}
+
+// driftRemediations groups the drifts per volume into one patch of the volume spec. The Longhorn
+// manager reconciles the replica count, selectors and data locality of attached volumes as well.
+func driftRemediations(drifts []ClassDrift) ([]driftRemediation, error) {
+	specs := make(map[string]map[string]interface{})
+	var volumeNames []string
+	for _, drift := range drifts {
+		spec, found := specs[drift.VolumeName]
+		if !found {
+			spec = make(map[string]interface{})
+			specs[drift.VolumeName] = spec
+			volumeNames = append(volumeNames, drift.VolumeName)
+		}
+		switch drift.Parameter {
+		case "numberOfReplicas":
+			replicas, err := strconv.Atoi(drift.ClassValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid numberOfReplicas %q of StorageClass %s", drift.ClassValue, drift.StorageClass)
+			}
+			spec[drift.Parameter] = replicas
+		case "diskSelector", "nodeSelector":
+			// An empty list rather than null, so the merge patch clears the selector
+			spec[drift.Parameter] = append([]string{}, splitTags(drift.ClassValue)...)
+		default:
+			spec[drift.Parameter] = drift.ClassValue
+		}
+	}
+
+	remediations := make([]driftRemediation, 0, len(volumeNames))
+	for _, volumeName := range volumeNames {
+		patch, err := json.Marshal(map[string]interface{}{"spec": specs[volumeName]})
+		if err != nil {
+			return nil, err
+		}
+		remediations = append(remediations, driftRemediation{VolumeName: volumeName, Patch: patch})
+	}
+	return remediations, nil
+}
+
+// applyRemediation patches a volume back to the values of its StorageClass
+func applyRemediation(dynClient dynamic.Interface, namespace string, remediation driftRemediation) error {
+	_, err := dynClient.Resource(longhornGVR(longhornVolumes)).Namespace(namespace).Patch(context.TODO(), remediation.VolumeName, types.MergePatchType, remediation.Patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch Longhorn volume %s: %v", remediation.VolumeName, err)
+	}
+	return nil
+}
+
+// runDrift implements the "lhmon4 drift" subcommand
+func runDrift(args []string) {
+	fs := flag.NewFlagSet("drift", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	apply := fs.Bool("apply", false, "patch the drifted volumes back to their StorageClass values after confirmation")
+	actionOpts := registerActionFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	namespace := *clientOpts.namespace
+	volumesGVR := longhornGVR(longhornVolumes)
+	pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, namespace, volumesGVR, "", "")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	drifts, err := findStorageClassDrift(dynClient, clientset, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	remediations, err := driftRemediations(drifts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printClassDrifts(drifts, remediations, namespace)
+	if !*apply || len(remediations) == 0 {
+		return
+	}
+
+	changes := make([]plannedChange, 0, len(remediations))
+	for _, remediation := range remediations {
+		changes = append(changes, remediation.change(namespace))
+	}
+	run, err := actionOpts.prepare("drift --apply", clientOpts, clientset, changes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if run == nil {
+		return
+	}
+
+	failed := false
+	for _, remediation := range remediations {
+		err := run.apply(remediation.change(namespace), func() (string, error) {
+			return "", applyRemediation(dynClient, namespace, remediation)
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Patched volume %s\n", colorize(remediation.VolumeName, Green))
+	}
+	run.close()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printStorageClassDrift prints volume settings that differ from their StorageClass parameters
+func printStorageClassDrift(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	drifts, err := findStorageClassDrift(dynClient, clientset, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	remediations, err := driftRemediations(drifts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	printClassDrifts(drifts, remediations, namespace)
+}
+
+// printClassDrifts prints the drifted volume settings and the commands restoring them
+func printClassDrifts(drifts []ClassDrift, remediations []driftRemediation, namespace string) {
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "STORAGE CLASS DRIFT",
+		Description: "Volume settings changed since provisioning, e.g. edited in the Longhorn UI, or StorageClasses changed afterwards",
+		Color:       Yellow,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSTORAGE CLASS\tPARAMETER\tSTORAGE CLASS VALUE\tVOLUME VALUE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSTORAGE CLASS\tPARAMETER\tSTORAGE CLASS VALUE\tVOLUME VALUE")
+	}
+
+	fmt.Fprintln(w, "──────\t─────────────\t─────────\t───────────────────\t────────────")
+
+	for _, drift := range drifts {
+		volumeValue := drift.VolumeValue
+		if volumeValue == "" {
+			volumeValue = "-"
+		}
+		classValue := drift.ClassValue
+		if classValue == "" {
+			classValue = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			drift.VolumeName,
+			drift.StorageClass,
+			drift.Parameter,
+			colorize(classValue, Green),
+			colorize(volumeValue, Yellow),
+		)
+	}
+
+	if len(drifts) == 0 {
+		fmt.Fprintln(w, "No volumes differ from their StorageClass")
+	}
+
+	w.Flush()
+
+	if len(remediations) > 0 {
+		fmt.Printf("\nRestore the StorageClass values with the following commands, or run %s drift --apply --read-only=false:\n", commandName)
+		for _, remediation := range remediations {
+			if useColors {
+				fmt.Printf("  %s%s%s\n", Bold+Cyan, remediation.command(namespace), Reset)
+			} else {
+				fmt.Printf("  %s\n", remediation.command(namespace))
+			}
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Issue categories assigned to failed conditions
+const (
+	issueDiskTag         = "disk-tag"
+	issueCapacity        = "capacity"
+	issueNodeSelector    = "node-selector"
+	issueAntiAffinity    = "anti-affinity"
+	issueNodeUnavailable = "node-unavailable"
+	issueEngineImage     = "engine-image"
+	issueCreation        = "creation-error"
+	issueAttach          = "attach-error"
+	issueUnknown         = "unknown"
+)
+
+// issuePattern assigns a category to conditions matching a reason code and/or a message pattern
+type issuePattern struct {
+	Category string `json:"category"`
+	Reason   string `json:"reason,omitempty"`  // Condition reason, matched exactly
+	Pattern  string `json:"pattern,omitempty"` // Regular expression matched against the message
+
+	re *regexp.Regexp
+}
+
+// builtinIssuePatterns covers the messages of the Longhorn versions seen so far. Reason codes are
+// preferred, the wording of messages changes between releases.
+var builtinIssuePatterns = []issuePattern{
+	{Category: issueDiskTag, Pattern: `(?i)tags? not fulfilled|no disk matches requirements`},
+	{Category: issueCapacity, Pattern: `(?i)insufficient storage|not enough (disk )?space`},
+	{Category: issueNodeSelector, Pattern: `(?i)node tag|node selector`},
+	{Category: issueAntiAffinity, Pattern: `(?i)anti-?affinity|affinity cannot be satisfied`},
+	{Category: issueNodeUnavailable, Pattern: `(?i)(nodes?|disks?) (are|is) unavailable`},
+	{Category: issueEngineImage, Pattern: `(?i)engine image .*not (ready|deployed)`},
+	{Category: issueCreation, Pattern: `(?i)error creating|create volume error`},
+	{Category: issueAttach, Reason: "AttachFailed"},
+	{Category: issueAttach, Pattern: `(?i)error attaching|failed to attach`},
+}
+
+// issuePatterns are the patterns in use, patterns from the config file take precedence
+var issuePatterns = mustCompileIssuePatterns(builtinIssuePatterns)
+
+// mustCompileIssuePatterns compiles patterns known to be valid
+func mustCompileIssuePatterns(patterns []issuePattern) []issuePattern {
+	compiled, err := compileIssuePatterns(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// compileIssuePatterns validates the patterns and compiles their regular expressions
+func compileIssuePatterns(patterns []issuePattern) ([]issuePattern, error) {
+	compiled := make([]issuePattern, 0, len(patterns))
+	for i, p := range patterns {
+		if p.Category == "" {
+			return nil, fmt.Errorf("issue pattern %d has no category", i+1)
+		}
+		if p.Reason == "" && p.Pattern == "" {
+			return nil, fmt.Errorf("issue pattern %d (%s) needs a reason or a pattern", i+1, p.Category)
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("issue pattern %d (%s): %v", i+1, p.Category, err)
+			}
+			p.re = re
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// setIssuePatterns puts the user's patterns in front of the built-in ones
+func setIssuePatterns(patterns []issuePattern) error {
+	custom, err := compileIssuePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	issuePatterns = append(custom, mustCompileIssuePatterns(builtinIssuePatterns)...)
+	return nil
+}
+
+// classifyCondition returns the category of the first pattern matching the condition
+func classifyCondition(reason, message string) string {
+	for _, p := range issuePatterns {
+		if p.Reason != "" && p.Reason != reason {
+			continue
+		}
+		if p.re != nil && !p.re.MatchString(message) {
+			continue
+		}
+		return p.Category
+	}
+	return issueUnknown
+}
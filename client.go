@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// longhornCSIDriver is the name of the Longhorn CSI driver and StorageClass provisioner
+const longhornCSIDriver = "driver.longhorn.io"
+
+// listPageSize is the number of objects requested per List call (0 disables pagination)
+var listPageSize int64
+
+// flagShorthands maps the kubectl-style short flags to the flags they stand for
+var flagShorthands = map[string]string{"n": "namespace"}
+
+// clientFlags holds the flags shared by every command that talks to the cluster
+type clientFlags struct {
+	kubeconfig *string
+	context    *string
+	namespace  *string
+	qps        *float64
+	burst      *int
+	pageSize   *int64
+	maxConc    *int
+	readOnly   *bool
+	replay     *string
+}
+
+// registerClientFlags registers the cluster connection flags on the given flag set
+func registerClientFlags(fs *flag.FlagSet) clientFlags {
+	var cf clientFlags
+	cf.kubeconfig = fs.String("kubeconfig", "", "path to the kubeconfig file (default $KUBECONFIG or ~/.kube/config, like kubectl)")
+	cf.context = fs.String("context", "", "name of the kubeconfig context to use (default the current context)")
+	cf.namespace = fs.String("namespace", "longhorn-system", "namespace for Longhorn resources")
+	fs.StringVar(cf.namespace, "n", "longhorn-system", "shorthand for --namespace")
+	cf.qps = fs.Float64("qps", 0, "maximum queries per second to the API server (0 uses the client-go default)")
+	cf.burst = fs.Int("burst", 0, "maximum burst of queries to the API server (0 uses the client-go default)")
+	cf.pageSize = fs.Int64("page-size", 0, "number of objects to request per List call (0 disables pagination)")
+	cf.maxConc = fs.Int("max-concurrency", maxConcurrency, "maximum simultaneous API calls, including per-namespace pod listings")
+	cf.readOnly = fs.Bool("read-only", true, "only use the get, list and watch verbs; commands that modify the cluster need --read-only=false")
+	cf.replay = fs.String("replay", "", "serve the Longhorn resources from the fixtures in this directory, written by \"dev dump-fixtures\", instead of a cluster")
+	return cf
+}
+
+// clientConfig loads the kubeconfig like kubectl: --kubeconfig, else the files listed in $KUBECONFIG,
+// else ~/.kube/config, falling back to the in-cluster config when none exists
+func (cf clientFlags) clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *cf.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: *cf.context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// newClients builds the dynamic and typed Kubernetes clients from the parsed flags
+func (cf clientFlags) newClients() (dynamic.Interface, *kubernetes.Clientset, error) {
+	// Get Kubernetes config, or reproduce a dumped cluster
+	var config *rest.Config
+	var err error
+	if *cf.replay != "" {
+		config, err = newReplayConfig(*cf.replay)
+	} else if config, err = cf.clientConfig().ClientConfig(); err != nil {
+		err = fmt.Errorf("error building kubeconfig: %v", err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *cf.qps > 0 {
+		config.QPS = float32(*cf.qps)
+	}
+	if *cf.burst > 0 {
+		config.Burst = *cf.burst
+	}
+	listPageSize = *cf.pageSize
+
+	// Count the requests actually sent, innermost so refused and queued requests are not counted twice
+	if runStats != nil {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &statsTransport{next: rt, stats: runStats}
+		})
+	}
+
+	// Bound the requests in flight, protecting fragile or heavily-loaded API servers
+	if *cf.maxConc > 0 {
+		maxConcurrency = *cf.maxConc
+		slots := make(chan struct{}, maxConcurrency)
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &limitedTransport{next: rt, slots: slots}
+		})
+	}
+
+	// Refuse every mutating request unless read-only mode was turned off explicitly
+	if *cf.readOnly {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &readOnlyTransport{next: rt}
+		})
+	}
+
+	// Create dynamic client for CRDs
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating dynamic client: %v", err)
+	}
+
+	// Core resources support protobuf, which decodes much faster than JSON on large pod lists.
+	// CRDs are JSON only, so the dynamic client keeps the default content type.
+	coreConfig := rest.CopyConfig(config)
+	coreConfig.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	coreConfig.ContentType = runtime.ContentTypeProtobuf
+
+	// Create standard client for core resources
+	clientset, err := kubernetes.NewForConfig(coreConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating Kubernetes client: %v", err)
+	}
+
+	return dynClient, clientset, nil
+}
+
+// longhornGVR returns the GroupVersionResource for a Longhorn CRD
+func longhornGVR(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: longhornGroup, Version: longhornVersion, Resource: resource}
+}
+
+// listAll lists all objects of a resource, served from the snapshot of the report being rendered
+// or from the watch cache in watch mode
+func listAll(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list, err := listLonghorn(dynClient, gvr, namespace)
+	if list != nil {
+		runStats.addObjects(len(list.Items))
+	}
+	return list, err
+}
+
+// listLonghorn lists a resource from the snapshot, the watch cache or the API server, in that order
+func listLonghorn(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	if currentSnapshot != nil {
+		if list, found, err := currentSnapshot.list(gvr, namespace); found {
+			return list, err
+		}
+	}
+	if listCache != nil {
+		return listCache.list(dynClient, gvr, namespace)
+	}
+	return listPages(dynClient, gvr, namespace)
+}
+
+// listPages lists all objects of a resource, following continue tokens when pagination is enabled
+func listPages(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	result := &unstructured.UnstructuredList{}
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := dynClient.Resource(gvr).Namespace(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Items = append(result.Items, page.Items...)
+		if page.GetContinue() == "" {
+			result.SetResourceVersion(page.GetResourceVersion())
+			return result, nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// forEachPage calls fn for every object of a resource one page at a time, so only a single page is
+// held in memory. It bypasses the snapshot and watch cache.
+func forEachPage(dynClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string, fn func(obj unstructured.Unstructured) error) error {
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := dynClient.Resource(gvr).Namespace(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return err
+		}
+
+		runStats.addObjects(len(page.Items))
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if page.GetContinue() == "" {
+			return nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// listPersistentVolumes lists all PersistentVolumes, following continue tokens when pagination is enabled
+func listPersistentVolumes(clientset *kubernetes.Clientset) (*corev1.PersistentVolumeList, error) {
+	result := &corev1.PersistentVolumeList{}
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := clientset.CoreV1().PersistentVolumes().List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		runStats.addObjects(len(page.Items))
+		result.Items = append(result.Items, page.Items...)
+		if page.Continue == "" {
+			result.ResourceVersion = page.ResourceVersion
+			return result, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// listNamedPersistentVolumes lists the PersistentVolumes page by page, keeping the named ones. One
+// paged list costs far fewer requests than a get per volume. PVs deleted since their name was
+// recorded are left out.
+func listNamedPersistentVolumes(clientset *kubernetes.Clientset, names []string) ([]corev1.PersistentVolume, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	list, err := listPersistentVolumes(clientset)
+	if err != nil {
+		return nil, err
+	}
+	var pvs []corev1.PersistentVolume
+	for _, pv := range list.Items {
+		if wanted[pv.Name] {
+			pvs = append(pvs, pv)
+		}
+	}
+	sort.Slice(pvs, func(i, j int) bool {
+		return pvs[i].Name < pvs[j].Name
+	})
+	return pvs, nil
+}
+
+// listPersistentVolumeClaims lists the PVCs of all namespaces, following continue tokens when pagination is enabled
+func listPersistentVolumeClaims(clientset *kubernetes.Clientset) (*corev1.PersistentVolumeClaimList, error) {
+	result := &corev1.PersistentVolumeClaimList{}
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		page, err := clientset.CoreV1().PersistentVolumeClaims("").List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		runStats.addObjects(len(page.Items))
+		result.Items = append(result.Items, page.Items...)
+		if page.Continue == "" {
+			result.ResourceVersion = page.ResourceVersion
+			return result, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// listLonghornStorageClasses returns the names of the StorageClasses provisioned by Longhorn
+func listLonghornStorageClasses(clientset *kubernetes.Clientset) (map[string]bool, error) {
+	list, err := clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	runStats.addObjects(len(list.Items))
+
+	classes := make(map[string]bool)
+	for _, class := range list.Items {
+		if class.Provisioner == longhornCSIDriver {
+			classes[class.Name] = true
+		}
+	}
+	return classes, nil
+}
+
+// pvcStorageClass returns the StorageClass of a PVC, including the legacy beta annotation
+func pvcStorageClass(pvc corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName
+	}
+	return pvc.Annotations[corev1.BetaStorageClassAnnotation]
+}
+
+// activePodsFieldSelector excludes completed pods, which no longer use their volumes
+const activePodsFieldSelector = "status.phase!=Succeeded,status.phase!=Failed"
+
+// listPods lists the pods in a namespace matching a field selector, following continue tokens when
+// pagination is enabled
+func listPods(clientset *kubernetes.Clientset, namespace, fieldSelector string) (*corev1.PodList, error) {
+	result := &corev1.PodList{}
+	opts := metav1.ListOptions{Limit: listPageSize, FieldSelector: fieldSelector}
+	for {
+		page, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		runStats.addObjects(len(page.Items))
+		result.Items = append(result.Items, page.Items...)
+		if page.Continue == "" {
+			result.ResourceVersion = page.ResourceVersion
+			return result, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// listEvents lists the events in a namespace matching a field selector, following continue tokens
+// when pagination is enabled
+func listEvents(clientset *kubernetes.Clientset, namespace, fieldSelector string) (*corev1.EventList, error) {
+	result := &corev1.EventList{}
+	opts := metav1.ListOptions{Limit: listPageSize, FieldSelector: fieldSelector}
+	for {
+		page, err := clientset.CoreV1().Events(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		runStats.addObjects(len(page.Items))
+		result.Items = append(result.Items, page.Items...)
+		if page.Continue == "" {
+			result.ResourceVersion = page.ResourceVersion
+			return result, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// getLonghornSetting returns the value of a Longhorn setting, or "" when the setting does not exist
+func getLonghornSetting(dynClient dynamic.Interface, namespace, name string) (string, error) {
+	setting, err := dynClient.Resource(longhornGVR(longhornSettings)).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get Longhorn setting %s: %v", name, err)
+	}
+
+	value, _, _ := unstructured.NestedString(setting.Object, "value")
+	return value, nil
+}
+
+// listEnginesByVolume lists all Longhorn engines and maps them by volume name
+func listEnginesByVolume(dynClient dynamic.Interface, namespace string) (map[string]unstructured.Unstructured, error) {
+	engines, err := listAll(dynClient, longhornGVR(longhornEngines), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn engines: %v", err)
+	}
+
+	volumeEngines := make(map[string]unstructured.Unstructured)
+	for _, engine := range engines.Items {
+		volumeName, _, _ := unstructured.NestedString(engine.Object, "spec", "volumeName")
+		volumeEngines[volumeName] = engine
+	}
+	return volumeEngines, nil
+}
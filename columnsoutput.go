@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// outputCustomColumns selects tables of chosen fields, -o custom-columns=NAME:.name,STATE:.state
+const outputCustomColumns = "custom-columns"
+
+// documentColumn is a column of -o custom-columns showing a field of the report rows
+type documentColumn struct {
+	Header string
+	Path   []string
+}
+
+// documentColumns are the columns of -o custom-columns
+var documentColumns []documentColumn
+
+// setDocumentColumns parses the kubectl style column list of -o custom-columns=. Paths use the
+// field names of -o json, e.g. NAME:.name,AVAILABLE:.storageAvailable.
+func setDocumentColumns(spec string) error {
+	var columns []documentColumn
+	for _, column := range strings.Split(spec, ",") {
+		header, path, found := strings.Cut(column, ":")
+		path = strings.TrimPrefix(strings.Trim(path, "{}"), ".")
+		if !found || header == "" || path == "" {
+			return fmt.Errorf("invalid custom column %q, expected HEADER:.field", column)
+		}
+		columns = append(columns, documentColumn{Header: strings.ToUpper(header), Path: strings.Split(path, ".")})
+	}
+	documentColumns = columns
+	return nil
+}
+
+// documentRows converts report rows into the field maps of their -o json form. Numbers are kept
+// as written so byte counts do not turn into floating point.
+func documentRows(rows interface{}) ([]map[string]interface{}, error) {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields []map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// renderCustomColumns prints the disks, volumes and replicas of the report with the chosen columns.
+// Tables whose rows have none of the fields are left out, so NAME:.name,STATE:.state prints the
+// volumes and replicas but not the disks.
+func renderCustomColumns(doc reportDocument) error {
+	type table struct {
+		title string
+		rows  []map[string]interface{}
+	}
+	var tables []table
+	for _, section := range []struct {
+		title string
+		rows  interface{}
+	}{
+		{"DISKS", doc.Disks},
+		{"VOLUMES", doc.Volumes},
+		{"REPLICAS", doc.Replicas},
+	} {
+		rows, err := documentRows(section.rows)
+		if err != nil {
+			return err
+		}
+		for _, column := range documentColumns {
+			if hasField(rows, column.Path) {
+				tables = append(tables, table{title: section.title, rows: rows})
+				break
+			}
+		}
+	}
+
+	headers := make([]string, len(documentColumns))
+	separators := make([]string, len(documentColumns))
+	for i, column := range documentColumns {
+		headers[i] = column.Header
+		separators[i] = strings.Repeat("─", len(column.Header))
+	}
+
+	for i, t := range tables {
+		// Titles only separate the tables when there is more than one
+		if len(tables) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(colorize(t.title, Bold))
+		}
+
+		w := newTableWriter()
+		if useColors {
+			fmt.Fprintf(w, "%s%s%s%s\n", Bold, Yellow, strings.Join(headers, "\t"), Reset)
+		} else {
+			fmt.Fprintln(w, strings.Join(headers, "\t"))
+		}
+		fmt.Fprintln(w, strings.Join(separators, "\t"))
+
+		for _, row := range t.rows {
+			cells := make([]string, len(documentColumns))
+			for j, column := range documentColumns {
+				value, found, err := unstructured.NestedFieldNoCopy(row, column.Path...)
+				cells[j] = formatCustomValue(value, found && err == nil)
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+		w.Flush()
+	}
+
+	if len(tables) == 0 {
+		fmt.Println("No disks, volumes or replicas with these fields found")
+	}
+	return nil
+}
+
+// hasField returns true if any row has the field, optional fields are left out of rows without them
+func hasField(rows []map[string]interface{}, path []string) bool {
+	for _, row := range rows {
+		if _, found, err := unstructured.NestedFieldNoCopy(row, path...); found && err == nil {
+			return true
+		}
+	}
+	return false
+}
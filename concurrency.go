@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// maxConcurrency bounds simultaneous API calls and parallel collection work
+var maxConcurrency = 8
+
+// limitedTransport wraps a round tripper so at most a fixed number of requests are in flight.
+// The slots are shared by every client built from the same config.
+// Watches are long-lived and would hold a slot forever, so they are not limited.
+type limitedTransport struct {
+	next  http.RoundTripper
+	slots chan struct{}
+}
+
+// RoundTrip sends the request once a slot is available
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("watch") == "true" {
+		return t.next.RoundTrip(req)
+	}
+
+	t.slots <- struct{}{}
+	defer func() { <-t.slots }()
+	return t.next.RoundTrip(req)
+}
+
+// forEachConcurrently calls fn for every item, running at most maxConcurrency calls at a time
+func forEachConcurrently(items []string, fn func(item string)) {
+	limit := maxConcurrency
+	if limit < 1 {
+		limit = 1
+	}
+
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(item string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
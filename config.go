@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+)
+
+// configFile overrides the location of the configuration file
+var configFile string
+
+// monitorConfig is the optional configuration file of lhmon4
+type monitorConfig struct {
+	AvailabilityPolicies []availabilityPolicy `json:"availabilityPolicies,omitempty"`
+	IssuePatterns        []issuePattern       `json:"issuePatterns,omitempty"` // Checked before the built-in patterns
+	Runbooks             map[string]string    `json:"runbooks,omitempty"`      // Issue category -> runbook URL
+	MaintenanceWindows   []maintenanceWindow  `json:"maintenanceWindows,omitempty"`
+	Notifiers            []notifierConfig     `json:"notifiers,omitempty"` // Backends notified by "lhmon4 issues --follow"
+
+	// Flag defaults by flag name, e.g. namespace: storage or nocolor: true, for every command
+	// defining the flag. Flags given on the command line take precedence.
+	Defaults map[string]interface{} `json:"defaults,omitempty"`
+}
+
+// configPath returns the path of the configuration file, defaulting to the user's config directory
+func configPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config directory: %v", err)
+	}
+	return filepath.Join(dir, "lhmon4", "config.yaml"), nil
+}
+
+// readConfig reads the configuration file. A missing default file yields an empty configuration,
+// a missing file passed with --config is an error.
+func readConfig() (*monitorConfig, string, error) {
+	config := &monitorConfig{}
+
+	path, err := configPath()
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && configFile == "" {
+			return config, path, nil
+		}
+		return nil, "", fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	// Unknown keys are rejected so typos do not silently disable a policy
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return config, path, nil
+}
+
+// loadConfig reads the configuration file and installs its issue patterns
+func loadConfig() (*monitorConfig, error) {
+	config, path, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, policy := range config.AvailabilityPolicies {
+		if policy.MinHealthyReplicas < 1 {
+			return nil, fmt.Errorf("availability policy %d (%s) needs minHealthyReplicas of at least 1", i+1, policy.Name)
+		}
+	}
+
+	if err := setIssuePatterns(config.IssuePatterns); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	if err := setMaintenanceWindows(config.MaintenanceWindows); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	if err := setNotifiers(config.Notifiers); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	runbooks = config.Runbooks
+	return config, nil
+}
+
+// parseFlags parses the command line of a command, then sets the flags not given on it to their
+// defaults from the configuration file. Lists set a repeatable flag once per element. The version
+// banner is printed last, when the output format is known.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	if fs.Lookup("config") == nil {
+		fs.StringVar(&configFile, "config", "", "configuration file with flag defaults (default config.yaml in the user config directory)")
+	}
+	fs.Parse(args)
+
+	config, path, err := readConfig()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+		if long, found := flagShorthands[f.Name]; found {
+			given[long] = true
+		}
+	})
+	for name, value := range config.Defaults {
+		if given[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			if err := fs.Set(name, configValue(v)); err != nil {
+				fmt.Printf("Error: invalid default %s in config file %s: %v\n", name, path, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	printVersionBanner(fs)
+}
+
+// configValue formats a YAML scalar as a flag value, integers without an exponent
+func configValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
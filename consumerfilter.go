@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// consumerFilter restricts reports to the volumes of one application
+type consumerFilter struct {
+	namespace string // Namespace of the PVC, empty for any
+	workload  string // Name or kind/name of a workload running consumer pods, empty for any
+}
+
+// volumeConsumerFilter is the filter set with --consumer-namespace and --consumer-workload
+var volumeConsumerFilter consumerFilter
+
+// active returns true if volumes are filtered by their consumers
+func (f consumerFilter) active() bool {
+	return f.namespace != "" || f.workload != ""
+}
+
+// matches returns true if the PVC of a volume belongs to the namespace and workload of the filter
+func (f consumerFilter) matches(pvInfo PersistentVolumeInfo) bool {
+	if f.namespace != "" && pvInfo.PVCNamespace != f.namespace {
+		return false
+	}
+	if f.workload == "" {
+		return true
+	}
+	for _, pod := range pvInfo.ConsumerPods {
+		_, name, _ := strings.Cut(pod.Workload, "/")
+		if f.workload == name || strings.EqualFold(f.workload, pod.Workload) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRelationships drops the volumes not matching the consumer filter
+func filterRelationships(pvInfoMap map[string]PersistentVolumeInfo) map[string]PersistentVolumeInfo {
+	if !volumeConsumerFilter.active() {
+		return pvInfoMap
+	}
+	for volumeName, pvInfo := range pvInfoMap {
+		if !volumeConsumerFilter.matches(pvInfo) {
+			delete(pvInfoMap, volumeName)
+		}
+	}
+	return pvInfoMap
+}
+
+// podWorkload returns the kind/name of the workload owning a pod, resolving ReplicaSets to their
+// Deployment by the pod-template-hash suffix. Bare pods are their own workload.
+func podWorkload(pod corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if owner.Kind == "ReplicaSet" {
+			if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+				return "Deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+		}
+		return owner.Kind + "/" + owner.Name
+	}
+	return "Pod/" + pod.Name
+}
+
+// restrictToVolumes drops the volume scoped objects of volumes outside the set from the snapshot,
+// so every section only reports on the filtered volumes
+func (s *clusterSnapshot) restrictToVolumes(volumes map[string]bool) {
+	// The path to the owning volume's name in each volume scoped resource
+	volumePaths := map[string][][]string{
+		longhornVolumes:       {{"metadata", "name"}},
+		longhornReplicas:      {{"spec", "volumeName"}},
+		longhornEngines:       {{"spec", "volumeName"}},
+		longhornAttachments:   {{"spec", "volume"}},
+		longhornBackupVolumes: {{"spec", "volumeName"}, {"metadata", "name"}},
+		longhornBackups:       {{"status", "volumeName"}, {"metadata", "labels", "backup-volume"}},
+	}
+
+	for resource, paths := range volumePaths {
+		list := s.lists[resource]
+		if list == nil {
+			continue
+		}
+
+		var kept []unstructured.Unstructured
+		for _, item := range list.Items {
+			for _, path := range paths {
+				if volumeName, _, _ := unstructured.NestedString(item.Object, path...); volumeName != "" {
+					if volumes[volumeName] {
+						kept = append(kept, item)
+					}
+					break
+				}
+			}
+		}
+		list.Items = kept
+	}
+}
+
+// applyConsumerFilter restricts the current snapshot to the volumes left in the relationships map
+func applyConsumerFilter(pvInfoMap map[string]PersistentVolumeInfo) {
+	if !volumeConsumerFilter.active() || currentSnapshot == nil {
+		return
+	}
+	volumes := make(map[string]bool)
+	for volumeName := range pvInfoMap {
+		volumes[volumeName] = true
+	}
+	currentSnapshot.restrictToVolumes(volumes)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reasons consumer pods of a PVC were not looked up
+const (
+	consumersOutOfScope = "outside --consumer-namespaces"
+	consumersForbidden  = "forbidden by RBAC"
+)
+
+// consumerScope limits the namespaces searched for consumer pods
+var consumerScope struct {
+	namespaces    map[string]bool // Empty searches every namespace owning a Longhorn PVC
+	allNamespaces bool            // List pods with a single cluster-wide call
+	node          string          // Only look up consumer pods scheduled on this node, empty for any
+}
+
+// setConsumerNamespaces parses the comma-separated --consumer-namespaces value
+func setConsumerNamespaces(value string) {
+	consumerScope.namespaces = make(map[string]bool)
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			consumerScope.namespaces[ns] = true
+		}
+	}
+}
+
+// consumerNamespaceInScope returns true if consumer pods are looked up in the namespace
+func consumerNamespaceInScope(namespace string) bool {
+	return len(consumerScope.namespaces) == 0 || consumerScope.namespaces[namespace]
+}
+
+// consumerPodsFieldSelector selects the active pods, on the --node of node-filtered runs only
+func consumerPodsFieldSelector() string {
+	if consumerScope.node != "" {
+		return activePodsFieldSelector + ",spec.nodeName=" + consumerScope.node
+	}
+	return activePodsFieldSelector
+}
+
+// skippedConsumerNamespaces groups the namespaces whose consumer pods were not looked up by reason
+func skippedConsumerNamespaces(pvInfoMap map[string]PersistentVolumeInfo) map[string][]string {
+	seen := make(map[string]map[string]bool)
+	for _, pvInfo := range pvInfoMap {
+		if pvInfo.ConsumersSkipped == "" {
+			continue
+		}
+		if seen[pvInfo.ConsumersSkipped] == nil {
+			seen[pvInfo.ConsumersSkipped] = make(map[string]bool)
+		}
+		seen[pvInfo.ConsumersSkipped][pvInfo.PVCNamespace] = true
+	}
+
+	skipped := make(map[string][]string)
+	for reason, namespaces := range seen {
+		skipped[reason] = sortedKeys(namespaces)
+	}
+	return skipped
+}
+
+// printSkippedConsumerNamespaces reports the namespaces whose consumer pods are unknown
+func printSkippedConsumerNamespaces(pvInfoMap map[string]PersistentVolumeInfo) {
+	skipped := skippedConsumerNamespaces(pvInfoMap)
+
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		fmt.Printf("%s %s\n",
+			colorize("Consumer pods not checked ("+reason+"):", Yellow),
+			strings.Join(skipped[reason], ", "))
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// cordonedMinScheduled is the scheduled storage from which a cordoned disk is reported, set with
+// --cordoned-min-scheduled
+var cordonedMinScheduled ByteSize = 10 * 1024 * 1024 * 1024
+
+// CordonedDiskInfo describes a healthy disk that accepts no new replicas but still holds storage
+type CordonedDiskInfo struct {
+	NodeName         string
+	DiskName         string
+	Reason           string
+	StorageMaximum   ByteSize
+	StorageScheduled ByteSize
+	CordonedSince    time.Time // First run that saw the cordon
+}
+
+// cordonReason returns why a disk accepts no new replicas, or "" when it is schedulable
+func cordonReason(node unstructured.Unstructured, diskSpec map[string]interface{}) string {
+	if allow, found, _ := unstructured.NestedBool(node.Object, "spec", "allowScheduling"); found && !allow {
+		return "node scheduling disabled"
+	}
+	if evict, _, _ := unstructured.NestedBool(node.Object, "spec", "evictionRequested"); evict {
+		return "node eviction requested"
+	}
+	if allow, ok := diskSpec["allowScheduling"].(bool); ok && !allow {
+		return "disk scheduling disabled"
+	}
+	if evict, _ := diskSpec["evictionRequested"].(bool); evict {
+		return "disk eviction requested"
+	}
+	return ""
+}
+
+// findCordonedDisks returns the ready disks that are cordoned but still hold at least
+// cordonedMinScheduled of scheduled storage. The start of every cordon is kept in the state
+// file, since Longhorn does not record when scheduling was disabled.
+func findCordonedDisks(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) ([]CordonedDiskInfo, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	state, err := sharedState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var cordoned []CordonedDiskInfo
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		for diskName, d := range disksMap {
+			diskSpec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			key := nodeName + "/" + diskName
+			recorded := state.Disks[key]
+			reason := cordonReason(node, diskSpec)
+			switch {
+			case reason == "":
+				recorded.CordonedSince = time.Time{}
+			case recorded.CordonedSince.IsZero():
+				recorded.CordonedSince = now
+			}
+			state.Disks[key] = recorded
+			if reason == "" {
+				continue
+			}
+
+			// Unhealthy disks are cordoned for a reason and reported as disk issues instead
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+			if diskStatus == nil || !conditionTrue(diskStatus, "Ready") {
+				continue
+			}
+
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			if ByteSize(scheduled) < cordonedMinScheduled {
+				continue
+			}
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+
+			cordoned = append(cordoned, CordonedDiskInfo{
+				NodeName:         nodeName,
+				DiskName:         diskName,
+				Reason:           reason,
+				StorageMaximum:   ByteSize(maximum),
+				StorageScheduled: ByteSize(scheduled),
+				CordonedSince:    recorded.CordonedSince,
+			})
+		}
+	}
+
+	if err := state.save(); err != nil {
+		return nil, err
+	}
+
+	// Cordons forgotten the longest come first
+	sort.Slice(cordoned, func(i, j int) bool {
+		if !cordoned[i].CordonedSince.Equal(cordoned[j].CordonedSince) {
+			return cordoned[i].CordonedSince.Before(cordoned[j].CordonedSince)
+		}
+		if cordoned[i].NodeName != cordoned[j].NodeName {
+			return cordoned[i].NodeName < cordoned[j].NodeName
+		}
+		return cordoned[i].DiskName < cordoned[j].DiskName
+	})
+
+	return cordoned, nil
+}
+
+// printCordonedDisks prints healthy disks that accept no new replicas while still holding storage,
+// returning the severity of the worst unacknowledged one
+func printCordonedDisks(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) severity {
+	cordoned, err := findCordonedDisks(dynClient, namespace, nodesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return severityNone
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "CORDONED DISKS",
+		Description: fmt.Sprintf("Healthy disks closed to new replicas while holding at least %s, shrinking usable capacity", cordonedMinScheduled),
+		Color:       Yellow,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tMAX\tSCHEDULED\tCORDONED FOR\tID\tSEVERITY\tREASON%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISK\tMAX\tSCHEDULED\tCORDONED FOR\tID\tSEVERITY\tREASON")
+	}
+
+	fmt.Fprintln(w, "────\t────\t───\t─────────\t────────────\t──\t────────\t──────")
+
+	worst := severityNone
+	for _, disk := range cordoned {
+		id := issueID("node/"+disk.NodeName, "disk "+disk.DiskName+": "+disk.Reason)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			disk.NodeName,
+			disk.DiskName,
+			disk.StorageMaximum,
+			disk.StorageScheduled,
+			formatAge(disk.CordonedSince),
+			id,
+			colorize(severityWarning.String(), severityWarning.color()),
+			formatIssue(withRunbook(disk.Reason, "disk"), id, severityWarning.color()),
+		)
+		if !acknowledged(id) {
+			worst = severityWarning
+		}
+	}
+
+	if len(cordoned) == 0 {
+		fmt.Fprintln(w, "No cordoned disks holding scheduled storage")
+	}
+
+	w.Flush()
+	return worst
+}
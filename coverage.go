@@ -0,0 +1,272 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Compliance of the protection of a volume, worst last
+const (
+	coverageCompliant = iota
+	coverageAtRisk
+	coverageNonCompliant
+)
+
+// coverageLabels name the compliance levels in the heatmap
+var coverageLabels = []string{"compliant", "at risk", "non-compliant"}
+
+// coverageColors color the compliance levels in the heatmap
+var coverageColors = []string{Green, Yellow, Red}
+
+// Recurring job tasks protecting a volume
+var (
+	snapshotTasks = []string{"snapshot", "snapshot-force-create"}
+	backupTasks   = []string{"backup", "backup-force-create"}
+)
+
+// protectionCoverage is the snapshot or backup protection of a volume
+type protectionCoverage struct {
+	Jobs        []string
+	Interval    time.Duration // Shortest interval of the jobs, zero when none can be parsed
+	LastSuccess time.Time
+	Compliance  int
+}
+
+// VolumeCoverage is the snapshot and backup protection of a volume
+type VolumeCoverage struct {
+	VolumeName string
+	Snapshot   protectionCoverage
+	Backup     protectionCoverage
+}
+
+// Compliance returns the worst compliance of the snapshot and backup protection
+func (c VolumeCoverage) Compliance() int {
+	if c.Snapshot.Compliance > c.Backup.Compliance {
+		return c.Snapshot.Compliance
+	}
+	return c.Backup.Compliance
+}
+
+// jobInterval returns the time between the next two runs of a job
+func jobInterval(job RecurringJobInfo, now time.Time) time.Duration {
+	if job.Schedule == nil {
+		return 0
+	}
+	runs := job.Schedule.nextRuns(now, 2)
+	if len(runs) < 2 {
+		return 0
+	}
+	return runs[1].Sub(runs[0])
+}
+
+// evaluateCoverage rates a protection by the age of its last success. A success within one and a
+// half intervals is compliant, one or two missed runs are at risk, volumes without a job or a
+// success in three intervals are non-compliant.
+func evaluateCoverage(coverage protectionCoverage, now time.Time) int {
+	if len(coverage.Jobs) == 0 || coverage.LastSuccess.IsZero() {
+		return coverageNonCompliant
+	}
+	if coverage.Interval == 0 {
+		return coverageAtRisk
+	}
+	age := now.Sub(coverage.LastSuccess)
+	switch {
+	case age <= coverage.Interval*3/2:
+		return coverageCompliant
+	case age <= coverage.Interval*3:
+		return coverageAtRisk
+	}
+	return coverageNonCompliant
+}
+
+// runCoverage implements the "lhmon4 coverage" subcommand
+func runCoverage(args []string) {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	volumeName := fs.String("volume", "", "only show this volume")
+	nonCompliant := fs.Bool("non-compliant", false, "only show volumes that are at risk or non-compliant")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	coverage, err := getVolumeCoverage(dynClient, *clientOpts.namespace, time.Now().UTC())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	printCoverageHeatmap(coverage, *volumeName, *nonCompliant)
+}
+
+// getVolumeCoverage collects the snapshot and backup jobs of every volume with their last success.
+// The last snapshot comes from the snapshot chain of the engine, the last backup from the volume status.
+func getVolumeCoverage(dynClient dynamic.Interface, namespace string, now time.Time) ([]VolumeCoverage, error) {
+	jobs, volumeJobs, err := getRecurringJobs(dynClient, namespace, longhornGVR(longhornVolumes))
+	if err != nil {
+		return nil, err
+	}
+	jobsByName := make(map[string]RecurringJobInfo)
+	for _, job := range jobs {
+		jobsByName[job.Name] = job
+	}
+
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := make([]VolumeCoverage, 0, len(volumes.Items))
+	for _, volume := range volumes.Items {
+		c := VolumeCoverage{VolumeName: volume.GetName()}
+
+		for _, jobName := range volumeJobs[c.VolumeName] {
+			job, found := jobsByName[jobName]
+			if !found {
+				continue
+			}
+			var protection *protectionCoverage
+			switch {
+			case contains(snapshotTasks, job.Task):
+				protection = &c.Snapshot
+			case contains(backupTasks, job.Task):
+				protection = &c.Backup
+			default:
+				continue
+			}
+			protection.Jobs = append(protection.Jobs, job.Name)
+			if interval := jobInterval(job, now); interval > 0 && (protection.Interval == 0 || interval < protection.Interval) {
+				protection.Interval = interval
+			}
+		}
+
+		if engine, found := volumeEngines[c.VolumeName]; found {
+			for _, snapshot := range engineSnapshots(engine) {
+				if snapshot.Name == volumeHeadSnapshot || snapshot.Removed {
+					continue
+				}
+				if created, err := time.Parse(time.RFC3339, snapshot.Created); err == nil && created.After(c.Snapshot.LastSuccess) {
+					c.Snapshot.LastSuccess = created
+				}
+			}
+		}
+		lastBackupAt, _, _ := unstructured.NestedString(volume.Object, "status", "lastBackupAt")
+		c.Backup.LastSuccess, _ = time.Parse(time.RFC3339, lastBackupAt)
+
+		c.Snapshot.Compliance = evaluateCoverage(c.Snapshot, now)
+		c.Backup.Compliance = evaluateCoverage(c.Backup, now)
+		coverage = append(coverage, c)
+	}
+
+	// Worst first, then by name
+	sort.Slice(coverage, func(i, j int) bool {
+		if ci, cj := coverage[i].Compliance(), coverage[j].Compliance(); ci != cj {
+			return ci > cj
+		}
+		return coverage[i].VolumeName < coverage[j].VolumeName
+	})
+
+	return coverage, nil
+}
+
+// formatCoverageJobs renders the jobs of a protection colored by its compliance
+func formatCoverageJobs(coverage protectionCoverage) string {
+	if len(coverage.Jobs) == 0 {
+		return colorize("none", Red)
+	}
+	jobs := strings.Join(coverage.Jobs, ",")
+	if coverage.Interval > 0 {
+		jobs += " (every " + formatDuration(coverage.Interval) + ")"
+	}
+	return colorize(jobs, coverageColors[coverage.Compliance])
+}
+
+// formatCoverageSuccess renders the age of the last success colored by compliance
+func formatCoverageSuccess(coverage protectionCoverage) string {
+	if coverage.LastSuccess.IsZero() {
+		return colorize("never", Red)
+	}
+	return colorize(formatAge(coverage.LastSuccess), coverageColors[coverage.Compliance])
+}
+
+// printCoverageHeatmap prints the protection of every volume colored by compliance
+func printCoverageHeatmap(coverage []VolumeCoverage, filterVolume string, nonCompliant bool) {
+	printSectionHeader(Section{
+		Title:       "PROTECTION COVERAGE",
+		Description: "Snapshot and backup jobs per volume and the age of their last success, worst first",
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSNAPSHOT JOB\tLAST SNAPSHOT\tBACKUP JOB\tLAST BACKUP\tCOMPLIANCE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSNAPSHOT JOB\tLAST SNAPSHOT\tBACKUP JOB\tLAST BACKUP\tCOMPLIANCE")
+	}
+
+	fmt.Fprintln(w, "──────\t────────────\t─────────────\t──────────\t───────────\t──────────")
+
+	counts := make([]int, len(coverageLabels))
+	shown := 0
+	for _, c := range coverage {
+		if filterVolume != "" && c.VolumeName != filterVolume {
+			continue
+		}
+		compliance := c.Compliance()
+		counts[compliance]++
+		if nonCompliant && compliance == coverageCompliant {
+			continue
+		}
+		shown++
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.VolumeName,
+			formatCoverageJobs(c.Snapshot),
+			formatCoverageSuccess(c.Snapshot),
+			formatCoverageJobs(c.Backup),
+			formatCoverageSuccess(c.Backup),
+			colorize(coverageLabels[compliance], coverageColors[compliance]),
+		)
+	}
+
+	if shown == 0 {
+		fmt.Fprintln(w, "No volumes found")
+	}
+
+	w.Flush()
+
+	fmt.Println()
+	for level, label := range coverageLabels {
+		fmt.Printf("%-14s %s\n", label+":", colorize(fmt.Sprint(counts[level]), coverageColors[level]))
+	}
+}
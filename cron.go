@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5-field cron expression (minute hour day-of-month month day-of-week)
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronField describes the valid range and names of a cron field
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronDescriptors maps the predefined schedules to their 5-field equivalent
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a standard 5-field cron expression or a predefined descriptor
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if descriptor, found := cronDescriptors[strings.ToLower(expr)]; found {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, found %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if s.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if s.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if s.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	if s.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+
+	// Sunday may be written as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps into a bit set
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		hasStep := false
+		if i := strings.Index(part, "/"); i >= 0 {
+			hasStep = true
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		start, end := spec.min, spec.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], spec); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(bounds[1], spec); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(part, spec); err != nil {
+				return 0, err
+			}
+			// A single value with a step means "starting at"
+			if !hasStep {
+				end = start
+			}
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a single numeric or named cron value
+func parseCronValue(value string, spec cronField) (int, error) {
+	if n, found := spec.names[strings.ToLower(value)]; found {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", n, spec.min, spec.max)
+	}
+	return n, nil
+}
+
+// dayMatches applies the cron rule that day-of-month and day-of-week are ORed when both are restricted
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first activation strictly after t, or the zero time if none is found within five years
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// nextRuns returns the next n activations after t
+func (s *cronSchedule) nextRuns(t time.Time, n int) []time.Time {
+	var runs []time.Time
+	for i := 0; i < n; i++ {
+		t = s.next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
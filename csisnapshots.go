@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// CSI snapshot resources of the external-snapshotter
+var (
+	volumeSnapshotsGVR        = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+	volumeSnapshotContentsGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotcontents"}
+	volumeSnapshotClassesGVR  = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotclasses"}
+)
+
+// CSISnapshotInfo correlates a VolumeSnapshot and its content with the Longhorn object behind it
+type CSISnapshotInfo struct {
+	VolumeSnapshot string // namespace/name, empty when the content has none
+	Content        string
+	Type           string // backup, snapshot or backing image, from the snapshot handle
+	LonghornVolume string
+	LonghornObject string
+	ReadyToUse     bool
+	Status         string // bound, stale, orphaned or pending
+}
+
+// parseSnapshotHandle splits a Longhorn CSI snapshot handle such as bs://volume/backup or snap://volume/snapshot
+func parseSnapshotHandle(handle string) (string, string, string) {
+	scheme, rest, found := strings.Cut(handle, "://")
+	if !found {
+		return "", "", ""
+	}
+	volume, name, _ := strings.Cut(rest, "/")
+
+	switch scheme {
+	case "bs":
+		return "backup", volume, name
+	case "snap":
+		return "snapshot", volume, name
+	case "bi":
+		return "backing image", volume, name
+	}
+	return scheme, volume, name
+}
+
+// getCSISnapshots joins VolumeSnapshots and VolumeSnapshotContents of the Longhorn driver with
+// Longhorn backups and snapshots
+func getCSISnapshots(dynClient dynamic.Interface, namespace string) ([]CSISnapshotInfo, error) {
+	// A NotFound error is returned as is, it means the snapshot CRDs are not installed
+	contents, err := listAll(dynClient, volumeSnapshotContentsGVR, "")
+	if apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshotContents: %v", err)
+	}
+	snapshots, err := listAll(dynClient, volumeSnapshotsGVR, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshots: %v", err)
+	}
+	classes, err := listAll(dynClient, volumeSnapshotClassesGVR, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshotClasses: %v", err)
+	}
+
+	longhornClasses := make(map[string]bool)
+	for _, class := range classes.Items {
+		if driver, _, _ := unstructured.NestedString(class.Object, "driver"); driver == longhornCSIDriver {
+			longhornClasses[class.GetName()] = true
+		}
+	}
+
+	backups, err := listAll(dynClient, longhornGVR(longhornBackups), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn backups: %v", err)
+	}
+	backupNames := make(map[string]bool)
+	for _, backup := range backups.Items {
+		backupNames[backup.GetName()] = true
+	}
+
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeSnapshots := make(map[string]unstructured.Unstructured)
+	for _, snapshot := range snapshots.Items {
+		volumeSnapshots[snapshot.GetNamespace()+"/"+snapshot.GetName()] = snapshot
+	}
+
+	// longhornObjectExists returns true if the backup or snapshot behind a handle still exists
+	longhornObjectExists := func(snapshotType, volumeName, name string) bool {
+		switch snapshotType {
+		case "backup":
+			return backupNames[name]
+		case "snapshot":
+			engine, found := volumeEngines[volumeName]
+			if !found {
+				return false
+			}
+			for _, snapshot := range engineSnapshots(engine) {
+				if snapshot.Name == name && !snapshot.Removed {
+					return true
+				}
+			}
+			return false
+		}
+		// Backing images are not tracked per snapshot
+		return true
+	}
+
+	var infos []CSISnapshotInfo
+	seenContents := make(map[string]bool)
+	for _, content := range contents.Items {
+		if driver, _, _ := unstructured.NestedString(content.Object, "spec", "driver"); driver != longhornCSIDriver {
+			continue
+		}
+		seenContents[content.GetName()] = true
+
+		handle, _, _ := unstructured.NestedString(content.Object, "status", "snapshotHandle")
+		if handle == "" {
+			handle, _, _ = unstructured.NestedString(content.Object, "spec", "source", "snapshotHandle")
+		}
+		refNamespace, _, _ := unstructured.NestedString(content.Object, "spec", "volumeSnapshotRef", "namespace")
+		refName, _, _ := unstructured.NestedString(content.Object, "spec", "volumeSnapshotRef", "name")
+		ready, _, _ := unstructured.NestedBool(content.Object, "status", "readyToUse")
+
+		info := CSISnapshotInfo{Content: content.GetName(), ReadyToUse: ready}
+		info.Type, info.LonghornVolume, info.LonghornObject = parseSnapshotHandle(handle)
+
+		if _, found := volumeSnapshots[refNamespace+"/"+refName]; found {
+			info.VolumeSnapshot = refNamespace + "/" + refName
+		}
+
+		switch {
+		case info.VolumeSnapshot == "":
+			info.Status = "orphaned"
+		case handle == "":
+			info.Status = "pending"
+		case !longhornObjectExists(info.Type, info.LonghornVolume, info.LonghornObject):
+			info.Status = "stale"
+		default:
+			info.Status = "bound"
+		}
+		infos = append(infos, info)
+	}
+
+	// VolumeSnapshots of Longhorn classes whose content is gone
+	for key, snapshot := range volumeSnapshots {
+		className, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName")
+		contentName, _, _ := unstructured.NestedString(snapshot.Object, "status", "boundVolumeSnapshotContentName")
+		if !longhornClasses[className] || contentName == "" || seenContents[contentName] {
+			continue
+		}
+		infos = append(infos, CSISnapshotInfo{
+			VolumeSnapshot: key,
+			Content:        contentName,
+			Status:         "stale",
+		})
+	}
+
+	// Problems first
+	statusOrder := map[string]int{"stale": 0, "orphaned": 1, "pending": 2, "bound": 3}
+	sort.Slice(infos, func(i, j int) bool {
+		if statusOrder[infos[i].Status] != statusOrder[infos[j].Status] {
+			return statusOrder[infos[i].Status] < statusOrder[infos[j].Status]
+		}
+		return infos[i].Content < infos[j].Content
+	})
+
+	return infos, nil
+}
+
+// printCSISnapshots prints the correlation of CSI VolumeSnapshots with Longhorn snapshots and backups
+func printCSISnapshots(dynClient dynamic.Interface, namespace string) {
+	infos, err := getCSISnapshots(dynClient, namespace)
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "CSI SNAPSHOTS",
+		Description: "VolumeSnapshots and their Longhorn snapshots and backups",
+		Color:       Magenta,
+	})
+
+	if apierrors.IsNotFound(err) {
+		fmt.Println("VolumeSnapshot CRDs are not installed")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUMESNAPSHOT\tCONTENT\tTYPE\tLONGHORN VOLUME\tLONGHORN OBJECT\tREADY\tSTATUS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUMESNAPSHOT\tCONTENT\tTYPE\tLONGHORN VOLUME\tLONGHORN OBJECT\tREADY\tSTATUS")
+	}
+
+	fmt.Fprintln(w, "──────────────\t───────\t────\t───────────────\t───────────────\t─────\t──────")
+
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	for _, info := range infos {
+		statusColor := Green
+		switch info.Status {
+		case "stale":
+			statusColor = Red
+		case "orphaned", "pending":
+			statusColor = Yellow
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+			orDash(info.VolumeSnapshot),
+			info.Content,
+			orDash(info.Type),
+			orDash(info.LonghornVolume),
+			orDash(info.LonghornObject),
+			info.ReadyToUse,
+			colorize(info.Status, statusColor),
+		)
+	}
+
+	if len(infos) == 0 {
+		fmt.Fprintln(w, "No Longhorn VolumeSnapshots found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvOutputDir is the directory -o csv writes to, set by --csv-dir
+var csvOutputDir string
+
+// csvTable is one CSV file of the export
+type csvTable struct {
+	file   string
+	header []string
+	rows   [][]string
+}
+
+// csvBytes renders a size as a plain byte count
+func csvBytes(size ByteSize) string {
+	return strconv.FormatInt(int64(size), 10)
+}
+
+// csvTime renders a time in UTC RFC 3339, empty when unknown
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvList joins a list with semicolons, which spreadsheets do not treat as a column separator
+func csvList(values []string) string {
+	return strings.Join(values, ";")
+}
+
+// csvTables converts the report into one table per section. Numbers are written without locale
+// formatting or units so spreadsheets can compute with them.
+func csvTables(doc reportDocument) []csvTable {
+	disks := csvTable{
+		file:   "disks.csv",
+		header: []string{"node", "disk", "path", "tags", "type", "storage_maximum_bytes", "storage_reserved_bytes", "storage_scheduled_bytes", "storage_available_bytes", "percent_used"},
+	}
+	for _, disk := range doc.Disks {
+		disks.rows = append(disks.rows, []string{
+			disk.NodeName,
+			disk.DiskName,
+			disk.Path,
+			csvList(disk.Tags),
+			disk.Type,
+			csvBytes(disk.StorageMaximum),
+			csvBytes(disk.StorageReserved),
+			csvBytes(disk.StorageScheduled),
+			csvBytes(disk.StorageAvailable),
+			strconv.FormatFloat(disk.PercentUsed, 'f', 2, 64),
+		})
+	}
+
+	volumes := csvTable{
+		file:   "volumes.csv",
+		header: []string{"volume", "size_bytes", "actual_size_bytes", "state", "robustness", "node", "replicas", "desired_replicas", "access_mode", "disk_selector", "node_selector", "groups", "created"},
+	}
+	for _, volume := range doc.Volumes {
+		volumes.rows = append(volumes.rows, []string{
+			volume.Name,
+			csvBytes(volume.Size),
+			csvBytes(volume.ActualSize),
+			volume.State,
+			volume.Robustness,
+			volume.Node,
+			strconv.Itoa(volume.ReplicaCount),
+			strconv.Itoa(volume.DesiredReplicas),
+			volume.AccessMode,
+			csvList(volume.DiskSelector),
+			csvList(volume.NodeSelector),
+			csvList(volume.Groups),
+			csvTime(volume.Created),
+		})
+	}
+
+	replicas := csvTable{
+		file:   "replicas.csv",
+		header: []string{"replica", "volume", "node", "disk_id", "disk_path", "state", "mode", "healthy", "size_bytes", "failed_at", "created"},
+	}
+	for _, replica := range doc.Replicas {
+		replicas.rows = append(replicas.rows, []string{
+			replica.Name,
+			replica.VolumeName,
+			replica.NodeID,
+			replica.DiskID,
+			replica.DiskPath,
+			replica.State,
+			replica.Mode,
+			strconv.FormatBool(replica.Healthy),
+			csvBytes(replica.Size),
+			replica.FailedAt,
+			csvTime(replica.Created),
+		})
+	}
+
+	relationships := csvTable{
+		file:   "relationships.csv",
+		header: []string{"volume", "pv", "pv_status", "storage_class", "capacity_bytes", "pvc_namespace", "pvc", "pvc_request_bytes", "pods", "created"},
+	}
+	for _, pvInfo := range doc.Relationships {
+		var pods []string
+		for _, pod := range pvInfo.ConsumerPods {
+			pods = append(pods, pod.Namespace+"/"+pod.Name)
+		}
+		relationships.rows = append(relationships.rows, []string{
+			pvInfo.LonghornVolumeID,
+			pvInfo.Name,
+			pvInfo.Status,
+			pvInfo.StorageClass,
+			strconv.FormatInt(pvInfo.CapacityBytes, 10),
+			pvInfo.PVCNamespace,
+			pvInfo.PVCName,
+			strconv.FormatInt(pvInfo.PVCRequestBytes, 10),
+			csvList(pods),
+			csvTime(pvInfo.Created),
+		})
+	}
+
+	return []csvTable{disks, volumes, replicas, relationships}
+}
+
+// writeCSVReport writes one CSV file per section to the directory, returning the written paths
+func writeCSVReport(dir string, doc reportDocument) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create CSV directory: %v", err)
+	}
+
+	var paths []string
+	for _, table := range csvTables(doc) {
+		path := filepath.Join(dir, table.file)
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create %s: %v", path, err)
+		}
+
+		w := csv.NewWriter(f)
+		w.Write(table.header)
+		w.WriteAll(table.rows)
+		err = w.Error()
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
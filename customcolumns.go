@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Tables custom columns can be added to
+const (
+	customVolume  = "volume"
+	customNode    = "node"
+	customReplica = "replica"
+)
+
+// diskPathSegment stands for the name of the disk in node paths, as disk rows come from one node object
+const diskPathSegment = "$disk"
+
+// customColumn is an extra table column showing a field of the Longhorn object behind each row
+type customColumn struct {
+	Resource string // volume, node or replica
+	Header   string
+	Path     []string
+}
+
+// customColumns are the columns added with --custom-column
+var customColumns []customColumn
+
+// customColumnsFlag parses repeated --custom-column flags of the form [resource/]HEADER:path
+type customColumnsFlag struct{}
+
+// String returns the configured columns
+func (customColumnsFlag) String() string {
+	var specs []string
+	for _, column := range customColumns {
+		specs = append(specs, fmt.Sprintf("%s/%s:%s", column.Resource, column.Header, strings.Join(column.Path, ".")))
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set adds a column, e.g. "ENGINE-IMAGE:status.currentImage" or "replica/ADDRESS:status.storageIP"
+func (customColumnsFlag) Set(value string) error {
+	spec := value
+	resource := customVolume
+	if i := strings.Index(spec, "/"); i >= 0 && i < strings.Index(spec, ":") {
+		resource, spec = spec[:i], spec[i+1:]
+	}
+	switch resource {
+	case customVolume, customNode, customReplica:
+	default:
+		return fmt.Errorf("invalid custom column %q: resource must be volume, node or replica", value)
+	}
+
+	header, path, found := strings.Cut(spec, ":")
+	// Accept kubectl custom-columns paths like .status.currentImage or {.status.currentImage}
+	path = strings.TrimPrefix(strings.Trim(path, "{}"), ".")
+	if !found || header == "" || path == "" {
+		return fmt.Errorf("invalid custom column %q, expected [resource/]HEADER:path", value)
+	}
+
+	customColumns = append(customColumns, customColumn{
+		Resource: resource,
+		Header:   strings.ToUpper(header),
+		Path:     strings.Split(path, "."),
+	})
+	return nil
+}
+
+// customHeader returns the header and separator cells of the custom columns of a table, including trailing tabs
+func customHeader(resource string) (string, string) {
+	var header, separator strings.Builder
+	for _, column := range customColumns {
+		if column.Resource != resource {
+			continue
+		}
+		header.WriteString(column.Header + "\t")
+		separator.WriteString(strings.Repeat("─", len(column.Header)) + "\t")
+	}
+	return header.String(), separator.String()
+}
+
+// customCells returns the custom column cells of a row, including trailing tabs. The disk name
+// replaces $disk segments in node paths.
+func customCells(resource string, obj map[string]interface{}, diskName string) string {
+	var cells strings.Builder
+	for _, column := range customColumns {
+		if column.Resource != resource {
+			continue
+		}
+
+		path := make([]string, len(column.Path))
+		for i, segment := range column.Path {
+			if segment == diskPathSegment {
+				segment = diskName
+			}
+			path[i] = segment
+		}
+
+		value, found, err := unstructured.NestedFieldNoCopy(obj, path...)
+		cells.WriteString(formatCustomValue(value, found && err == nil) + "\t")
+	}
+	return cells.String()
+}
+
+// formatCustomValue renders a field value, nested objects and lists as compact JSON
+func formatCustomValue(value interface{}, found bool) string {
+	if !found || value == nil {
+		return "-"
+	}
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "-"
+		}
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ReplicaDiskConflict describes a replica claiming a disk that is no longer part of its node. Such
+// replicas cannot be started and silently block rebuilds of their volume.
+type ReplicaDiskConflict struct {
+	ReplicaName string
+	VolumeName  string
+	NodeID      string
+	DiskID      string
+	DiskPath    string
+	Reason      string
+}
+
+// getReplicaDiskConflicts compares the disk of every scheduled replica with the disks in its node's spec
+func getReplicaDiskConflicts(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) ([]ReplicaDiskConflict, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	// Node -> disk UUID of every disk still in the spec, and node -> paths of those disks
+	nodeDisks := make(map[string]map[string]bool)
+	nodePaths := make(map[string]map[string]bool)
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+		nodeDisks[nodeName] = make(map[string]bool)
+		nodePaths[nodeName] = make(map[string]bool)
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		for diskName, diskSpec := range disksMap {
+			if diskSpecMap, ok := diskSpec.(map[string]interface{}); ok {
+				if path, _ := diskSpecMap["path"].(string); path != "" {
+					nodePaths[nodeName][path] = true
+				}
+			}
+			// The UUID is only known from the status of disks present in the spec
+			if diskUUID, _, _ := unstructured.NestedString(node.Object, "status", "diskStatus", diskName, "diskUUID"); diskUUID != "" {
+				nodeDisks[nodeName][diskUUID] = true
+			}
+		}
+	}
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	var conflicts []ReplicaDiskConflict
+	for _, replica := range replicas.Items {
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		diskID, _, _ := unstructured.NestedString(replica.Object, "spec", "diskID")
+		if nodeID == "" || diskID == "" {
+			// Not scheduled yet
+			continue
+		}
+		diskPath, _, _ := unstructured.NestedString(replica.Object, "spec", "diskPath")
+
+		reason := ""
+		disks, nodeFound := nodeDisks[nodeID]
+		switch {
+		case !nodeFound:
+			reason = "node no longer exists"
+		case disks[diskID]:
+			continue
+		case nodePaths[nodeID][diskPath]:
+			reason = "disk at this path was replaced, its UUID changed"
+		default:
+			reason = "disk was removed from or renamed in the node spec"
+		}
+
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		conflicts = append(conflicts, ReplicaDiskConflict{
+			ReplicaName: replica.GetName(),
+			VolumeName:  volumeName,
+			NodeID:      nodeID,
+			DiskID:      diskID,
+			DiskPath:    diskPath,
+			Reason:      reason,
+		})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].VolumeName != conflicts[j].VolumeName {
+			return conflicts[i].VolumeName < conflicts[j].VolumeName
+		}
+		return conflicts[i].ReplicaName < conflicts[j].ReplicaName
+	})
+
+	return conflicts, nil
+}
+
+// printReplicaDiskConflicts prints replicas claiming disks missing from their node, returning the
+// severity of the worst unacknowledged conflict
+func printReplicaDiskConflicts(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) severity {
+	conflicts, err := getReplicaDiskConflicts(dynClient, namespace, nodesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return severityNone
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "REPLICA DISK CONFLICTS",
+		Description: "Replicas claiming disks that no longer exist on their node, blocking rebuilds",
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tREPLICA\tNODE\tDISK\tPATH\tID\tSEVERITY\tISSUE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tREPLICA\tNODE\tDISK\tPATH\tID\tSEVERITY\tISSUE")
+	}
+
+	fmt.Fprintln(w, "──────\t───────\t────\t────\t────\t──\t────────\t─────")
+
+	worst := severityNone
+	for _, conflict := range conflicts {
+		id := issueID("replica/"+conflict.ReplicaName, "disk "+conflict.DiskID+": "+conflict.Reason)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			colorize(conflict.VolumeName, Blue),
+			conflict.ReplicaName,
+			conflict.NodeID,
+			conflict.DiskID,
+			conflict.DiskPath,
+			id,
+			colorize(severityCritical.String(), severityCritical.color()),
+			formatIssue(withRunbook(conflict.Reason, "disk"), id, severityCritical.color()),
+		)
+		if !acknowledged(id) {
+			worst = severityCritical
+		}
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Fprintln(w, "No replica disk conflicts found")
+	}
+
+	w.Flush()
+	return worst
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// diskUsageRange restricts the disk section to the disks that matter during a capacity crunch
+type diskUsageRange struct {
+	minFree        ByteSize // Only disks with less available space, 0 disables
+	maxUsedPercent float64  // Only disks using more than this percentage, 0 disables
+	diskType       string   // "filesystem" or "block", empty for both
+}
+
+// diskUsageFilter is the disk filter set with --disk-min-free, --disk-max-used-percent and --disk-type
+var diskUsageFilter diskUsageRange
+
+// setDiskUsageFilter validates and sets the disk filter flags
+func setDiskUsageFilter(minFree string, maxUsedPercent float64, diskType string) error {
+	diskUsageFilter = diskUsageRange{maxUsedPercent: maxUsedPercent}
+
+	if minFree != "" {
+		quantity, err := resource.ParseQuantity(minFree)
+		if err != nil {
+			return fmt.Errorf("invalid --disk-min-free %q: %v", minFree, err)
+		}
+		diskUsageFilter.minFree = ByteSize(quantity.Value())
+	}
+
+	if maxUsedPercent < 0 || maxUsedPercent > 100 {
+		return fmt.Errorf("invalid --disk-max-used-percent %v, expected 0 to 100", maxUsedPercent)
+	}
+
+	switch diskType {
+	case "":
+	case "fs", "filesystem":
+		diskUsageFilter.diskType = "filesystem"
+	case "block":
+		diskUsageFilter.diskType = "block"
+	default:
+		return fmt.Errorf("invalid --disk-type %q, expected fs or block", diskType)
+	}
+	return nil
+}
+
+// matches returns true if the disk passes the filter. A disk below either capacity threshold
+// matches, so both can be combined to catch disks that are short in absolute or relative terms.
+func (r diskUsageRange) matches(diskType string, available ByteSize, percentUsed float64) bool {
+	// Disks without a type predate block disks and are filesystem disks
+	if diskType == "" {
+		diskType = "filesystem"
+	}
+	if r.diskType != "" && diskType != r.diskType {
+		return false
+	}
+
+	if r.minFree == 0 && r.maxUsedPercent == 0 {
+		return true
+	}
+	return (r.minFree > 0 && available < r.minFree) || (r.maxUsedPercent > 0 && percentUsed > r.maxUsedPercent)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"time"
+)
+
+// diskHighlighter decides which disks are highlighted in the disk table
+type diskHighlighter struct {
+	pattern        *regexp.Regexp // Disks whose name matches are highlighted, nil disables
+	expandedWithin time.Duration  // Disks that grew within this period are highlighted, 0 disables
+	state          *monitorState
+}
+
+// newDiskHighlighter creates a highlighter, returning nil when no highlighting is requested
+func newDiskHighlighter(pattern string, expandedWithin time.Duration) (*diskHighlighter, error) {
+	if pattern == "" && expandedWithin == 0 {
+		return nil, nil
+	}
+
+	h := &diskHighlighter{expandedWithin: expandedWithin}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		h.pattern = re
+	}
+
+	// Expansion is detected by comparing against the capacity recorded by previous runs
+	if expandedWithin > 0 {
+		state, err := sharedState()
+		if err != nil {
+			return nil, err
+		}
+		h.state = state
+	}
+
+	return h, nil
+}
+
+// record compares the disks against the recorded capacities, remembering when a disk grew
+func (h *diskHighlighter) record(disks []DiskInfo) error {
+	if h == nil || h.state == nil {
+		return nil
+	}
+
+	now := time.Now()
+	for _, disk := range disks {
+		key := disk.NodeName + "/" + disk.DiskName
+		recorded, found := h.state.Disks[key]
+		if found && disk.StorageMaximum > recorded.StorageMaximum {
+			recorded.ExpandedAt = now
+		}
+		recorded.StorageMaximum = disk.StorageMaximum
+		h.state.Disks[key] = recorded
+	}
+
+	return h.state.save()
+}
+
+// highlighted returns true if the disk matches the name pattern or was recently expanded
+func (h *diskHighlighter) highlighted(disk DiskInfo) bool {
+	if h == nil {
+		return false
+	}
+	if h.pattern != nil && h.pattern.MatchString(disk.DiskName) {
+		return true
+	}
+	if h.state != nil {
+		expandedAt := h.state.Disks[disk.NodeName+"/"+disk.DiskName].ExpandedAt
+		return !expandedAt.IsZero() && time.Since(expandedAt) <= h.expandedWithin
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// exitRemovalUnsafe is the exit status of "disk can-remove" and "node can-remove" when something still depends on it
+const exitRemovalUnsafe = 5
+
+// DiskRemovalCheck describes whether a disk can be removed from its node and what still depends on it
+type DiskRemovalCheck struct {
+	NodeName          string
+	DiskName          string
+	DiskPath          string
+	DiskUUID          string
+	AllowScheduling   bool
+	EvictionRequested bool
+	Replicas          []DiskReplica
+}
+
+// DiskReplica is a replica still placed on a disk that is about to be removed
+type DiskReplica struct {
+	ReplicaName string
+	VolumeName  string
+	State       string
+	// Healthy replicas of the same volume on other disks
+	OtherHealthy int
+}
+
+// Safe reports whether no replica depends on the disk anymore
+func (c DiskRemovalCheck) Safe() bool {
+	return len(c.Replicas) == 0
+}
+
+// runDisk implements the "lhmon4 disk" subcommand
+func runDisk(args []string) {
+	if len(args) == 0 || args[0] != "can-remove" {
+		fmt.Printf("Usage: %s disk can-remove <node>:<disk> [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("disk can-remove", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() != 1 {
+		fmt.Printf("Usage: %s disk can-remove <node>:<disk> [flags]\n", commandName)
+		os.Exit(2)
+	}
+	nodeName, diskName, ok := strings.Cut(fs.Arg(0), ":")
+	if !ok || nodeName == "" || diskName == "" {
+		fmt.Printf("Error: invalid disk %q, expected <node>:<disk>\n", fs.Arg(0))
+		os.Exit(2)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	namespace := *clientOpts.namespace
+	check, err := checkDiskRemoval(dynClient, namespace, nodeName, diskName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiskRemovalCheck(check, namespace)
+	if !check.Safe() {
+		os.Exit(exitRemovalUnsafe)
+	}
+}
+
+// checkDiskRemoval collects the scheduling, eviction and replica state of a single disk
+func checkDiskRemoval(dynClient dynamic.Interface, namespace, nodeName, diskName string) (DiskRemovalCheck, error) {
+	check := DiskRemovalCheck{NodeName: nodeName, DiskName: diskName}
+
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return check, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	var node *unstructured.Unstructured
+	for i := range nodes.Items {
+		if nodes.Items[i].GetName() == nodeName {
+			node = &nodes.Items[i]
+			break
+		}
+	}
+	if node == nil {
+		return check, fmt.Errorf("Longhorn node %s not found", nodeName)
+	}
+
+	diskSpec, found, _ := unstructured.NestedMap(node.Object, "spec", "disks", diskName)
+	if !found {
+		return check, fmt.Errorf("disk %s not found on node %s", diskName, nodeName)
+	}
+	check.DiskPath, _ = diskSpec["path"].(string)
+	check.AllowScheduling, _ = diskSpec["allowScheduling"].(bool)
+	check.EvictionRequested, _ = diskSpec["evictionRequested"].(bool)
+	check.DiskUUID, _, _ = unstructured.NestedString(node.Object, "status", "diskStatus", diskName, "diskUUID")
+
+	// Replicas the node status still counts against the disk, used when the UUID is unknown
+	scheduled, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName, "scheduledReplica")
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return check, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	// Count healthy replicas per volume that live elsewhere, so dependents can be judged
+	healthyElsewhere := make(map[string]int)
+	onDisk := make(map[string]bool)
+	for _, replica := range replicas.Items {
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		diskID, _, _ := unstructured.NestedString(replica.Object, "spec", "diskID")
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+
+		if nodeID == nodeName && ((check.DiskUUID != "" && diskID == check.DiskUUID) || scheduled[replica.GetName()] != nil) {
+			onDisk[replica.GetName()] = true
+			continue
+		}
+
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if state == "running" && failedAt == "" {
+			healthyElsewhere[volumeName]++
+		}
+	}
+
+	for _, replica := range replicas.Items {
+		if !onDisk[replica.GetName()] {
+			continue
+		}
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		check.Replicas = append(check.Replicas, DiskReplica{
+			ReplicaName:  replica.GetName(),
+			VolumeName:   volumeName,
+			State:        state,
+			OtherHealthy: healthyElsewhere[volumeName],
+		})
+	}
+
+	sort.Slice(check.Replicas, func(i, j int) bool {
+		if check.Replicas[i].VolumeName != check.Replicas[j].VolumeName {
+			return check.Replicas[i].VolumeName < check.Replicas[j].VolumeName
+		}
+		return check.Replicas[i].ReplicaName < check.Replicas[j].ReplicaName
+	})
+
+	return check, nil
+}
+
+// printDiskRemovalCheck prints the verdict, the dependent volumes and the retirement procedure
+func printDiskRemovalCheck(check DiskRemovalCheck, namespace string) {
+	printSectionHeader(Section{
+		Title:       "DISK REMOVAL CHECK",
+		Description: fmt.Sprintf("Whether disk %s on node %s (%s) can be removed safely", check.DiskName, check.NodeName, check.DiskPath),
+		Color:       Cyan,
+	})
+
+	scheduling := colorize("enabled", Yellow)
+	if !check.AllowScheduling {
+		scheduling = colorize("disabled", Green)
+	}
+	eviction := colorize("not requested", Yellow)
+	if check.EvictionRequested {
+		eviction = colorize("requested", Green)
+		if len(check.Replicas) > 0 {
+			eviction = colorize("in progress", Yellow)
+		}
+	}
+	fmt.Printf("Scheduling: %s\n", scheduling)
+	fmt.Printf("Eviction:   %s\n", eviction)
+	fmt.Printf("Replicas:   %d\n\n", len(check.Replicas))
+
+	if len(check.Replicas) > 0 {
+		w := newTableWriter()
+
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sVOLUME\tREPLICA\tSTATE\tHEALTHY ELSEWHERE%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "VOLUME\tREPLICA\tSTATE\tHEALTHY ELSEWHERE")
+		}
+
+		fmt.Fprintln(w, "──────\t───────\t─────\t─────────────────")
+
+		for _, replica := range check.Replicas {
+			healthyColor := Green
+			if replica.OtherHealthy == 0 {
+				// Evicting is the only way to keep this volume's data
+				healthyColor = Red
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				colorize(replica.VolumeName, Blue),
+				replica.ReplicaName,
+				replica.State,
+				colorize(fmt.Sprint(replica.OtherHealthy), healthyColor),
+			)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	if check.Safe() {
+		fmt.Println(colorize("SAFE: no replicas depend on this disk", Green+Bold))
+	} else {
+		fmt.Println(colorize(fmt.Sprintf("NOT SAFE: %d replica(s) still on this disk", len(check.Replicas)), Red+Bold))
+	}
+
+	// Print the retirement procedure, marking the steps that are already done
+	fmt.Println("\nSteps to retire the disk:")
+	resource := fmt.Sprintf("kubectl -n %s patch nodes.longhorn.io %s", namespace, check.NodeName)
+	steps := []struct {
+		text    string
+		command string
+		done    bool
+	}{
+		{"Disable scheduling on the disk",
+			fmt.Sprintf(`%s --type merge -p '{"spec":{"disks":{"%s":{"allowScheduling":false}}}}'`, resource, check.DiskName),
+			!check.AllowScheduling},
+		{"Request eviction of its replicas",
+			fmt.Sprintf(`%s --type merge -p '{"spec":{"disks":{"%s":{"evictionRequested":true}}}}'`, resource, check.DiskName),
+			check.EvictionRequested},
+		{"Wait until all replicas have been rebuilt elsewhere",
+			fmt.Sprintf("%s disk can-remove %s:%s -namespace %s", commandName, check.NodeName, check.DiskName, namespace),
+			check.Safe()},
+		{"Remove the disk from the node",
+			fmt.Sprintf(`%s --type json -p '[{"op":"remove","path":"/spec/disks/%s"}]'`, resource, check.DiskName),
+			false},
+		{"Unmount and wipe the disk on the node", fmt.Sprintf("umount %s", check.DiskPath), false},
+	}
+
+	for i, step := range steps {
+		marker := "[ ]"
+		if step.done {
+			marker = colorize("[x]", Green)
+		}
+		fmt.Printf("  %d. %s %s\n", i+1, marker, step.text)
+		if !step.done {
+			if useColors {
+				fmt.Printf("     %s%s%s\n", Bold+Cyan, step.command, Reset)
+			} else {
+				fmt.Printf("     %s\n", step.command)
+			}
+		}
+	}
+	fmt.Println()
+}
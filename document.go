@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reportDocumentVersion is incremented whenever a field of the -o json and -o yaml document
+// changes incompatibly
+const reportDocumentVersion = 1
+
+// reportDocument is the report written by -o json and -o yaml. YAML uses the JSON field names.
+type reportDocument struct {
+	Version       int                    `json:"version"`
+	Collection    string                 `json:"collection"`
+	Disks         []DiskInfo             `json:"disks"`
+	Volumes       []VolumeInfo           `json:"volumes"`
+	Replicas      []ReplicaInfo          `json:"replicas"`
+	Relationships []PersistentVolumeInfo `json:"relationships"`
+	Issues        []issueRecord          `json:"issues,omitempty"` // Only collected for the JSON, YAML, HTML, JUnit and PDF reports
+}
+
+// reportFilters are the report filters applied to the -o json and -o yaml document
+type reportFilters struct {
+	node, disk, volume, diskTag, group string
+	replicas, relationships, issues    bool
+}
+
+// getReportDocument collects the disks, volumes, replicas and PV relationships of the report. It
+// renders from the collected snapshot like the table report.
+func getReportDocument(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, nodesGVR, volumesGVR, replicasGVR schema.GroupVersionResource, filters reportFilters, hardware *hardwareScraper) (reportDocument, error) {
+	doc := reportDocument{
+		Version:       reportDocumentVersion,
+		Collection:    currentSnapshot.id,
+		Disks:         []DiskInfo{},
+		Volumes:       []VolumeInfo{},
+		Replicas:      []ReplicaInfo{},
+		Relationships: []PersistentVolumeInfo{},
+	}
+
+	pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, namespace, volumesGVR, filters.volume, filters.diskTag)
+	if err != nil {
+		return doc, err
+	}
+	applyConsumerFilter(pvInfoMap)
+
+	disks, err := getDiskInfo(dynClient, namespace, nodesGVR, filters.node, filters.disk, filters.diskTag, hardware)
+	if err != nil {
+		return doc, err
+	}
+	doc.Disks = append(doc.Disks, disks...)
+
+	volumes, err := getVolumeInfo(dynClient, namespace, volumesGVR, filters.volume, filters.diskTag, filters.group, pvInfoMap)
+	if err != nil {
+		return doc, err
+	}
+	doc.Volumes = append(doc.Volumes, volumes...)
+
+	if filters.replicas {
+		replicas, err := getReplicaInfo(dynClient, namespace, replicasGVR, volumesGVR, filters.volume, filters.diskTag, filters.group)
+		if err != nil {
+			return doc, err
+		}
+		doc.Replicas = append(doc.Replicas, replicas...)
+	}
+
+	if filters.relationships {
+		for _, pvInfo := range pvInfoMap {
+			doc.Relationships = append(doc.Relationships, pvInfo)
+		}
+		sort.Slice(doc.Relationships, func(i, j int) bool {
+			return doc.Relationships[i].LonghornVolumeID < doc.Relationships[j].LonghornVolumeID
+		})
+	}
+
+	if filters.issues {
+		issues, err := getIssueRecords(dynClient, namespace, filters.node, filters.volume)
+		if err != nil {
+			return doc, err
+		}
+		doc.Issues = issues
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// RestoreStatusInfo stores the restore status of a single replica as reported by the engine
+type RestoreStatusInfo struct {
+	Replica       string
+	IsRestoring   bool
+	Progress      int64
+	LastRestored  string
+	CurrentBackup string
+	BackupURL     string
+	State         string
+	Error         string
+}
+
+// DRVolumeInfo stores information about a standby (disaster recovery) volume
+type DRVolumeInfo struct {
+	Name          string
+	FromBackup    string
+	LastBackup    string
+	LastBackupAt  string
+	LastRestored  string
+	State         string
+	Progress      int64 // Average restore progress over all replicas
+	Restoring     bool
+	RestoreErrors []string
+}
+
+// engineRestoreStatus returns the per-replica restore status reported in an engine's status
+func engineRestoreStatus(engine unstructured.Unstructured) []RestoreStatusInfo {
+	statusMap, found, _ := unstructured.NestedMap(engine.Object, "status", "restoreStatus")
+	if !found {
+		return nil
+	}
+
+	var statuses []RestoreStatusInfo
+	for replica, s := range statusMap {
+		status, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		isRestoring, _ := status["isRestoring"].(bool)
+		progress, _ := longhorn.Float64(status, "progress")
+		lastRestored, _ := status["lastRestored"].(string)
+		currentBackup, _ := status["currentRestoringBackup"].(string)
+		backupURL, _ := status["backupURL"].(string)
+		state, _ := status["state"].(string)
+		errMsg, _ := status["error"].(string)
+
+		statuses = append(statuses, RestoreStatusInfo{
+			Replica:       replica,
+			IsRestoring:   isRestoring,
+			Progress:      int64(progress),
+			LastRestored:  lastRestored,
+			CurrentBackup: currentBackup,
+			BackupURL:     backupURL,
+			State:         state,
+			Error:         errMsg,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Replica < statuses[j].Replica
+	})
+
+	return statuses
+}
+
+// getDRVolumes collects all standby volumes with their sync state
+func getDRVolumes(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) ([]DRVolumeInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var drVolumes []DRVolumeInfo
+	for _, volume := range volumes.Items {
+		if !longhorn.IsStandby(volume) {
+			continue
+		}
+
+		info := DRVolumeInfo{Name: volume.GetName()}
+		info.FromBackup, _, _ = unstructured.NestedString(volume.Object, "spec", "fromBackup")
+		info.LastBackup, _, _ = unstructured.NestedString(volume.Object, "status", "lastBackup")
+		info.LastBackupAt, _, _ = unstructured.NestedString(volume.Object, "status", "lastBackupAt")
+		info.State, _, _ = unstructured.NestedString(volume.Object, "status", "state")
+
+		if engine, found := volumeEngines[info.Name]; found {
+			info.LastRestored, _, _ = unstructured.NestedString(engine.Object, "status", "lastRestoredBackup")
+
+			statuses := engineRestoreStatus(engine)
+			var total int64
+			for _, status := range statuses {
+				total += status.Progress
+				if status.IsRestoring {
+					info.Restoring = true
+				}
+				if status.Error != "" {
+					info.RestoreErrors = append(info.RestoreErrors, fmt.Sprintf("%s: %s", status.Replica, status.Error))
+				}
+			}
+			if len(statuses) > 0 {
+				info.Progress = total / int64(len(statuses))
+			}
+		}
+
+		drVolumes = append(drVolumes, info)
+	}
+
+	sort.Slice(drVolumes, func(i, j int) bool {
+		return drVolumes[i].Name < drVolumes[j].Name
+	})
+
+	return drVolumes, nil
+}
+
+// printDRVolumes prints standby volumes with their last synced backup and restore progress
+func printDRVolumes(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) error {
+	drVolumes, err := getDRVolumes(dynClient, namespace, volumesGVR)
+	if err != nil {
+		return err
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "DR VOLUMES",
+		Description: "Standby volumes restoring incrementally from a backup target",
+		Color:       Blue,
+	})
+
+	if len(drVolumes) == 0 {
+		fmt.Println("No standby volumes found")
+		return nil
+	}
+
+	// Setup tabwriter
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSTATE\tLAST BACKUP\tBACKUP AT\tLAST RESTORED\tRESTORE\tSOURCE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSTATE\tLAST BACKUP\tBACKUP AT\tLAST RESTORED\tRESTORE\tSOURCE")
+	}
+
+	fmt.Fprintln(w, "──────\t─────\t───────────\t─────────\t─────────────\t───────\t──────")
+
+	for _, dr := range drVolumes {
+		// A standby is in sync when the last restored backup is the latest backup
+		restore := "in sync"
+		restoreColor := Green
+		switch {
+		case len(dr.RestoreErrors) > 0:
+			restore = "error"
+			restoreColor = Red
+		case dr.Restoring:
+			restore = fmt.Sprintf("restoring %d%%", dr.Progress)
+			restoreColor = Yellow
+		case dr.LastBackup != "" && dr.LastRestored != dr.LastBackup:
+			restore = "behind"
+			restoreColor = Yellow
+		}
+
+		lastBackup := dr.LastBackup
+		if lastBackup == "" {
+			lastBackup = "none"
+		}
+		lastRestored := dr.LastRestored
+		if lastRestored == "" {
+			lastRestored = "none"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			colorize(dr.Name, Blue),
+			dr.State,
+			lastBackup,
+			dr.LastBackupAt,
+			lastRestored,
+			colorize(restore, restoreColor),
+			dr.FromBackup,
+		)
+	}
+	w.Flush()
+
+	for _, dr := range drVolumes {
+		for _, restoreErr := range dr.RestoreErrors {
+			fmt.Printf("%s: %s\n", dr.Name, colorize(restoreErr, Red))
+		}
+	}
+
+	return nil
+}
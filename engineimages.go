@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Deployment state of an engine image on a node
+const (
+	imageReady     = "ready"
+	imageDeploying = "deploying"
+	imageMissing   = "missing"
+)
+
+// EngineImageInfo stores an engine image and where it is deployed
+type EngineImageInfo struct {
+	Name     string
+	Image    string
+	State    string          // ready, deploying, incompatible or error
+	RefCount int64           // Volumes using the image
+	Deployed map[string]bool // Nodes the image is deployed on, false while deploying
+}
+
+// nodeState returns whether the image is ready, deploying or missing on a node
+func (e EngineImageInfo) nodeState(node string) string {
+	deployed, found := e.Deployed[node]
+	switch {
+	case deployed:
+		return imageReady
+	case found || e.State == imageDeploying:
+		return imageDeploying
+	}
+	return imageMissing
+}
+
+// imageTag shortens an image reference to its tag for column headers, e.g. v1.6.0
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[i+1:]
+	}
+	return image
+}
+
+// getEngineImageMatrix returns the engine images and the Longhorn nodes they are deployed on
+func getEngineImageMatrix(dynClient dynamic.Interface, namespace string) ([]EngineImageInfo, []unstructured.Unstructured, error) {
+	images, err := listAll(dynClient, longhornGVR(longhornEngineImages), namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn engine images: %v", err)
+	}
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	var infos []EngineImageInfo
+	for _, image := range images.Items {
+		info := EngineImageInfo{Name: image.GetName(), Deployed: make(map[string]bool)}
+		info.Image, _, _ = unstructured.NestedString(image.Object, "spec", "image")
+		info.State, _, _ = unstructured.NestedString(image.Object, "status", "state")
+		info.RefCount, _, _ = unstructured.NestedInt64(image.Object, "status", "refCount")
+		deployments, _, _ := unstructured.NestedMap(image.Object, "status", "nodeDeploymentMap")
+		for node, deployed := range deployments {
+			info.Deployed[node], _ = deployed.(bool)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Image < infos[j].Image
+	})
+
+	sort.Slice(nodes.Items, func(i, j int) bool {
+		return nodes.Items[i].GetName() < nodes.Items[j].GetName()
+	})
+	return infos, nodes.Items, nil
+}
+
+// printEngineImageMatrix prints a node by engine image matrix of where each image is ready
+func printEngineImageMatrix(dynClient dynamic.Interface, namespace string) {
+	images, nodes, err := getEngineImageMatrix(dynClient, namespace)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "ENGINE IMAGE READINESS",
+		Description: "Where each engine image is deployed, volumes cannot attach on nodes missing their image",
+		Color:       Cyan,
+	})
+
+	if len(images) == 0 {
+		fmt.Println("No engine images found")
+		return
+	}
+
+	// One line per image, the matrix uses their tags as column headers
+	for _, image := range images {
+		state := image.State
+		switch state {
+		case imageReady:
+			state = colorize(state, Green)
+		case imageDeploying:
+			state = colorize(state, Yellow)
+		default:
+			state = colorize(state, Red)
+		}
+		fmt.Printf("  %s  %s, used by %d volume(s)\n", image.Image, state, image.RefCount)
+	}
+	fmt.Println()
+
+	w := newTableWriter()
+
+	// Print header
+	headers := []string{"NODE", "NODE READY"}
+	separators := []string{"────", "──────────"}
+	for _, image := range images {
+		headers = append(headers, imageTag(image.Image))
+		separators = append(separators, strings.Repeat("─", len(imageTag(image.Image))))
+	}
+	if useColors {
+		fmt.Fprintf(w, "%s%s%s%s\n", Bold, Yellow, strings.Join(headers, "\t"), Reset)
+	} else {
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+	}
+
+	fmt.Fprintln(w, strings.Join(separators, "\t"))
+
+	partial := 0
+	for _, node := range nodes {
+		ready := colorize("yes", Green)
+		nodeReady := conditionTrue(node.Object, "Ready")
+		if !nodeReady {
+			ready = colorize("no", Red)
+		}
+
+		cells := []string{node.GetName(), ready}
+		for _, image := range images {
+			switch state := image.nodeState(node.GetName()); {
+			case state == imageReady:
+				cells = append(cells, colorize(state, Green))
+			case !nodeReady:
+				// Images are expected to be missing on nodes that are down
+				cells = append(cells, colorize(state, Yellow))
+			case state == imageDeploying:
+				partial++
+				cells = append(cells, colorize(state, Yellow))
+			default:
+				partial++
+				cells = append(cells, colorize(state, Red))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	if len(nodes) == 0 {
+		fmt.Fprintln(w, "No nodes found")
+	}
+
+	w.Flush()
+
+	if partial > 0 {
+		fmt.Printf("\n%s\n", colorize(fmt.Sprintf("%d image deployment(s) missing or in progress on ready nodes, volumes using these images cannot attach there", partial), Yellow))
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Placement verdicts of a candidate disk
+const (
+	placementEligible   = "eligible"
+	placementHolds      = "holds replica"
+	placementIneligible = "ineligible"
+)
+
+// DiskPlacement explains whether a disk can take a new replica of a volume
+type DiskPlacement struct {
+	NodeName    string
+	DiskName    string
+	Schedulable ByteSize // Largest replica the disk can accept
+	Verdict     string
+	Reasons     []string // Why the disk is not eligible, or the replica it holds
+}
+
+// PlacementExplanation walks every disk of the cluster for one volume
+type PlacementExplanation struct {
+	VolumeName       string
+	Size             ByteSize
+	DesiredReplicas  int
+	Replicas         int // Healthy replicas of the volume
+	DiskSelector     []string
+	NodeSelector     []string
+	SoftAntiAffinity bool
+	OverProvisioning int
+	MinimalAvailable int
+	Disks            []DiskPlacement
+}
+
+// eligibleNodes returns the nodes with at least one eligible disk
+func (e PlacementExplanation) eligibleNodes() []string {
+	var nodes []string
+	for _, disk := range e.Disks {
+		if disk.Verdict == placementEligible && !contains(nodes, disk.NodeName) {
+			nodes = append(nodes, disk.NodeName)
+		}
+	}
+	return nodes
+}
+
+// runExplain implements the "lhmon4 explain" subcommand
+func runExplain(args []string) {
+	if len(args) == 0 || args[0] != "placement" {
+		fmt.Printf("Usage: %s explain placement <volume> [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("explain placement", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() != 1 {
+		fmt.Printf("Usage: %s explain placement <volume> [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	explanation, err := explainPlacement(dynClient, *clientOpts.namespace, fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	printPlacementExplanation(explanation)
+}
+
+// explainPlacement evaluates the Longhorn replica scheduling rules for a volume against every disk:
+// node and disk scheduling, node and disk tags, replica anti-affinity and the capacity limits.
+func explainPlacement(dynClient dynamic.Interface, namespace, volumeName string) (PlacementExplanation, error) {
+	obj, err := dynClient.Resource(longhornGVR(longhornVolumes)).Namespace(namespace).Get(context.TODO(), volumeName, metav1.GetOptions{})
+	if err != nil {
+		return PlacementExplanation{}, fmt.Errorf("failed to get Longhorn volume %s: %v", volumeName, err)
+	}
+	volume := longhorn.ParseVolume(*obj)
+
+	e := PlacementExplanation{
+		VolumeName:      volume.Name,
+		Size:            ByteSize(volume.Size),
+		DesiredReplicas: volume.DesiredReplicas,
+		DiskSelector:    volume.DiskSelector,
+		NodeSelector:    volume.NodeSelector,
+	}
+	if e.OverProvisioning, e.MinimalAvailable, err = getSchedulingSettings(dynClient, namespace); err != nil {
+		return e, err
+	}
+
+	// The volume overrides the global anti-affinity setting unless it is set to ignored
+	softAntiAffinity, err := getLonghornSetting(dynClient, namespace, replicaSoftAntiAffinitySetting)
+	if err != nil {
+		return e, err
+	}
+	e.SoftAntiAffinity = softAntiAffinity == "true"
+	switch override, _, _ := unstructured.NestedString(obj.Object, "spec", "replicaSoftAntiAffinity"); override {
+	case "enabled":
+		e.SoftAntiAffinity = true
+	case "disabled":
+		e.SoftAntiAffinity = false
+	}
+
+	// Healthy replicas of the volume by disk UUID and by node
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return e, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	replicaOnDisk := make(map[string]string)
+	replicaOnNode := make(map[string]string)
+	for _, item := range replicas.Items {
+		replica := longhorn.ParseReplica(item)
+		if replica.VolumeName != volumeName || !replica.Healthy {
+			continue
+		}
+		e.Replicas++
+		replicaOnDisk[replica.DiskID] = replica.Name
+		replicaOnNode[replica.NodeID] = replica.Name
+	}
+
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return e, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	plan := &capacityPlan{OverProvisioning: e.OverProvisioning, MinimalAvailable: e.MinimalAvailable}
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+		var nodeReasons []string
+		if reason := nodeUnschedulable(node); reason != "" {
+			nodeReasons = append(nodeReasons, reason)
+		}
+		nodeTags, _, _ := unstructured.NestedStringSlice(node.Object, "spec", "tags")
+		if missing := missingTags(nodeTags, e.NodeSelector); len(missing) > 0 {
+			nodeReasons = append(nodeReasons, "node tags lack "+strings.Join(missing, ","))
+		}
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		for diskName, d := range disksMap {
+			diskSpec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+			diskUUID, _ := diskStatus["diskUUID"].(string)
+			placement := DiskPlacement{NodeName: nodeName, DiskName: diskName, Verdict: placementIneligible}
+
+			if replica, found := replicaOnDisk[diskUUID]; found && diskUUID != "" {
+				placement.Verdict = placementHolds
+				placement.Reasons = []string{replica}
+				e.Disks = append(e.Disks, placement)
+				continue
+			}
+
+			reasons := append([]string(nil), nodeReasons...)
+			if replica, found := replicaOnNode[nodeName]; found && !e.SoftAntiAffinity {
+				reasons = append(reasons, fmt.Sprintf("node anti-affinity: node holds replica %s", replica))
+			}
+			reasons = append(reasons, diskIneligibility(diskSpec, diskStatus)...)
+			diskTags, _, _ := unstructured.NestedStringSlice(diskSpec, "tags")
+			if missing := missingTags(diskTags, e.DiskSelector); len(missing) > 0 {
+				reasons = append(reasons, "disk tags lack "+strings.Join(missing, ","))
+			}
+
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+			size, constraint := plan.schedulable(&planDisk{
+				Maximum:   ByteSize(maximum),
+				Reserved:  ByteSize(reserved),
+				Scheduled: ByteSize(scheduled),
+				Available: ByteSize(available),
+			})
+			placement.Schedulable = size
+			if size < e.Size {
+				reasons = append(reasons, fmt.Sprintf("only %s schedulable, limited by %s", size, constraint))
+			}
+
+			if len(reasons) == 0 {
+				placement.Verdict = placementEligible
+			}
+			placement.Reasons = reasons
+			e.Disks = append(e.Disks, placement)
+		}
+	}
+
+	sort.Slice(e.Disks, func(i, j int) bool {
+		if e.Disks[i].NodeName != e.Disks[j].NodeName {
+			return e.Disks[i].NodeName < e.Disks[j].NodeName
+		}
+		return e.Disks[i].DiskName < e.Disks[j].DiskName
+	})
+	return e, nil
+}
+
+// missingTags returns the wanted tags that are not present
+func missingTags(tags, wanted []string) []string {
+	var missing []string
+	for _, tag := range wanted {
+		if !contains(tags, tag) {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// diskIneligibility returns why a disk refuses new replicas, see diskAcceptsReplicas
+func diskIneligibility(diskSpec, diskStatus map[string]interface{}) []string {
+	var reasons []string
+	if allow, ok := diskSpec["allowScheduling"].(bool); ok && !allow {
+		reasons = append(reasons, "scheduling disabled on disk")
+	}
+	if evict, _ := diskSpec["evictionRequested"].(bool); evict {
+		reasons = append(reasons, "eviction requested on disk")
+	}
+	if diskStatus == nil {
+		return append(reasons, "no disk status available")
+	}
+	if !conditionTrue(diskStatus, "Schedulable") {
+		reason := "disk is not schedulable"
+		for _, condition := range longhorn.Conditions(diskStatus) {
+			if condition.Type == "Schedulable" && condition.Reason != "" {
+				reason += ": " + condition.Reason
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// printPlacementExplanation prints the verdict of every disk and whether the missing replicas fit
+func printPlacementExplanation(e PlacementExplanation) {
+	description := fmt.Sprintf("Volume of %s with %d of %d healthy replica(s)", e.Size, e.Replicas, e.DesiredReplicas)
+	if len(e.DiskSelector) > 0 {
+		description += ", disk selector " + strings.Join(e.DiskSelector, ",")
+	}
+	if len(e.NodeSelector) > 0 {
+		description += ", node selector " + strings.Join(e.NodeSelector, ",")
+	}
+	printSectionHeader(Section{
+		Title:       "REPLICA PLACEMENT FOR " + strings.ToUpper(e.VolumeName),
+		Description: description,
+		Color:       Cyan,
+	})
+
+	antiAffinity := "hard"
+	if e.SoftAntiAffinity {
+		antiAffinity = "soft"
+	}
+	fmt.Printf("Over-provisioning: %d%%, minimal available: %d%%, node anti-affinity: %s\n\n",
+		e.OverProvisioning, e.MinimalAvailable, antiAffinity)
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tSCHEDULABLE\tVERDICT\tREASON%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISK\tSCHEDULABLE\tVERDICT\tREASON")
+	}
+
+	fmt.Fprintln(w, "────\t────\t───────────\t───────\t──────")
+
+	for _, disk := range e.Disks {
+		verdictColor := Red
+		switch disk.Verdict {
+		case placementEligible:
+			verdictColor = Green
+		case placementHolds:
+			verdictColor = Blue
+		}
+		reason := strings.Join(disk.Reasons, "; ")
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			disk.NodeName,
+			disk.DiskName,
+			disk.Schedulable,
+			colorize(disk.Verdict, verdictColor),
+			reason,
+		)
+	}
+
+	if len(e.Disks) == 0 {
+		fmt.Fprintln(w, "No disks found")
+	}
+
+	w.Flush()
+	fmt.Println()
+
+	// New replicas need separate disks, and separate nodes with hard anti-affinity
+	missing := e.DesiredReplicas - e.Replicas
+	eligibleDisks := 0
+	for _, disk := range e.Disks {
+		if disk.Verdict == placementEligible {
+			eligibleDisks++
+		}
+	}
+	candidates := eligibleDisks
+	if !e.SoftAntiAffinity {
+		candidates = len(e.eligibleNodes())
+	}
+	switch {
+	case missing <= 0:
+		fmt.Println(colorize(fmt.Sprintf("All %d replica(s) are placed, %d disk(s) could take a rebuilt replica", e.DesiredReplicas, eligibleDisks), Green+Bold))
+	case candidates >= missing:
+		fmt.Println(colorize(fmt.Sprintf("%d missing replica(s) can be placed on %d eligible disk(s)", missing, eligibleDisks), Green+Bold))
+	default:
+		fmt.Println(colorize(fmt.Sprintf("%d missing replica(s) but only %d placement(s) available", missing, candidates), Red+Bold))
+	}
+}
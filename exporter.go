@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metricFamily is a metric with its samples, rendered in the Prometheus text exposition format
+type metricFamily struct {
+	Name    string
+	Help    string
+	Type    string // gauge or counter
+	Samples []metricSample
+}
+
+// metricSample is one labelled value of a metric family. Labels are name/value pairs in order.
+type metricSample struct {
+	Labels []string
+	Value  float64
+}
+
+// add appends a sample with the given label name/value pairs
+func (f *metricFamily) add(value float64, labels ...string) {
+	f.Samples = append(f.Samples, metricSample{Labels: labels, Value: value})
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetrics renders metric families in the Prometheus text exposition format
+func writeMetrics(out io.Writer, families []*metricFamily) error {
+	w := bufio.NewWriter(out)
+	for _, family := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n", family.Name, family.Help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", family.Name, family.Type)
+		for _, sample := range family.Samples {
+			w.WriteString(family.Name)
+			if len(sample.Labels) > 0 {
+				w.WriteString("{")
+				for i := 0; i+1 < len(sample.Labels); i += 2 {
+					if i > 0 {
+						w.WriteString(",")
+					}
+					fmt.Fprintf(w, `%s="%s"`, sample.Labels[i], labelEscaper.Replace(sample.Labels[i+1]))
+				}
+				w.WriteString("}")
+			}
+			fmt.Fprintf(w, " %s\n", strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		}
+	}
+	return w.Flush()
+}
+
+// joinTags renders a tag list as a single label value wrapped in commas, e.g. ",ssd,fast,", so a
+// single tag can be matched with =~".*,ssd,.*"
+func joinTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return "," + strings.Join(sorted, ",") + ","
+}
+
+// diskMetrics are the capacity metrics of each disk. The exporter and -o prometheus both build them
+// here, so their samples carry the same names and labels and can be joined.
+type diskMetrics struct {
+	maximum, available, scheduled, reserved *metricFamily
+}
+
+// newDiskMetrics creates the disk metric families without samples
+func newDiskMetrics() *diskMetrics {
+	return &diskMetrics{
+		maximum:   &metricFamily{Name: "lhmon_disk_storage_maximum_bytes", Help: "Capacity of a Longhorn disk.", Type: "gauge"},
+		available: &metricFamily{Name: "lhmon_disk_storage_available_bytes", Help: "Available space on a Longhorn disk.", Type: "gauge"},
+		scheduled: &metricFamily{Name: "lhmon_disk_storage_scheduled_bytes", Help: "Storage scheduled to the replicas on a Longhorn disk.", Type: "gauge"},
+		reserved:  &metricFamily{Name: "lhmon_disk_storage_reserved_bytes", Help: "Storage reserved on a Longhorn disk.", Type: "gauge"},
+	}
+}
+
+// add records the capacity of a disk, labelled by node, disk, disk tags and node tags
+func (m *diskMetrics) add(node, disk string, diskTags, nodeTags []string, maximum, available, scheduled, reserved float64) {
+	labels := []string{"node", node, "disk", disk, "disk_tags", joinTags(diskTags), "node_tags", joinTags(nodeTags)}
+	m.maximum.add(maximum, labels...)
+	m.available.add(available, labels...)
+	m.scheduled.add(scheduled, labels...)
+	m.reserved.add(reserved, labels...)
+}
+
+// families returns the disk metric families in exposition order
+func (m *diskMetrics) families() []*metricFamily {
+	return []*metricFamily{m.maximum, m.available, m.scheduled, m.reserved}
+}
+
+// volumeMetrics are the size metrics of each volume, shared like diskMetrics
+type volumeMetrics struct {
+	size, actual *metricFamily
+}
+
+// newVolumeMetrics creates the volume metric families without samples
+func newVolumeMetrics() *volumeMetrics {
+	return &volumeMetrics{
+		size:   &metricFamily{Name: "lhmon_volume_size_bytes", Help: "Provisioned size of a Longhorn volume.", Type: "gauge"},
+		actual: &metricFamily{Name: "lhmon_volume_actual_size_bytes", Help: "Space used by a Longhorn volume, per replica.", Type: "gauge"},
+	}
+}
+
+// add records the size of a volume, labelled by volume, storage class and disk selector
+func (m *volumeMetrics) add(volume, storageClass string, diskSelector []string, size, actual float64) {
+	labels := []string{"volume", volume, "storage_class", storageClass, "disk_selector", joinTags(diskSelector)}
+	m.size.add(size, labels...)
+	m.actual.add(actual, labels...)
+}
+
+// families returns the volume metric families in exposition order
+func (m *volumeMetrics) families() []*metricFamily {
+	return []*metricFamily{m.size, m.actual}
+}
+
+// tierCapacity sums the disk capacity of one disk or node tag
+type tierCapacity struct {
+	maximum, available, scheduled ByteSize
+	disks                         int
+}
+
+// classUsage sums the volumes of one storage class
+type classUsage struct {
+	size, actual ByteSize
+	volumes      int
+}
+
+// collectMetrics gathers the capacity metrics of the cluster. Besides node, disk and volume labels,
+// capacity is labelled and aggregated by disk tag, node tag and storage class, so dashboards can
+// be built per tier without relabeling rules.
+func collectMetrics(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) ([]*metricFamily, error) {
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	diskMetrics := newDiskMetrics()
+	diskTiers := make(map[string]*tierCapacity)
+	nodeTiers := make(map[string]*tierCapacity)
+	addTier := func(tiers map[string]*tierCapacity, tags []string, maximum, available, scheduled ByteSize) {
+		// Untagged capacity is reported with an empty tag
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, tag := range tags {
+			tier := tiers[tag]
+			if tier == nil {
+				tier = &tierCapacity{}
+				tiers[tag] = tier
+			}
+			tier.maximum += maximum
+			tier.available += available
+			tier.scheduled += scheduled
+			tier.disks++
+		}
+	}
+
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+		nodeTags, _, _ := unstructured.NestedStringSlice(node.Object, "spec", "tags")
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		diskNames := make([]string, 0, len(disksMap))
+		for diskName := range disksMap {
+			diskNames = append(diskNames, diskName)
+		}
+		sort.Strings(diskNames)
+
+		for _, diskName := range diskNames {
+			diskSpec, _ := disksMap[diskName].(map[string]interface{})
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+			diskTags, _, _ := unstructured.NestedStringSlice(diskSpec, "tags")
+
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+
+			diskMetrics.add(nodeName, diskName, diskTags, nodeTags, maximum, available, scheduled, reserved)
+
+			addTier(diskTiers, diskTags, ByteSize(maximum), ByteSize(available), ByteSize(scheduled))
+			addTier(nodeTiers, nodeTags, ByteSize(maximum), ByteSize(available), ByteSize(scheduled))
+		}
+	}
+
+	families := diskMetrics.families()
+	for _, tiering := range []struct {
+		label string
+		tiers map[string]*tierCapacity
+	}{{"disk_tag", diskTiers}, {"node_tag", nodeTiers}} {
+		prefix := "lhmon_" + tiering.label
+		per := " per " + strings.Replace(tiering.label, "_", " ", 1) + "."
+		maximum := &metricFamily{Name: prefix + "_storage_maximum_bytes", Help: "Capacity of the Longhorn disks" + per, Type: "gauge"}
+		available := &metricFamily{Name: prefix + "_storage_available_bytes", Help: "Available space on the Longhorn disks" + per, Type: "gauge"}
+		scheduled := &metricFamily{Name: prefix + "_storage_scheduled_bytes", Help: "Storage scheduled on the Longhorn disks" + per, Type: "gauge"}
+		disks := &metricFamily{Name: prefix + "_disks", Help: "Number of Longhorn disks" + per, Type: "gauge"}
+
+		tags := make([]string, 0, len(tiering.tiers))
+		for tag := range tiering.tiers {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			tier := tiering.tiers[tag]
+			maximum.add(float64(tier.maximum), tiering.label, tag)
+			available.add(float64(tier.available), tiering.label, tag)
+			scheduled.add(float64(tier.scheduled), tiering.label, tag)
+			disks.add(float64(tier.disks), tiering.label, tag)
+		}
+		families = append(families, maximum, available, scheduled, disks)
+	}
+
+	// Longhorn volumes do not record their StorageClass, it comes from the bound PV
+	classes := make(map[string]string)
+	pvs, err := listPersistentVolumes(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %v", err)
+	}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == longhornCSIDriver {
+			classes[pv.Spec.CSI.VolumeHandle] = pv.Spec.StorageClassName
+		}
+	}
+
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	volumeMetrics := newVolumeMetrics()
+	classSize := &metricFamily{Name: "lhmon_storage_class_volume_size_bytes", Help: "Provisioned size of the Longhorn volumes per storage class.", Type: "gauge"}
+	classActual := &metricFamily{Name: "lhmon_storage_class_volume_actual_size_bytes", Help: "Space used by the Longhorn volumes per storage class, per replica.", Type: "gauge"}
+	classVolumes := &metricFamily{Name: "lhmon_storage_class_volumes", Help: "Number of Longhorn volumes per storage class.", Type: "gauge"}
+
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	snapshotCount := &metricFamily{Name: "lhmon_volume_snapshot_count", Help: "Number of snapshots of a Longhorn volume, including removed snapshots not yet purged.", Type: "gauge"}
+	backupAge := &metricFamily{Name: "lhmon_volume_last_backup_age_seconds", Help: "Seconds since the last completed backup of a Longhorn volume, absent for volumes never backed up.", Type: "gauge"}
+
+	now := time.Now()
+	classTotals := make(map[string]*classUsage)
+	for _, volume := range volumes.Items {
+		name := volume.GetName()
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseFloat(sizeStr, 64)
+		actual, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		diskSelector, _, _ := unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
+		class := classes[name]
+
+		volumeMetrics.add(name, class, diskSelector, size, float64(actual))
+
+		// Snapshots are only known while the volume has an engine
+		if engine, found := volumeEngines[name]; found {
+			count := 0
+			for _, snapshot := range engineSnapshots(engine) {
+				if snapshot.Name != volumeHeadSnapshot {
+					count++
+				}
+			}
+			snapshotCount.add(float64(count), "volume", name, "storage_class", class)
+		}
+		lastBackupAt, _, _ := unstructured.NestedString(volume.Object, "status", "lastBackupAt")
+		if at, err := time.Parse(time.RFC3339, lastBackupAt); err == nil {
+			backupAge.add(now.Sub(at).Seconds(), "volume", name, "storage_class", class)
+		}
+
+		total := classTotals[class]
+		if total == nil {
+			total = &classUsage{}
+			classTotals[class] = total
+		}
+		total.size += ByteSize(size)
+		total.actual += ByteSize(actual)
+		total.volumes++
+	}
+
+	classNames := make([]string, 0, len(classTotals))
+	for class := range classTotals {
+		classNames = append(classNames, class)
+	}
+	sort.Strings(classNames)
+	for _, class := range classNames {
+		total := classTotals[class]
+		classSize.add(float64(total.size), "storage_class", class)
+		classActual.add(float64(total.actual), "storage_class", class)
+		classVolumes.add(float64(total.volumes), "storage_class", class)
+	}
+
+	families = append(families, volumeMetrics.families()...)
+	return append(families, snapshotCount, backupAge, classSize, classActual, classVolumes), nil
+}
+
+// exporterListenAddress is the default address of the exporter. longhorn-manager serves on 9500 to
+// 9503, so the exporter stays clear of them and can run as a sidecar or with hostNetwork next to it.
+const exporterListenAddress = ":9769"
+
+// runExporter implements the "lhmon4 exporter" subcommand, serving metrics for Prometheus
+func runExporter(args []string) {
+	fs := flag.NewFlagSet("exporter", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	listen := fs.String("listen", exporterListenAddress, "address to serve /metrics on, away from longhorn-manager's 9500 to 9503")
+	parseFlags(fs, args)
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Scrapes are served from watches instead of listing everything on each request
+	listCache = newResourceCache()
+
+	// Transitions are counted from the watch stream, between scrapes
+	transitions := newTransitionCounter()
+	events := make(chan followEvent)
+	go followResource(dynClient, *clientOpts.namespace, longhornVolumes, "volume", metav1.ListOptions{}, events)
+	go followResource(dynClient, *clientOpts.namespace, longhornEngines, "engine", metav1.ListOptions{}, events)
+	go transitions.follow(events)
+
+	var scrape sync.Mutex
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		scrape.Lock()
+		defer scrape.Unlock()
+
+		families, err := collectMetrics(dynClient, clientset, *clientOpts.namespace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		families = append(families, transitions.families()...)
+
+		var body bytes.Buffer
+		writeMetrics(&body, families)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(body.Bytes())
+	})
+
+	fmt.Printf("Serving metrics on %s/metrics\n", *listen)
+	if err := http.ListenAndServe(*listen, nil); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
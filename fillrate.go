@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DiskFillInfo describes a disk whose available space shrinks fast enough to run out soon
+type DiskFillInfo struct {
+	NodeName         string
+	DiskName         string
+	StorageMaximum   ByteSize
+	StorageAvailable ByteSize
+	ShrinkPerDay     ByteSize
+	TimeToFull       time.Duration
+	Period           time.Duration
+}
+
+// findFillingDisks records the available space of every disk in the history store and returns
+// the disks that fill up within the horizon at their current rate
+func findFillingDisks(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, horizon time.Duration) ([]DiskFillInfo, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	state, err := sharedState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var filling []DiskFillInfo
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+
+		diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
+		if err != nil || !found {
+			continue
+		}
+
+		for diskName, diskStatusInterface := range diskStatusMap {
+			diskStatus, ok := diskStatusInterface.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			storageMaxFloat, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			storageAvailableFloat, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			available := ByteSize(storageAvailableFloat)
+
+			key := nodeName + "/" + diskName
+			recorded := state.Disks[key]
+			rate, period, ok := sampleRate(recorded.Samples, now, available)
+			recorded.Samples = recordSample(recorded.Samples, now, available)
+			state.Disks[key] = recorded
+
+			// Only shrinking available space fills a disk
+			if !ok || rate >= 0 {
+				continue
+			}
+
+			shrinkPerDay := -rate
+			timeToFull := time.Duration(float64(available) / shrinkPerDay * float64(24*time.Hour))
+			if timeToFull > horizon {
+				continue
+			}
+
+			filling = append(filling, DiskFillInfo{
+				NodeName:         nodeName,
+				DiskName:         diskName,
+				StorageMaximum:   ByteSize(storageMaxFloat),
+				StorageAvailable: available,
+				ShrinkPerDay:     ByteSize(shrinkPerDay),
+				TimeToFull:       timeToFull,
+				Period:           period,
+			})
+		}
+	}
+
+	if err := state.save(); err != nil {
+		return nil, err
+	}
+
+	// Disks filling up first are the most urgent
+	sort.Slice(filling, func(i, j int) bool {
+		return filling[i].TimeToFull < filling[j].TimeToFull
+	})
+
+	return filling, nil
+}
+
+// printDiskFillRate prints disks whose available space runs out within the horizon
+func printDiskFillRate(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, horizon time.Duration) {
+	filling, err := findFillingDisks(dynClient, namespace, nodesGVR, horizon)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "DISK FILL RATE",
+		Description: fmt.Sprintf("Disks expected to run out of space within %s at their recent rate", formatDuration(horizon)),
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tMAX\tAVAILABLE\tSHRINK/DAY\tFULL IN\tMEASURED OVER%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISK\tMAX\tAVAILABLE\tSHRINK/DAY\tFULL IN\tMEASURED OVER")
+	}
+
+	fmt.Fprintln(w, "────\t────\t───\t─────────\t──────────\t───────\t─────────────")
+
+	for _, disk := range filling {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			disk.NodeName,
+			disk.DiskName,
+			disk.StorageMaximum,
+			disk.StorageAvailable,
+			disk.ShrinkPerDay,
+			colorize(withRunbook(formatDuration(disk.TimeToFull), "disk"), Red),
+			disk.Period.Round(time.Minute),
+		)
+	}
+
+	if len(filling) == 0 {
+		fmt.Fprintln(w, "No disks filling up within the horizon")
+	}
+
+	w.Flush()
+}
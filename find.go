@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// VolumeMatch is a volume found by "lhmon4 find" with the fields that matched the search
+type VolumeMatch struct {
+	Volume  VolumeInfo
+	PVC     string // namespace/name of the bound PVC, empty when there is none
+	Matches []string
+}
+
+// runFind implements the "lhmon4 find" subcommand
+func runFind(args []string) {
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() == 0 {
+		fmt.Printf("Usage: %s find <text>... [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	namespace := *clientOpts.namespace
+	volumesGVR := longhornGVR(longhornVolumes)
+	pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, namespace, volumesGVR, "", "")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	volumes, err := getVolumeInfo(dynClient, namespace, volumesGVR, "", "", "", pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	matches := findVolumes(volumes, pvInfoMap, fs.Args())
+	printVolumeMatches(matches, strings.Join(fs.Args(), " "))
+	if len(matches) == 0 {
+		output.finish()
+		os.Exit(1)
+	}
+}
+
+// findVolumes returns the volumes matching every search term. A term matches when it is contained,
+// ignoring case, in the volume name, the PV or PVC name, the PVC namespace, a volume label or the
+// name of a consumer pod.
+func findVolumes(volumes []VolumeInfo, pvInfoMap map[string]PersistentVolumeInfo, terms []string) []VolumeMatch {
+	var matches []VolumeMatch
+	for _, volume := range volumes {
+		// Searchable fields, described as they are reported
+		fields := []string{"volume " + volume.Name}
+		for _, label := range volume.Labels {
+			fields = append(fields, "label "+label)
+		}
+		pvc := ""
+		if pvInfo, found := pvInfoMap[volume.Name]; found {
+			fields = append(fields, "pv "+pvInfo.Name)
+			if pvInfo.PVCName != "" {
+				pvc = pvInfo.PVCNamespace + "/" + pvInfo.PVCName
+				fields = append(fields, "pvc "+pvc)
+			}
+			for _, pod := range pvInfo.ConsumerPods {
+				fields = append(fields, "pod "+pod.Namespace+"/"+pod.Name)
+			}
+		}
+
+		var matched []string
+		for _, term := range terms {
+			found := false
+			for _, field := range fields {
+				// The description is not searched, "pod" must not match every volume with a consumer
+				_, value, _ := strings.Cut(field, " ")
+				if strings.Contains(strings.ToLower(value), strings.ToLower(term)) {
+					found = true
+					if !contains(matched, field) {
+						matched = append(matched, field)
+					}
+				}
+			}
+			if !found {
+				matched = nil
+				break
+			}
+		}
+		if matched != nil {
+			matches = append(matches, VolumeMatch{Volume: volume, PVC: pvc, Matches: matched})
+		}
+	}
+	return matches
+}
+
+// printVolumeMatches prints the volumes found with their health and the fields that matched
+func printVolumeMatches(matches []VolumeMatch, search string) {
+	printSectionHeader(Section{
+		Title:       "VOLUMES MATCHING " + strings.ToUpper(search),
+		Description: "Volumes whose name, PV, PVC, namespace, labels or consumer pods contain every search term",
+		Color:       Magenta,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tPVC\tSIZE\tSTATE\tROBUSTNESS\tMATCHED%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tPVC\tSIZE\tSTATE\tROBUSTNESS\tMATCHED")
+	}
+
+	fmt.Fprintln(w, "──────\t───\t────\t─────\t──────────\t───────")
+
+	for _, match := range matches {
+		vol := match.Volume
+		robustnessColor := Green
+		if vol.Robustness == "degraded" {
+			robustnessColor = Yellow
+		} else if vol.Robustness == "faulted" || vol.Robustness == "unknown" {
+			robustnessColor = Red
+		}
+		pvc := match.PVC
+		if pvc == "" {
+			pvc = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			vol.Name,
+			pvc,
+			vol.Size,
+			vol.State,
+			colorize(vol.Robustness, robustnessColor),
+			strings.Join(match.Matches, ", "),
+		)
+	}
+
+	if len(matches) == 0 {
+		fmt.Fprintln(w, "No volumes found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// fixtureResources are the Longhorn resources exported as fixtures
+var fixtureResources = append([]string{longhornSettings, longhornInstances}, snapshotResources...)
+
+// ipv4Pattern matches IPv4 addresses embedded anywhere in a string value
+var ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+
+// ipv6Pattern matches candidate IPv6 addresses, anything with two colons between hex digits, dots
+// included for IPv4-mapped addresses. Candidates that do not parse, such as times of day, are kept.
+var ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f:.]*:[0-9A-Fa-f:.]*:[0-9A-Fa-f:.]*`)
+
+// redactedFields are replaced entirely, they may contain bucket names, hosts or credentials
+var redactedFields = map[string]bool{
+	"backupTargetURL":  true,
+	"credentialSecret": true,
+	"fromBackup":       true,
+	"url":              true,
+}
+
+// redactedSettingPrefix selects the settings whose value is redacted, the legacy backup-target
+// settings hold the full s3:// or nfs:// bucket URL and the credential secret
+const redactedSettingPrefix = "backup-target"
+
+// runDev implements the "lhmon4 dev" subcommands for contributors
+func runDev(args []string) {
+	if len(args) == 0 || args[0] != "dump-fixtures" {
+		fmt.Printf("Usage: %s dev dump-fixtures [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("dev dump-fixtures", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	outputDir := fs.String("output", "fixtures", "directory the fixtures are written to, one <resource>.json list per resource")
+	parseFlags(fs, args[1:])
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		fmt.Printf("Error: failed to create output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	lists := make(map[string]*unstructured.UnstructuredList)
+	for _, resource := range fixtureResources {
+		list, err := listAll(dynClient, longhornGVR(resource), *clientOpts.namespace)
+		if err != nil {
+			// Older Longhorn releases lack some resources, the others are still useful
+			fmt.Printf("Skipping %s: %v\n", resource, err)
+			continue
+		}
+		lists[resource] = list
+	}
+
+	// Node names are hostnames and appear in most resources, map them before sanitizing any
+	sanitizer := newFixtureSanitizer()
+	if nodes := lists[longhornNodes]; nodes != nil {
+		sanitizer.mapNodes(nodes.Items)
+	}
+
+	for _, resource := range fixtureResources {
+		list := lists[resource]
+		if list == nil {
+			continue
+		}
+
+		for i := range list.Items {
+			sanitizer.sanitize(&list.Items[i])
+		}
+		if sanitizer.err != nil {
+			fmt.Printf("Error: failed to sanitize %s: %v\n", resource, sanitizer.err)
+			os.Exit(1)
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			return list.Items[i].GetName() < list.Items[j].GetName()
+		})
+		list.SetAPIVersion(longhornGroup + "/" + longhornVersion)
+		list.SetKind("List")
+
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			fmt.Printf("Error: failed to encode %s: %v\n", resource, err)
+			os.Exit(1)
+		}
+		path := filepath.Join(*outputDir, resource+".json")
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			fmt.Printf("Error: failed to write %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %d %s to %s\n", len(list.Items), resource, path)
+	}
+
+	fmt.Println("Review the fixtures for anything sensitive before sharing them.")
+	fmt.Printf("Reproduce a report from them with: %s --replay %s\n", commandName, *outputDir)
+}
+
+// fixtureSanitizer strips server-assigned metadata and replaces addresses and node names
+// consistently, so the same IP or node maps to the same placeholder across all fixtures of a dump
+type fixtureSanitizer struct {
+	addresses map[string]string
+	ipv4      uint32 // Placeholders handed out from 10.0.0.0/8
+	ipv6      uint64 // Placeholders handed out from 2001:db8::/32
+
+	nodes       map[string]string
+	nodePattern *regexp.Regexp // Nil until the node names are known
+
+	err error // Set when the placeholders run out, the dump must not be written
+}
+
+// newFixtureSanitizer creates a sanitizer with empty address and node mappings
+func newFixtureSanitizer() *fixtureSanitizer {
+	return &fixtureSanitizer{
+		addresses: make(map[string]string),
+		nodes:     make(map[string]string),
+	}
+}
+
+// mapNodes assigns the placeholders node-1, node-2, ... to the node names in sorted order. Kubernetes
+// node names are the hostnames of the machines and show up in spec.nodeID, labels and object names.
+func (s *fixtureSanitizer) mapNodes(nodes []unstructured.Unstructured) {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.GetName())
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	sort.Strings(names)
+	alternatives := make([]string, len(names))
+	for i, name := range names {
+		s.nodes[name] = fmt.Sprintf("node-%d", i+1)
+		alternatives[i] = regexp.QuoteMeta(name)
+	}
+
+	// Longest first, so node10 is not replaced as node1 followed by a 0
+	sort.Slice(alternatives, func(i, j int) bool {
+		return len(alternatives[i]) > len(alternatives[j])
+	})
+	s.nodePattern = regexp.MustCompile(`\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+}
+
+// sanitize removes metadata irrelevant to parsing and masks addresses, node names and redacted fields
+func (s *fixtureSanitizer) sanitize(obj *unstructured.Unstructured) {
+	obj.SetManagedFields(nil)
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+
+	annotations := obj.GetAnnotations()
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+
+	references := obj.GetOwnerReferences()
+	for i := range references {
+		references[i].UID = ""
+	}
+	obj.SetOwnerReferences(references)
+
+	// Settings keep their value at the top level, not under a redacted key
+	if obj.GetKind() == "Setting" && strings.HasPrefix(obj.GetName(), redactedSettingPrefix) {
+		if value, _, _ := unstructured.NestedString(obj.Object, "value"); value != "" {
+			unstructured.SetNestedField(obj.Object, "redacted", "value")
+		}
+	}
+
+	obj.Object = s.sanitizeValue("", obj.Object).(map[string]interface{})
+}
+
+// sanitizeValue walks a value, returning it with addresses, node names and redacted fields masked
+func (s *fixtureSanitizer) sanitizeValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		sanitized := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			// Some maps are keyed by node name
+			sanitized[s.sanitizeString(k)] = s.sanitizeValue(k, child)
+		}
+		return sanitized
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.sanitizeValue(key, child)
+		}
+		return v
+	case string:
+		if redactedFields[key] && v != "" {
+			return "redacted"
+		}
+		return s.sanitizeString(v)
+	}
+	return value
+}
+
+// sanitizeString replaces the addresses and node names in a string. IPv6 goes first, so the IPv4
+// part of an IPv4-mapped address is not replaced on its own.
+func (s *fixtureSanitizer) sanitizeString(v string) string {
+	v = ipv6Pattern.ReplaceAllStringFunc(v, s.address)
+	v = ipv4Pattern.ReplaceAllStringFunc(v, s.address)
+	if s.nodePattern != nil {
+		v = s.nodePattern.ReplaceAllStringFunc(v, func(name string) string {
+			return s.nodes[name]
+		})
+	}
+	return v
+}
+
+// address maps an IP address to a placeholder, IPv4 from 10.0.0.0/8 and IPv6 from the documentation
+// prefix 2001:db8::/32. Strings that merely look like addresses are returned unchanged.
+func (s *fixtureSanitizer) address(ip string) string {
+	if placeholder, found := s.addresses[ip]; found {
+		return placeholder
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	var placeholder string
+	if addr.Is4() {
+		// Distinct addresses must never share a placeholder, that would join unrelated objects
+		if s.ipv4 == 1<<24-2 {
+			s.err = fmt.Errorf("more than %d distinct IPv4 addresses", s.ipv4)
+			return ip
+		}
+		s.ipv4++
+		placeholder = netip.AddrFrom4([4]byte{10, byte(s.ipv4 >> 16), byte(s.ipv4 >> 8), byte(s.ipv4)}).String()
+	} else {
+		s.ipv6++
+		b := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+		binary.BigEndian.PutUint64(b[8:], s.ipv6)
+		placeholder = netip.AddrFrom16(b).String()
+	}
+	s.addresses[ip] = placeholder
+	return placeholder
+}
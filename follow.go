@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// followEvent is a watch event on one of the followed objects
+type followEvent struct {
+	Kind   string // volume, engine or replica
+	Type   watch.EventType
+	Object *unstructured.Unstructured
+}
+
+// runFollow implements the "lhmon4 follow <volume>" subcommand
+func runFollow(args []string) {
+	fs := flag.NewFlagSet("follow", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() != 1 {
+		fmt.Printf("Usage: %s follow [flags] <volume>\n", commandName)
+		os.Exit(1)
+	}
+	volumeName := fs.Arg(0)
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Engines and replicas carry the name of their volume as a label
+	events := make(chan followEvent)
+	go followResource(dynClient, *clientOpts.namespace, longhornVolumes, "volume", metav1.ListOptions{FieldSelector: "metadata.name=" + volumeName}, events)
+	go followResource(dynClient, *clientOpts.namespace, longhornEngines, "engine", metav1.ListOptions{LabelSelector: "longhornvolume=" + volumeName}, events)
+	go followResource(dynClient, *clientOpts.namespace, longhornReplicas, "replica", metav1.ListOptions{LabelSelector: "longhornvolume=" + volumeName}, events)
+
+	fmt.Printf("Following volume %s. Press Ctrl+C to exit...\n\n", colorize(volumeName, Bold+Blue))
+
+	// Remember the last seen fields of every object to only print changes
+	known := make(map[string]map[string]string)
+	for event := range events {
+		key := event.Kind + "/" + event.Object.GetName()
+		timestamp := colorize(formatClock(time.Now()), Cyan)
+
+		if event.Type == watch.Deleted {
+			delete(known, key)
+			fmt.Printf("%s  %s  %s\n", timestamp, colorize(key, Blue), colorize("deleted", Red))
+			continue
+		}
+
+		fields := followedFields(event.Kind, event.Object)
+		previous, seen := known[key]
+		known[key] = fields
+
+		if !seen {
+			fmt.Printf("%s  %s  %s\n", timestamp, colorize(key, Blue), formatFollowedFields(fields))
+			continue
+		}
+
+		for _, change := range diffFollowedFields(previous, fields) {
+			fmt.Printf("%s  %s  %s\n", timestamp, colorize(key, Blue), change)
+		}
+	}
+}
+
+// followResource watches a Longhorn resource and forwards its events, re-establishing the watch when it closes
+func followResource(dynClient dynamic.Interface, namespace, resource, kind string, opts metav1.ListOptions, events chan<- followEvent) {
+	for {
+		watcher, err := dynClient.Resource(longhornGVR(resource)).Namespace(namespace).Watch(context.TODO(), opts)
+		if err != nil {
+			fmt.Printf("Error: failed to watch Longhorn %s: %v\n", resource, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for event := range watcher.ResultChan() {
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			events <- followEvent{Kind: kind, Type: event.Type, Object: obj}
+		}
+		watcher.Stop()
+	}
+}
+
+// followedFields extracts the fields of an object whose changes are reported
+func followedFields(kind string, obj *unstructured.Unstructured) map[string]string {
+	fields := make(map[string]string)
+
+	switch kind {
+	case "volume":
+		fields["state"], _, _ = unstructured.NestedString(obj.Object, "status", "state")
+		fields["robustness"], _, _ = unstructured.NestedString(obj.Object, "status", "robustness")
+		fields["node"], _, _ = unstructured.NestedString(obj.Object, "status", "currentNodeID")
+
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			condType, _ := condition["type"].(string)
+			status, _ := condition["status"].(string)
+			reason, _ := condition["reason"].(string)
+			if reason != "" {
+				status += " (" + reason + ")"
+			}
+			fields["condition "+condType] = status
+		}
+
+	case "engine":
+		fields["state"], _, _ = unstructured.NestedString(obj.Object, "status", "currentState")
+		fields["node"], _, _ = unstructured.NestedString(obj.Object, "spec", "nodeID")
+
+		modes, _, _ := unstructured.NestedStringMap(obj.Object, "status", "replicaModeMap")
+		for replica, mode := range modes {
+			fields["replica "+replica+" mode"] = mode
+		}
+
+		rebuilds, _, _ := unstructured.NestedMap(obj.Object, "status", "rebuildStatus")
+		for address, r := range rebuilds {
+			rebuild, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			state, _ := rebuild["state"].(string)
+			progress, _ := longhorn.Float64(rebuild, "progress")
+			fields["rebuild "+address] = fmt.Sprintf("%s %d%%", state, int64(progress))
+		}
+
+	case "replica":
+		fields["state"], _, _ = unstructured.NestedString(obj.Object, "status", "currentState")
+		fields["node"], _, _ = unstructured.NestedString(obj.Object, "spec", "nodeID")
+		fields["disk"], _, _ = unstructured.NestedString(obj.Object, "spec", "diskID")
+		fields["failed at"], _, _ = unstructured.NestedString(obj.Object, "spec", "failedAt")
+	}
+
+	// Unset fields are not worth reporting
+	for name, value := range fields {
+		if value == "" {
+			delete(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// formatFollowedFields formats the initial state of an object as name=value pairs
+func formatFollowedFields(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+fields[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// diffFollowedFields returns a line for every field that changed between two observations
+func diffFollowedFields(previous, current map[string]string) []string {
+	names := make(map[string]bool)
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, name := range sorted {
+		before, after := previous[name], current[name]
+		if before == after {
+			continue
+		}
+		if before == "" {
+			before = "-"
+		}
+		if after == "" {
+			after = "-"
+		}
+		changes = append(changes, fmt.Sprintf("%s: %s → %s", name, colorize(before, Yellow), colorize(after, Green)))
+	}
+	return changes
+}
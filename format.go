@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numberLocale describes how numbers are written in a locale
+type numberLocale struct {
+	thousands string // Thousands separator, empty for no grouping
+	decimal   string
+}
+
+// numberLocales maps language codes, and language_TERRITORY codes deviating from their language,
+// to their number conventions
+var numberLocales = map[string]numberLocale{
+	"C":     {thousands: "", decimal: "."},
+	"en":    {thousands: ",", decimal: "."},
+	"ja":    {thousands: ",", decimal: "."},
+	"zh":    {thousands: ",", decimal: "."},
+	"de":    {thousands: ".", decimal: ","},
+	"nl":    {thousands: ".", decimal: ","},
+	"it":    {thousands: ".", decimal: ","},
+	"es":    {thousands: ".", decimal: ","},
+	"pt":    {thousands: ".", decimal: ","},
+	"da":    {thousands: ".", decimal: ","},
+	"tr":    {thousands: ".", decimal: ","},
+	"fr":    {thousands: "\u202f", decimal: ","},
+	"sv":    {thousands: "\u00a0", decimal: ","},
+	"nb":    {thousands: "\u00a0", decimal: ","},
+	"fi":    {thousands: "\u00a0", decimal: ","},
+	"pl":    {thousands: "\u00a0", decimal: ","},
+	"cs":    {thousands: "\u00a0", decimal: ","},
+	"ru":    {thousands: "\u00a0", decimal: ","},
+	"de_CH": {thousands: "'", decimal: "."},
+}
+
+// outputLocale is the number format set with --locale, the default keeps plain numbers
+var outputLocale = numberLocales["C"]
+
+// displayUTC renders all timestamps in UTC instead of the local time zone
+var displayUTC bool
+
+// Timestamp styles selectable with --timestamps
+const (
+	timestampsRFC3339  = "rfc3339"
+	timestampsRelative = "relative"
+	timestampsUnix     = "unix"
+)
+
+// timestampStyle is the style set with --timestamps, empty for the default mix of readable
+// timestamps and relative ages
+var timestampStyle string
+
+// registerFormatFlags registers the flags controlling number and time formatting
+func registerFormatFlags(fs *flag.FlagSet) {
+	fs.Func("locale", "number format locale, e.g. en_US, de_DE or auto for $LC_ALL/$LC_NUMERIC/$LANG (default plain numbers)", setLocale)
+	fs.BoolVar(&displayUTC, "utc", false, "show timestamps in UTC instead of the local time zone")
+	fs.Func("timestamps", "render times and ages as rfc3339, relative or unix (default readable timestamps and relative ages)", setTimestampStyle)
+}
+
+// setTimestampStyle validates and sets the --timestamps style
+func setTimestampStyle(style string) error {
+	switch style {
+	case timestampsRFC3339, timestampsRelative, timestampsUnix:
+		timestampStyle = style
+		return nil
+	}
+	return fmt.Errorf("invalid timestamp style %q, expected rfc3339, relative or unix", style)
+}
+
+// absoluteTimestamps returns true if times must be rendered as points in time, never as ages
+func absoluteTimestamps() bool {
+	return timestampStyle == timestampsRFC3339 || timestampStyle == timestampsUnix
+}
+
+// setLocale selects the number format of a locale name such as de_DE.UTF-8
+func setLocale(name string) error {
+	if name == "auto" {
+		name = "C"
+		for _, env := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+			if value := os.Getenv(env); value != "" {
+				name = value
+				break
+			}
+		}
+	}
+
+	// Strip the encoding and modifier, e.g. de_DE.UTF-8@euro
+	name = strings.SplitN(strings.SplitN(name, ".", 2)[0], "@", 2)[0]
+	name = strings.ReplaceAll(name, "-", "_")
+	if name == "POSIX" {
+		name = "C"
+	}
+
+	if locale, found := numberLocales[name]; found {
+		outputLocale = locale
+		return nil
+	}
+	language := strings.ToLower(strings.SplitN(name, "_", 2)[0])
+	if locale, found := numberLocales[language]; found {
+		outputLocale = locale
+		return nil
+	}
+	return fmt.Errorf("unsupported locale %q", name)
+}
+
+// formatNumber renders a number with the given decimals in the output locale
+func formatNumber(value float64, decimals int) string {
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	integer, fraction, _ := strings.Cut(text, ".")
+
+	if outputLocale.thousands != "" {
+		var grouped strings.Builder
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				grouped.WriteString(outputLocale.thousands)
+			}
+			grouped.WriteRune(digit)
+		}
+		integer = grouped.String()
+	}
+
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + outputLocale.decimal + fraction
+}
+
+// displayTime converts a time to the display time zone
+func displayTime(t time.Time) time.Time {
+	if displayUTC {
+		return t.UTC()
+	}
+	return t.Local()
+}
+
+// formatTimestamp renders a point in time in the --timestamps style, by default with its zone so
+// reports from different places compare
+func formatTimestamp(t time.Time) string {
+	switch timestampStyle {
+	case timestampsRFC3339:
+		return displayTime(t).Format(time.RFC3339)
+	case timestampsUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case timestampsRelative:
+		return formatRelative(t)
+	}
+	return displayTime(t).Format("2006-01-02 15:04:05 MST")
+}
+
+// formatRelative renders a time relative to now, e.g. 3h5m ago or in 2d4h
+func formatRelative(t time.Time) string {
+	if d := time.Until(t); d > 0 {
+		return "in " + formatDuration(d)
+	}
+	return formatDuration(time.Since(t)) + " ago"
+}
+
+// formatWhen renders a past event as "3h5m ago", or as a timestamp with absolute --timestamps
+func formatWhen(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if absoluteTimestamps() {
+		return formatTimestamp(t)
+	}
+	return formatRelative(t)
+}
+
+// formatSchedule renders a scheduled run, by default as weekday, date and time of day
+func formatSchedule(t time.Time) string {
+	if timestampStyle != "" {
+		return formatTimestamp(t)
+	}
+	return displayTime(t).Format("Mon 01-02 15:04")
+}
+
+// formatClock renders the time of day for line oriented output such as issue logs. Relative
+// times make no sense for lines printed as they happen, they keep the time of day.
+func formatClock(t time.Time) string {
+	if absoluteTimestamps() {
+		return formatTimestamp(t)
+	}
+	return displayTime(t).Format("15:04:05")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// VolumeGrowthInfo describes a volume whose actual size grows abnormally fast
+type VolumeGrowthInfo struct {
+	VolumeName    string
+	Size          ByteSize
+	ActualSize    ByteSize
+	BytesPerDay   ByteSize
+	PercentPerDay float64 // Growth relative to the actual size at the start of the period
+	Period        time.Duration
+}
+
+// findVolumeGrowthAnomalies records the actual size of every volume in the history store and
+// returns the volumes growing faster than either threshold. Thresholds of 0 are disabled.
+func findVolumeGrowthAnomalies(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, percentPerDay float64, bytesPerDay ByteSize) ([]VolumeGrowthInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	state, err := sharedState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var anomalies []VolumeGrowthInfo
+	seen := make(map[string]bool)
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+		seen[volumeName] = true
+
+		actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseFloat(sizeStr, 64)
+
+		recorded := state.Volumes[volumeName]
+		rate, period, ok := sampleRate(recorded.Samples, now, ByteSize(actualSize))
+		var startSize ByteSize
+		if len(recorded.Samples) > 0 {
+			startSize = recorded.Samples[0].Size
+		}
+		recorded.Samples = recordSample(recorded.Samples, now, ByteSize(actualSize))
+		state.Volumes[volumeName] = recorded
+
+		if !ok || rate <= 0 {
+			continue
+		}
+
+		info := VolumeGrowthInfo{
+			VolumeName:  volumeName,
+			Size:        ByteSize(size),
+			ActualSize:  ByteSize(actualSize),
+			BytesPerDay: ByteSize(rate),
+			Period:      period,
+		}
+		if startSize > 0 {
+			info.PercentPerDay = 100 * rate / float64(startSize)
+		}
+
+		if (percentPerDay > 0 && startSize > 0 && info.PercentPerDay > percentPerDay) ||
+			(bytesPerDay > 0 && info.BytesPerDay > bytesPerDay) {
+			anomalies = append(anomalies, info)
+		}
+	}
+
+	// Forget deleted volumes
+	for volumeName := range state.Volumes {
+		if !seen[volumeName] {
+			delete(state.Volumes, volumeName)
+		}
+	}
+
+	if err := state.save(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].BytesPerDay > anomalies[j].BytesPerDay
+	})
+
+	return anomalies, nil
+}
+
+// printVolumeGrowth prints volumes whose actual size grows abnormally fast
+func printVolumeGrowth(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, percentPerDay float64, bytesPerDay ByteSize) {
+	anomalies, err := findVolumeGrowthAnomalies(dynClient, namespace, volumesGVR, percentPerDay, bytesPerDay)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	description := fmt.Sprintf("Volumes growing more than %.0f%% per day", percentPerDay)
+	if bytesPerDay > 0 {
+		description += fmt.Sprintf(" or %s per day", bytesPerDay)
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "VOLUME GROWTH",
+		Description: description,
+		Color:       Yellow,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tACTUAL SIZE\tGROWTH/DAY\tPERCENT/DAY\tMEASURED OVER%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSIZE\tACTUAL SIZE\tGROWTH/DAY\tPERCENT/DAY\tMEASURED OVER")
+	}
+
+	fmt.Fprintln(w, "──────\t────\t───────────\t──────────\t───────────\t─────────────")
+
+	for _, anomaly := range anomalies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			anomaly.VolumeName,
+			anomaly.Size,
+			anomaly.ActualSize,
+			colorize(anomaly.BytesPerDay.String(), Red),
+			colorize(formatNumber(anomaly.PercentPerDay, 1)+"%", Red),
+			anomaly.Period.Round(time.Minute),
+		)
+	}
+
+	if len(anomalies) == 0 {
+		fmt.Fprintln(w, "No abnormal volume growth detected")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// DiskHardwareInfo stores block device metrics for the device backing a Longhorn disk
+type DiskHardwareInfo struct {
+	Device         string  `json:"device"`
+	IOLatencyMs    float64 `json:"ioLatencyMs"`
+	HasIOLatency   bool    `json:"hasIOLatency"`
+	MediaErrors    float64 `json:"mediaErrors"`
+	HasMediaErrors bool    `json:"hasMediaErrors"`
+	SmartFailed    bool    `json:"smartFailed"`
+}
+
+// promSample is a single sample parsed from the Prometheus text exposition format
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// hardwareScraper fetches node-exporter and smartctl_exporter metrics through the API server node proxy
+type hardwareScraper struct {
+	clientset        *kubernetes.Clientset
+	nodeExporterPort int
+	smartctlPort     int
+	nodeMetrics      map[string][]promSample
+	smartMetrics     map[string][]promSample
+}
+
+// newHardwareScraper creates a scraper; a smartctl port of 0 disables the smartctl_exporter integration
+func newHardwareScraper(clientset *kubernetes.Clientset, nodeExporterPort, smartctlPort int) *hardwareScraper {
+	return &hardwareScraper{
+		clientset:        clientset,
+		nodeExporterPort: nodeExporterPort,
+		smartctlPort:     smartctlPort,
+	}
+}
+
+// scrape fetches the metrics endpoint of an exporter on a node
+func (h *hardwareScraper) scrape(nodeName string, port int) ([]promSample, error) {
+	body, err := h.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", fmt.Sprintf("%s:%d", nodeName, port), "proxy", "metrics").
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape %s:%d: %v", nodeName, port, err)
+	}
+	return parsePromText(body), nil
+}
+
+// enrich annotates each disk with metrics of its backing block device. Nodes whose exporters
+// cannot be reached are skipped, leaving the disk without hardware information.
+func (h *hardwareScraper) enrich(disks []DiskInfo) {
+	// Metrics are cached per node for a single refresh only
+	h.nodeMetrics = make(map[string][]promSample)
+	h.smartMetrics = make(map[string][]promSample)
+
+	// Scrape every node up front, the exporters are queried in parallel
+	nodes := make(map[string]bool)
+	for _, disk := range disks {
+		nodes[disk.NodeName] = true
+	}
+	var mu sync.Mutex
+	forEachConcurrently(sortedKeys(nodes), func(nodeName string) {
+		nodeSamples, _ := h.scrape(nodeName, h.nodeExporterPort)
+		var smartSamples []promSample
+		if h.smartctlPort > 0 && len(nodeSamples) > 0 {
+			smartSamples, _ = h.scrape(nodeName, h.smartctlPort)
+		}
+		mu.Lock()
+		h.nodeMetrics[nodeName] = nodeSamples
+		h.smartMetrics[nodeName] = smartSamples
+		mu.Unlock()
+	})
+
+	for i := range disks {
+		disk := &disks[i]
+
+		nodeSamples := h.nodeMetrics[disk.NodeName]
+		if len(nodeSamples) == 0 {
+			continue
+		}
+
+		device := deviceForPath(nodeSamples, disk.Path)
+		if device == "" {
+			continue
+		}
+
+		info := &DiskHardwareInfo{Device: device}
+		info.IOLatencyMs, info.HasIOLatency = averageIOLatency(nodeSamples, device)
+
+		if h.smartctlPort > 0 {
+			smartSamples := h.smartMetrics[disk.NodeName]
+			info.MediaErrors, info.HasMediaErrors, info.SmartFailed = smartStatus(smartSamples, parentDevice(device))
+		}
+
+		disk.Hardware = info
+	}
+}
+
+// deviceForPath finds the block device of the filesystem with the longest mountpoint containing the path
+func deviceForPath(samples []promSample, diskPath string) string {
+	device := ""
+	bestLen := -1
+	for _, s := range samples {
+		if s.Name != "node_filesystem_size_bytes" {
+			continue
+		}
+		mountpoint := s.Labels["mountpoint"]
+		if mountpoint == "" || !strings.HasPrefix(s.Labels["device"], "/dev/") {
+			continue
+		}
+		if diskPath != mountpoint && !strings.HasPrefix(diskPath, strings.TrimSuffix(mountpoint, "/")+"/") {
+			continue
+		}
+		if len(mountpoint) > bestLen {
+			bestLen = len(mountpoint)
+			device = path.Base(s.Labels["device"])
+		}
+	}
+	return device
+}
+
+// averageIOLatency computes the average I/O latency in milliseconds since boot from node_disk counters
+func averageIOLatency(samples []promSample, device string) (float64, bool) {
+	var seconds, ops float64
+	for _, s := range samples {
+		if s.Labels["device"] != device {
+			continue
+		}
+		switch s.Name {
+		case "node_disk_read_time_seconds_total", "node_disk_write_time_seconds_total":
+			seconds += s.Value
+		case "node_disk_reads_completed_total", "node_disk_writes_completed_total":
+			ops += s.Value
+		}
+	}
+	if ops == 0 {
+		return 0, false
+	}
+	return 1000 * seconds / ops, true
+}
+
+// smartStatus returns the media error count and SMART failure state reported by smartctl_exporter
+func smartStatus(samples []promSample, device string) (float64, bool, bool) {
+	var mediaErrors float64
+	found := false
+	failed := false
+	for _, s := range samples {
+		smartDevice := s.Labels["device"]
+		if smartDevice == "" {
+			continue
+		}
+		if smartDevice != device && !strings.HasPrefix(device, smartDevice+"n") {
+			continue
+		}
+		switch s.Name {
+		case "smartctl_device_media_errors":
+			mediaErrors += s.Value
+			found = true
+		case "smartctl_device_attribute":
+			if s.Labels["attribute_name"] == "Reported_Uncorrect" && s.Labels["attribute_value_type"] == "raw" {
+				mediaErrors += s.Value
+				found = true
+			}
+		case "smartctl_device_smart_status":
+			if s.Value == 0 {
+				failed = true
+			}
+		}
+	}
+	return mediaErrors, found, failed
+}
+
+// parentDevice strips the partition suffix from a block device name (sdb1 -> sdb, nvme0n1p2 -> nvme0n1)
+func parentDevice(device string) string {
+	if strings.HasPrefix(device, "nvme") || strings.HasPrefix(device, "mmcblk") {
+		if i := strings.LastIndex(device, "p"); i > 0 && i < len(device)-1 {
+			if _, err := strconv.Atoi(device[i+1:]); err == nil {
+				return device[:i]
+			}
+		}
+		return device
+	}
+	if strings.HasPrefix(device, "dm-") || strings.HasPrefix(device, "md") {
+		return device
+	}
+	return strings.TrimRight(device, "0123456789")
+}
+
+// parsePromText parses the Prometheus text exposition format, ignoring comments and malformed lines
+func parsePromText(body []byte) []promSample {
+	var samples []promSample
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample := promSample{Labels: make(map[string]string)}
+		rest := line
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			j := strings.LastIndexByte(line, '}')
+			if j < i {
+				continue
+			}
+			sample.Name = line[:i]
+			parseLabels(line[i+1:j], sample.Labels)
+			rest = strings.TrimSpace(line[j+1:])
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			sample.Name = fields[0]
+			rest = strings.Join(fields[1:], " ")
+		}
+
+		// The value may be followed by a timestamp
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		sample.Value = value
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// parseLabels parses a Prometheus label set of the form a="x",b="y"
+func parseLabels(s string, labels map[string]string) {
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 || eq+1 >= len(s) || s[eq+1] != '"' {
+			return
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(s[:eq], ","))
+
+		// Find the closing quote, honoring escapes
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			if s[i] == '"' {
+				break
+			}
+			value.WriteByte(s[i])
+		}
+		labels[name] = value.String()
+
+		if i+1 >= len(s) {
+			return
+		}
+		s = strings.TrimPrefix(s[i+1:], ",")
+	}
+}
+
+// formatDiskHardware renders the block device columns of the disk table, including trailing tabs
+func formatDiskHardware(info *DiskHardwareInfo) string {
+	if info == nil {
+		return "-\t-\t-\t"
+	}
+
+	latency := "-"
+	if info.HasIOLatency {
+		latencyColor := Green
+		if info.IOLatencyMs > 50 {
+			latencyColor = Red
+		} else if info.IOLatencyMs > 10 {
+			latencyColor = Yellow
+		}
+		latency = colorize(formatNumber(info.IOLatencyMs, 2)+"ms", latencyColor)
+	}
+
+	mediaErrors := "-"
+	if info.HasMediaErrors {
+		errorColor := Green
+		if info.MediaErrors > 0 {
+			errorColor = Red
+		}
+		mediaErrors = colorize(strconv.FormatFloat(info.MediaErrors, 'f', 0, 64), errorColor)
+	}
+	if info.SmartFailed {
+		mediaErrors += colorize(" (SMART FAILED)", Red)
+	}
+
+	return fmt.Sprintf("%s\t%s\t%s\t", info.Device, latency, mediaErrors)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hookTimeout bounds the time a hook may hold up the follow loop
+const hookTimeout = 30 * time.Second
+
+// hookNotifier runs a user-provided program for new or for resolved issues, passing the
+// notification payload as JSON on stdin
+type hookNotifier struct {
+	path     string
+	resolved bool // Run for resolved instead of new issues
+}
+
+// addHook installs a hook set with --hook-on-issue or --hook-on-resolve
+func addHook(path string, resolved bool) {
+	name := "hook-on-issue"
+	if resolved {
+		name = "hook-on-resolve"
+	}
+	notifiers = append(notifiers, configuredNotifier{name: name, notifier: hookNotifier{path: path, resolved: resolved}})
+}
+
+// Notify runs the hook. A non-zero exit is reported with the output of the hook.
+func (h hookNotifier) Notify(n notification) error {
+	if n.Resolved != h.resolved {
+		return nil
+	}
+	data, err := json.Marshal(n.payload())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.path)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s did not finish within %s", h.path, hookTimeout)
+	}
+	if err != nil {
+		if text := strings.TrimSpace(string(output)); text != "" {
+			return fmt.Errorf("%s failed: %v: %s", h.path, err, text)
+		}
+		return fmt.Errorf("%s failed: %v", h.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Where reclaimed space is freed
+const (
+	locationDisks        = "disks"
+	locationBackupTarget = "backup target"
+)
+
+// HousekeepingItem is a single cleanup recommendation
+type HousekeepingItem struct {
+	Category    string // volume, snapshot, replica, orphan or backup
+	Name        string
+	Detail      string
+	Reclaimable ByteSize // Estimated space freed, 0 when unknown
+	Location    string
+}
+
+// housekeepingOptions sets the ages after which snapshots and backups are worth reviewing
+type housekeepingOptions struct {
+	snapshotAge time.Duration
+	backupAge   time.Duration
+}
+
+// runHousekeeping implements the "lhmon4 housekeeping" subcommand, a digest meant to be scheduled weekly
+func runHousekeeping(args []string) {
+	fs := flag.NewFlagSet("housekeeping", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	snapshotAge := fs.String("snapshot-age", "30d", "recommend reviewing snapshots older than this age")
+	backupAge := fs.String("backup-age", "90d", "recommend reviewing backups older than this age")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var opts housekeepingOptions
+	var err error
+	if opts.snapshotAge, err = parseAge(*snapshotAge); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.backupAge, err = parseAge(*backupAge); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, clientset, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	takeSnapshot(dynClient, *clientOpts.namespace, snapshotResources)
+	pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, *clientOpts.namespace, longhornGVR(longhornVolumes), "", "")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	items, err := collectHousekeeping(dynClient, *clientOpts.namespace, pvInfoMap, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	printCollectionStamp()
+	printHousekeepingDigest(items)
+}
+
+// collectHousekeeping gathers safe-to-delete volumes, stale snapshots, failed replicas, orphaned
+// data and old backups, largest reclaimable space first. Ages of 0 skip old snapshots and backups.
+func collectHousekeeping(dynClient dynamic.Interface, namespace string, pvInfoMap map[string]PersistentVolumeInfo, opts housekeepingOptions) ([]HousekeepingItem, error) {
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	replicaCount := make(map[string]int)
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		replicaCount[volumeName]++
+	}
+
+	var items []HousekeepingItem
+	actualSizes := make(map[string]ByteSize)
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+		actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		actualSizes[volumeName] = ByteSize(actualSize)
+
+		// Volumes whose PV was released or failed, every replica holds a copy of the data
+		if pvInfo, found := pvInfoMap[volumeName]; found && (pvInfo.Status == "Released" || pvInfo.Status == "Failed") {
+			items = append(items, HousekeepingItem{
+				Category:    "volume",
+				Name:        volumeName,
+				Detail:      fmt.Sprintf("PV %s is %s", pvInfo.Name, pvInfo.Status),
+				Reclaimable: ByteSize(actualSize) * ByteSize(replicaCount[volumeName]),
+				Location:    locationDisks,
+			})
+		}
+
+		engine, found := volumeEngines[volumeName]
+		if !found {
+			continue
+		}
+		for _, snapshot := range engineSnapshots(engine) {
+			if snapshot.Name == volumeHeadSnapshot {
+				continue
+			}
+
+			detail := ""
+			created, err := time.Parse(time.RFC3339, snapshot.Created)
+			switch {
+			case snapshot.Removed:
+				detail = "removed but not purged yet"
+			case opts.snapshotAge > 0 && err == nil && time.Since(created) > opts.snapshotAge:
+				detail = "created " + formatWhen(created)
+			default:
+				continue
+			}
+
+			items = append(items, HousekeepingItem{
+				Category:    "snapshot",
+				Name:        volumeName + "/" + snapshot.Name,
+				Detail:      detail,
+				Reclaimable: snapshot.Size * ByteSize(replicaCount[volumeName]),
+				Location:    locationDisks,
+			})
+		}
+	}
+
+	// Failed replicas keep their data on disk until they are deleted or rebuilt
+	for _, replica := range replicas.Items {
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if failedAt == "" {
+			continue
+		}
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		items = append(items, HousekeepingItem{
+			Category:    "replica",
+			Name:        replica.GetName(),
+			Detail:      fmt.Sprintf("failed at %s on %s", failedAt, nodeID),
+			Reclaimable: actualSizes[volumeName],
+			Location:    locationDisks,
+		})
+	}
+
+	orphans, err := listAll(dynClient, longhornGVR(longhornOrphans), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn orphans: %v", err)
+	}
+	for _, orphan := range orphans.Items {
+		nodeID, _, _ := unstructured.NestedString(orphan.Object, "spec", "nodeID")
+		diskPath, _, _ := unstructured.NestedString(orphan.Object, "spec", "parameters", "DiskPath")
+		dataName, _, _ := unstructured.NestedString(orphan.Object, "spec", "parameters", "DataName")
+		items = append(items, HousekeepingItem{
+			Category: "orphan",
+			Name:     orphan.GetName(),
+			Detail:   fmt.Sprintf("%s/replicas/%s on %s, size unknown", diskPath, dataName, nodeID),
+			Location: locationDisks,
+		})
+	}
+
+	if opts.backupAge > 0 {
+		backupItems, err := oldBackups(dynClient, namespace, opts.backupAge)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, backupItems...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Reclaimable != items[j].Reclaimable {
+			return items[i].Reclaimable > items[j].Reclaimable
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	return items, nil
+}
+
+// oldBackups lists the backups older than maxAge, freeing space on the backup target
+func oldBackups(dynClient dynamic.Interface, namespace string, maxAge time.Duration) ([]HousekeepingItem, error) {
+	backups, err := listAll(dynClient, longhornGVR(longhornBackups), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn backups: %v", err)
+	}
+
+	var items []HousekeepingItem
+	for _, b := range backups.Items {
+		backup := getBackupInfo(b)
+		created, err := time.Parse(time.RFC3339, backup.CreatedAt)
+		if err != nil || time.Since(created) <= maxAge {
+			continue
+		}
+
+		// Newly uploaded data is what deleting the backup frees, unchanged blocks are shared
+		reclaimable := backup.Size
+		if backup.HasUploadSize {
+			reclaimable = backup.Uploaded
+		}
+		items = append(items, HousekeepingItem{
+			Category:    "backup",
+			Name:        backup.Name,
+			Detail:      fmt.Sprintf("backup of %s created %s", backup.VolumeName, formatWhen(created)),
+			Reclaimable: reclaimable,
+			Location:    locationBackupTarget,
+		})
+	}
+	return items, nil
+}
+
+// printHousekeepingDigest prints the cleanup recommendations with the reclaimable space per item and in total
+func printHousekeepingDigest(items []HousekeepingItem) {
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "HOUSEKEEPING DIGEST",
+		Description: "Cleanup recommendations, largest reclaimable space first",
+		Color:       Green,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%s#\tCATEGORY\tITEM\tDETAIL\tRECLAIMABLE\tLOCATION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "#\tCATEGORY\tITEM\tDETAIL\tRECLAIMABLE\tLOCATION")
+	}
+
+	fmt.Fprintln(w, "─\t────────\t────\t──────\t───────────\t────────")
+
+	totals := make(map[string]ByteSize)
+	for i, item := range items {
+		reclaimable := "unknown"
+		if item.Reclaimable > 0 {
+			reclaimable = item.Reclaimable.String()
+		}
+		totals[item.Location] += item.Reclaimable
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			i+1,
+			item.Category,
+			item.Name,
+			item.Detail,
+			colorize(reclaimable, Green),
+			item.Location,
+		)
+	}
+
+	if len(items) == 0 {
+		fmt.Fprintln(w, "Nothing to clean up")
+	}
+
+	w.Flush()
+
+	if len(items) > 0 {
+		fmt.Println()
+		fmt.Printf("Estimated reclaimable on disks:         %s\n", colorize(totals[locationDisks].String(), Bold+Green))
+		fmt.Printf("Estimated reclaimable on backup target: %s\n", colorize(totals[locationBackupTarget].String(), Bold+Green))
+	}
+}
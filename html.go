@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// htmlReportPath is the file -o html writes to, set by --report-html. Standard output when empty.
+var htmlReportPath string
+
+// getIssueRecords evaluates the issue heuristics against the nodes and volumes of the report
+func getIssueRecords(dynClient dynamic.Interface, namespace, filterNode, filterVolume string) ([]issueRecord, error) {
+	var records []issueRecord
+	add := func(kind string, obj unstructured.Unstructured) {
+		key := kind + "/" + obj.GetName()
+		for _, issue := range objectIssues(kind, obj) {
+			id := issueID(key, issue.Text)
+			records = append(records, issueRecord{ID: id, Object: key, Issue: issue.Text, Severity: issue.Severity.String(), Acknowledged: acknowledged(id), Runbook: issue.Runbook})
+		}
+	}
+
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		if filterNode == "" || node.GetName() == filterNode {
+			add("node", node)
+		}
+	}
+
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	for _, volume := range volumes.Items {
+		if filterVolume == "" || volume.GetName() == filterVolume {
+			add("volume", volume)
+		}
+	}
+
+	// Worst first, then by object
+	sort.SliceStable(records, func(i, j int) bool {
+		si, _ := parseSeverity(records[i].Severity)
+		sj, _ := parseSeverity(records[j].Severity)
+		if si != sj {
+			return si > sj
+		}
+		return records[i].Object < records[j].Object
+	})
+	return records, nil
+}
+
+// htmlCell is a table cell. Sort holds the raw value the column is sorted by, e.g. a byte count.
+type htmlCell struct {
+	Text  string
+	Sort  string
+	Class string
+}
+
+// htmlSection is one table of the HTML report
+type htmlSection struct {
+	Title  string
+	Empty  string
+	Header []string
+	Rows   [][]htmlCell
+}
+
+// htmlBytes renders a size as a cell sorted by its byte count
+func htmlBytes(size ByteSize) htmlCell {
+	return htmlCell{Text: size.String(), Sort: fmt.Sprintf("%d", int64(size))}
+}
+
+// htmlTime renders a time as a cell sorted chronologically
+func htmlTime(t time.Time) htmlCell {
+	if t.IsZero() {
+		return htmlCell{}
+	}
+	return htmlCell{Text: formatTimestamp(t), Sort: t.UTC().Format(time.RFC3339)}
+}
+
+// htmlState renders a state or robustness with the class of its color in the tables
+func htmlState(value string) htmlCell {
+	switch value {
+	case "healthy", "attached", "running", "Bound", "RW":
+		return htmlCell{Text: value, Class: "ok"}
+	case "degraded", "unknown", "WO":
+		return htmlCell{Text: value, Class: "warning"}
+	case "faulted", "error", "ERR":
+		return htmlCell{Text: value, Class: "critical"}
+	}
+	return htmlCell{Text: value}
+}
+
+// htmlSections converts the report into the tables of the HTML page
+func htmlSections(doc reportDocument) []htmlSection {
+	disks := htmlSection{Title: "Longhorn Disks", Empty: "No disks found", Header: []string{"Node", "Disk", "Path", "Tags", "Type", "Maximum", "Reserved", "Scheduled", "Available", "Used"}}
+	for _, disk := range doc.Disks {
+		used := htmlCell{Text: fmt.Sprintf("%.1f%%", disk.PercentUsed), Sort: fmt.Sprintf("%f", disk.PercentUsed)}
+		switch {
+		case disk.PercentUsed >= 90:
+			used.Class = "critical"
+		case disk.PercentUsed >= 75:
+			used.Class = "warning"
+		}
+		disks.Rows = append(disks.Rows, []htmlCell{
+			{Text: disk.NodeName}, {Text: disk.DiskName}, {Text: disk.Path}, {Text: strings.Join(disk.Tags, ", ")}, {Text: disk.Type},
+			htmlBytes(disk.StorageMaximum), htmlBytes(disk.StorageReserved), htmlBytes(disk.StorageScheduled), htmlBytes(disk.StorageAvailable), used,
+		})
+	}
+
+	volumes := htmlSection{Title: "Longhorn Volumes", Empty: "No volumes found", Header: []string{"Volume", "Size", "Actual Size", "State", "Robustness", "Node", "Replicas", "Access Mode", "Created"}}
+	for _, volume := range doc.Volumes {
+		replicas := htmlCell{Text: fmt.Sprintf("%d/%d", volume.ReplicaCount, volume.DesiredReplicas), Sort: fmt.Sprintf("%d", volume.ReplicaCount)}
+		if volume.ReplicaCount < volume.DesiredReplicas {
+			replicas.Class = "warning"
+		}
+		volumes.Rows = append(volumes.Rows, []htmlCell{
+			{Text: volume.Name}, htmlBytes(volume.Size), htmlBytes(volume.ActualSize), htmlState(volume.State), htmlState(volume.Robustness),
+			{Text: volume.Node}, replicas, {Text: volume.AccessMode}, htmlTime(volume.Created),
+		})
+	}
+
+	replicas := htmlSection{Title: "Longhorn Replicas", Empty: "No replicas found", Header: []string{"Replica", "Volume", "Node", "Disk Path", "State", "Mode", "Size", "Failed At", "Created"}}
+	for _, replica := range doc.Replicas {
+		replicas.Rows = append(replicas.Rows, []htmlCell{
+			{Text: replica.Name}, {Text: replica.VolumeName}, {Text: replica.NodeID}, {Text: replica.DiskPath}, htmlState(replica.State),
+			htmlState(replica.Mode), htmlBytes(replica.Size), {Text: replica.FailedAt}, htmlTime(replica.Created),
+		})
+	}
+
+	relationships := htmlSection{Title: "Kubernetes Relationships", Empty: "No PersistentVolumes found", Header: []string{"Volume", "PV", "Status", "Storage Class", "PVC", "Consumer Pods"}}
+	for _, pvInfo := range doc.Relationships {
+		var pods []string
+		for _, pod := range pvInfo.ConsumerPods {
+			pods = append(pods, pod.Namespace+"/"+pod.Name)
+		}
+		pvc := ""
+		if pvInfo.PVCName != "" {
+			pvc = pvInfo.PVCNamespace + "/" + pvInfo.PVCName
+		}
+		relationships.Rows = append(relationships.Rows, []htmlCell{
+			{Text: pvInfo.LonghornVolumeID}, {Text: pvInfo.Name}, htmlState(pvInfo.Status), {Text: pvInfo.StorageClass}, {Text: pvc}, {Text: strings.Join(pods, ", ")},
+		})
+	}
+
+	issues := htmlSection{Title: "Issues", Empty: "No issues found", Header: []string{"ID", "Severity", "Object", "Issue", "Acknowledged"}}
+	for _, issue := range doc.Issues {
+		level, _ := parseSeverity(issue.Severity)
+		acked := ""
+		if issue.Acknowledged {
+			acked = "yes"
+		}
+		issues.Rows = append(issues.Rows, []htmlCell{
+			{Text: issue.ID}, {Text: strings.ToUpper(issue.Severity), Sort: fmt.Sprintf("%d", level), Class: issue.Severity}, {Text: issue.Object}, {Text: issue.Issue}, {Text: acked},
+		})
+	}
+
+	sections := []htmlSection{issues, disks, volumes}
+	if len(doc.Replicas) > 0 {
+		sections = append(sections, replicas)
+	}
+	if len(doc.Relationships) > 0 {
+		sections = append(sections, relationships)
+	}
+	return sections
+}
+
+// htmlReportTemplate is a standalone page, styles and the column sorting script are inlined so
+// the file can be attached or uploaded as is
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Longhorn storage report {{.Collection}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em; }
+.stamp { color: #666; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { padding: 0.3em 0.8em; text-align: left; border-bottom: 1px solid #eee; white-space: nowrap; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tr:hover td { background: #fafafa; }
+.ok { color: #1a7f37; }
+.info { color: #0969da; }
+.warning { color: #9a6700; font-weight: bold; }
+.critical { color: #cf222e; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Longhorn storage report</h1>
+<p class="stamp">Collection {{.Collection}} at {{.Generated}}</p>
+{{range .Sections}}
+<h2>{{.Title}} ({{len .Rows}})</h2>
+{{if .Rows}}<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td{{if .Class}} class="{{.Class}}"{{end}}{{if .Sort}} data-sort="{{.Sort}}"{{end}}>{{.Text}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>{{else}}<p>{{.Empty}}</p>{{end}}
+{{end}}
+<script>
+document.querySelectorAll("th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0], index = th.cellIndex;
+    var asc = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var key = function (row) {
+      var cell = row.cells[index];
+      return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent;
+    };
+    Array.from(body.rows).sort(function (a, b) {
+      var x = key(a), y = key(b), nx = Number(x), ny = Number(y);
+      var order = (x !== "" && y !== "" && !isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return asc ? order : -order;
+    }).forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// writeHTMLReport renders the report as a standalone HTML page
+func writeHTMLReport(out io.Writer, doc reportDocument) error {
+	return htmlReportTemplate.Execute(out, struct {
+		Collection string
+		Generated  string
+		Sections   []htmlSection
+	}{
+		Collection: doc.Collection,
+		Generated:  formatTimestamp(time.Now()),
+		Sections:   htmlSections(doc),
+	})
+}
+
+// renderHTML writes the HTML report to the --report-html file, or to standard output
+func renderHTML(doc reportDocument) error {
+	if htmlReportPath == "" {
+		return writeHTMLReport(os.Stdout, doc)
+	}
+
+	f, err := os.Create(htmlReportPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", htmlReportPath, err)
+	}
+	err = writeHTMLReport(f, doc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", htmlReportPath, err)
+	}
+	fmt.Printf("Wrote %s\n", htmlReportPath)
+	return nil
+}
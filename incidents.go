@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runReport implements the "lhmon4 report" subcommand
+func runReport(args []string) {
+	if len(args) == 0 || args[0] != "incidents" {
+		fmt.Printf("Usage: %s report incidents [--since 7d] [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("report incidents", flag.ExitOnError)
+	registerFormatFlags(fs)
+	since := fs.String("since", "7d", "period to report, e.g. 24h, 7d or 30d")
+	minSeverityName := fs.String("min-severity", "info", "only report issues of this severity or worse: info, warning or critical")
+	fs.StringVar(&stateFile, "state-file", "", "file recording the issues detected by \"lhmon4 issues --follow\" (default in the user cache directory)")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	period, err := parseAge(*since)
+	if err != nil || period <= 0 {
+		fmt.Printf("Error: invalid period %q\n", *since)
+		os.Exit(1)
+	}
+	minSeverity, err := parseSeverity(*minSeverityName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	state, err := loadState()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	incidents := periodIncidents(state, now.Add(-period), minSeverity)
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	printIncidentReport(incidents, *since, now)
+}
+
+// periodIncidents returns the issues open at any time since the start of the period, oldest first.
+// Issues still open are returned with a zero resolution time.
+func periodIncidents(state *monitorState, start time.Time, minSeverity severity) []incident {
+	var incidents []incident
+	for _, i := range state.Incidents {
+		if !i.Resolved.Before(start) && i.Severity >= minSeverity {
+			incidents = append(incidents, i)
+		}
+	}
+	for id, alerted := range state.Alerted {
+		if alerted.Severity >= minSeverity {
+			incidents = append(incidents, incident{
+				ID:            id,
+				Object:        alerted.Object,
+				Issue:         alerted.Issue,
+				Severity:      alerted.Severity,
+				Since:         alerted.Since,
+				InMaintenance: alerted.InMaintenance,
+			})
+		}
+	}
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].Since.Before(incidents[j].Since)
+	})
+	return incidents
+}
+
+// duration returns how long the incident lasted, or has lasted so far when it is still open
+func (i incident) duration(now time.Time) time.Duration {
+	if i.Resolved.IsZero() {
+		return now.Sub(i.Since)
+	}
+	return i.Resolved.Sub(i.Since)
+}
+
+// printIncidentReport prints every issue of the period, followed by totals per severity and the
+// most affected volumes and nodes
+func printIncidentReport(incidents []incident, period string, now time.Time) {
+	printSectionHeader(Section{
+		Title:       "INCIDENTS",
+		Description: fmt.Sprintf("Issues detected by \"lhmon4 issues --follow\" and open during the last %s", period),
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sDETECTED\tRESOLVED\tDURATION\tSEVERITY\tOBJECT\tISSUE\tRESOLUTION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "DETECTED\tRESOLVED\tDURATION\tSEVERITY\tOBJECT\tISSUE\tRESOLUTION")
+	}
+
+	fmt.Fprintln(w, "────────\t────────\t────────\t────────\t──────\t─────\t──────────")
+
+	counts := make(map[severity]int)
+	durations := make(map[severity]time.Duration)
+	objects := make(map[string]int)
+	var open int
+	for _, i := range incidents {
+		resolved := colorize("open", Red)
+		resolution := colorize("open", Red)
+		if !i.Resolved.IsZero() {
+			resolved = formatTimestamp(i.Resolved)
+			resolution = colorize(i.Resolution, Green)
+		} else {
+			open++
+		}
+		if i.InMaintenance {
+			resolution += " (maintenance)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTimestamp(i.Since),
+			resolved,
+			formatDuration(i.duration(now)),
+			formatSeverity(i.Severity),
+			colorize(i.Object, Blue),
+			i.Issue,
+			resolution,
+		)
+
+		counts[i.Severity]++
+		durations[i.Severity] += i.duration(now)
+		objects[i.Object]++
+	}
+
+	if len(incidents) == 0 {
+		fmt.Fprintln(w, "No incidents recorded in this period")
+	}
+
+	w.Flush()
+
+	if len(incidents) == 0 {
+		return
+	}
+
+	// Totals per severity, worst first
+	fmt.Println()
+	w = newTableWriter()
+	if useColors {
+		fmt.Fprintf(w, "%s%sSEVERITY\tINCIDENTS\tTOTAL DURATION\tMEAN DURATION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "SEVERITY\tINCIDENTS\tTOTAL DURATION\tMEAN DURATION")
+	}
+	fmt.Fprintln(w, "────────\t─────────\t──────────────\t─────────────")
+	for _, s := range []severity{severityCritical, severityWarning, severityInfo} {
+		if counts[s] == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n",
+			formatSeverity(s),
+			counts[s],
+			formatDuration(durations[s]),
+			formatDuration(durations[s]/time.Duration(counts[s])),
+		)
+	}
+	w.Flush()
+
+	// Affected volumes and nodes, most incidents first
+	var volumes, nodes []string
+	for object := range objects {
+		switch {
+		case strings.HasPrefix(object, "volume/"):
+			volumes = append(volumes, object)
+		case strings.HasPrefix(object, "node/"):
+			nodes = append(nodes, object)
+		}
+	}
+	byCount := func(names []string) {
+		sort.Slice(names, func(i, j int) bool {
+			if objects[names[i]] != objects[names[j]] {
+				return objects[names[i]] > objects[names[j]]
+			}
+			return names[i] < names[j]
+		})
+	}
+	byCount(volumes)
+	byCount(nodes)
+
+	fmt.Println()
+	fmt.Printf("Incidents: %d (%d still open)\n", len(incidents), open)
+	for _, affected := range []struct {
+		label   string
+		objects []string
+	}{
+		{"Affected volumes", volumes},
+		{"Affected nodes", nodes},
+	} {
+		listed := make([]string, len(affected.objects))
+		for i, object := range affected.objects {
+			listed[i] = fmt.Sprintf("%s (%d)", strings.SplitN(object, "/", 2)[1], objects[object])
+		}
+		if len(listed) == 0 {
+			listed = []string{"none"}
+		}
+		fmt.Printf("%s (%d): %s\n", affected.label, len(affected.objects), strings.Join(listed, ", "))
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// runIssues implements the "lhmon4 issues" subcommand
+func runIssues(args []string) {
+	fs := flag.NewFlagSet("issues", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	follow := fs.Bool("follow", false, "print one line per newly detected or resolved issue as changes happen")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	kbFile := fs.String("kb", "", "knowledge base YAML overriding or extending the built-in issue solutions (optional)")
+	fs.StringVar(&configFile, "config", "", "configuration file with flag defaults, issue patterns and notifiers (default config.yaml in the user config directory)")
+	failOnName := fs.String("fail-on", "", "exit with code 4 when an unacknowledged issue of this severity or worse is found: info, warning or critical (optional)")
+	minSeverityName := fs.String("min-severity", "info", "with --follow, only report issues of this severity or worse: info, warning or critical")
+	fs.StringVar(&stateFile, "state-file", "", "file recording reported and acknowledged issues between runs (default in the user cache directory)")
+	hookOnIssue := fs.String("hook-on-issue", "", "with --follow, run this program with the issue as JSON on stdin whenever a new issue is detected (optional)")
+	listenAlerts := fs.String("listen-alerts", "", "with --follow, receive Alertmanager webhooks on this address, e.g. :9770, and report their alerts as issues of the volume or node in their labels (optional)")
+	hookOnResolve := fs.String("hook-on-resolve", "", "with --follow, run this program with the issue as JSON on stdin whenever an issue is resolved (optional)")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failOn, err := parseSeverity(*failOnName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	minSeverity, err := parseSeverity(*minSeverityName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if _, err := loadConfig(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := loadKnowledgeBase(*kbFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *hookOnIssue != "" {
+		addHook(*hookOnIssue, false)
+	}
+	if *hookOnResolve != "" {
+		addHook(*hookOnResolve, true)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !*follow {
+		output := startPager(*noPager)
+		defer output.finish()
+
+		takeSnapshot(dynClient, *clientOpts.namespace, snapshotResources)
+		printCollectionStamp()
+		worst := printProblematicDisks(dynClient, *clientOpts.namespace, longhornGVR(longhornNodes))
+		fmt.Println()
+		if conflictWorst := printReplicaDiskConflicts(dynClient, *clientOpts.namespace, longhornGVR(longhornNodes)); conflictWorst > worst {
+			worst = conflictWorst
+		}
+		fmt.Println()
+		if cordonWorst := printCordonedDisks(dynClient, *clientOpts.namespace, longhornGVR(longhornNodes)); cordonWorst > worst {
+			worst = cordonWorst
+		}
+		fmt.Println()
+		if volumeWorst := printDetailedVolumeIssues(dynClient, *clientOpts.namespace, longhornGVR(longhornVolumes), longhornGVR(longhornNodes)); volumeWorst > worst {
+			worst = volumeWorst
+		}
+		fmt.Println()
+		printEngineUpgrades(dynClient, *clientOpts.namespace, longhornGVR(longhornVolumes))
+
+		if failOn != severityNone && worst >= failOn {
+			output.finish()
+			os.Exit(exitIssueSeverity)
+		}
+		return
+	}
+
+	// Issues reported by a previous run are not reported again
+	state, err := sharedState()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	events := make(chan followEvent)
+	go followResource(dynClient, *clientOpts.namespace, longhornNodes, "node", metav1.ListOptions{}, events)
+	go followResource(dynClient, *clientOpts.namespace, longhornVolumes, "volume", metav1.ListOptions{}, events)
+
+	// Alerts received from Alertmanager are merged into the reported issues
+	alerts := make(chan externalAlert)
+	if *listenAlerts != "" {
+		go serveAlerts(*listenAlerts, alerts)
+		fmt.Printf("Receiving Alertmanager webhooks on %s%s\n", *listenAlerts, alertsPath)
+	}
+
+	// Every transition of the run is stamped with one collection ID, like the outputs of a report
+	collection := newCollectionID(time.Now())
+	fmt.Printf("Following issues, collection %s. Press Ctrl+C to exit...\n", collection)
+
+	// Remember the open issues of every object and their severity to report only transitions.
+	// Alerts are kept apart, so an object event does not resolve the alerts about the object.
+	open := make(map[string]map[string]severity)
+	external := make(map[string]map[string]severity)
+
+	// Runbooks are found from the condition reason, which is gone once the issue is resolved
+	issueRunbooks := make(map[string]string)
+	runbookOf := func(key, issue string) string {
+		if url, found := issueRunbooks[issueID(key, issue)]; found {
+			return url
+		}
+		return issueRunbook(key, issue, "")
+	}
+
+	// Issues reported by a previous run count as open until the object is first seen again
+	recorded := func(key string, fromAlert bool) map[string]severity {
+		issues := make(map[string]severity)
+		for _, alerted := range state.Alerted {
+			if alerted.Object == key && alerted.External == fromAlert {
+				issues[alerted.Issue] = alerted.Severity
+			}
+		}
+		return issues
+	}
+
+	for {
+		var key string
+		var current, previous map[string]severity
+		var deleted, fromAlert bool
+
+		select {
+		case event := <-events:
+			key = event.Kind + "/" + event.Object.GetName()
+			deleted = event.Type == watch.Deleted
+
+			current = make(map[string]severity)
+			if !deleted {
+				for _, issue := range objectIssues(event.Kind, *event.Object) {
+					current[issue.Text] = issue.Severity
+					issueRunbooks[issueID(key, issue.Text)] = issue.Runbook
+				}
+			}
+			seen := false
+			if previous, seen = open[key]; !seen {
+				previous = recorded(key, false)
+			}
+			open[key] = current
+
+		case alert := <-alerts:
+			key = alert.Object
+			fromAlert = true
+			seen := false
+			if previous, seen = external[key]; !seen {
+				previous = recorded(key, true)
+			}
+			current = make(map[string]severity)
+			for issue, s := range previous {
+				current[issue] = s
+			}
+			if alert.Resolved {
+				delete(current, alert.Issue)
+			} else {
+				current[alert.Issue] = alert.Severity
+			}
+			external[key] = current
+		}
+
+		now := time.Now()
+		timestamp := colorize(formatClock(now), Cyan)
+		changed := false
+		for _, issue := range sortedSeverityKeys(current) {
+			id := issueID(key, issue)
+			if _, open := previous[issue]; open {
+				continue
+			}
+			_, maintenance := inMaintenance(now)
+			state.Alerted[id] = alertedIssue{Object: key, Issue: issue, Severity: current[issue], Since: now, InMaintenance: maintenance, External: fromAlert}
+			changed = true
+			if acknowledged(id) || maintenance || current[issue] < minSeverity {
+				continue
+			}
+			fmt.Printf("%s  %s  %s  %s  %s  %s\n", timestamp, colorize("ISSUE   ", Red), id, formatSeverity(current[issue]), colorize(key, Blue), issue)
+			notify(notification{ID: id, Object: key, Issue: issue, Severity: current[issue], At: now, Runbook: runbookOf(key, issue), Collection: collection})
+		}
+		for _, issue := range sortedSeverityKeys(previous) {
+			if _, open := current[issue]; open {
+				continue
+			}
+			id := issueID(key, issue)
+			runbook := runbookOf(key, issue)
+			delete(issueRunbooks, id)
+			alerted, found := state.Alerted[id]
+			delete(state.Alerted, id)
+			changed = true
+			if found {
+				resolution := resolutionCleared
+				if deleted {
+					resolution = resolutionDeleted
+				}
+				state.recordIncident(id, alerted, now, resolution)
+			}
+
+			// Issues that were never alerted on are not reported as resolved either
+			if _, maintenance := inMaintenance(now); acknowledged(id) || maintenance || alerted.InMaintenance || previous[issue] < minSeverity {
+				continue
+			}
+			fmt.Printf("%s  %s  %s  %s  %s  %s\n", timestamp, colorize("RESOLVED", Green), id, formatSeverity(previous[issue]), colorize(key, Blue), issue)
+			notify(notification{Resolved: true, ID: id, Object: key, Issue: issue, Severity: previous[issue], At: now, Runbook: runbook, Collection: collection})
+		}
+
+		if changed {
+			if err := state.save(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// objectIssue is an issue detected on a node or volume
+type objectIssue struct {
+	Text     string
+	Severity severity
+	Runbook  string // Runbook URL of the issue category, empty when none
+}
+
+// objectIssues evaluates the issue heuristics against a single node or volume
+func objectIssues(kind string, obj unstructured.Unstructured) []objectIssue {
+	var issues []objectIssue
+	key := kind + "/" + obj.GetName()
+
+	switch kind {
+	case "node":
+		for _, issue := range diskIssues(obj) {
+			text := fmt.Sprintf("disk %s: %s", issue.DiskName, issue.Issue)
+			issues = append(issues, objectIssue{Text: text, Severity: issue.Severity, Runbook: issueRunbook(key, text, "")})
+		}
+
+	case "volume":
+		hasIssue, failedConditions := volumeIssueConditions(obj)
+		state, _, _ := unstructured.NestedString(obj.Object, "status", "state")
+		robustness, _, _ := unstructured.NestedString(obj.Object, "status", "robustness")
+		for _, cond := range failedConditions {
+			text := fmt.Sprintf("%s: %s", cond.Type, cond.Message)
+			issues = append(issues, objectIssue{Text: text, Severity: volumeConditionSeverity(state, robustness), Runbook: issueRunbook(key, text, cond.Reason)})
+		}
+		if hasIssue && len(failedConditions) == 0 {
+			text := fmt.Sprintf("state %s, robustness %s", state, robustness)
+			issues = append(issues, objectIssue{Text: text, Severity: volumeSeverity(state, robustness), Runbook: issueRunbook(key, text, "")})
+		}
+	}
+
+	return issues
+}
+
+// sortedSeverityKeys returns the issues of an object in sorted order
+func sortedSeverityKeys(issues map[string]severity) []string {
+	keys := make([]string, 0, len(issues))
+	for key := range issues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scheduleConflictWindow is how far ahead recurring job runs are compared
+const scheduleConflictWindow = 7 * 24 * time.Hour
+
+// JobOverlap stores two recurring jobs that start at the same minute on shared volumes
+type JobOverlap struct {
+	JobA, JobB    string
+	SharedVolumes int
+	FirstOverlap  time.Time
+	Occurrences   int // Coinciding runs within the window
+}
+
+// BackupWindow stores a minute at which many volumes start a job at once
+type BackupWindow struct {
+	Time    time.Time
+	Jobs    []string
+	Volumes int // Volumes targeted by the jobs starting at this minute
+	Load    int // Volumes processed in parallel, bounded by each job's concurrency
+}
+
+// jobRunsWithin returns the runs of a job between now and the end of the window
+func jobRunsWithin(job RecurringJobInfo, now time.Time, window time.Duration) []time.Time {
+	if job.Schedule == nil {
+		return nil
+	}
+	var runs []time.Time
+	end := now.Add(window)
+	for t := job.Schedule.next(now); !t.IsZero() && !t.After(end); t = job.Schedule.next(t) {
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// findJobOverlaps finds pairs of jobs that start at the same minute on at least one common volume
+func findJobOverlaps(jobs []RecurringJobInfo, now time.Time) []JobOverlap {
+	runs := make([]map[time.Time]bool, len(jobs))
+	for i, job := range jobs {
+		runs[i] = make(map[time.Time]bool)
+		for _, t := range jobRunsWithin(job, now, scheduleConflictWindow) {
+			runs[i][t] = true
+		}
+	}
+
+	var overlaps []JobOverlap
+	for i := range jobs {
+		for j := i + 1; j < len(jobs); j++ {
+			shared := 0
+			for _, volume := range jobs[i].Volumes {
+				if contains(jobs[j].Volumes, volume) {
+					shared++
+				}
+			}
+			if shared == 0 {
+				continue
+			}
+
+			overlap := JobOverlap{JobA: jobs[i].Name, JobB: jobs[j].Name, SharedVolumes: shared}
+			for t := range runs[i] {
+				if !runs[j][t] {
+					continue
+				}
+				overlap.Occurrences++
+				if overlap.FirstOverlap.IsZero() || t.Before(overlap.FirstOverlap) {
+					overlap.FirstOverlap = t
+				}
+			}
+			if overlap.Occurrences > 0 {
+				overlaps = append(overlaps, overlap)
+			}
+		}
+	}
+
+	sort.Slice(overlaps, func(i, j int) bool {
+		return overlaps[i].SharedVolumes > overlaps[j].SharedVolumes
+	})
+
+	return overlaps
+}
+
+// findBackupWindows finds minutes at which the number of volumes starting a backup reaches the threshold
+func findBackupWindows(jobs []RecurringJobInfo, now time.Time, threshold int) []BackupWindow {
+	windows := make(map[time.Time]*BackupWindow)
+	for _, job := range jobs {
+		if !isBackupTask(job.Task) || len(job.Volumes) == 0 {
+			continue
+		}
+
+		load := len(job.Volumes)
+		if job.Concurrency > 0 && int(job.Concurrency) < load {
+			load = int(job.Concurrency)
+		}
+
+		for _, t := range jobRunsWithin(job, now, scheduleConflictWindow) {
+			window, found := windows[t]
+			if !found {
+				window = &BackupWindow{Time: t}
+				windows[t] = window
+			}
+			window.Jobs = append(window.Jobs, job.Name)
+			window.Volumes += len(job.Volumes)
+			window.Load += load
+		}
+	}
+
+	var result []BackupWindow
+	for _, window := range windows {
+		if window.Volumes >= threshold {
+			sort.Strings(window.Jobs)
+			result = append(result, *window)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+
+	return result
+}
+
+// isBackupTask returns true for recurring job tasks that upload to the backup target
+func isBackupTask(task string) bool {
+	return task == "backup" || task == "backup-force-create"
+}
+
+// printScheduleConflicts prints overlapping jobs and thundering-herd backup windows
+func printScheduleConflicts(jobs []RecurringJobInfo, now time.Time, threshold int) {
+	printSectionHeader(Section{
+		Title:       "SCHEDULE CONFLICTS",
+		Description: "Recurring jobs starting at the same minute within the next 7 days",
+		Color:       Red,
+	})
+
+	overlaps := findJobOverlaps(jobs, now)
+	windows := findBackupWindows(jobs, now, threshold)
+
+	if len(overlaps) == 0 && len(windows) == 0 {
+		fmt.Println(colorize("No conflicting recurring job schedules found", Green))
+		return
+	}
+
+	w := newTableWriter()
+
+	if len(overlaps) > 0 {
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sJOB\tOVERLAPS WITH\tSHARED VOLUMES\tCOINCIDING RUNS\tFIRST AT%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "JOB\tOVERLAPS WITH\tSHARED VOLUMES\tCOINCIDING RUNS\tFIRST AT")
+		}
+
+		fmt.Fprintln(w, "───\t─────────────\t──────────────\t───────────────\t────────")
+
+		for _, overlap := range overlaps {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+				colorize(overlap.JobA, Blue),
+				colorize(overlap.JobB, Blue),
+				overlap.SharedVolumes,
+				overlap.Occurrences,
+				formatSchedule(overlap.FirstOverlap),
+			)
+		}
+		w.Flush()
+	}
+
+	if len(windows) > 0 {
+		if len(overlaps) > 0 {
+			fmt.Println()
+		}
+
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sBACKUP WINDOW\tJOBS\tVOLUMES\tPARALLEL BACKUPS%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "BACKUP WINDOW\tJOBS\tVOLUMES\tPARALLEL BACKUPS")
+		}
+
+		fmt.Fprintln(w, "─────────────\t────\t───────\t────────────────")
+
+		for _, window := range windows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n",
+				formatSchedule(window.Time),
+				strings.Join(window.Jobs, ","),
+				colorize(fmt.Sprintf("%d", window.Volumes), Red),
+				window.Load,
+			)
+		}
+		w.Flush()
+	}
+
+	fmt.Println()
+	if len(overlaps) > 0 {
+		fmt.Println(colorize("Jobs starting at the same minute on the same volumes compete for the engine: stagger their minute fields", Yellow))
+	}
+	if len(windows) > 0 {
+		fmt.Printf("%s\n", colorize(fmt.Sprintf("At least %d volumes start a backup at the same minute: stagger the backup jobs' schedules or lower their concurrency to spread the load on the backup target", threshold), Yellow))
+	}
+}
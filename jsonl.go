@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Output formats selected with -o
+const (
+	outputTable      = "table"
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputCSV        = "csv"
+	outputPrometheus = "prometheus"
+	outputHTML       = "html"
+	outputJSONLines  = "jsonl"
+	jsonLinesVersion = 1
+)
+
+// structuredOutput reports whether a parsed command writes -o json, yaml, prometheus, html, junit,
+// pdf, custom-columns, go-template, jsonpath or jsonl, or is "lhmon4 rbac". The version banner must
+// be left out of structured output.
+func structuredOutput(fs *flag.FlagSet) bool {
+	if fs.Name() == "rbac" {
+		return true
+	}
+	o := fs.Lookup("o")
+	if o == nil {
+		return false
+	}
+	format, _, _ := strings.Cut(o.Value.String(), "=")
+	switch format {
+	case outputJSON, outputYAML, outputPrometheus, outputHTML, outputJUnit, outputPDF, outputCustomColumns, outputGoTemplate, outputJSONPath, outputJSONLines:
+		return true
+	}
+	return false
+}
+
+// jsonLinesRecord is the envelope of every line of -o jsonl, Kind selects the layout of Data
+type jsonLinesRecord struct {
+	Version    int         `json:"version"`
+	Collection string      `json:"collection"`
+	Kind       string      `json:"kind"`
+	Data       interface{} `json:"data"`
+}
+
+// Records of -o jsonl
+type (
+	diskRecord struct {
+		Node              string   `json:"node"`
+		Disk              string   `json:"disk"`
+		Path              string   `json:"path"`
+		UUID              string   `json:"uuid,omitempty"`
+		Type              string   `json:"type,omitempty"`
+		Tags              []string `json:"tags,omitempty"`
+		AllowScheduling   bool     `json:"allowScheduling"`
+		EvictionRequested bool     `json:"evictionRequested"`
+		StorageMaximum    int64    `json:"storageMaximum"`
+		StorageAvailable  int64    `json:"storageAvailable"`
+		StorageScheduled  int64    `json:"storageScheduled"`
+		StorageReserved   int64    `json:"storageReserved"`
+	}
+
+	volumeRecord struct {
+		Name            string    `json:"name"`
+		Size            int64     `json:"size"`
+		ActualSize      int64     `json:"actualSize"`
+		State           string    `json:"state"`
+		Robustness      string    `json:"robustness"`
+		Node            string    `json:"node,omitempty"`
+		DesiredReplicas int64     `json:"desiredReplicas"`
+		AccessMode      string    `json:"accessMode,omitempty"`
+		Created         time.Time `json:"created"`
+	}
+
+	replicaRecord struct {
+		Name     string    `json:"name"`
+		Volume   string    `json:"volume"`
+		Node     string    `json:"node"`
+		DiskID   string    `json:"diskID"`
+		DiskPath string    `json:"diskPath"`
+		State    string    `json:"state"`
+		FailedAt string    `json:"failedAt,omitempty"`
+		Created  time.Time `json:"created"`
+	}
+
+	relationshipRecord struct {
+		Volume       string    `json:"volume"`
+		PV           string    `json:"pv"`
+		PVStatus     string    `json:"pvStatus"`
+		StorageClass string    `json:"storageClass,omitempty"`
+		PVC          string    `json:"pvc,omitempty"`
+		PVCNamespace string    `json:"pvcNamespace,omitempty"`
+		Pods         []PodInfo `json:"pods,omitempty"`
+		PodsSkipped  string    `json:"podsSkipped,omitempty"` // Why pods were not looked up
+	}
+
+	issueRecord struct {
+		ID           string `json:"id"`
+		Object       string `json:"object"`
+		Issue        string `json:"issue"`
+		Severity     string `json:"severity"`
+		Acknowledged bool   `json:"acknowledged"`
+		Runbook      string `json:"runbook,omitempty"` // Runbook URL configured for the issue category
+	}
+)
+
+// jsonLinesWriter emits one record per line to standard output as soon as it is collected
+type jsonLinesWriter struct {
+	encoder    *json.Encoder
+	collection string
+	worst      severity
+}
+
+// emit writes a single record
+func (w *jsonLinesWriter) emit(kind string, data interface{}) error {
+	return w.encoder.Encode(jsonLinesRecord{Version: jsonLinesVersion, Collection: w.collection, Kind: kind, Data: data})
+}
+
+// emitIssues writes the issues detected on a node or volume
+func (w *jsonLinesWriter) emitIssues(kind string, obj unstructured.Unstructured) error {
+	key := kind + "/" + obj.GetName()
+	for _, issue := range objectIssues(kind, obj) {
+		id := issueID(key, issue.Text)
+		acked := acknowledged(id)
+		if !acked && issue.Severity > w.worst {
+			w.worst = issue.Severity
+		}
+		record := issueRecord{ID: id, Object: key, Issue: issue.Text, Severity: issue.Severity.String(), Acknowledged: acked, Runbook: issue.Runbook}
+		if err := w.emit("issue", record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderJSONLines streams the report as JSON Lines, --fail-on applies to the issues it wrote
+func renderJSONLines(run *reportRun) error {
+	runStats.startSection("collection")
+	worst, err := printJSONLines(run.dynClient, run.clientset, run.namespace, run.filters)
+	if err != nil {
+		return err
+	}
+	printStatsSummary()
+	if run.failOn != severityNone && worst >= run.failOn {
+		run.exitCode = exitIssueSeverity
+	}
+	return nil
+}
+
+// printJSONLines streams disks, volumes, replicas, relationships and issues as JSON Lines. Longhorn
+// objects are requested one page at a time (see --page-size) and written while the next page is
+// fetched. The filters and the age and consumer filters apply like in the other formats. It returns
+// the severity of the worst unacknowledged issue.
+func printJSONLines(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, filters reportFilters) (severity, error) {
+	w := &jsonLinesWriter{encoder: json.NewEncoder(os.Stdout), collection: newCollectionID(time.Now())}
+
+	// The consumer filter needs the PV relationships before the volumes are streamed
+	var consumers map[string]PersistentVolumeInfo
+	if volumeConsumerFilter.active() {
+		pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, namespace, longhornGVR(longhornVolumes), filters.volume, filters.diskTag)
+		if err != nil {
+			return w.worst, err
+		}
+		consumers = filterRelationships(pvInfoMap)
+	}
+
+	err := forEachPage(dynClient, longhornGVR(longhornNodes), namespace, func(node unstructured.Unstructured) error {
+		if filters.node != "" && node.GetName() != filters.node {
+			return nil
+		}
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		diskNames := make([]string, 0, len(disksMap))
+		for diskName := range disksMap {
+			diskNames = append(diskNames, diskName)
+		}
+		sort.Strings(diskNames)
+
+		for _, diskName := range diskNames {
+			if filters.disk != "" && diskName != filters.disk {
+				continue
+			}
+			diskSpec, _ := disksMap[diskName].(map[string]interface{})
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+
+			record := diskRecord{Node: node.GetName(), Disk: diskName}
+			record.Path, _ = diskSpec["path"].(string)
+			record.Type, _ = diskSpec["diskType"].(string)
+			record.AllowScheduling, _ = diskSpec["allowScheduling"].(bool)
+			record.EvictionRequested, _ = diskSpec["evictionRequested"].(bool)
+			record.Tags, _, _ = unstructured.NestedStringSlice(diskSpec, "tags")
+			if filters.diskTag != "" && !contains(record.Tags, filters.diskTag) {
+				continue
+			}
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+			record.StorageReserved = int64(reserved)
+			record.UUID, _ = diskStatus["diskUUID"].(string)
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			record.StorageMaximum, record.StorageAvailable, record.StorageScheduled = int64(maximum), int64(available), int64(scheduled)
+
+			percentUsed := 0.0
+			if maximum > 0 {
+				percentUsed = 100 * (maximum - available) / maximum
+			}
+			if !diskUsageFilter.matches(record.Type, ByteSize(available), percentUsed) {
+				continue
+			}
+
+			if err := w.emit("disk", record); err != nil {
+				return err
+			}
+		}
+		return w.emitIssues("node", node)
+	})
+	if err != nil {
+		return w.worst, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	// Volumes passing the disk tag, group and consumer filters, only their replicas are streamed
+	matched := make(map[string]bool)
+	volumesFiltered := filters.diskTag != "" || filters.group != "" || consumers != nil
+
+	err = forEachPage(dynClient, longhornGVR(longhornVolumes), namespace, func(volume unstructured.Unstructured) error {
+		if filters.volume != "" && volume.GetName() != filters.volume {
+			return nil
+		}
+		diskSelector, _, _ := unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
+		if filters.diskTag != "" && !contains(diskSelector, filters.diskTag) {
+			return nil
+		}
+		if filters.group != "" && !contains(volumeGroupMembership(volume.GetLabels()), filters.group) {
+			return nil
+		}
+		if _, found := consumers[volume.GetName()]; consumers != nil && !found {
+			return nil
+		}
+		matched[volume.GetName()] = true
+		if !ageFilter.matches(volume.GetCreationTimestamp().Time) {
+			return nil
+		}
+
+		record := volumeRecord{Name: volume.GetName(), Created: volume.GetCreationTimestamp().Time}
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseInt(sizeStr, 10, 64)
+		record.Size = size
+		record.ActualSize, _, _ = unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		record.State, _, _ = unstructured.NestedString(volume.Object, "status", "state")
+		record.Robustness, _, _ = unstructured.NestedString(volume.Object, "status", "robustness")
+		record.Node, _, _ = unstructured.NestedString(volume.Object, "status", "currentNodeID")
+		record.DesiredReplicas, _, _ = unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
+		record.AccessMode, _, _ = unstructured.NestedString(volume.Object, "spec", "accessMode")
+
+		if err := w.emit("volume", record); err != nil {
+			return err
+		}
+		return w.emitIssues("volume", volume)
+	})
+	if err != nil {
+		return w.worst, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	err = forEachPage(dynClient, longhornGVR(longhornReplicas), namespace, func(replica unstructured.Unstructured) error {
+		record := replicaRecord{Name: replica.GetName(), Created: replica.GetCreationTimestamp().Time}
+		record.Volume, _, _ = unstructured.NestedString(replica.Object, "spec", "volumeName")
+		if filters.volume != "" && record.Volume != filters.volume {
+			return nil
+		}
+		if volumesFiltered && !matched[record.Volume] {
+			return nil
+		}
+		if !ageFilter.matches(record.Created) {
+			return nil
+		}
+		record.Node, _, _ = unstructured.NestedString(replica.Object, "spec", "nodeID")
+		if filters.node != "" && record.Node != filters.node {
+			return nil
+		}
+		record.DiskID, _, _ = unstructured.NestedString(replica.Object, "spec", "diskID")
+		record.DiskPath, _, _ = unstructured.NestedString(replica.Object, "spec", "diskPath")
+		record.State, _, _ = unstructured.NestedString(replica.Object, "status", "currentState")
+		record.FailedAt, _, _ = unstructured.NestedString(replica.Object, "spec", "failedAt")
+		return w.emit("replica", record)
+	})
+	if err != nil {
+		return w.worst, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	// Relationships join PVs, PVCs and pods, so they are emitted once that join is complete
+	pvInfoMap := consumers
+	if pvInfoMap == nil {
+		pvInfoMap, err = getKubernetesRelationships(dynClient, clientset, namespace, longhornGVR(longhornVolumes), filters.volume, filters.diskTag)
+		if err != nil {
+			return w.worst, err
+		}
+	}
+	volumeIDs := make([]string, 0, len(pvInfoMap))
+	for volumeID := range pvInfoMap {
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	sort.Strings(volumeIDs)
+	for _, volumeID := range volumeIDs {
+		pvInfo := pvInfoMap[volumeID]
+		if (volumesFiltered && !matched[volumeID]) || !ageFilter.matches(pvInfo.Created) {
+			continue
+		}
+		record := relationshipRecord{
+			Volume:       volumeID,
+			PV:           pvInfo.Name,
+			PVStatus:     pvInfo.Status,
+			StorageClass: pvInfo.StorageClass,
+			PVC:          pvInfo.PVCName,
+			PVCNamespace: pvInfo.PVCNamespace,
+			Pods:         pvInfo.ConsumerPods,
+			PodsSkipped:  pvInfo.ConsumersSkipped,
+		}
+		if err := w.emit("relationship", record); err != nil {
+			return w.worst, err
+		}
+	}
+
+	return w.worst, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// outputJUnit turns the health checks into JUnit test cases for CI pipelines, -o junit
+const outputJUnit = "junit"
+
+// junitTestSuites is the root element of the JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is one health check, with a test case per disk or volume it checks
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a health check for one disk or volume
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+// junitFailure describes why a test case failed, Type holds the severity
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSkipped marks a test case whose issues are all acknowledged
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// add appends a test case and updates the counters of the suite
+func (s *junitTestSuite) add(testCase junitTestCase) {
+	s.Tests++
+	if testCase.Failure != nil {
+		s.Failures++
+	}
+	if testCase.Skipped != nil {
+		s.Skipped++
+	}
+	s.Cases = append(s.Cases, testCase)
+}
+
+// issueTestCase fails a test case with the unacknowledged issues, or skips it when every issue
+// is acknowledged
+func issueTestCase(name, className string, issues []issueRecord) junitTestCase {
+	testCase := junitTestCase{Name: name, ClassName: className}
+	var open []string
+	worst := severityNone
+	for _, issue := range issues {
+		if issue.Acknowledged {
+			continue
+		}
+		open = append(open, issue.Issue)
+		if s, err := parseSeverity(issue.Severity); err == nil && s > worst {
+			worst = s
+		}
+	}
+	switch {
+	case len(open) > 0:
+		testCase.Failure = &junitFailure{Message: open[0], Type: worst.String(), Text: strings.Join(open, "\n")}
+	case len(issues) > 0:
+		testCase.Skipped = &junitSkipped{Message: fmt.Sprintf("%d acknowledged issue(s)", len(issues))}
+	}
+	return testCase
+}
+
+// junitReport evaluates the disk issue, volume issue, robustness and scheduling checks against the
+// report document
+func junitReport(doc reportDocument) junitTestSuites {
+	objectIssues := make(map[string][]issueRecord)
+	for _, issue := range doc.Issues {
+		objectIssues[issue.Object] = append(objectIssues[issue.Object], issue)
+	}
+
+	diskSuite := junitTestSuite{Name: "disk issues"}
+	for _, disk := range doc.Disks {
+		// Disk issues are reported against their node, prefixed with the disk name
+		var issues []issueRecord
+		for _, issue := range objectIssues["node/"+disk.NodeName] {
+			if strings.HasPrefix(issue.Issue, "disk "+disk.DiskName+": ") {
+				issues = append(issues, issue)
+			}
+		}
+		diskSuite.add(issueTestCase(disk.NodeName+"/"+disk.DiskName, "disks."+disk.NodeName, issues))
+	}
+
+	volumeSuite := junitTestSuite{Name: "volume issues"}
+	robustnessSuite := junitTestSuite{Name: "degraded robustness"}
+	schedulingSuite := junitTestSuite{Name: "unscheduled volumes"}
+	for _, vol := range doc.Volumes {
+		volumeSuite.add(issueTestCase(vol.Name, "volumes", objectIssues["volume/"+vol.Name]))
+
+		// Detached volumes report unknown robustness, only attached ones are checked
+		robustness := junitTestCase{Name: vol.Name, ClassName: "volumes"}
+		if vol.State == "attached" && vol.Robustness != "healthy" {
+			robustness.Failure = &junitFailure{
+				Message: fmt.Sprintf("robustness %s", vol.Robustness),
+				Type:    volumeSeverity(vol.State, vol.Robustness).String(),
+				Text:    fmt.Sprintf("%d of %d replicas", vol.ReplicaCount, vol.DesiredReplicas),
+			}
+		}
+		robustnessSuite.add(robustness)
+
+		scheduling := junitTestCase{Name: vol.Name, ClassName: "volumes"}
+		if !vol.Scheduled {
+			scheduling.Failure = &junitFailure{Message: "replicas not scheduled", Type: severityWarning.String(), Text: vol.Message}
+		}
+		schedulingSuite.add(scheduling)
+	}
+
+	report := junitTestSuites{Name: "lhmon4"}
+	for _, suite := range []junitTestSuite{diskSuite, volumeSuite, robustnessSuite, schedulingSuite} {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// renderJUnit writes the health checks of the report as JUnit XML
+func renderJUnit(doc reportDocument) error {
+	data, err := xml.MarshalIndent(junitReport(doc), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Print(xml.Header)
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
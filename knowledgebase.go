@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// builtinKnowledgeBase maps issues to the remediation steps shipped with lhmon4
+//
+//go:embed knowledgebase.yaml
+var builtinKnowledgeBase []byte
+
+// knowledgeBase is the issue to solution mapping, as read from YAML
+type knowledgeBase struct {
+	Solutions []kbEntry `json:"solutions"`
+}
+
+// kbEntry is the solution of a single issue
+type kbEntry struct {
+	Issue    string `json:"issue"`
+	Solution string `json:"solution,omitempty"` // Go template rendered with a solutionContext
+	Append   string `json:"append,omitempty"`   // Extra steps such as runbook links or ticket queues
+
+	tmpl *template.Template
+}
+
+// solutionContext holds the details of an issue that solutions may refer to
+type solutionContext struct {
+	DiskSelector   string
+	NodeSelector   string
+	VolumeSize     ByteSize
+	AvailableSpace ByteSize
+}
+
+// solutions is the knowledge base in use, keyed by issue
+var solutions map[string]kbEntry
+
+// parseKnowledgeBase reads a knowledge base and merges it into the given solutions. Entries replace
+// the solution of an existing issue when they have one, and add their append text to it.
+func parseKnowledgeBase(data []byte, into map[string]kbEntry) error {
+	var kb knowledgeBase
+	if err := yaml.UnmarshalStrict(data, &kb); err != nil {
+		return err
+	}
+
+	for i, entry := range kb.Solutions {
+		if entry.Issue == "" {
+			return fmt.Errorf("solution %d has no issue", i+1)
+		}
+
+		existing, found := into[entry.Issue]
+		if !found && entry.Solution == "" {
+			return fmt.Errorf("solution %d (%s) appends to an unknown issue", i+1, entry.Issue)
+		}
+		if entry.Solution != "" {
+			tmpl, err := template.New(entry.Issue).Option("missingkey=error").Parse(entry.Solution)
+			if err != nil {
+				return fmt.Errorf("solution %d (%s): %v", i+1, entry.Issue, err)
+			}
+			existing.Issue = entry.Issue
+			existing.Solution = entry.Solution
+			existing.tmpl = tmpl
+		}
+		if entry.Append != "" {
+			existing.Append = entry.Append
+		}
+		into[entry.Issue] = existing
+	}
+	return nil
+}
+
+// loadKnowledgeBase installs the built-in knowledge base, overridden by the file at path if set
+func loadKnowledgeBase(path string) error {
+	merged := make(map[string]kbEntry)
+	if err := parseKnowledgeBase(builtinKnowledgeBase, merged); err != nil {
+		return fmt.Errorf("failed to parse built-in knowledge base: %v", err)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read knowledge base: %v", err)
+		}
+		if err := parseKnowledgeBase(data, merged); err != nil {
+			return fmt.Errorf("failed to parse knowledge base %s: %v", path, err)
+		}
+	}
+
+	solutions = merged
+	return nil
+}
+
+// solutionFor renders the solution of an issue, falling back to the unknown issue
+func solutionFor(issue string, ctx solutionContext) string {
+	if solutions == nil {
+		if err := loadKnowledgeBase(""); err != nil {
+			return err.Error()
+		}
+	}
+
+	entry, found := solutions[issue]
+	if !found {
+		entry = solutions[issueUnknown]
+	}
+
+	var buf bytes.Buffer
+	if entry.tmpl != nil {
+		if err := entry.tmpl.Execute(&buf, ctx); err != nil {
+			buf.Reset()
+			buf.WriteString(entry.Solution)
+		}
+	}
+
+	solution := buf.String()
+	if entry.Append != "" {
+		solution = strings.TrimSpace(solution + " " + entry.Append)
+	}
+	return solution
+}
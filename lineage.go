@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Data source kinds of a Longhorn volume
+const (
+	dataSourceVolume       = "volume"
+	dataSourceSnapshot     = "snapshot"
+	dataSourceBackingImage = "backing-image"
+	dataSourceBackup       = "backup"
+)
+
+// VolumeDataSource describes where a volume's initial data came from
+type VolumeDataSource struct {
+	Kind       string `json:"kind"`       // volume, snapshot, backing-image or backup
+	Source     string `json:"source"`     // Name of the source volume, snapshot, backing image or backup URL
+	Volume     string `json:"volume"`     // Source volume for volume and snapshot clones
+	CloneState string `json:"cloneState"` // State of the clone operation, if any
+}
+
+// String returns a compact representation of the data source
+func (d VolumeDataSource) String() string {
+	if d.Kind == "" {
+		return "none"
+	}
+	return d.Kind + ":" + d.Source
+}
+
+// lineageEntry is a node in the clone tree
+type lineageEntry struct {
+	Name       string
+	CloneState string
+	Children   []string
+}
+
+// getVolumeDataSource extracts the data source of a Longhorn volume from its spec and clone status
+func getVolumeDataSource(volume unstructured.Unstructured) VolumeDataSource {
+	cloneState, _, _ := unstructured.NestedString(volume.Object, "status", "cloneStatus", "state")
+
+	dataSource, _, _ := unstructured.NestedString(volume.Object, "spec", "dataSource")
+	switch {
+	case strings.HasPrefix(dataSource, "vol://"):
+		name := strings.TrimPrefix(dataSource, "vol://")
+		return VolumeDataSource{Kind: dataSourceVolume, Source: name, Volume: name, CloneState: cloneState}
+	case strings.HasPrefix(dataSource, "snap://"):
+		source := strings.TrimPrefix(dataSource, "snap://")
+		volumeName := strings.SplitN(source, "/", 2)[0]
+		return VolumeDataSource{Kind: dataSourceSnapshot, Source: source, Volume: volumeName, CloneState: cloneState}
+	}
+
+	if backingImage, _, _ := unstructured.NestedString(volume.Object, "spec", "backingImage"); backingImage != "" {
+		return VolumeDataSource{Kind: dataSourceBackingImage, Source: backingImage}
+	}
+
+	if fromBackup, _, _ := unstructured.NestedString(volume.Object, "spec", "fromBackup"); fromBackup != "" {
+		return VolumeDataSource{Kind: dataSourceBackup, Source: fromBackup}
+	}
+
+	return VolumeDataSource{}
+}
+
+// buildLineage builds the clone tree of all volumes. Backing images are included as
+// roots named "backing-image/<name>" since they are shared by every volume using them.
+func buildLineage(volumes []unstructured.Unstructured) map[string]*lineageEntry {
+	entries := make(map[string]*lineageEntry)
+	entry := func(name string) *lineageEntry {
+		if e, found := entries[name]; found {
+			return e
+		}
+		e := &lineageEntry{Name: name}
+		entries[name] = e
+		return e
+	}
+
+	for _, volume := range volumes {
+		source := getVolumeDataSource(volume)
+		child := entry(volume.GetName())
+		child.CloneState = source.CloneState
+
+		parent := ""
+		switch source.Kind {
+		case dataSourceVolume, dataSourceSnapshot:
+			parent = source.Volume
+		case dataSourceBackingImage:
+			parent = dataSourceBackingImage + "/" + source.Source
+		}
+		if parent != "" {
+			p := entry(parent)
+			p.Children = append(p.Children, child.Name)
+		}
+	}
+
+	for _, e := range entries {
+		sort.Strings(e.Children)
+	}
+
+	return entries
+}
+
+// inProgressClones returns the clones of a volume that still depend on it
+func inProgressClones(lineage map[string]*lineageEntry, volumeName string) []string {
+	e, found := lineage[volumeName]
+	if !found {
+		return nil
+	}
+	var clones []string
+	for _, child := range e.Children {
+		if state := lineage[child].CloneState; state != "" && state != "completed" && state != "failed" {
+			clones = append(clones, child)
+		}
+	}
+	return clones
+}
+
+// printVolumeLineage prints clone trees for all volumes with a data source or dependents
+func printVolumeLineage(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		fmt.Printf("Error listing volumes: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "VOLUME LINEAGE",
+		Description: "Clone trees of volumes, snapshots and backing images",
+		Color:       Magenta,
+	})
+
+	lineage := buildLineage(volumes.Items)
+
+	// Roots are entries with children that are not clones themselves
+	isChild := make(map[string]bool)
+	for _, e := range lineage {
+		for _, child := range e.Children {
+			isChild[child] = true
+		}
+	}
+	var roots []string
+	for name, e := range lineage {
+		if !isChild[name] && len(e.Children) > 0 {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+
+	if len(roots) == 0 {
+		fmt.Println("No cloned volumes or backing images found")
+		return
+	}
+
+	visited := make(map[string]bool)
+	var printEntry func(name, prefix string, last, root bool)
+	printEntry = func(name, prefix string, last, root bool) {
+		label := name
+		if state := lineage[name].CloneState; state != "" && state != "completed" {
+			label += colorize(" [clone "+state+"]", Yellow)
+		}
+		if pvInfo, exists := pvInfoMap[name]; exists && (pvInfo.Status == "Released" || pvInfo.Status == "Failed") {
+			label += colorize(" (safe to delete)", Green)
+			if clones := inProgressClones(lineage, name); len(clones) > 0 {
+				label += colorize(fmt.Sprintf(" - deleting breaks %d in-progress clone(s)", len(clones)), Red)
+			}
+		}
+
+		connector := ""
+		childPrefix := prefix
+		if !root {
+			if last {
+				connector = "└─ "
+				childPrefix += "   "
+			} else {
+				connector = "├─ "
+				childPrefix += "│  "
+			}
+		}
+		fmt.Printf("%s%s%s\n", prefix, connector, label)
+
+		// Guard against cycles from inconsistent data sources
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		children := lineage[name].Children
+		for i, child := range children {
+			printEntry(child, childPrefix, i == len(children)-1, false)
+		}
+	}
+
+	for _, root := range roots {
+		printEntry(root, "", true, true)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ReplicaLocalityInfo describes whether the consumers of a volume run next to one of its replicas
+type ReplicaLocalityInfo struct {
+	VolumeName    string
+	DataLocality  string
+	AttachedNode  string
+	ConsumerNodes []string
+	ReplicaNodes  []string
+	LocalNodes    int // Consumer nodes hosting a healthy replica
+}
+
+// getReplicaLocality compares the nodes of the pods consuming each volume with its replica nodes
+func getReplicaLocality(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) ([]ReplicaLocalityInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	replicaNodes := make(map[string]map[string]bool) // volume -> nodes with a healthy replica
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if nodeID == "" || failedAt != "" {
+			continue
+		}
+		if replicaNodes[volumeName] == nil {
+			replicaNodes[volumeName] = make(map[string]bool)
+		}
+		replicaNodes[volumeName][nodeID] = true
+	}
+
+	var localities []ReplicaLocalityInfo
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		consumerNodes := make(map[string]bool)
+		for _, pod := range pvInfoMap[volumeName].ConsumerPods {
+			if pod.NodeName != "" {
+				consumerNodes[pod.NodeName] = true
+			}
+		}
+		if len(consumerNodes) == 0 {
+			continue
+		}
+
+		info := ReplicaLocalityInfo{
+			VolumeName:    volumeName,
+			ConsumerNodes: sortedKeys(consumerNodes),
+			ReplicaNodes:  sortedKeys(replicaNodes[volumeName]),
+		}
+		info.DataLocality, _, _ = unstructured.NestedString(volume.Object, "spec", "dataLocality")
+		info.AttachedNode, _, _ = unstructured.NestedString(volume.Object, "status", "currentNodeID")
+		for node := range consumerNodes {
+			if replicaNodes[volumeName][node] {
+				info.LocalNodes++
+			}
+		}
+
+		localities = append(localities, info)
+	}
+
+	// Volumes without any local replica first
+	sort.Slice(localities, func(i, j int) bool {
+		if (localities[i].LocalNodes == 0) != (localities[j].LocalNodes == 0) {
+			return localities[i].LocalNodes == 0
+		}
+		return localities[i].VolumeName < localities[j].VolumeName
+	})
+
+	return localities, nil
+}
+
+// printReplicaLocality prints whether the pods consuming each volume run on a node hosting one of its replicas
+func printReplicaLocality(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	localities, err := getReplicaLocality(dynClient, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "REPLICA LOCALITY",
+		Description: "Whether consumer pods run on a node hosting a replica of their volume",
+		Color:       Cyan,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tDATA LOCALITY\tATTACHED TO\tCONSUMER NODES\tREPLICA NODES\tLOCAL%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tDATA LOCALITY\tATTACHED TO\tCONSUMER NODES\tREPLICA NODES\tLOCAL")
+	}
+
+	fmt.Fprintln(w, "──────\t─────────────\t───────────\t──────────────\t─────────────\t─────")
+
+	for _, info := range localities {
+		dataLocality := info.DataLocality
+		if dataLocality == "" {
+			dataLocality = "disabled"
+		}
+		attachedNode := info.AttachedNode
+		if attachedNode == "" {
+			attachedNode = "-"
+		}
+
+		// Remote reads are only a problem for volumes that asked for locality
+		local := colorize("yes", Green)
+		switch {
+		case info.LocalNodes == 0 && dataLocality != "disabled":
+			local = colorize("no", Red)
+		case info.LocalNodes == 0:
+			local = colorize("no", Yellow)
+		case info.LocalNodes < len(info.ConsumerNodes):
+			local = colorize(fmt.Sprintf("partial (%d/%d nodes)", info.LocalNodes, len(info.ConsumerNodes)), Yellow)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			info.VolumeName,
+			dataLocality,
+			attachedNode,
+			strings.Join(info.ConsumerNodes, ", "),
+			strings.Join(info.ReplicaNodes, ", "),
+			local,
+		)
+	}
+
+	if len(localities) == 0 {
+		fmt.Fprintln(w, "No volumes with running consumer pods found")
+	}
+
+	w.Flush()
+}
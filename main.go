@@ -1,24 +1,25 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
-	"text/tabwriter"
+	"sync"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/pascal71/lhmon4/pkg/analyze"
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 )
 
 var version = "dev"
@@ -27,6 +28,7 @@ var version = "dev"
 const (
 	Reset     = "\033[0m"
 	Bold      = "\033[1m"
+	Dim       = "\033[2m"
 	Underline = "\033[4m"
 	Red       = "\033[31m"
 	Green     = "\033[32m"
@@ -47,14 +49,23 @@ const (
 
 // Constants for the Longhorn CRDs
 const (
-	longhornGroup     = "longhorn.io"
-	longhornVersion   = "v1beta2"
-	longhornNodes     = "nodes"
-	longhornVolumes   = "volumes"
-	longhornReplicas  = "replicas"
-	longhornSettings  = "settings"
-	longhornInstances = "instancemanagers"
-	longhornEngines   = "engines"
+	longhornGroup         = longhorn.Group
+	longhornVersion       = longhorn.Version
+	longhornNodes         = longhorn.Nodes
+	longhornVolumes       = longhorn.Volumes
+	longhornReplicas      = longhorn.Replicas
+	longhornSettings      = longhorn.Settings
+	longhornInstances     = longhorn.Instances
+	longhornEngines       = longhorn.Engines
+	longhornBackupTargets = longhorn.BackupTargets
+	longhornBackupVolumes = longhorn.BackupVolumes
+	longhornBackups       = longhorn.Backups
+	longhornRecurringJobs = longhorn.RecurringJobs
+	longhornBackingImages = longhorn.BackingImages
+	longhornOrphans       = longhorn.Orphans
+	longhornAttachments   = longhorn.Attachments
+	longhornEngineImages  = longhorn.EngineImages
+	longhornSnapshots     = longhorn.Snapshots
 )
 
 // ByteSize represents a size in bytes
@@ -74,98 +85,116 @@ const (
 func (b ByteSize) String() string {
 	switch {
 	case b >= PB:
-		return fmt.Sprintf("%.2f PB", b/PB)
+		return formatNumber(float64(b/PB), 2) + " PB"
 	case b >= TB:
-		return fmt.Sprintf("%.2f TB", b/TB)
+		return formatNumber(float64(b/TB), 2) + " TB"
 	case b >= GB:
-		return fmt.Sprintf("%.2f GB", b/GB)
+		return formatNumber(float64(b/GB), 2) + " GB"
 	case b >= MB:
-		return fmt.Sprintf("%.2f MB", b/MB)
+		return formatNumber(float64(b/MB), 2) + " MB"
 	case b >= KB:
-		return fmt.Sprintf("%.2f KB", b/KB)
+		return formatNumber(float64(b/KB), 2) + " KB"
 	default:
-		return fmt.Sprintf("%.2f B", b)
+		return formatNumber(float64(b), 2) + " B"
 	}
 }
 
 // DiskInfo stores information about a Longhorn disk
 type DiskInfo struct {
-	NodeName         string
-	DiskName         string
-	Path             string
-	Tags             []string
-	StorageMaximum   ByteSize
-	StorageReserved  ByteSize
-	StorageScheduled ByteSize
-	StorageAvailable ByteSize
-	Type             string
-	PercentUsed      float64
+	NodeName         string            `json:"nodeName"`
+	DiskName         string            `json:"diskName"`
+	Path             string            `json:"path"`
+	Tags             []string          `json:"tags"`
+	NodeTags         []string          `json:"nodeTags"`
+	StorageMaximum   ByteSize          `json:"storageMaximum"`
+	StorageReserved  ByteSize          `json:"storageReserved"`
+	StorageScheduled ByteSize          `json:"storageScheduled"`
+	StorageAvailable ByteSize          `json:"storageAvailable"`
+	Type             string            `json:"type"`
+	PercentUsed      float64           `json:"percentUsed"`
+	Conditions       []ConditionInfo   `json:"conditions"`
+	Hardware         *DiskHardwareInfo `json:"hardware,omitempty"` // Block device metrics, nil when not enriched
+	Custom           string            `json:"-"`                  // Custom column cells, including trailing tabs
 }
 
 // VolumeInfo stores information about a Longhorn volume
 type VolumeInfo struct {
-	Name            string
-	Size            ByteSize
-	ActualSize      ByteSize
-	State           string
-	Robustness      string
-	Node            string
-	ReplicaCount    int
-	DesiredReplicas int
-	Scheduled       bool
-	Message         string
-	DiskSelector    []string
-	NodeSelector    []string
-	Conditions      []ConditionInfo
-	SafeToDelete    bool   // True if volume can be safely deleted
-	DeleteReason    string // Reason why it's safe to delete
+	Name            string           `json:"name"`
+	Size            ByteSize         `json:"size"`
+	ActualSize      ByteSize         `json:"actualSize"`
+	State           string           `json:"state"`
+	Robustness      string           `json:"robustness"`
+	Node            string           `json:"node"`
+	ReplicaCount    int              `json:"replicaCount"`
+	DesiredReplicas int              `json:"desiredReplicas"`
+	Scheduled       bool             `json:"scheduled"`
+	Message         string           `json:"message"`
+	DiskSelector    []string         `json:"diskSelector"`
+	NodeSelector    []string         `json:"nodeSelector"`
+	Conditions      []ConditionInfo  `json:"conditions"`
+	SafeToDelete    bool             `json:"safeToDelete"`  // True if volume can be safely deleted
+	DeleteReason    string           `json:"deleteReason"`  // Reason why it's safe to delete
+	AccessMode      string           `json:"accessMode"`    // rwo or rwx
+	StorageClass    string           `json:"storageClass"`  // From the bound PV, empty without one
+	ShareEndpoint   string           `json:"shareEndpoint"` // NFS endpoint of the share manager (RWX only)
+	ShareState      string           `json:"shareState"`    // State of the share manager (RWX only)
+	AttachedNodes   int              `json:"attachedNodes"` // Number of distinct nodes running consumer pods
+	DataSource      VolumeDataSource `json:"dataSource"`
+	Standby         bool             `json:"standby"` // True for DR volumes restoring from a backup target
+	Groups          []string         `json:"groups"`  // Recurring job groups the volume belongs to
+	Labels          []string         `json:"labels"`  // Volume labels as key=value, without recurring job labels
+	Created         time.Time        `json:"created"`
+	Custom          string           `json:"-"` // Custom column cells, including trailing tabs
 }
 
 // ConditionInfo stores information about a condition
-type ConditionInfo struct {
-	Type      string
-	Status    string
-	Reason    string
-	Message   string
-	Timestamp string
-}
+type ConditionInfo = longhorn.Condition
 
 // ReplicaInfo stores information about a Longhorn replica
 type ReplicaInfo struct {
-	Name       string
-	VolumeName string
-	InstanceID string
-	NodeID     string
-	DiskID     string
-	DiskPath   string
-	DataPath   string
-	State      string
-	FailedAt   string
-	Size       ByteSize
-	Mode       string
-	Healthy    bool
+	Name       string    `json:"name"`
+	VolumeName string    `json:"volumeName"`
+	InstanceID string    `json:"instanceID"`
+	NodeID     string    `json:"nodeID"`
+	DiskID     string    `json:"diskID"`
+	DiskPath   string    `json:"diskPath"`
+	DataPath   string    `json:"dataPath"`
+	State      string    `json:"state"`
+	FailedAt   string    `json:"failedAt"`
+	Size       ByteSize  `json:"size"`
+	Mode       string    `json:"mode"`
+	Healthy    bool      `json:"healthy"`
+	Created    time.Time `json:"created"`
+	Custom     string    `json:"-"` // Custom column cells, including trailing tabs
 }
 
 // PersistentVolumeInfo stores information about a PV and its related resources
 type PersistentVolumeInfo struct {
-	Name             string
-	Namespace        string
-	StorageClass     string
-	Size             string
-	Status           string
-	VolumeHandle     string
-	PVCName          string
-	PVCNamespace     string
-	ConsumerPods     []PodInfo
-	LonghornVolumeID string
+	Name             string    `json:"name"`
+	Namespace        string    `json:"namespace"`
+	StorageClass     string    `json:"storageClass"`
+	Size             string    `json:"size"`
+	Status           string    `json:"status"`
+	VolumeHandle     string    `json:"volumeHandle"`
+	PVCName          string    `json:"pvcName"`
+	PVCNamespace     string    `json:"pvcNamespace"`
+	ConsumerPods     []PodInfo `json:"consumerPods"`
+	ConsumersSkipped string    `json:"consumersSkipped,omitempty"` // Why consumer pods were not looked up, empty when they were
+	LonghornVolumeID string    `json:"longhornVolumeID"`
+	CapacityBytes    int64     `json:"capacityBytes"`    // PV spec capacity
+	PVCRequestBytes  int64     `json:"pvcRequestBytes"`  // Storage requested by the bound PVC
+	PVCCapacityBytes int64     `json:"pvcCapacityBytes"` // Storage reported in the bound PVC's status
+	PVCPolicy        []string  `json:"pvcPolicy"`        // Recurring job selectors, provisioner hints and protection set on the PVC
+	Created          time.Time `json:"created"`
 }
 
 // PodInfo stores basic information about a pod
 type PodInfo struct {
-	Name      string
-	Namespace string
-	Status    string
-	NodeName  string
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+	NodeName  string `json:"node"`
+	Workload  string `json:"workload,omitempty"` // kind/name of the controlling workload, e.g. StatefulSet/postgres
 }
 
 // Section holds configuration for a section header
@@ -177,157 +206,618 @@ type Section struct {
 
 var (
 	// Define global color enablement
-	useColors     = true
-	compactOutput = false
-)
+	useColors = true
 
-func main() {
-	// Parse command line flags
-	var kubeconfig *string
+	// Whether the terminal interprets ANSI escape sequences
+	ansiSupported = true
+)
 
-	fmt.Println("LHMON4 Version:", version)
+// printVersionBanner prints the version banner once the flags of a command are parsed, including
+// the defaults from the configuration file, which may select structured output. Output piped into
+// other tools, e.g. by kubectl plugin users, is left without the banner.
+func printVersionBanner(fs *flag.FlagSet) {
+	if term.IsTerminal(int(os.Stdout.Fd())) && !structuredOutput(fs) {
+		fmt.Println("LHMON4 Version:", version)
+	}
+}
 
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "absolute path to the kubeconfig file")
-	} else {
-		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+func main() {
+	// Colors and screen clearing need ANSI escape sequences, which Windows consoles must opt into
+	ansiSupported = enableANSI()
+
+	// Dispatch subcommands before parsing the global flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "backup-target":
+			runBackupTarget(os.Args[2:])
+			return
+		case "backup":
+			runBackup(os.Args[2:])
+			return
+		case "backups":
+			runBackups(os.Args[2:])
+			return
+		case "recurring-jobs":
+			runRecurringJobs(os.Args[2:])
+			return
+		case "follow":
+			runFollow(os.Args[2:])
+			return
+		case "issues":
+			runIssues(os.Args[2:])
+			return
+		case "housekeeping":
+			runHousekeeping(os.Args[2:])
+			return
+		case "ack":
+			runAck(os.Args[2:])
+			return
+		case "dev":
+			runDev(os.Args[2:])
+			return
+		case "disk":
+			runDisk(os.Args[2:])
+			return
+		case "node":
+			runNode(os.Args[2:])
+			return
+		case "plan":
+			runPlan(os.Args[2:])
+			return
+		case "exporter":
+			runExporter(os.Args[2:])
+			return
+		case "whatif":
+			runWhatIf(os.Args[2:])
+			return
+		case "drift":
+			runDrift(os.Args[2:])
+			return
+		case "coverage":
+			runCoverage(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "rbac":
+			runRBAC(os.Args[2:])
+			return
+		case "find":
+			runFind(os.Args[2:])
+			return
+		case "explain":
+			runExplain(os.Args[2:])
+			return
+		}
 	}
-	namespace := flag.String("namespace", "longhorn-system", "namespace for Longhorn resources")
-	nodeName := flag.String("node", "", "filter by node name (optional)")
+
+	// Parse command line flags
+	clientOpts := registerClientFlags(flag.CommandLine)
+	registerFormatFlags(flag.CommandLine)
+	namespace := clientOpts.namespace
+	nodeName := flag.String("node", "", "filter by node name, also limiting consumer pods to those scheduled on the node (optional)")
 	diskName := flag.String("disk", "", "filter by disk name (optional)")
 	volumeName := flag.String("volume", "", "filter by volume name (optional)")
 	diskTag := flag.String("disktag", "", "filter by disk tag (optional)")
+	diskMinFree := flag.String("disk-min-free", "", "only show disks with less available space than this, e.g. 100Gi (optional)")
+	diskMaxUsed := flag.Float64("disk-max-used-percent", 0, "only show disks using more than this percentage of their capacity (0 disables)")
+	diskTypeName := flag.String("disk-type", "", "only show disks of this type: fs or block (optional)")
+	group := flag.String("group", "", "filter by recurring job group (optional)")
+	olderThan := flag.String("older-than", "", "only show volumes, replicas and PVs older than this age, e.g. 7d or 36h (optional)")
+	newerThan := flag.String("newer-than", "", "only show volumes, replicas and PVs newer than this age, e.g. 7d or 36h (optional)")
 	watch := flag.Bool("watch", false, "watch for changes")
 	interval := flag.Int("interval", 5, "interval in seconds for watch mode")
 	showReplicas := flag.Bool("replicas", true, "show detailed replica information")
 	showRelationships := flag.Bool("relationships", true, "show Kubernetes resource relationships")
+	flag.Func("hide-sections", "comma-separated sections of the table report to leave out, e.g. lineage,growth (repeatable): "+strings.Join(reportSections, ", "), hideSections)
 	verbose := flag.Bool("verbose", false, "show verbose error information")
 	nocolor := flag.Bool("nocolor", false, "disable color output")
-	compact := flag.Bool("compact", false, "use compact output format")
-	flag.Parse()
+	tableStyleName := flag.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	compact := flag.Bool("compact", false, "same as --output-profile narrow")
+	profileName := flag.String("output-profile", profileNormal, "table layout: narrow for a minimal 4-column view, normal, or wide to add node, data path, condition and age columns")
+	noPager := flag.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	stats := flag.Bool("stats", false, "print a footer with the API requests, objects processed, bytes transferred and time per section")
+	nodeExporter := flag.Bool("node-exporter", false, "annotate disks with block device metrics scraped from node-exporter")
+	nodeExporterPort := flag.Int("node-exporter-port", 9100, "node-exporter port, scraped through the API server node proxy")
+	smartctlPort := flag.Int("smartctl-exporter-port", 0, "smartctl_exporter port for media error counts (0 disables)")
+	chainThreshold := flag.Int("snapshot-chain-threshold", 0, "warn about volumes with at least this many snapshots (0 warns at the snapshot-max-count limit)")
+	highlightDisk := flag.String("highlight-disk-regex", "", "highlight disks whose name matches this regular expression (optional)")
+	highlightExpanded := flag.String("highlight-recently-expanded", "", "highlight disks whose capacity grew within this period, e.g. 24h or 7d (optional)")
+	flag.StringVar(&stateFile, "state-file", "", "file recording state between runs (default in the user cache directory)")
+	flag.StringVar(&volumeConsumerFilter.namespace, "consumer-namespace", "", "only report volumes whose PVC is in this namespace (optional)")
+	flag.StringVar(&volumeConsumerFilter.workload, "consumer-workload", "", "only report volumes used by this workload, by name or kind/name, e.g. StatefulSet/postgres (optional)")
+	consumerNamespaces := flag.String("consumer-namespaces", "", "comma-separated namespaces to search for consumer pods (default all namespaces owning Longhorn PVCs)")
+	flag.BoolVar(&consumerScope.allNamespaces, "all-namespaces", false, "list consumer pods with one cluster-wide call instead of one call per namespace")
+	kbFile := flag.String("kb", "", "knowledge base YAML overriding or extending the built-in issue solutions (optional)")
+	flag.StringVar(&configFile, "config", "", "configuration file with flag defaults, availability policies and issue patterns (default config.yaml in the user config directory)")
+	bloatRatio := flag.Float64("snapshot-bloat-ratio", 1.5, "flag volumes whose per-replica usage exceeds this multiple of the volume size")
+	growthPercent := flag.Float64("growth-percent-per-day", 20, "flag volumes whose actual size grows more than this percentage per day (0 disables)")
+	growthBytes := flag.String("growth-bytes-per-day", "", "flag volumes whose actual size grows more than this amount per day, e.g. 50Gi (optional)")
+	flag.Var(customColumnsFlag{}, "custom-column", "add a table column from a field of the Longhorn objects, [volume|node|replica/]HEADER:path, e.g. ENGINE-IMAGE:status.currentImage; $disk in node paths is the disk of the row (repeatable)")
+	failOnName := flag.String("fail-on", "", "exit with code 4 when an unacknowledged issue of this severity or worse is found: info, warning or critical (optional)")
+	cordonedMin := flag.String("cordoned-min-scheduled", "10Gi", "report disks with scheduling disabled or eviction requested that still hold this much scheduled storage")
+	fillHorizon := flag.String("fill-horizon", "72h", "alert on disks whose available space runs out within this period at their recent rate, e.g. 72h or 7d")
+	tagHorizon := flag.String("tag-forecast-horizon", "90d", "highlight disk tags whose capacity runs out within this period at their recent rate, e.g. 30d")
+	outputFormat := flag.String("o", outputTable, "output format: table, csv (see --csv-dir), json or yaml for a single document with the disks, volumes, replicas and PV relationships, prometheus for the disk and volume capacity in the text exposition format, html for a standalone page (see --report-html), pdf for a paginated capacity report (see --report-pdf), junit for the disk issue, volume issue, robustness and scheduling checks as JUnit XML test cases, custom-columns=NAME:.name,STATE:.state for tables of chosen -o json fields of the disks, volumes and replicas, go-template='{{...}}' or jsonpath='{...}' to extract values from the -o json document (go-template adds the add, has and bytes functions), or jsonl to stream one JSON object per disk, volume, replica, relationship and issue")
+	flag.StringVar(&csvOutputDir, "csv-dir", "", "write disks.csv, volumes.csv, replicas.csv and relationships.csv to this directory instead of printing tables (implies -o csv)")
+	flag.StringVar(&htmlReportPath, "report-html", "", "write the disks, volumes, replicas, relationships and issues as a standalone HTML page with sortable tables to this file (implies -o html)")
+	flag.StringVar(&pdfReportPath, "report-pdf", "", "write the cluster capacity, per-node utilization, disks, volumes and issues as a paginated PDF to this file (implies -o pdf)")
+	flag.StringVar(&textfilePath, "textfile", "", "atomically write the capacity metrics in OpenMetrics format to this file on every run or refresh, for the node_exporter textfile collector, e.g. /var/lib/node_exporter/lhmon4.prom (optional)")
+	parseFlags(flag.CommandLine, os.Args[1:])
 
 	// Set global color setting
-	useColors = !*nocolor
-	compactOutput = *compact
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Set the age filter
+	var err error
+	if ageFilter.olderThan, err = parseAge(*olderThan); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ageFilter.newerThan, err = parseAge(*newerThan); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *compact {
+		*profileName = profileNarrow
+	}
+	if err := setOutputProfile(*profileName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := setDiskUsageFilter(*diskMinFree, *diskMaxUsed, *diskTypeName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	setConsumerNamespaces(*consumerNamespaces)
+	consumerScope.node = *nodeName
 
-	// Get Kubernetes config
-	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	// Set the volume growth thresholds
+	var growthBytesPerDay ByteSize
+	if *growthBytes != "" {
+		quantity, err := resource.ParseQuantity(*growthBytes)
+		if err != nil {
+			fmt.Printf("Error: invalid --growth-bytes-per-day %q: %v\n", *growthBytes, err)
+			os.Exit(1)
+		}
+		growthBytesPerDay = ByteSize(quantity.Value())
+	}
+
+	quantity, err := resource.ParseQuantity(*cordonedMin)
 	if err != nil {
-		fmt.Printf("Error building kubeconfig: %v\n", err)
+		fmt.Printf("Error: invalid --cordoned-min-scheduled %q: %v\n", *cordonedMin, err)
 		os.Exit(1)
 	}
+	cordonedMinScheduled = ByteSize(quantity.Value())
 
-	// Create dynamic client for CRDs
-	dynClient, err := dynamic.NewForConfig(config)
+	config, err := loadConfig()
 	if err != nil {
-		fmt.Printf("Error creating dynamic client: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := loadKnowledgeBase(*kbFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Create standard client for core resources
-	clientset, err := kubernetes.NewForConfig(config)
+	// Count the API requests of the clients created below
+	if *stats {
+		runStats = &requestStats{}
+	}
+
+	// Create the Kubernetes clients
+	dynClient, clientset, err := clientOpts.newClients()
 	if err != nil {
-		fmt.Printf("Error creating Kubernetes client: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Define API resources
-	nodesGVR := schema.GroupVersionResource{Group: longhornGroup, Version: longhornVersion, Resource: longhornNodes}
-	volumesGVR := schema.GroupVersionResource{Group: longhornGroup, Version: longhornVersion, Resource: longhornVolumes}
-	replicasGVR := schema.GroupVersionResource{Group: longhornGroup, Version: longhornVersion, Resource: longhornReplicas}
+	failOn, err := parseSeverity(*failOnName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fillWithin, err := parseAge(*fillHorizon)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	tagWithin, err := parseAge(*tagHorizon)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if csvOutputDir != "" && *outputFormat == outputTable {
+		*outputFormat = outputCSV
+	}
+	*outputFormat, err = parseOutputExpression(*outputFormat)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if htmlReportPath != "" && *outputFormat == outputTable {
+		*outputFormat = outputHTML
+	}
+	if pdfReportPath != "" && *outputFormat == outputTable {
+		*outputFormat = outputPDF
+	}
+	if *outputFormat == outputCSV && csvOutputDir == "" {
+		csvOutputDir = "."
+	}
+	if _, found := lookupRenderer(*outputFormat); !found {
+		fmt.Printf("Error: invalid output format %q, expected one of %s\n", *outputFormat, outputFormats())
+		os.Exit(1)
+	}
+
+	// Set up disk highlighting
+	expandedWithin, err := parseAge(*highlightExpanded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	highlight, err := newDiskHighlighter(*highlightDisk, expandedWithin)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Set up the optional hardware integration
+	var hardware *hardwareScraper
+	if *nodeExporter {
+		hardware = newHardwareScraper(clientset, *nodeExporterPort, *smartctlPort)
+	}
+
+	// Structured output replaces the tables, errors go to stderr to keep stdout parseable
+	renderer, _ := lookupRenderer(*outputFormat)
+	run := &reportRun{
+		format:    *outputFormat,
+		dynClient: dynClient,
+		clientset: clientset,
+		namespace: *namespace,
+		filters: reportFilters{
+			node:          *nodeName,
+			disk:          *diskName,
+			volume:        *volumeName,
+			diskTag:       *diskTag,
+			group:         *group,
+			replicas:      *showReplicas,
+			relationships: *showRelationships,
+		},
+		hardware:          hardware,
+		highlight:         highlight,
+		config:            config,
+		failOn:            failOn,
+		watch:             *watch,
+		interval:          time.Duration(*interval) * time.Second,
+		verbose:           *verbose,
+		noPager:           *noPager,
+		fillWithin:        fillWithin,
+		tagWithin:         tagWithin,
+		growthPercent:     *growthPercent,
+		growthBytesPerDay: growthBytesPerDay,
+		bloatRatio:        *bloatRatio,
+		chainThreshold:    *chainThreshold,
+	}
+	if err := renderer.Render(run); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The renderer chose the exit code of policy violations and issues
+	if run.exitCode != 0 {
+		os.Exit(run.exitCode)
+	}
+}
+
+// printTableReport prints the table report, once or redrawn every --interval in watch mode. The
+// sections query the collected snapshot while they print.
+func printTableReport(run *reportRun) error {
+	nodesGVR, volumesGVR, replicasGVR := longhornGVR(longhornNodes), longhornGVR(longhornVolumes), longhornGVR(longhornReplicas)
 
 	// Run once or in watch mode
-	if *watch {
+	if run.watch {
+		newResources = newNewResourceTracker()
+		listCache = newResourceCache()
 		for {
+			// Collect before clearing the screen, so the previous report stays visible meanwhile
+			run.collect(reportResources())
+
 			clearScreen()
 			printHeader()
 
 			// Get relationships first to determine safe-to-delete volumes
-			pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, *namespace, volumesGVR, *volumeName, *diskTag)
+			pvInfoMap, err := getKubernetesRelationships(run.dynClient, run.clientset, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag)
 			if err != nil {
 				fmt.Printf("Error getting relationships: %v\n", err)
 			}
+			applyConsumerFilter(pvInfoMap)
 
-			err = printDiskInfo(dynClient, *namespace, nodesGVR, *nodeName, *diskName, *diskTag)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
+			if showSection("disks") {
+				err = printDiskInfo(run.dynClient, run.namespace, nodesGVR, run.filters.node, run.filters.disk, run.filters.diskTag, run.hardware, run.highlight)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+				}
+				fmt.Println()
 			}
 
-			fmt.Println()
-			err = printVolumeInfo(dynClient, *namespace, volumesGVR, *volumeName, *diskTag, *verbose, pvInfoMap)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
+			if showSection("volumes") {
+				err = printVolumeInfo(run.dynClient, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag, run.filters.group, run.verbose, pvInfoMap)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+				}
+				fmt.Println()
+			}
+
+			if showSection("dr-volumes") {
+				err = printDRVolumes(run.dynClient, run.namespace, volumesGVR)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+				}
+			}
+
+			if showSection("restores") {
+				err = printRestoreProgress(run.dynClient, run.namespace, volumesGVR, run.filters.volume)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+				}
 			}
 
-			if *showReplicas {
+			if run.filters.replicas && showSection("replicas") {
 				fmt.Println()
-				err = printReplicaInfo(dynClient, *namespace, replicasGVR, volumesGVR, *volumeName, *diskTag)
+				err = printReplicaInfo(run.dynClient, run.namespace, replicasGVR, volumesGVR, run.filters.volume, run.filters.diskTag, run.filters.group)
 				if err != nil {
 					fmt.Printf("Error: %v\n", err)
 				}
 			}
 
-			if *showRelationships {
+			if run.filters.relationships && showSection("relationships") {
 				fmt.Println()
-				err = printKubernetesRelationships(dynClient, clientset, *namespace, volumesGVR, *volumeName, *diskTag)
+				err = printKubernetesRelationships(run.dynClient, run.clientset, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag)
 				if err != nil {
 					fmt.Printf("Error: %v\n", err)
 				}
 			}
 
-			fmt.Printf("\n%sLast updated: %s%s\n", Bold, time.Now().Format("2006-01-02 15:04:05"), Reset)
+			printStats()
+			fmt.Printf("\n%s\n", colorize("Last updated: "+formatTimestamp(time.Now()), Bold))
 			fmt.Printf("Watching for changes. Press Ctrl+C to exit...\n")
-			time.Sleep(time.Duration(*interval) * time.Second)
+			newResources.advance()
+			time.Sleep(run.interval)
 		}
 	} else {
+		// Long reports are paged, watch mode redraws the screen itself
+		output := startPager(run.noPager)
+		defer output.finish()
+
+		// Every section renders from the same collected state
+		run.collect(reportResources())
+
 		printHeader()
 
 		// Get relationships first to determine safe-to-delete volumes
-		pvInfoMap, err := getKubernetesRelationships(dynClient, clientset, *namespace, volumesGVR, *volumeName, *diskTag)
+		pvInfoMap, err := getKubernetesRelationships(run.dynClient, run.clientset, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag)
 		if err != nil {
 			fmt.Printf("Error getting relationships: %v\n", err)
 		}
+		applyConsumerFilter(pvInfoMap)
 
-		err = printDiskInfo(dynClient, *namespace, nodesGVR, *nodeName, *diskName, *diskTag)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+		if showSection("summary") {
+			printSummary(run.dynClient, run.namespace, volumesGVR, pvInfoMap)
+			fmt.Println()
 		}
 
-		fmt.Println()
-		err = printVolumeInfo(dynClient, *namespace, volumesGVR, *volumeName, *diskTag, *verbose, pvInfoMap)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+		if showSection("disks") {
+			err = printDiskInfo(run.dynClient, run.namespace, nodesGVR, run.filters.node, run.filters.disk, run.filters.diskTag, run.hardware, run.highlight)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				output.finish()
+				os.Exit(1)
+			}
+			fmt.Println()
 		}
 
-		if *showReplicas {
+		if showSection("volumes") {
+			err = printVolumeInfo(run.dynClient, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag, run.filters.group, run.verbose, pvInfoMap)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
 			fmt.Println()
-			err = printReplicaInfo(dynClient, *namespace, replicasGVR, volumesGVR, *volumeName, *diskTag)
+		}
+
+		if showSection("dr-volumes") {
+			err = printDRVolumes(run.dynClient, run.namespace, volumesGVR)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 			}
 		}
 
-		if *showRelationships {
+		if showSection("restores") {
+			err = printRestoreProgress(run.dynClient, run.namespace, volumesGVR, run.filters.volume)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		}
+
+		if run.filters.replicas && showSection("replicas") {
+			fmt.Println()
+			err = printReplicaInfo(run.dynClient, run.namespace, replicasGVR, volumesGVR, run.filters.volume, run.filters.diskTag, run.filters.group)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		}
+
+		if run.filters.relationships && showSection("relationships") {
 			fmt.Println()
-			err = printKubernetesRelationships(dynClient, clientset, *namespace, volumesGVR, *volumeName, *diskTag)
+			err = printKubernetesRelationships(run.dynClient, run.clientset, run.namespace, volumesGVR, run.filters.volume, run.filters.diskTag)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 			}
 		}
 
+		if showSection("attachment-history") {
+			fmt.Println("\nAttachment history:")
+			printAttachmentHistory(run.dynClient, run.clientset, run.namespace, volumesGVR)
+		}
+
+		if showSection("locality") {
+			fmt.Println("\nReplica locality:")
+			printReplicaLocality(run.dynClient, run.namespace, volumesGVR, pvInfoMap)
+		}
+
+		if showSection("statefulsets") {
+			fmt.Println("\nStatefulSet volume pinning:")
+			printStatefulSetPinning(run.dynClient, run.clientset, run.namespace, volumesGVR, pvInfoMap)
+		}
+
+		if showSection("lineage") {
+			fmt.Println()
+			printVolumeLineage(run.dynClient, run.namespace, volumesGVR, pvInfoMap)
+		}
+
 		// Print volumes safe to delete first - more important information
-		printVolumeDeletionSummary(dynClient, *namespace, volumesGVR, pvInfoMap)
+		if showSection("deletion") {
+			printVolumeDeletionSummary(run.dynClient, run.namespace, volumesGVR, pvInfoMap)
+		}
+
+		worst := severityNone
+		if showSection("disk-issues") {
+			fmt.Println("\nDisks with issues:")
+			worst = printProblematicDisks(run.dynClient, run.namespace, nodesGVR)
+		}
+
+		if showSection("disk-conflicts") {
+			fmt.Println("\nReplica disk conflicts:")
+			if conflictWorst := printReplicaDiskConflicts(run.dynClient, run.namespace, nodesGVR); conflictWorst > worst {
+				worst = conflictWorst
+			}
+		}
+
+		if showSection("cordoned") {
+			fmt.Println("\nCordoned disks:")
+			if cordonWorst := printCordonedDisks(run.dynClient, run.namespace, nodesGVR); cordonWorst > worst {
+				worst = cordonWorst
+			}
+		}
 
-		fmt.Println("\nDisks with issues:")
-		printProblematicDisks(dynClient, *namespace, nodesGVR)
+		if showSection("fill-rate") {
+			fmt.Println("\nDisk fill rate:")
+			printDiskFillRate(run.dynClient, run.namespace, nodesGVR, run.fillWithin)
+		}
 
-		fmt.Println("\nVolumes with issues (detailed):")
-		printDetailedVolumeIssues(dynClient, *namespace, volumesGVR, nodesGVR)
+		if showSection("scheduling") {
+			fmt.Println("\nScheduling capacity:")
+			printSchedulingCapacity(run.dynClient, run.namespace, nodesGVR)
+		}
 
-		fmt.Println("\nVolumes using disk tags:")
-		printVolumesByDiskTag(dynClient, *namespace, volumesGVR)
+		if showSection("node-instances") {
+			fmt.Println("\nNode instances:")
+			printNodeInstances(run.dynClient, run.clientset, run.namespace, nodesGVR, volumesGVR)
+		}
+
+		if showSection("mounts") {
+			fmt.Println("\nFilesystems and mounts:")
+			printMountCheck(run.dynClient, run.namespace, nodesGVR, run.hardware)
+		}
+
+		if showSection("storage-network") {
+			fmt.Println("\nStorage network:")
+			printStorageNetworkCheck(run.dynClient, run.clientset, run.namespace)
+		}
+
+		if showSection("size-mismatches") {
+			fmt.Println("\nSize mismatches:")
+			printSizeMismatches(run.dynClient, run.namespace, volumesGVR, pvInfoMap)
+		}
+
+		if showSection("drift") {
+			fmt.Println("\nStorage class drift:")
+			printStorageClassDrift(run.dynClient, run.clientset, run.namespace, volumesGVR, pvInfoMap)
+		}
+
+		if showSection("backing-images") {
+			fmt.Println("\nBacking image placement:")
+			printBackingImagePlacement(run.dynClient, run.namespace)
+		}
+
+		if showSection("csi-snapshots") {
+			fmt.Println("\nCSI snapshots:")
+			printCSISnapshots(run.dynClient, run.namespace)
+		}
+
+		if showSection("growth") {
+			fmt.Println("\nVolume growth:")
+			printVolumeGrowth(run.dynClient, run.namespace, volumesGVR, run.growthPercent, run.growthBytesPerDay)
+		}
+
+		if showSection("snapshot-bloat") {
+			fmt.Println("\nSnapshot bloat:")
+			printSnapshotBloat(run.dynClient, run.namespace, volumesGVR, run.bloatRatio)
+		}
+
+		if showSection("snapshot-chains") {
+			fmt.Println("\nSnapshot chains:")
+			printSnapshotChains(run.dynClient, run.namespace, volumesGVR, run.chainThreshold)
+		}
+
+		if showSection("unschedulable") {
+			fmt.Println("\nUnschedulable queue:")
+			printUnschedulableQueue(run.dynClient, run.clientset, run.namespace, volumesGVR)
+		}
+
+		if showSection("volume-issues") {
+			fmt.Println("\nVolumes with issues (detailed):")
+			if volumeWorst := printDetailedVolumeIssues(run.dynClient, run.namespace, volumesGVR, nodesGVR); volumeWorst > worst {
+				worst = volumeWorst
+			}
+		}
+
+		var violations int
+		if len(run.config.AvailabilityPolicies) > 0 && showSection("availability") {
+			fmt.Println("\nAvailability policy:")
+			violations = printAvailabilityPolicy(run.dynClient, run.namespace, volumesGVR, pvInfoMap, run.config.AvailabilityPolicies)
+		}
+
+		if showSection("engine-upgrades") {
+			fmt.Println("\nEngine upgrades:")
+			printEngineUpgrades(run.dynClient, run.namespace, volumesGVR)
+		}
+
+		if showSection("engine-images") {
+			fmt.Println("\nEngine image readiness:")
+			printEngineImageMatrix(run.dynClient, run.namespace)
+		}
+
+		if showSection("disk-tags") {
+			fmt.Println("\nVolumes using disk tags:")
+			printVolumesByDiskTag(run.dynClient, run.namespace, volumesGVR)
+		}
+
+		if showSection("tag-forecast") {
+			fmt.Println("\nDisk tag capacity forecast:")
+			printTagForecast(run.dynClient, run.namespace, run.tagWithin)
+		}
+
+		printStats()
+
+		// Let scripts and CI detect policy violations and issues
+		if violations > 0 {
+			run.exitCode = exitPolicyViolation
+		} else if run.failOn != severityNone && worst >= run.failOn {
+			run.exitCode = exitIssueSeverity
+		}
 	}
+	return nil
 }
 
 // printHeader prints a header for the output
@@ -341,11 +831,16 @@ func printHeader() {
 		fmt.Println("            LONGHORN STORAGE MONITOR            ")
 		fmt.Println("═════════════════════════════════════════════════")
 	}
+	printCollectionStamp()
 	fmt.Println()
 }
 
 // clearScreen clears the terminal screen
 func clearScreen() {
+	if !ansiSupported {
+		clearScreenFallback()
+		return
+	}
 	fmt.Print("\033[H\033[2J")
 }
 
@@ -387,132 +882,50 @@ func colorize(text string, color string) string {
 //	return text
 //}
 
-// printDiskInfo prints disk information
-func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, filterNode, filterDisk, filterTag string) error {
+// getDiskInfo collects the disks matching the filters, sorted by node and disk name
+func getDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, filterNode, filterDisk, filterTag string, hardware *hardwareScraper) ([]DiskInfo, error) {
 	// Get all nodes
-	nodes, err := dynClient.Resource(nodesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to list Longhorn nodes: %v", err)
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
 	}
 
-	// Print section header
-	printSectionHeader(Section{
-		Title:       "DISK INFORMATION",
-		Description: "Storage capacity and utilization of Longhorn disks",
-		Color:       Blue,
-	})
-
 	// Collect all disk information
 	var disks []DiskInfo
 	for _, node := range nodes.Items {
-		nodeName := node.GetName()
-
 		// Skip if we're filtering by node and this isn't the right one
-		if filterNode != "" && nodeName != filterNode {
-			continue
-		}
-
-		// Get disk map from spec
-		disksMap, found, err := unstructured.NestedMap(node.Object, "spec", "disks")
-		if err != nil || !found || disksMap == nil {
-			continue
-		}
-
-		// Get disk status map from status
-		diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
-		if err != nil || !found || diskStatusMap == nil {
+		if filterNode != "" && node.GetName() != filterNode {
 			continue
 		}
+		nodeTags, _, _ := unstructured.NestedStringSlice(node.Object, "spec", "tags")
 
-		// Process each disk
-		for diskName, diskSpec := range disksMap {
-			// Skip if we're filtering by disk and this isn't the right one
-			if filterDisk != "" && diskName != filterDisk {
+		for _, disk := range longhorn.NodeDisks(node) {
+			// Skip disks outside the name, tag, capacity and type filters
+			if filterDisk != "" && disk.DiskName != filterDisk {
 				continue
 			}
-
-			diskSpecMap, ok := diskSpec.(map[string]interface{})
-			if !ok {
+			if filterTag != "" && !contains(disk.Tags, filterTag) {
 				continue
 			}
-
-			// Get disk path
-			path, _ := diskSpecMap["path"].(string)
-
-			// Get disk tags
-			var tags []string
-			tagsInterface, found := diskSpecMap["tags"]
-			if found && tagsInterface != nil {
-				tagsSlice, ok := tagsInterface.([]interface{})
-				if ok {
-					for _, t := range tagsSlice {
-						if str, ok := t.(string); ok {
-							tags = append(tags, str)
-						}
-					}
-				}
-			}
-
-			// Skip if we're filtering by tag and this disk doesn't have that tag
-			if filterTag != "" {
-				hasTag := false
-				for _, tag := range tags {
-					if tag == filterTag {
-						hasTag = true
-						break
-					}
-				}
-				if !hasTag {
-					continue
-				}
-			}
-
-			// Get disk type
-			diskType, _ := diskSpecMap["diskType"].(string)
-
-			// Get disk status
-			diskStatusInterface, found := diskStatusMap[diskName]
-			if !found {
+			if !diskUsageFilter.matches(disk.Type, ByteSize(disk.StorageAvailable), disk.PercentUsed) {
 				continue
 			}
 
-			diskStatus, ok := diskStatusInterface.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			// Get storage metrics
-			storageMaxFloat, _ := getFloat64(diskStatus, "storageMaximum")
-			storageReservedFloat, _ := getFloat64(diskStatus, "storageReserved")
-			storageScheduledFloat, _ := getFloat64(diskStatus, "storageScheduled")
-			storageAvailableFloat, _ := getFloat64(diskStatus, "storageAvailable")
-
-			storageMax := ByteSize(storageMaxFloat)
-			storageReserved := ByteSize(storageReservedFloat)
-			storageScheduled := ByteSize(storageScheduledFloat)
-			storageAvailable := ByteSize(storageAvailableFloat)
-
-			// Calculate percentage used
-			percentUsed := 0.0
-			if storageMax > 0 {
-				percentUsed = 100.0 * (float64(storageMax-storageAvailable) / float64(storageMax))
-			}
-
-			// Create disk info
-			disk := DiskInfo{
-				NodeName:         nodeName,
-				DiskName:         diskName,
-				Path:             path,
-				Tags:             tags,
-				Type:             diskType,
-				StorageMaximum:   storageMax,
-				StorageReserved:  storageReserved,
-				StorageScheduled: storageScheduled,
-				StorageAvailable: storageAvailable,
-				PercentUsed:      percentUsed,
-			}
-
-			disks = append(disks, disk)
+			disks = append(disks, DiskInfo{
+				NodeName:         disk.NodeName,
+				DiskName:         disk.DiskName,
+				Path:             disk.Path,
+				Tags:             disk.Tags,
+				NodeTags:         nodeTags,
+				Type:             disk.Type,
+				StorageMaximum:   ByteSize(disk.StorageMaximum),
+				StorageReserved:  ByteSize(disk.StorageReserved),
+				StorageScheduled: ByteSize(disk.StorageScheduled),
+				StorageAvailable: ByteSize(disk.StorageAvailable),
+				PercentUsed:      disk.PercentUsed,
+				Conditions:       disk.Conditions,
+				Custom:           customCells(customNode, node.Object, disk.DiskName),
+			})
 		}
 	}
 
@@ -524,26 +937,64 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 		return disks[i].NodeName < disks[j].NodeName
 	})
 
+	// Annotate disks with block device metrics when the hardware integration is enabled
+	if hardware != nil {
+		hardware.enrich(disks)
+	}
+
+	return disks, nil
+}
+
+// printDiskInfo prints disk information
+func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, filterNode, filterDisk, filterTag string, hardware *hardwareScraper, highlight *diskHighlighter) error {
+	disks, err := getDiskInfo(dynClient, namespace, nodesGVR, filterNode, filterDisk, filterTag, hardware)
+	if err != nil {
+		return err
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "DISK INFORMATION",
+		Description: "Storage capacity and utilization of Longhorn disks",
+		Color:       Blue,
+	})
+
 	// Print disk information in a table
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
 
 	// Print header
-	if useColors {
-		fmt.Fprintf(w, "%s%sNODE\tDISK\tTAGS\tTYPE\tTOTAL\tAVAILABLE\tSCHEDULED\tUSED%%\tPATH%s\n", Bold, Yellow, Reset)
-	} else {
-		fmt.Fprintln(w, "NODE\tDISK\tTAGS\tTYPE\tTOTAL\tAVAILABLE\tSCHEDULED\tUSED%\tPATH")
+	hardwareHeader := ""
+	hardwareSeparator := ""
+	if hardware != nil {
+		hardwareHeader = "DEVICE\tIO LATENCY\tMEDIA ERRORS\t"
+		hardwareSeparator = "──────\t──────────\t────────────\t"
+	}
+	// Wide tables add the disk conditions after the hardware columns
+	if outputProfile == profileWide {
+		hardwareHeader += "CONDITIONS\t"
+		hardwareSeparator += "──────────\t"
 	}
+	customHeaders, customSeparator := customHeader(customNode)
 
-	fmt.Fprintln(w, "────\t────\t────\t────\t─────\t─────────\t─────────\t─────\t────")
+	// Detect expanded disks against the recorded state
+	if err := highlight.record(disks); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
 
-	// Calculate the max total storage to find the expanded disks
-	var maxStorage ByteSize = 0
-	for _, disk := range disks {
-		if disk.DiskName == "lv_01" && disk.StorageMaximum > maxStorage {
-			maxStorage = disk.StorageMaximum
-		}
+	if outputProfile == profileNarrow {
+		printDisksNarrow(w, disks)
+		w.Flush()
+		return nil
 	}
 
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tTAGS\tTYPE\tTOTAL\tAVAILABLE\tSCHEDULED\tUSED%%\t%s%sPATH%s\n", Bold, Yellow, hardwareHeader, customHeaders, Reset)
+	} else {
+		fmt.Fprintf(w, "NODE\tDISK\tTAGS\tTYPE\tTOTAL\tAVAILABLE\tSCHEDULED\tUSED%%\t%s%sPATH\n", hardwareHeader, customHeaders)
+	}
+
+	fmt.Fprintf(w, "────\t────\t────\t────\t─────\t─────────\t─────────\t─────\t%s%s────\n", hardwareSeparator, customSeparator)
+
 	// Print each disk with color coding for usage levels
 	for _, disk := range disks {
 		tagStr := "none"
@@ -552,7 +1003,7 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 		}
 
 		// Color code the usage percentage
-		usageStr := fmt.Sprintf("%.1f%%", disk.PercentUsed)
+		usageStr := formatNumber(disk.PercentUsed, 1) + "%"
 		usageColor := Green
 		if disk.PercentUsed > 80 {
 			usageColor = Red
@@ -560,16 +1011,26 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 			usageColor = Yellow
 		}
 
-		// Highlight expanded disks (specifically lv_01 on k3sc003n02)
+		// Highlight matching or recently expanded disks
 		nodeColor := ""
 		diskColor := ""
-		if disk.DiskName == "lv_01" && disk.StorageMaximum > ByteSize(float64(maxStorage)*0.9) {
+		if highlight.highlighted(disk) {
 			nodeColor = Green
 			diskColor = Green + Bold
 		}
 
+		// Format block device metrics
+		hardwareColumns := ""
+		if hardware != nil {
+			hardwareColumns = formatDiskHardware(disk.Hardware)
+		}
+		if outputProfile == profileWide {
+			conditions, conditionsColor := formatConditions(disk.Conditions)
+			hardwareColumns += colorize(conditions, conditionsColor) + "\t"
+		}
+
 		if useColors {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s%s\n",
 				colorize(disk.NodeName, nodeColor),
 				colorize(disk.DiskName, diskColor),
 				colorize(tagStr, Cyan),
@@ -578,10 +1039,12 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 				colorize(disk.StorageAvailable.String(), Green),
 				colorize(disk.StorageScheduled.String(), Yellow),
 				colorize(usageStr, usageColor),
+				hardwareColumns,
+				disk.Custom,
 				disk.Path,
 			)
 		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s%s\n",
 				disk.NodeName,
 				disk.DiskName,
 				tagStr,
@@ -590,6 +1053,8 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 				disk.StorageAvailable,
 				disk.StorageScheduled,
 				usageStr,
+				hardwareColumns,
+				disk.Custom,
 				disk.Path,
 			)
 		}
@@ -599,21 +1064,14 @@ func printDiskInfo(dynClient dynamic.Interface, namespace string, nodesGVR schem
 	return nil
 }
 
-// printVolumeInfo prints volume information
-func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, filterVolume, filterTag string, verbose bool, pvInfoMap map[string]PersistentVolumeInfo) error {
+// getVolumeInfo collects the volumes matching the filters, sorted by name
+func getVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, filterVolume, filterTag, filterGroup string, pvInfoMap map[string]PersistentVolumeInfo) ([]VolumeInfo, error) {
 	// Get all volumes
-	volumes, err := dynClient.Resource(volumesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to list Longhorn volumes: %v", err)
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
 	}
 
-	// Print section header
-	printSectionHeader(Section{
-		Title:       "VOLUME INFORMATION",
-		Description: "Longhorn volumes and their status",
-		Color:       Magenta,
-	})
-
 	// Collect volume information
 	var volumeInfos []VolumeInfo
 	for _, volume := range volumes.Items {
@@ -624,135 +1082,72 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 			continue
 		}
 
-		// Get disk selector
-		diskSelector, found, _ := unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
-
-		// Skip if we're filtering by disk tag and this volume doesn't use that tag
-		if filterTag != "" && (!found || !contains(diskSelector, filterTag)) {
-			continue
-		}
-
-		// Get node selector
-		nodeSelector, _, _ := unstructured.NestedStringSlice(volume.Object, "spec", "nodeSelector")
-
-		// Get volume details
-		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
-		size, _ := strconv.ParseFloat(sizeStr, 64)
-
-		actualSizeFloat, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
-
-		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
-		robustness, _, _ := unstructured.NestedString(volume.Object, "status", "robustness")
-		nodeID, _, _ := unstructured.NestedString(volume.Object, "status", "currentNodeID")
-
-		// Get replica count
-		desiredReplicas, _, _ := unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
-
-		// Determine if volume is scheduled
-		scheduled := true
-		message := ""
-
-		// Get all conditions
-		var conditions []ConditionInfo
-		conditionsSlice, found, _ := unstructured.NestedSlice(volume.Object, "status", "conditions")
-		if found {
-			for _, c := range conditionsSlice {
-				condition, ok := c.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				condType, _ := condition["type"].(string)
-				status, _ := condition["status"].(string)
-				reason, _ := condition["reason"].(string)
-				msg, _ := condition["message"].(string)
-				ts, _ := condition["lastTransitionTime"].(string)
-
-				// Check for scheduling issues
-				if condType == "Scheduled" && status == "False" {
-					scheduled = false
-					message = msg
-				}
-
-				// Add to conditions
-				conditions = append(conditions, ConditionInfo{
-					Type:      condType,
-					Status:    status,
-					Reason:    reason,
-					Message:   msg,
-					Timestamp: ts,
-				})
-			}
-		}
-
-		// Count actual replicas
-		// Count actual replicas - check both the map length and replica status
-		replicaCount := 0
-		activeReplicaCount := 0
-		replicas, found, _ := unstructured.NestedMap(volume.Object, "status", "replicas")
-		if found {
-			// First count all replicas
-			replicaCount = len(replicas)
-
-			// Then count active replicas
-			for _, r := range replicas {
-				replica, ok := r.(map[string]interface{})
-				if !ok {
-					continue
-				}
+		// Get disk selector
+		diskSelector, found, _ := unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
 
-				// Check the mode - RW means active replica
-				mode, modeFound, _ := unstructured.NestedString(replica, "mode")
-				if modeFound && mode == "RW" {
-					activeReplicaCount++
-				}
-			}
+		// Skip if we're filtering by disk tag and this volume doesn't use that tag
+		if filterTag != "" && (!found || !contains(diskSelector, filterTag)) {
+			continue
 		}
 
-		// If there are no direct replicas but the volume is attached and healthy,
-		// we can assume it has at least one working replica
-		if activeReplicaCount == 0 && state == "attached" && robustness == "healthy" {
-			activeReplicaCount = 1
+		// Skip if we're filtering by recurring job group and this volume isn't a member
+		groups := volumeGroupMembership(volume.GetLabels())
+		if filterGroup != "" && !contains(groups, filterGroup) {
+			continue
 		}
 
-		// Use the active replica count for display
-		// replicaStatus := fmt.Sprintf("%d/%d", activeReplicaCount, desiredReplicas)
+		// Skip if the volume is outside the requested age range
+		if !ageFilter.matches(volume.GetCreationTimestamp().Time) {
+			continue
+		}
 
-		// Check if this volume is safe to delete
-		safeToDelete := false
-		deleteReason := ""
+		info := longhorn.ParseVolume(volume)
 
-		// Check PV status from the relationships
+		// Count the nodes the volume is used from, and check whether it can be deleted safely
+		attachedNodes := 0
+		pvPhase := ""
+		storageClass := ""
 		if pvInfo, exists := pvInfoMap[volumeName]; exists {
-			if pvInfo.Status == "Released" {
-				safeToDelete = true
-				deleteReason = "PV is in Released state and no longer used by any pod"
-			} else if pvInfo.Status == "Failed" {
-				safeToDelete = true
-				deleteReason = "PV is in Failed state"
+			nodes := make(map[string]bool)
+			for _, pod := range pvInfo.ConsumerPods {
+				if pod.NodeName != "" {
+					nodes[pod.NodeName] = true
+				}
 			}
-		} else if state == "detached" {
-			safeToDelete = true
-			deleteReason = "Volume is detached and not bound to any PV"
+			attachedNodes = len(nodes)
+			pvPhase = pvInfo.Status
+			storageClass = pvInfo.StorageClass
 		}
+		safeToDelete, deleteReason := analyze.SafeToDelete(info, pvPhase)
 
 		// Create volume info
 		volumeInfo := VolumeInfo{
 			Name:            volumeName,
-			Size:            ByteSize(size),
-			ActualSize:      ByteSize(actualSizeFloat),
-			State:           state,
-			Robustness:      robustness,
-			Node:            nodeID,
-			ReplicaCount:    replicaCount,
-			DesiredReplicas: int(desiredReplicas),
-			Scheduled:       scheduled,
-			Message:         message,
-			DiskSelector:    diskSelector,
-			NodeSelector:    nodeSelector,
-			Conditions:      conditions,
+			Size:            ByteSize(info.Size),
+			ActualSize:      ByteSize(info.ActualSize),
+			State:           info.State,
+			Robustness:      info.Robustness,
+			Node:            info.Node,
+			ReplicaCount:    info.ReplicaCount,
+			DesiredReplicas: info.DesiredReplicas,
+			Scheduled:       info.Scheduled,
+			Message:         info.Message,
+			DiskSelector:    info.DiskSelector,
+			NodeSelector:    info.NodeSelector,
+			Conditions:      info.Conditions,
 			SafeToDelete:    safeToDelete,
 			DeleteReason:    deleteReason,
+			AccessMode:      info.AccessMode,
+			StorageClass:    storageClass,
+			ShareEndpoint:   info.ShareEndpoint,
+			ShareState:      info.ShareState,
+			AttachedNodes:   attachedNodes,
+			DataSource:      getVolumeDataSource(volume),
+			Standby:         info.Standby,
+			Groups:          groups,
+			Labels:          volumeUserLabels(volume.GetLabels()),
+			Created:         info.Created,
+			Custom:          customCells(customVolume, volume.Object, ""),
 		}
 
 		volumeInfos = append(volumeInfos, volumeInfo)
@@ -763,26 +1158,58 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 		return volumeInfos[i].Name < volumeInfos[j].Name
 	})
 
+	return volumeInfos, nil
+}
+
+// printVolumeInfo prints volume information
+func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, filterVolume, filterTag, filterGroup string, verbose bool, pvInfoMap map[string]PersistentVolumeInfo) error {
+	volumeInfos, err := getVolumeInfo(dynClient, namespace, volumesGVR, filterVolume, filterTag, filterGroup, pvInfoMap)
+	if err != nil {
+		return err
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "VOLUME INFORMATION",
+		Description: "Longhorn volumes and their status",
+		Color:       Magenta,
+	})
+
 	// Print volume information in a table
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
+
+	if outputProfile == profileNarrow {
+		printVolumesNarrow(w, volumeInfos)
+		w.Flush()
+		return nil
+	}
+
+	// The wide profile shows the verbose columns and the volume conditions
+	wide := outputProfile == profileWide
+	verbose = verbose || wide
 
 	// Print header
+	customHeaders, customSeparator := customHeader(customVolume)
+	if wide {
+		customHeaders = "CONDITIONS\t" + customHeaders
+		customSeparator = "──────────\t" + customSeparator
+	}
 	if verbose {
 		if useColors {
-			fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tSTATE\tROBUSTNESS\tNODE\tREPLICAS\tDISK SELECTOR\tSAFE TO DELETE%s\n", Bold, Yellow, Reset)
+			fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tACCESS\tSTATE\tROBUSTNESS\tNODE\tREPLICAS\tDISK SELECTOR\tGROUPS\tLABELS\tSHARE ENDPOINT\tDATA SOURCE\tAGE\t%sSAFE TO DELETE%s\n", Bold, Yellow, customHeaders, Reset)
 		} else {
-			fmt.Fprintln(w, "VOLUME\tSIZE\tSTATE\tROBUSTNESS\tNODE\tREPLICAS\tDISK SELECTOR\tSAFE TO DELETE")
+			fmt.Fprintf(w, "VOLUME\tSIZE\tACCESS\tSTATE\tROBUSTNESS\tNODE\tREPLICAS\tDISK SELECTOR\tGROUPS\tLABELS\tSHARE ENDPOINT\tDATA SOURCE\tAGE\t%sSAFE TO DELETE\n", customHeaders)
 		}
+		fmt.Fprintf(w, "──────\t────\t──────\t─────\t──────────\t────\t────────\t─────────────\t──────\t──────\t──────────────\t───────────\t───\t%s──────────────\n", customSeparator)
 	} else {
 		if useColors {
-			fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tSTATE\tROBUSTNESS\tREPLICAS\tDISK SELECTOR\tSAFE TO DELETE%s\n", Bold, Yellow, Reset)
+			fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tACCESS\tSTATE\tROBUSTNESS\tREPLICAS\tDISK SELECTOR\tGROUPS\tSHARE ENDPOINT\tAGE\t%sSAFE TO DELETE%s\n", Bold, Yellow, customHeaders, Reset)
 		} else {
-			fmt.Fprintln(w, "VOLUME\tSIZE\tSTATE\tROBUSTNESS\tREPLICAS\tDISK SELECTOR\tSAFE TO DELETE")
+			fmt.Fprintf(w, "VOLUME\tSIZE\tACCESS\tSTATE\tROBUSTNESS\tREPLICAS\tDISK SELECTOR\tGROUPS\tSHARE ENDPOINT\tAGE\t%sSAFE TO DELETE\n", customHeaders)
 		}
+		fmt.Fprintf(w, "──────\t────\t──────\t─────\t──────────\t────────\t─────────────\t──────\t──────────────\t───\t%s──────────────\n", customSeparator)
 	}
 
-	fmt.Fprintln(w, "──────\t────\t─────\t──────────\t────\t────────\t─────────────\t──────────────")
-
 	for _, vol := range volumeInfos {
 		replicaStatus := fmt.Sprintf("%d/%d", vol.ReplicaCount, vol.DesiredReplicas)
 
@@ -791,6 +1218,16 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 			diskSelectorStr = strings.Join(vol.DiskSelector, ",")
 		}
 
+		groupsStr := "none"
+		if len(vol.Groups) > 0 {
+			groupsStr = strings.Join(vol.Groups, ",")
+		}
+
+		labelsStr := "none"
+		if len(vol.Labels) > 0 {
+			labelsStr = strings.Join(vol.Labels, ",")
+		}
+
 		// Color code the different fields
 		volNameColor := ""
 		stateColor := Green
@@ -820,6 +1257,27 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 			replicaColor = Red
 		}
 
+		// Format access mode and share endpoint, RWX volumes are highlighted
+		accessStr := strings.ToUpper(vol.AccessMode)
+		if accessStr == "" {
+			accessStr = "RWO"
+		}
+		accessColor := ""
+		shareStr := "-"
+		if vol.AccessMode == "rwx" {
+			accessStr = fmt.Sprintf("RWX (%d nodes)", vol.AttachedNodes)
+			accessColor = Magenta
+			shareStr = vol.ShareEndpoint
+			if shareStr == "" {
+				shareStr = "none (" + vol.ShareState + ")"
+			}
+		}
+
+		// Standby volumes are expected to be in restore mode
+		if vol.Standby {
+			safeDeleteText = "No - standby DR volume"
+		}
+
 		// Safe to delete highlighting
 		if vol.SafeToDelete {
 			safeDeleteText = "Yes - " + vol.DeleteReason
@@ -827,49 +1285,82 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 			volNameColor = BgGreen + Black + Bold // Highlight volume name with green background
 		}
 
+		// Mark volumes that appeared since the previous watch refresh
+		badge := newBadge("volume", vol.Name)
+
+		custom := vol.Custom
+		if wide {
+			conditions, conditionsColor := formatConditions(vol.Conditions)
+			custom = colorize(conditions, conditionsColor) + "\t" + custom
+		}
+
 		if verbose {
 			if useColors {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					colorize(vol.Name, volNameColor),
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					colorize(vol.Name, volNameColor)+badge,
 					colorize(vol.Size.String(), Blue),
+					colorize(accessStr, accessColor),
 					colorize(vol.State, stateColor),
 					colorize(vol.Robustness, robustnessColor),
 					vol.Node,
 					colorize(replicaStatus, replicaColor),
 					colorize(diskSelectorStr, Cyan),
+					colorize(groupsStr, Cyan),
+					labelsStr,
+					shareStr,
+					colorize(vol.DataSource.String(), Magenta),
+					formatAge(vol.Created),
+					custom,
 					colorize(safeDeleteText, safeDeleteColor),
 				)
 			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					vol.Name,
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					vol.Name+badge,
 					vol.Size,
+					accessStr,
 					vol.State,
 					vol.Robustness,
 					vol.Node,
 					replicaStatus,
 					diskSelectorStr,
+					groupsStr,
+					labelsStr,
+					shareStr,
+					vol.DataSource,
+					formatAge(vol.Created),
+					custom,
 					safeDeleteText,
 				)
 			}
 		} else {
 			if useColors {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					colorize(vol.Name, volNameColor),
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					colorize(vol.Name, volNameColor)+badge,
 					colorize(vol.Size.String(), Blue),
+					colorize(accessStr, accessColor),
 					colorize(vol.State, stateColor),
 					colorize(vol.Robustness, robustnessColor),
 					colorize(replicaStatus, replicaColor),
 					colorize(diskSelectorStr, Cyan),
+					colorize(groupsStr, Cyan),
+					shareStr,
+					formatAge(vol.Created),
+					custom,
 					colorize(safeDeleteText, safeDeleteColor),
 				)
 			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					vol.Name,
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					vol.Name+badge,
 					vol.Size,
+					accessStr,
 					vol.State,
 					vol.Robustness,
 					replicaStatus,
 					diskSelectorStr,
+					groupsStr,
+					shareStr,
+					formatAge(vol.Created),
+					custom,
 					safeDeleteText,
 				)
 			}
@@ -880,25 +1371,19 @@ func printVolumeInfo(dynClient dynamic.Interface, namespace string, volumesGVR s
 	return nil
 }
 
-// printReplicaInfo prints detailed information about volume replicas
-func printReplicaInfo(dynClient dynamic.Interface, namespace string, replicasGVR, volumesGVR schema.GroupVersionResource, filterVolume, filterTag string) error {
+// getReplicaInfo collects the replicas matching the filters, sorted by volume, node and name
+func getReplicaInfo(dynClient dynamic.Interface, namespace string, replicasGVR, volumesGVR schema.GroupVersionResource, filterVolume, filterTag, filterGroup string) ([]ReplicaInfo, error) {
 	// Get all replicas
-	replicas, err := dynClient.Resource(replicasGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	replicas, err := listAll(dynClient, replicasGVR, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to list Longhorn replicas: %v", err)
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
 	}
 
-	// Print section header
-	printSectionHeader(Section{
-		Title:       "REPLICA INFORMATION",
-		Description: "Volume replicas and their placement",
-		Color:       Cyan,
-	})
-
-	// If filtering by tag, we need to check which volumes use this tag
+	// If filtering by tag or group, we need to check which volumes match
 	volumesWithTag := make(map[string]bool)
-	if filterTag != "" {
-		volumes, err := dynClient.Resource(volumesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	volumesInGroup := make(map[string]bool)
+	if filterTag != "" || filterGroup != "" {
+		volumes, err := listAll(dynClient, volumesGVR, namespace)
 		if err == nil {
 			for _, volume := range volumes.Items {
 				volumeName := volume.GetName()
@@ -906,132 +1391,154 @@ func printReplicaInfo(dynClient dynamic.Interface, namespace string, replicasGVR
 				if found && contains(diskSelector, filterTag) {
 					volumesWithTag[volumeName] = true
 				}
+				if contains(volumeGroupMembership(volume.GetLabels()), filterGroup) {
+					volumesInGroup[volumeName] = true
+				}
 			}
 		}
 	}
 
-	// Create a map of volume name to a list of its replicas
-	volumeReplicas := make(map[string][]ReplicaInfo)
+	var replicaInfos []ReplicaInfo
 
 	// Process each replica
 	for _, replica := range replicas.Items {
-		replicaName := replica.GetName()
-
-		// Get replica info
-		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		info := longhorn.ParseReplica(replica)
 
 		// Skip if we're filtering by volume and this isn't the right one
-		if filterVolume != "" && volumeName != filterVolume {
+		if filterVolume != "" && info.VolumeName != filterVolume {
 			continue
 		}
 
 		// Skip if we're filtering by tag and this volume doesn't use that tag
-		if filterTag != "" && !volumesWithTag[volumeName] {
+		if filterTag != "" && !volumesWithTag[info.VolumeName] {
 			continue
 		}
 
-		instanceID, _, _ := unstructured.NestedString(replica.Object, "status", "instanceID")
-		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
-		diskID, _, _ := unstructured.NestedString(replica.Object, "spec", "diskID")
-		diskPath, _, _ := unstructured.NestedString(replica.Object, "spec", "diskPath")
-		dataPath, _, _ := unstructured.NestedString(replica.Object, "status", "currentReplicaAddressMap", "dataPath")
-		failedAt, _, _ := unstructured.NestedString(replica.Object, "status", "failedAt")
-
-		sizeStr, _, _ := unstructured.NestedString(replica.Object, "spec", "size")
-		size, _ := strconv.ParseFloat(sizeStr, 64)
-
-		state, _, _ := unstructured.NestedString(replica.Object, "status", "state")
-		mode, _, _ := unstructured.NestedString(replica.Object, "spec", "mode")
+		// Skip if we're filtering by group and this volume isn't a member
+		if filterGroup != "" && !volumesInGroup[info.VolumeName] {
+			continue
+		}
 
-		// Determine if replica is healthy
-		healthy := true
-		if state == "ERR" || state == "FAILED" || failedAt != "" {
-			healthy = false
+		// Skip if the replica is outside the requested age range
+		if !ageFilter.matches(info.Created) {
+			continue
 		}
 
 		// Create replica info
 		replicaInfo := ReplicaInfo{
-			Name:       replicaName,
-			VolumeName: volumeName,
-			InstanceID: instanceID,
-			NodeID:     nodeID,
-			DiskID:     diskID,
-			DiskPath:   diskPath,
-			DataPath:   dataPath,
-			State:      state,
-			FailedAt:   failedAt,
-			Size:       ByteSize(size),
-			Mode:       mode,
-			Healthy:    healthy,
+			Name:       info.Name,
+			VolumeName: info.VolumeName,
+			InstanceID: info.InstanceID,
+			NodeID:     info.NodeID,
+			DiskID:     info.DiskID,
+			DiskPath:   info.DiskPath,
+			DataPath:   info.DataPath,
+			State:      info.State,
+			FailedAt:   info.FailedAt,
+			Size:       ByteSize(info.Size),
+			Mode:       info.Mode,
+			Healthy:    info.Healthy,
+			Created:    info.Created,
+			Custom:     customCells(customReplica, replica.Object, ""),
+		}
+
+		replicaInfos = append(replicaInfos, replicaInfo)
+	}
+
+	// Sort replicas by volume, node and name
+	sort.Slice(replicaInfos, func(i, j int) bool {
+		a, b := replicaInfos[i], replicaInfos[j]
+		if a.VolumeName != b.VolumeName {
+			return a.VolumeName < b.VolumeName
 		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Name < b.Name
+	})
 
-		// Add to the map
-		volumeReplicas[volumeName] = append(volumeReplicas[volumeName], replicaInfo)
+	return replicaInfos, nil
+}
+
+// printReplicaInfo prints detailed information about volume replicas
+func printReplicaInfo(dynClient dynamic.Interface, namespace string, replicasGVR, volumesGVR schema.GroupVersionResource, filterVolume, filterTag, filterGroup string) error {
+	replicas, err := getReplicaInfo(dynClient, namespace, replicasGVR, volumesGVR, filterVolume, filterTag, filterGroup)
+	if err != nil {
+		return err
 	}
 
-	// Sort and print replicas by volume
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "REPLICA INFORMATION",
+		Description: "Volume replicas and their placement",
+		Color:       Cyan,
+	})
+
+	w := newTableWriter()
+
+	if outputProfile == profileNarrow {
+		printReplicasNarrow(w, replicas)
+		w.Flush()
+		return nil
+	}
 
 	// Print header
+	customHeaders, customSeparator := customHeader(customReplica)
+	if outputProfile == profileWide {
+		customHeaders = "DATA PATH\t" + customHeaders
+		customSeparator = "─────────\t" + customSeparator
+	}
 	if useColors {
-		fmt.Fprintf(w, "%s%sVOLUME\tREPLICA\tNODE\tDISK\tSTATE\tMODE\tHEALTHY\tSIZE%s\n", Bold, Yellow, Reset)
+		fmt.Fprintf(w, "%s%sVOLUME\tREPLICA\tNODE\tDISK\tSTATE\tMODE\tHEALTHY\tSIZE\t%sAGE%s\n", Bold, Yellow, customHeaders, Reset)
 	} else {
-		fmt.Fprintln(w, "VOLUME\tREPLICA\tNODE\tDISK\tSTATE\tMODE\tHEALTHY\tSIZE")
+		fmt.Fprintf(w, "VOLUME\tREPLICA\tNODE\tDISK\tSTATE\tMODE\tHEALTHY\tSIZE\t%sAGE\n", customHeaders)
 	}
 
-	fmt.Fprintln(w, "──────\t───────\t────\t────\t─────\t────\t───────\t────")
-
-	// Get sorted volume names
-	volumeNames := make([]string, 0, len(volumeReplicas))
-	for volumeName := range volumeReplicas {
-		volumeNames = append(volumeNames, volumeName)
-	}
-	sort.Strings(volumeNames)
+	fmt.Fprintf(w, "──────\t───────\t────\t────\t─────\t────\t───────\t────\t%s───\n", customSeparator)
 
-	// Print replicas for each volume
-	for _, volumeName := range volumeNames {
-		replicas := volumeReplicas[volumeName]
+	// Print replicas, grouped by volume
+	for _, replica := range replicas {
+		healthStatus := "Yes"
+		healthColor := Green
+		if !replica.Healthy {
+			healthStatus = "No"
+			healthColor = Red
+		}
 
-		// Sort replicas by node and name
-		sort.Slice(replicas, func(i, j int) bool {
-			if replicas[i].NodeID == replicas[j].NodeID {
-				return replicas[i].Name < replicas[j].Name
-			}
-			return replicas[i].NodeID < replicas[j].NodeID
-		})
+		// Mark replicas that appeared since the previous watch refresh
+		badge := newBadge("replica", replica.Name)
 
-		// Print replicas
-		for _, replica := range replicas {
-			healthStatus := "Yes"
-			healthColor := Green
-			if !replica.Healthy {
-				healthStatus = "No"
-				healthColor = Red
-			}
+		custom := replica.Custom
+		if outputProfile == profileWide {
+			custom = replica.DataPath + "\t" + custom
+		}
 
-			if useColors {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					colorize(replica.VolumeName, Blue),
-					replica.Name,
-					colorize(replica.NodeID, Cyan),
-					replica.DiskID,
-					replica.State,
-					replica.Mode,
-					colorize(healthStatus, healthColor),
-					replica.Size,
-				)
-			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					replica.VolumeName,
-					replica.Name,
-					replica.NodeID,
-					replica.DiskID,
-					replica.State,
-					replica.Mode,
-					healthStatus,
-					replica.Size,
-				)
-			}
+		if useColors {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+				colorize(replica.VolumeName, Blue),
+				replica.Name+badge,
+				colorize(replica.NodeID, Cyan),
+				replica.DiskID,
+				replica.State,
+				replica.Mode,
+				colorize(healthStatus, healthColor),
+				replica.Size,
+				custom,
+				formatAge(replica.Created),
+			)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+				replica.VolumeName,
+				replica.Name+badge,
+				replica.NodeID,
+				replica.DiskID,
+				replica.State,
+				replica.Mode,
+				healthStatus,
+				replica.Size,
+				custom,
+				formatAge(replica.Created),
+			)
 		}
 	}
 	w.Flush()
@@ -1042,13 +1549,14 @@ func printReplicaInfo(dynClient dynamic.Interface, namespace string, replicasGVR
 // getKubernetesRelationships gets the relationships between Longhorn volumes, PVs, PVCs, and Pods
 func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource, filterVolume, filterTag string) (map[string]PersistentVolumeInfo, error) {
 	// Get all Longhorn volumes
-	volumes, err := dynClient.Resource(volumesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
 	}
 
 	// Build a map of Longhorn volume ID to volume name
 	longhornVolumes := make(map[string]string) // volumeID -> volumeName
+	volumePVs := make(map[string]string)       // PV name -> volumeName
 	for _, volume := range volumes.Items {
 		volumeName := volume.GetName()
 
@@ -1067,32 +1575,54 @@ func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernet
 
 		// Add to map
 		longhornVolumes[volumeName] = volumeName
+
+		// Longhorn records the PV bound to the volume, which also covers statically-provisioned volumes
+		if pvName, _, _ := unstructured.NestedString(volume.Object, "status", "kubernetesStatus", "pvName"); pvName != "" {
+			volumePVs[pvName] = volumeName
+		}
+	}
+
+	// Start from the PVCs bound to the PVs Longhorn records, a much smaller set than all PVs and pods
+	pvcList, err := listPersistentVolumeClaims(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumeClaims: %v", err)
+	}
+	pvcs := make(map[string]corev1.PersistentVolumeClaim) // PV name -> bound PVC
+	for _, pvc := range pvcList.Items {
+		if pvc.Spec.VolumeName != "" && volumePVs[pvc.Spec.VolumeName] != "" {
+			pvcs[pvc.Spec.VolumeName] = pvc
+		}
 	}
 
-	// Get all PVs
-	pvs, err := clientset.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+	// Join outwards to the PVs of the Longhorn volumes only, which also carry unbound and released
+	// volumes
+	pvNames := make([]string, 0, len(volumePVs))
+	for pvName := range volumePVs {
+		pvNames = append(pvNames, pvName)
+	}
+	pvs, err := listNamedPersistentVolumes(clientset, pvNames)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list PersistentVolumes: %v", err)
 	}
 
 	// Build map of PV information
 	pvInfoMap := make(map[string]PersistentVolumeInfo) // LH volume ID -> PVInfo
-	for _, pv := range pvs.Items {
-		// Skip if this PV doesn't use the CSI driver for Longhorn
-		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != "driver.longhorn.io" {
-			continue
+	for _, pv := range pvs {
+		// Get the Longhorn volume ID from the CSI volume handle, or from the volume bound to the PV
+		var longhornVolumeID string
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == longhornCSIDriver {
+			longhornVolumeID = pv.Spec.CSI.VolumeHandle
+		} else {
+			longhornVolumeID = volumePVs[pv.Name]
 		}
 
-		// Get the Longhorn volume ID from the volume handle
-		longhornVolumeID := pv.Spec.CSI.VolumeHandle
-
-		// Skip if we're filtering by volume
-		if filterVolume != "" && longhornVolumeID != filterVolume {
+		// Skip if this PV isn't a Longhorn volume
+		if longhornVolumeID == "" {
 			continue
 		}
 
-		// Skip if we're filtering by tag and this volume isn't in our map
-		if filterTag != "" && longhornVolumes[longhornVolumeID] == "" {
+		// Skip if we're filtering and this volume isn't in our map
+		if (filterVolume != "" || filterTag != "") && longhornVolumes[longhornVolumeID] == "" {
 			continue
 		}
 
@@ -1101,9 +1631,11 @@ func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernet
 			Name:             pv.Name,
 			StorageClass:     pv.Spec.StorageClassName,
 			Size:             pv.Spec.Capacity.Storage().String(),
+			CapacityBytes:    pv.Spec.Capacity.Storage().Value(),
 			Status:           string(pv.Status.Phase),
 			VolumeHandle:     longhornVolumeID,
 			LonghornVolumeID: longhornVolumeID,
+			Created:          pv.CreationTimestamp.Time,
 		}
 
 		// Set PVC info if bound
@@ -1111,26 +1643,77 @@ func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernet
 			pvInfo.PVCName = pv.Spec.ClaimRef.Name
 			pvInfo.PVCNamespace = pv.Spec.ClaimRef.Namespace
 		}
+		if pvc, found := pvcs[pv.Name]; found && pvc.Namespace == pvInfo.PVCNamespace && pvc.Name == pvInfo.PVCName {
+			pvInfo.PVCRequestBytes = pvc.Spec.Resources.Requests.Storage().Value()
+			pvInfo.PVCCapacityBytes = pvc.Status.Capacity.Storage().Value()
+			pvInfo.PVCPolicy = pvcPolicy(pvc)
+		}
 
 		// Add to map
 		pvInfoMap[longhornVolumeID] = pvInfo
 	}
 
-	// Now get all pods and associate them with PVCs
+	// Only namespaces owning a bound Longhorn PVC can have consumer pods
+	podNamespaces := make(map[string]bool)
 	for volumeID, pvInfo := range pvInfoMap {
-		// Skip if PVC info is not set
-		if pvInfo.PVCName == "" || pvInfo.PVCNamespace == "" {
+		pvc, found := pvcs[pvInfo.Name]
+		if !found || pvc.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+		if !consumerNamespaceInScope(pvc.Namespace) {
+			pvInfo.ConsumersSkipped = consumersOutOfScope
+			pvInfoMap[volumeID] = pvInfo
 			continue
 		}
+		podNamespaces[pvc.Namespace] = true
+	}
+
+	// Get the running and pending pods of each namespace once, shared by every PVC in it
+	pods := make(map[string][]corev1.Pod) // namespace -> pods
+	forbidden := make(map[string]bool)
+	var mu sync.Mutex
+	if consumerScope.allNamespaces {
+		// A single cluster-wide list is cheaper than many namespaces, but needs cluster-wide RBAC
+		podList, err := listPods(clientset, "", consumerPodsFieldSelector())
+		if apierrors.IsForbidden(err) {
+			for podNamespace := range podNamespaces {
+				forbidden[podNamespace] = true
+			}
+		}
+		if err == nil {
+			for _, pod := range podList.Items {
+				pods[pod.Namespace] = append(pods[pod.Namespace], pod)
+			}
+		}
+	} else {
+		forEachConcurrently(sortedKeys(podNamespaces), func(podNamespace string) {
+			podList, err := listPods(clientset, podNamespace, consumerPodsFieldSelector())
+			mu.Lock()
+			defer mu.Unlock()
+			if apierrors.IsForbidden(err) {
+				forbidden[podNamespace] = true
+			}
+			if err == nil {
+				pods[podNamespace] = podList.Items
+			}
+		})
+	}
+	for volumeID, pvInfo := range pvInfoMap {
+		if forbidden[pvInfo.PVCNamespace] {
+			pvInfo.ConsumersSkipped = consumersForbidden
+			pvInfoMap[volumeID] = pvInfo
+		}
+	}
 
-		// Get all pods in the PVC's namespace
-		pods, err := clientset.CoreV1().Pods(pvInfo.PVCNamespace).List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
+	// Now associate the pods with PVCs
+	for volumeID, pvInfo := range pvInfoMap {
+		// Skip if PVC info is not set
+		if pvInfo.PVCName == "" || pvInfo.PVCNamespace == "" {
 			continue
 		}
 
 		// Find pods using this PVC
-		for _, pod := range pods.Items {
+		for _, pod := range pods[pvInfo.PVCNamespace] {
 			// Check each volume in the pod
 			for _, volume := range pod.Spec.Volumes {
 				// Check if this volume uses a PVC
@@ -1141,6 +1724,7 @@ func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernet
 						Namespace: pod.Namespace,
 						Status:    string(pod.Status.Phase),
 						NodeName:  pod.Spec.NodeName,
+						Workload:  podWorkload(pod),
 					}
 
 					pvInfo.ConsumerPods = append(pvInfo.ConsumerPods, podInfo)
@@ -1153,7 +1737,7 @@ func getKubernetesRelationships(dynClient dynamic.Interface, clientset *kubernet
 		}
 	}
 
-	return pvInfoMap, nil
+	return filterRelationships(pvInfoMap), nil
 }
 
 // printKubernetesRelationships prints the relationships between Longhorn volumes, PVs, PVCs, and Pods
@@ -1172,20 +1756,24 @@ func printKubernetesRelationships(dynClient dynamic.Interface, clientset *kubern
 	})
 
 	// Print the relationship information
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
 
 	// Print header
 	if useColors {
-		fmt.Fprintf(w, "%s%sLONGHORN VOLUME\tPV NAME\tPVC NAME\tPVC NAMESPACE\tSTORAGE CLASS\tSIZE\tSTATUS\tCONSUMER PODS%s\n", Bold, Yellow, Reset)
+		fmt.Fprintf(w, "%s%sLONGHORN VOLUME\tPV NAME\tPVC NAME\tPVC NAMESPACE\tSTORAGE CLASS\tSIZE\tSTATUS\tAGE\tCONSUMER PODS\tPVC POLICY%s\n", Bold, Yellow, Reset)
 	} else {
-		fmt.Fprintln(w, "LONGHORN VOLUME\tPV NAME\tPVC NAME\tPVC NAMESPACE\tSTORAGE CLASS\tSIZE\tSTATUS\tCONSUMER PODS")
+		fmt.Fprintln(w, "LONGHORN VOLUME\tPV NAME\tPVC NAME\tPVC NAMESPACE\tSTORAGE CLASS\tSIZE\tSTATUS\tAGE\tCONSUMER PODS\tPVC POLICY")
 	}
 
-	fmt.Fprintln(w, "──────────────\t───────\t────────\t─────────────\t─────────────\t────\t──────\t────────────")
+	fmt.Fprintln(w, "──────────────\t───────\t────────\t─────────────\t─────────────\t────\t──────\t───\t────────────\t──────────")
 
 	// Create a sorted list of volume IDs for consistent output
 	volumeIDs := make([]string, 0, len(pvInfoMap))
-	for volumeID := range pvInfoMap {
+	for volumeID, pvInfo := range pvInfoMap {
+		// Skip if the PV is outside the requested age range
+		if !ageFilter.matches(pvInfo.Created) {
+			continue
+		}
 		volumeIDs = append(volumeIDs, volumeID)
 	}
 	sort.Strings(volumeIDs)
@@ -1196,7 +1784,9 @@ func printKubernetesRelationships(dynClient dynamic.Interface, clientset *kubern
 
 		// Format consumer pods
 		consumerPods := "none"
-		if len(pvInfo.ConsumerPods) > 0 {
+		if pvInfo.ConsumersSkipped != "" {
+			consumerPods = "unknown (" + pvInfo.ConsumersSkipped + ")"
+		} else if len(pvInfo.ConsumerPods) > 0 {
 			podStrings := make([]string, 0, len(pvInfo.ConsumerPods))
 			for _, pod := range pvInfo.ConsumerPods {
 				podStrings = append(podStrings, fmt.Sprintf("%s (%s)", pod.Name, pod.Status))
@@ -1215,6 +1805,11 @@ func printKubernetesRelationships(dynClient dynamic.Interface, clientset *kubern
 			pvcNamespace = pvInfo.PVCNamespace
 		}
 
+		pvcPolicy := "none"
+		if len(pvInfo.PVCPolicy) > 0 {
+			pvcPolicy = strings.Join(pvInfo.PVCPolicy, ", ")
+		}
+
 		// Color coding based on status
 		statusColor := Green
 		if pvInfo.Status == "Released" {
@@ -1229,27 +1824,34 @@ func printKubernetesRelationships(dynClient dynamic.Interface, clientset *kubern
 			volumeColor = BgGreen + Black + Bold
 		}
 
+		// Mark PVs that appeared since the previous watch refresh
+		badge := newBadge("pv", pvInfo.Name)
+
 		if useColors {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 				colorize(pvInfo.LonghornVolumeID, volumeColor),
-				pvInfo.Name,
+				pvInfo.Name+badge,
 				colorize(pvcInfo, Blue),
 				pvcNamespace,
 				colorize(pvInfo.StorageClass, Cyan),
 				pvInfo.Size,
 				colorize(pvInfo.Status, statusColor),
+				formatAge(pvInfo.Created),
 				consumerPods,
+				colorize(pvcPolicy, Cyan),
 			)
 		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 				pvInfo.LonghornVolumeID,
-				pvInfo.Name,
+				pvInfo.Name+badge,
 				pvcInfo,
 				pvcNamespace,
 				pvInfo.StorageClass,
 				pvInfo.Size,
 				pvInfo.Status,
+				formatAge(pvInfo.Created),
 				consumerPods,
+				pvcPolicy,
 			)
 		}
 	}
@@ -1260,11 +1862,19 @@ func printKubernetesRelationships(dynClient dynamic.Interface, clientset *kubern
 		fmt.Println("No Kubernetes resources found using Longhorn volumes")
 	}
 
+	printSkippedConsumerNamespaces(pvInfoMap)
+
 	return nil
 }
 
 // printVolumeDeletionSummary prints a summary of volumes that are safe to delete
 func printVolumeDeletionSummary(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	// Build the clone tree so dependents of safe volumes can be reported
+	var lineage map[string]*lineageEntry
+	if volumes, err := listAll(dynClient, volumesGVR, namespace); err == nil {
+		lineage = buildLineage(volumes.Items)
+	}
+
 	// Find volumes that are safe to delete
 	var safeDeletion []string
 	var commands []string
@@ -1291,6 +1901,9 @@ func printVolumeDeletionSummary(dynClient dynamic.Interface, namespace string, v
 			} else {
 				fmt.Printf("  %s - %s\n", vol, pvInfoMap[vol].Status)
 			}
+			if clones := inProgressClones(lineage, vol); len(clones) > 0 {
+				fmt.Printf("    %s\n", colorize("WARNING: clones still copying from this volume: "+strings.Join(clones, ", "), Red))
+			}
 		}
 
 		fmt.Println("\nYou can delete them with the following commands:")
@@ -1305,13 +1918,14 @@ func printVolumeDeletionSummary(dynClient dynamic.Interface, namespace string, v
 	}
 }
 
-// printProblematicDisks prints disks with potential issues
-func printProblematicDisks(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) {
+// printProblematicDisks prints disks with potential issues, returning the severity of the worst
+// unacknowledged issue
+func printProblematicDisks(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource) severity {
 	// Get all nodes
-	nodes, err := dynClient.Resource(nodesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
 	if err != nil {
 		fmt.Printf("Error listing nodes: %v\n", err)
-		return
+		return severityNone
 	}
 
 	// Print section header
@@ -1322,88 +1936,34 @@ func printProblematicDisks(dynClient dynamic.Interface, namespace string, nodesG
 	})
 
 	// Setup tabwriter
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
 
 	// Print header
 	if useColors {
-		fmt.Fprintf(w, "%s%sNODE\tDISK\tISSUE%s\n", Bold, Yellow, Reset)
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tID\tSEVERITY\tISSUE%s\n", Bold, Yellow, Reset)
 	} else {
-		fmt.Fprintln(w, "NODE\tDISK\tISSUE")
+		fmt.Fprintln(w, "NODE\tDISK\tID\tSEVERITY\tISSUE")
 	}
 
-	fmt.Fprintln(w, "────\t────\t─────")
+	fmt.Fprintln(w, "────\t────\t──\t────────\t─────")
 
 	foundIssues := false
+	worst := severityNone
 
 	// Process each node
 	for _, node := range nodes.Items {
-		nodeName := node.GetName()
-
-		// Get disk map from spec
-		disksMap, found, err := unstructured.NestedMap(node.Object, "spec", "disks")
-		if err != nil || !found {
-			continue
-		}
-
-		// Get disk status map from status
-		diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
-		if err != nil || !found {
-			continue
-		}
-
-		// Process each disk
-		for diskName, diskSpec := range disksMap {
-			diskSpecMap, ok := diskSpec.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			// Check if disk has tags
-			tags, found := diskSpecMap["tags"]
-			if !found || tags == nil {
-				if useColors {
-					fmt.Fprintf(w, "%s\t%s\t%sNo tags defined%s\n", nodeName, diskName, Red, Reset)
-				} else {
-					fmt.Fprintf(w, "%s\t%s\tNo tags defined\n", nodeName, diskName)
-				}
-				foundIssues = true
-				continue
-			}
-
-			// Check if disk has status
-			_, found = diskStatusMap[diskName]
-			if !found {
-				if useColors {
-					fmt.Fprintf(w, "%s\t%s\t%sNo disk status available%s\n", nodeName, diskName, Red, Reset)
-				} else {
-					fmt.Fprintf(w, "%s\t%s\tNo disk status available\n", nodeName, diskName)
-				}
-				foundIssues = true
-				continue
-			}
-
-			// Check disk conditions for any issues
-			conditions, found, _ := unstructured.NestedSlice(diskStatusMap, diskName, "conditions")
-			if found {
-				for _, c := range conditions {
-					condition, ok := c.(map[string]interface{})
-					if !ok {
-						continue
-					}
-
-					condType, _ := condition["type"].(string)
-					status, _ := condition["status"].(string)
-					reason, _ := condition["reason"].(string)
-
-					if status == "False" && condType != "" {
-						if useColors {
-							fmt.Fprintf(w, "%s\t%s\t%s%s: %s%s\n", nodeName, diskName, Red, condType, reason, Reset)
-						} else {
-							fmt.Fprintf(w, "%s\t%s\t%s: %s\n", nodeName, diskName, condType, reason)
-						}
-						foundIssues = true
-					}
-				}
+		for _, issue := range diskIssues(node) {
+			id := issueID("node/"+node.GetName(), fmt.Sprintf("disk %s: %s", issue.DiskName, issue.Issue))
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				node.GetName(),
+				issue.DiskName,
+				id,
+				colorize(issue.Severity.String(), issue.Severity.color()),
+				formatIssue(withRunbook(issue.Issue, "disk"), id, issue.Severity.color()),
+			)
+			foundIssues = true
+			if issue.Severity > worst && !acknowledged(id) {
+				worst = issue.Severity
 			}
 		}
 	}
@@ -1413,18 +1973,40 @@ func printProblematicDisks(dynClient dynamic.Interface, namespace string, nodesG
 	}
 
 	w.Flush()
+	return worst
+}
+
+// DiskIssue stores a problem detected on a Longhorn disk
+type DiskIssue struct {
+	DiskName string
+	Issue    string
+	Severity severity
+}
+
+// diskIssues returns the problems detected on the disks of a Longhorn node
+func diskIssues(node unstructured.Unstructured) []DiskIssue {
+	var issues []DiskIssue
+	for _, issue := range analyze.DiskIssues(node) {
+		issues = append(issues, DiskIssue{DiskName: issue.DiskName, Issue: issue.Issue, Severity: severity(issue.Severity)})
+	}
+	return issues
+}
+
+// volumeIssueConditions reports whether a volume has issues, along with the failed conditions describing them
+func volumeIssueConditions(volume unstructured.Unstructured) (bool, []ConditionInfo) {
+	return analyze.VolumeIssues(volume)
 }
 
-func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, volumesGVR, nodesGVR schema.GroupVersionResource) {
+func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, volumesGVR, nodesGVR schema.GroupVersionResource) severity {
 	// Get all volumes
-	volumes, err := dynClient.Resource(volumesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
 	if err != nil {
 		fmt.Printf("Error listing volumes: %v\n", err)
-		return
+		return severityNone
 	}
 
 	// Get all nodes for disk info
-	nodes, err := dynClient.Resource(nodesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
 	if err != nil {
 		fmt.Printf("Error listing nodes: %v\n", err)
 	}
@@ -1436,112 +2018,28 @@ func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, vo
 		Color:       Red,
 	})
 
-	// Build disk info map for diagnostics
-	diskInfoMap := make(map[string]map[string]DiskInfo) // node -> disk -> info
+	// Collect the disks for diagnostics
+	var disks []longhorn.Disk
 	if err == nil {
 		for _, node := range nodes.Items {
-			nodeName := node.GetName()
-			diskInfoMap[nodeName] = make(map[string]DiskInfo)
-
-			// Get disk map from spec
-			disksMap, found, err := unstructured.NestedMap(node.Object, "spec", "disks")
-			if err != nil || !found || disksMap == nil {
-				continue
-			}
-
-			// Get disk status map from status
-			diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
-			if err != nil || !found || diskStatusMap == nil {
-				continue
-			}
-
-			// Process each disk
-			for diskName, diskSpec := range disksMap {
-				diskSpecMap, ok := diskSpec.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				// Get disk path
-				path, _ := diskSpecMap["path"].(string)
-
-				// Get disk tags
-				var tags []string
-				tagsInterface, found := diskSpecMap["tags"]
-				if found && tagsInterface != nil {
-					tagsSlice, ok := tagsInterface.([]interface{})
-					if ok {
-						for _, t := range tagsSlice {
-							if str, ok := t.(string); ok {
-								tags = append(tags, str)
-							}
-						}
-					}
-				}
-
-				// Get disk type
-				diskType, _ := diskSpecMap["diskType"].(string)
-
-				// Get disk status
-				diskStatusInterface, found := diskStatusMap[diskName]
-				if !found {
-					continue
-				}
-
-				diskStatus, ok := diskStatusInterface.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				// Get storage metrics
-				storageMaxFloat, _ := getFloat64(diskStatus, "storageMaximum")
-				storageReservedFloat, _ := getFloat64(diskStatus, "storageReserved")
-				storageScheduledFloat, _ := getFloat64(diskStatus, "storageScheduled")
-				storageAvailableFloat, _ := getFloat64(diskStatus, "storageAvailable")
-
-				storageMax := ByteSize(storageMaxFloat)
-				storageReserved := ByteSize(storageReservedFloat)
-				storageScheduled := ByteSize(storageScheduledFloat)
-				storageAvailable := ByteSize(storageAvailableFloat)
-
-				// Calculate percentage used
-				percentUsed := 0.0
-				if storageMax > 0 {
-					percentUsed = 100.0 * (float64(storageMax-storageAvailable) / float64(storageMax))
-				}
-
-				// Create disk info
-				disk := DiskInfo{
-					NodeName:         nodeName,
-					DiskName:         diskName,
-					Path:             path,
-					Tags:             tags,
-					Type:             diskType,
-					StorageMaximum:   storageMax,
-					StorageReserved:  storageReserved,
-					StorageScheduled: storageScheduled,
-					StorageAvailable: storageAvailable,
-					PercentUsed:      percentUsed,
-				}
-
-				diskInfoMap[nodeName][diskName] = disk
-			}
+			disks = append(disks, longhorn.NodeDisks(node)...)
 		}
 	}
 
 	// Setup tabwriter
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
 
 	// Print header
 	if useColors {
-		fmt.Fprintf(w, "%s%sVOLUME\tSTATE\tROBUSTNESS\tREPLICAS\tISSUE\tPOSSIBLE SOLUTION%s\n", Bold, Yellow, Reset)
+		fmt.Fprintf(w, "%s%sVOLUME\tSTATE\tROBUSTNESS\tREPLICAS\tID\tSEVERITY\tISSUE\tPOSSIBLE SOLUTION%s\n", Bold, Yellow, Reset)
 	} else {
-		fmt.Fprintln(w, "VOLUME\tSTATE\tROBUSTNESS\tREPLICAS\tISSUE\tPOSSIBLE SOLUTION")
+		fmt.Fprintln(w, "VOLUME\tSTATE\tROBUSTNESS\tREPLICAS\tID\tSEVERITY\tISSUE\tPOSSIBLE SOLUTION")
 	}
 
-	fmt.Fprintln(w, "──────\t─────\t──────────\t────────\t─────\t─────────────────")
+	fmt.Fprintln(w, "──────\t─────\t──────────\t────────\t──\t────────\t─────\t─────────────────")
 
 	foundIssues := false
+	worst := severityNone
 
 	// Process each volume
 	for _, volume := range volumes.Items {
@@ -1602,55 +2100,7 @@ func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, vo
 		}
 
 		// Check if this volume actually has issues
-		hasIssue := false
-
-		// Volumes with attached state but unhealthy robustness
-		if state == "attached" && (robustness == "degraded" || robustness == "faulted" || robustness == "unknown") {
-			hasIssue = true
-		}
-
-		// Detached or errored volumes
-		if state == "detached" || state == "error" {
-			hasIssue = true
-		}
-
-		// Explicit check for condition failures
-		conditionFailure := false
-		failedConditions := make([]ConditionInfo, 0)
-
-		conditions, found, _ := unstructured.NestedSlice(volume.Object, "status", "conditions")
-		if found {
-			for _, c := range conditions {
-				condition, ok := c.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				condType, _ := condition["type"].(string)
-				status, _ := condition["status"].(string)
-				reason, _ := condition["reason"].(string)
-				message, _ := condition["message"].(string)
-
-				// Skip certain condition types that don't indicate problems
-				if condType == "Restore" || condType == "WaitForBackingImage" {
-					continue
-				}
-
-				if status == "False" && message != "" {
-					conditionFailure = true
-					failedConditions = append(failedConditions, ConditionInfo{
-						Type:    condType,
-						Status:  status,
-						Reason:  reason,
-						Message: message,
-					})
-				}
-			}
-		}
-
-		if conditionFailure {
-			hasIssue = true
-		}
+		hasIssue, failedConditions := volumeIssueConditions(volume)
 
 		// Only process volumes with actual issues
 		if hasIssue {
@@ -1658,10 +2108,16 @@ func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, vo
 			if len(failedConditions) > 0 {
 				for _, cond := range failedConditions {
 					// Perform diagnostics based on the issue type and add solutions
-					solution := "Unknown issue, check Longhorn logs for more details"
+					category := classifyCondition(cond.Reason, cond.Message)
+					issue := category
+					ctx := solutionContext{
+						DiskSelector: strings.Join(diskSelector, ","),
+						NodeSelector: strings.Join(nodeSelector, ","),
+						VolumeSize:   volumeSize,
+					}
 
 					// Tag issues - check if any disk has the required tag
-					if strings.Contains(cond.Message, "tags not fulfilled") || strings.Contains(cond.Message, "no disk matches requirements") {
+					if category == issueDiskTag {
 						// Analyze available disks vs required tags
 						availableDisks := 0
 						availableSpace := ByteSize(0)
@@ -1673,100 +2129,106 @@ func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, vo
 						}
 
 						// Count disks with the required tags and their available space
-						for _, disks := range diskInfoMap {
-							for _, diskInfo := range disks {
-								hasAllTags := true
-								for tag := range requiredTags {
-									if !contains(diskInfo.Tags, tag) {
-										hasAllTags = false
-										break
-									}
+						for _, disk := range disks {
+							hasAllTags := true
+							for tag := range requiredTags {
+								if !contains(disk.Tags, tag) {
+									hasAllTags = false
+									break
 								}
+							}
 
-								if hasAllTags {
-									availableDisks++
-									availableSpace += diskInfo.StorageAvailable
-								}
+							if hasAllTags {
+								availableDisks++
+								availableSpace += ByteSize(disk.StorageAvailable)
 							}
 						}
 
-						// Generate solution based on findings
+						// Refine the issue based on findings
+						ctx.AvailableSpace = availableSpace
 						if availableDisks == 0 {
-							solution = fmt.Sprintf("No disks found with required tags: %s. Add these tags to appropriate disks or modify volume to use different tags.", strings.Join(diskSelector, ","))
+							issue = "disk-tag-missing"
 						} else if availableSpace < volumeSize {
-							solution = fmt.Sprintf("Insufficient space on disks with required tags. Available: %s, Required: %s. Extend disk space or reduce volume size.", availableSpace, volumeSize)
-						} else {
-							solution = fmt.Sprintf("Disk tags match but scheduling failed. Check node conditions and Longhorn manager logs.")
+							issue = "disk-tag-insufficient-space"
 						}
-					} else if strings.Contains(cond.Message, "insufficient storage") {
-						// Storage space issues
-						solution = fmt.Sprintf("Not enough storage space available for volume size %s. Extend storage on disks with appropriate tags or reduce volume size.", volumeSize)
-					} else if strings.Contains(cond.Message, "specified node tag") || strings.Contains(cond.Message, "node tag") {
-						// Node tag issues
-						solution = fmt.Sprintf("Node selector tags not fulfilled: %s. Add these tags to appropriate nodes or modify volume to use different node selector.", strings.Join(nodeSelector, ","))
-					} else if strings.Contains(cond.Message, "error creating") || strings.Contains(cond.Message, "create volume error") {
-						// Volume creation issues
-						solution = "Error during volume creation. Check Longhorn manager logs for details. Try deleting and recreating the volume."
-					} else if strings.Contains(cond.Message, "error attaching") {
-						// Volume attachment issues
-						solution = "Error attaching volume. Check that the node has access to the storage. Try restarting the Longhorn manager on the node."
 					}
+					solution := withRunbook(solutionFor(issue, ctx), issue, category)
 
 					issueText := fmt.Sprintf("%s: %s", cond.Type, cond.Message)
+					id := issueID("volume/"+volumeName, issueText)
+					issueSeverity := volumeConditionSeverity(state, robustness)
 					if useColors {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 							volumeName,
 							colorize(state, stateColor),
 							colorize(robustness, robustnessColor),
 							replicaStatus,
-							colorize(issueText, Red),
+							id,
+							colorize(issueSeverity.String(), issueSeverity.color()),
+							formatIssue(issueText, id, issueSeverity.color()),
 							colorize(solution, Yellow),
 						)
 					} else {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 							volumeName,
 							state,
 							robustness,
 							replicaStatus,
-							issueText,
+							id,
+							issueSeverity,
+							formatIssue(issueText, id, ""),
 							solution,
 						)
 					}
 					foundIssues = true
+					if issueSeverity > worst && !acknowledged(id) {
+						worst = issueSeverity
+					}
 				}
 			} else {
 				// Handle volumes with state/robustness issues but no explicit condition failure
-				solution := "Unknown issue, check Longhorn logs for more details"
+				issue := issueUnknown
 				issueText := "Volume has issues but no specific condition found"
 
 				if state == "detached" {
-					solution = "Volume is detached. Attach the volume to a workload or delete it if no longer needed."
+					issue = "detached"
 				} else if robustness == "unknown" {
-					solution = "Volume robustness is unknown. This may be a transient state. If it persists, try restarting the Longhorn manager."
+					issue = "robustness-unknown"
 				} else if state == "error" {
-					solution = "Volume is in error state. Check Longhorn manager logs for details."
+					issue = "error-state"
 				}
+				solution := withRunbook(solutionFor(issue, solutionContext{VolumeSize: volumeSize}), issue)
 
+				// Same fingerprint as "lhmon4 issues --follow" reports for this volume
+				id := issueID("volume/"+volumeName, fmt.Sprintf("state %s, robustness %s", state, robustness))
+				issueSeverity := volumeSeverity(state, robustness)
 				if useColors {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 						volumeName,
 						colorize(state, stateColor),
 						colorize(robustness, robustnessColor),
 						replicaStatus,
-						colorize(issueText, Red),
+						id,
+						colorize(issueSeverity.String(), issueSeverity.color()),
+						formatIssue(issueText, id, issueSeverity.color()),
 						colorize(solution, Yellow),
 					)
 				} else {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 						volumeName,
 						state,
 						robustness,
 						replicaStatus,
-						issueText,
+						id,
+						issueSeverity,
+						formatIssue(issueText, id, ""),
 						solution,
 					)
 				}
 				foundIssues = true
+				if issueSeverity > worst && !acknowledged(id) {
+					worst = issueSeverity
+				}
 			}
 		}
 	}
@@ -1776,12 +2238,13 @@ func printDetailedVolumeIssues(dynClient dynamic.Interface, namespace string, vo
 	}
 
 	w.Flush()
+	return worst
 }
 
 // printVolumesByDiskTag prints volumes that use specific disk tags
 func printVolumesByDiskTag(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) {
 	// Get all volumes
-	volumes, err := dynClient.Resource(volumesGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
 	if err != nil {
 		fmt.Printf("Error listing volumes: %v\n", err)
 		return
@@ -1795,7 +2258,7 @@ func printVolumesByDiskTag(dynClient dynamic.Interface, namespace string, volume
 	})
 
 	// Setup tabwriter
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+	w := newTableWriter()
 
 	// Print header
 	if useColors {
@@ -1906,31 +2369,6 @@ func printVolumesByDiskTag(dynClient dynamic.Interface, namespace string, volume
 	w.Flush()
 }
 
-// getFloat64 extracts a float64 value from a map
-func getFloat64(m map[string]interface{}, key string) (float64, bool) {
-	v, found := m[key]
-	if !found {
-		return 0, false
-	}
-
-	switch value := v.(type) {
-	case float64:
-		return value, true
-	case int:
-		return float64(value), true
-	case int64:
-		return float64(value), true
-	case string:
-		f, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			return 0, false
-		}
-		return f, true
-	default:
-		return 0, false
-	}
-}
-
 // contains checks if a string slice contains a specific value
 func contains(slice []string, value string) bool {
 	for _, item := range slice {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// maintenanceWindow is a recurring period, e.g. planned node reboots, during which issues are
+// expected and not alerted on
+type maintenanceWindow struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"` // Cron expression of the window start
+	Duration string `json:"duration"` // e.g. 2h or 1d
+
+	schedule *cronSchedule
+	duration time.Duration
+}
+
+// maintenanceWindows are the windows from the configuration file
+var maintenanceWindows []maintenanceWindow
+
+// setMaintenanceWindows parses and installs the configured maintenance windows
+func setMaintenanceWindows(windows []maintenanceWindow) error {
+	for i := range windows {
+		window := &windows[i]
+
+		schedule, err := parseCron(window.Schedule)
+		if err != nil {
+			return fmt.Errorf("maintenance window %d (%s): %v", i+1, window.Name, err)
+		}
+		duration, err := parseAge(window.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("maintenance window %d (%s): invalid duration %q", i+1, window.Name, window.Duration)
+		}
+
+		window.schedule = schedule
+		window.duration = duration
+	}
+	maintenanceWindows = windows
+	return nil
+}
+
+// active returns true if the window started less than its duration before t
+func (m maintenanceWindow) active(t time.Time) bool {
+	start := m.schedule.next(t.Add(-m.duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+// inMaintenance returns the name of the maintenance window active at t, either configured or
+// started ad hoc with "lhmon4 ack --silence"
+func inMaintenance(t time.Time) (string, bool) {
+	for _, window := range maintenanceWindows {
+		if window.active(t) {
+			return window.Name, true
+		}
+	}
+
+	// A silence may have been started by "lhmon4 ack --silence" since the state was read
+	state, err := freshState()
+	if err == nil && t.Before(state.MaintenanceUntil) {
+		return "ad hoc", true
+	}
+	return "", false
+}
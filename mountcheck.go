@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// expectedDiskFilesystems are the filesystems Longhorn supports for filesystem-type disks
+var expectedDiskFilesystems = []string{"ext4", "xfs"}
+
+// MountIssue stores a filesystem or mount problem of a Longhorn disk
+type MountIssue struct {
+	NodeName   string
+	DiskName   string
+	Path       string
+	Filesystem string
+	Mountpoint string
+	Issue      string
+}
+
+// checkDiskMounts cross-checks each disk's path against the filesystem reported by Longhorn and,
+// when the hardware integration is enabled, the mounts reported by node-exporter
+func checkDiskMounts(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, hardware *hardwareScraper) ([]MountIssue, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	var issues []MountIssue
+	for _, node := range nodes.Items {
+		nodeName := node.GetName()
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		diskStatusMap, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus")
+
+		var samples []promSample
+		if hardware != nil && len(disksMap) > 0 {
+			samples, _ = hardware.scrape(nodeName, hardware.nodeExporterPort)
+		}
+
+		for diskName, d := range disksMap {
+			diskSpec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			// Block-type disks are used raw by the V2 data engine and carry no filesystem
+			diskType, _ := diskSpec["diskType"].(string)
+			if diskType == "block" {
+				continue
+			}
+
+			path, _ := diskSpec["path"].(string)
+			filesystem, _, _ := unstructured.NestedString(diskStatusMap, diskName, "filesystemType")
+
+			newIssue := func(mountpoint, issue string) {
+				issues = append(issues, MountIssue{
+					NodeName:   nodeName,
+					DiskName:   diskName,
+					Path:       path,
+					Filesystem: filesystem,
+					Mountpoint: mountpoint,
+					Issue:      issue,
+				})
+			}
+
+			if filesystem != "" && !contains(expectedDiskFilesystems, filesystem) {
+				newIssue("", fmt.Sprintf("Longhorn reports unexpected filesystem %s", filesystem))
+			}
+
+			// Read-only remounts surface as failed disk conditions
+			conditions, _, _ := unstructured.NestedSlice(diskStatusMap, diskName, "conditions")
+			for _, c := range conditions {
+				condition, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				condType, _ := condition["type"].(string)
+				status, _ := condition["status"].(string)
+				message, _ := condition["message"].(string)
+				lower := strings.ToLower(message)
+				if status == "False" && (strings.Contains(lower, "read-only") || strings.Contains(lower, "readonly")) {
+					newIssue("", fmt.Sprintf("%s: %s", condType, message))
+				}
+			}
+
+			mount := mountForPath(samples, path)
+			if mount == nil {
+				continue
+			}
+			mountpoint := mount.Labels["mountpoint"]
+			mountFilesystem := mount.Labels["fstype"]
+
+			if mountpoint == "/" {
+				newIssue(mountpoint, "Disk path is on the root filesystem, the disk may not be mounted")
+			}
+			if !contains(expectedDiskFilesystems, mountFilesystem) {
+				newIssue(mountpoint, fmt.Sprintf("Mounted filesystem is %s, expected %s", mountFilesystem, strings.Join(expectedDiskFilesystems, " or ")))
+			} else if filesystem != "" && filesystem != mountFilesystem {
+				newIssue(mountpoint, fmt.Sprintf("Longhorn reports %s but %s is mounted, the disk was remounted or replaced", filesystem, mountFilesystem))
+			}
+			if mountReadOnly(samples, mount) {
+				newIssue(mountpoint, "Filesystem is mounted read-only, replicas on this disk cannot be written or scheduled")
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].NodeName == issues[j].NodeName {
+			return issues[i].DiskName < issues[j].DiskName
+		}
+		return issues[i].NodeName < issues[j].NodeName
+	})
+
+	return issues, nil
+}
+
+// mountForPath returns the node-exporter filesystem sample of the longest mountpoint containing the path
+func mountForPath(samples []promSample, diskPath string) *promSample {
+	var best *promSample
+	for i, s := range samples {
+		if s.Name != "node_filesystem_size_bytes" {
+			continue
+		}
+		mountpoint := s.Labels["mountpoint"]
+		if mountpoint == "" {
+			continue
+		}
+		if diskPath != mountpoint && !strings.HasPrefix(diskPath, strings.TrimSuffix(mountpoint, "/")+"/") {
+			continue
+		}
+		if best == nil || len(mountpoint) > len(best.Labels["mountpoint"]) {
+			best = &samples[i]
+		}
+	}
+	return best
+}
+
+// mountReadOnly returns true if node-exporter reports the mount as read-only
+func mountReadOnly(samples []promSample, mount *promSample) bool {
+	for _, s := range samples {
+		if s.Name == "node_filesystem_readonly" &&
+			s.Labels["mountpoint"] == mount.Labels["mountpoint"] &&
+			s.Labels["device"] == mount.Labels["device"] {
+			return s.Value == 1
+		}
+	}
+	return false
+}
+
+// printMountCheck prints filesystem and mount problems of Longhorn disks
+func printMountCheck(dynClient dynamic.Interface, namespace string, nodesGVR schema.GroupVersionResource, hardware *hardwareScraper) {
+	issues, err := checkDiskMounts(dynClient, namespace, nodesGVR, hardware)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "FILESYSTEMS AND MOUNTS",
+		Description: "Disk filesystems and read-only mounts",
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISK\tPATH\tFILESYSTEM\tMOUNTPOINT\tISSUE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISK\tPATH\tFILESYSTEM\tMOUNTPOINT\tISSUE")
+	}
+
+	fmt.Fprintln(w, "────\t────\t────\t──────────\t──────────\t─────")
+
+	for _, issue := range issues {
+		filesystem := issue.Filesystem
+		if filesystem == "" {
+			filesystem = "-"
+		}
+		mountpoint := issue.Mountpoint
+		if mountpoint == "" {
+			mountpoint = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			issue.NodeName,
+			issue.DiskName,
+			issue.Path,
+			filesystem,
+			mountpoint,
+			colorize(withRunbook(issue.Issue, "mount"), Red),
+		)
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No filesystem or mount issues found")
+	}
+
+	w.Flush()
+
+	if hardware == nil {
+		fmt.Println("Run with --node-exporter to verify the mounts on the nodes themselves")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// newBadgeCycles is the number of watch refreshes a new resource keeps its NEW badge
+const newBadgeCycles = 3
+
+// newResources tracks resources appearing between watch refreshes, nil outside watch mode
+var newResources *newResourceTracker
+
+// newResourceTracker remembers the resources seen in previous refreshes
+type newResourceTracker struct {
+	seen     map[string]bool
+	observed map[string]bool // Resources seen during the current refresh
+	badges   map[string]int  // Remaining refreshes showing the NEW badge
+	primed   bool            // False until the first refresh completed, everything is new then
+}
+
+// newNewResourceTracker creates an empty tracker
+func newNewResourceTracker() *newResourceTracker {
+	return &newResourceTracker{
+		seen:     make(map[string]bool),
+		observed: make(map[string]bool),
+		badges:   make(map[string]int),
+	}
+}
+
+// newBadge records a resource and returns the NEW badge to append to its name, if any
+func newBadge(kind, name string) string {
+	t := newResources
+	if t == nil {
+		return ""
+	}
+
+	key := kind + "/" + name
+	t.observed[key] = true
+	if !t.seen[key] {
+		t.seen[key] = true
+		if t.primed {
+			t.badges[key] = newBadgeCycles
+		}
+	}
+
+	if t.badges[key] > 0 {
+		return " " + colorize("NEW", Bold+Cyan)
+	}
+	return ""
+}
+
+// advance ends a refresh, ageing the badges and forgetting resources that disappeared
+func (t *newResourceTracker) advance() {
+	if t == nil {
+		return
+	}
+
+	for key := range t.seen {
+		if !t.observed[key] {
+			delete(t.seen, key)
+			delete(t.badges, key)
+		}
+	}
+	for key := range t.badges {
+		t.badges[key]--
+		if t.badges[key] <= 0 {
+			delete(t.badges, key)
+		}
+	}
+
+	t.observed = make(map[string]bool)
+	t.primed = true
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Longhorn settings limiting the instances and rebuilds on a node, with their Longhorn defaults
+const (
+	guaranteedCPUSetting          = "guaranteed-instance-manager-cpu"
+	rebuildLimitSetting           = "concurrent-replica-rebuild-per-node-limit"
+	defaultGuaranteedCPUPercent   = 12
+	defaultRebuildLimit           = 5
+	instanceMilliCPU              = 100 // CPU Longhorn recommends guaranteeing per engine or replica
+	instanceCapacityWarnThreshold = 80  // Percentage of the instance capacity flagged as approaching it
+)
+
+// NodeInstanceInfo stores the engine, replica and rebuild load of a node against its limits
+type NodeInstanceInfo struct {
+	NodeName        string
+	AttachedVolumes int
+	Engines         int
+	Replicas        int
+	Rebuilding      int
+	InstanceLimit   int // Instances the guaranteed instance manager CPU covers, 0 when unknown
+	RebuildLimit    int // 0 means unlimited
+}
+
+// Warning returns why the node is approaching its limits, or an empty string
+func (n NodeInstanceInfo) Warning() string {
+	instances := n.Engines + n.Replicas
+	switch {
+	case n.InstanceLimit > 0 && instances >= n.InstanceLimit:
+		return "instance manager CPU exhausted, attaches may fail"
+	case n.InstanceLimit > 0 && 100*instances >= instanceCapacityWarnThreshold*n.InstanceLimit:
+		return "approaching instance manager capacity"
+	case n.RebuildLimit > 0 && n.Rebuilding >= n.RebuildLimit:
+		return "concurrent rebuild limit reached, further rebuilds are queued"
+	}
+	return ""
+}
+
+// intSetting returns a numeric Longhorn setting, or the default when it is unset or invalid
+func intSetting(dynClient dynamic.Interface, namespace, name string, defaultValue int) (int, error) {
+	value, err := getLonghornSetting(dynClient, namespace, name)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return n, nil
+	}
+	return defaultValue, nil
+}
+
+// getNodeInstances counts the attached volumes, engines, replicas and rebuilds on every node
+func getNodeInstances(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, nodesGVR, volumesGVR schema.GroupVersionResource) ([]NodeInstanceInfo, error) {
+	nodes, err := listAll(dynClient, nodesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	engines, err := listAll(dynClient, longhornGVR(longhornEngines), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn engines: %v", err)
+	}
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	guaranteedCPU, err := intSetting(dynClient, namespace, guaranteedCPUSetting, defaultGuaranteedCPUPercent)
+	if err != nil {
+		return nil, err
+	}
+	rebuildLimit, err := intSetting(dynClient, namespace, rebuildLimitSetting, defaultRebuildLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// The guaranteed CPU is a percentage of the node's allocatable CPU
+	allocatableCPU := make(map[string]int64)
+	k8sNodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err == nil {
+		for _, node := range k8sNodes.Items {
+			allocatableCPU[node.Name] = node.Status.Allocatable.Cpu().MilliValue()
+		}
+	}
+
+	infos := make(map[string]*NodeInstanceInfo)
+	for _, node := range nodes.Items {
+		infos[node.GetName()] = &NodeInstanceInfo{
+			NodeName:      node.GetName(),
+			InstanceLimit: int(allocatableCPU[node.GetName()] * int64(guaranteedCPU) / 100 / instanceMilliCPU),
+			RebuildLimit:  rebuildLimit,
+		}
+	}
+
+	for _, volume := range volumes.Items {
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		nodeID, _, _ := unstructured.NestedString(volume.Object, "status", "currentNodeID")
+		if info, found := infos[nodeID]; found && state == "attached" {
+			info.AttachedVolumes++
+		}
+	}
+
+	// Replicas in write-only mode are being rebuilt
+	replicaModes := make(map[string]string)
+	for _, engine := range engines.Items {
+		nodeID, _, _ := unstructured.NestedString(engine.Object, "spec", "nodeID")
+		state, _, _ := unstructured.NestedString(engine.Object, "status", "currentState")
+		if info, found := infos[nodeID]; found && state == "running" {
+			info.Engines++
+		}
+		modes, _, _ := unstructured.NestedStringMap(engine.Object, "status", "replicaModeMap")
+		for name, mode := range modes {
+			replicaModes[name] = mode
+		}
+	}
+
+	for _, replica := range replicas.Items {
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		info, found := infos[nodeID]
+		if !found || state != "running" {
+			continue
+		}
+		info.Replicas++
+		if replicaModes[replica.GetName()] == "WO" {
+			info.Rebuilding++
+		}
+	}
+
+	var result []NodeInstanceInfo
+	for _, info := range infos {
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NodeName < result[j].NodeName
+	})
+
+	return result, nil
+}
+
+// printNodeInstances prints the attached volumes and instances per node against the instance manager limits
+func printNodeInstances(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, nodesGVR, volumesGVR schema.GroupVersionResource) {
+	infos, err := getNodeInstances(dynClient, clientset, namespace, nodesGVR, volumesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "NODE INSTANCES",
+		Description: "Attached volumes, engines and replicas per node against instance manager and rebuild limits",
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tATTACHED\tENGINES\tREPLICAS\tINSTANCES\tREBUILDING\tWARNING%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tATTACHED\tENGINES\tREPLICAS\tINSTANCES\tREBUILDING\tWARNING")
+	}
+
+	fmt.Fprintln(w, "────\t────────\t───────\t────────\t─────────\t──────────\t───────")
+
+	for _, info := range infos {
+		instances := strconv.Itoa(info.Engines + info.Replicas)
+		if info.InstanceLimit > 0 {
+			instances += "/" + strconv.Itoa(info.InstanceLimit)
+		}
+		rebuilding := strconv.Itoa(info.Rebuilding)
+		if info.RebuildLimit > 0 {
+			rebuilding += "/" + strconv.Itoa(info.RebuildLimit)
+		}
+
+		warning := info.Warning()
+		warningColor := Yellow
+		if info.InstanceLimit > 0 && info.Engines+info.Replicas >= info.InstanceLimit {
+			warningColor = Red
+		}
+		if warning == "" {
+			warning = "-"
+			warningColor = Green
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			info.NodeName,
+			info.AttachedVolumes,
+			info.Engines,
+			info.Replicas,
+			instances,
+			rebuilding,
+			colorize(warning, warningColor),
+		)
+	}
+
+	if len(infos) == 0 {
+		fmt.Fprintln(w, "No Longhorn nodes found")
+	}
+
+	w.Flush()
+}
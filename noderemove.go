@@ -0,0 +1,332 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// replicaSoftAntiAffinitySetting allows Longhorn to place two replicas of a volume on the same node
+const replicaSoftAntiAffinitySetting = "replica-soft-anti-affinity"
+
+// NodeRemovalCheck combines the drain check, replica placement and capacity what-if for a node
+type NodeRemovalCheck struct {
+	NodeName          string
+	AllowScheduling   bool
+	EvictionRequested bool
+	Attached          []string // Volumes attached to the node, detached by a drain
+	Volumes           []NodeVolumeImpact
+	MoveSize          ByteSize // Replica data that has to be rebuilt on other nodes
+	SpareCapacity     ByteSize // Schedulable capacity left on the other nodes
+}
+
+// NodeVolumeImpact describes what removing a node means for one volume with replicas on it
+type NodeVolumeImpact struct {
+	VolumeName   string
+	Size         ByteSize
+	Replicas     int // Replicas on the node
+	OtherHealthy int // Healthy replicas on other nodes
+	Candidates   int // Other nodes able to take a replica
+	Problem      string
+}
+
+// Blockers returns the reasons the node cannot be drained even after eviction
+func (c NodeRemovalCheck) Blockers() []string {
+	var blockers []string
+	for _, volume := range c.Volumes {
+		if volume.Candidates < volume.Replicas {
+			blockers = append(blockers, fmt.Sprintf("volume %s: %s", volume.VolumeName, volume.Problem))
+		}
+	}
+	if c.MoveSize > c.SpareCapacity {
+		blockers = append(blockers, fmt.Sprintf("other nodes can take %s but %s has to move", c.SpareCapacity, c.MoveSize))
+	}
+	return blockers
+}
+
+// Safe reports whether nothing depends on the node anymore
+func (c NodeRemovalCheck) Safe() bool {
+	return len(c.Volumes) == 0 && len(c.Attached) == 0
+}
+
+// runNode implements the "lhmon4 node" subcommand
+func runNode(args []string) {
+	if len(args) == 0 || args[0] != "can-remove" {
+		fmt.Printf("Usage: %s node can-remove <node> [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("node can-remove", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args[1:])
+
+	useColors = !*nocolor && ansiSupported
+
+	if fs.NArg() != 1 {
+		fmt.Printf("Usage: %s node can-remove <node> [flags]\n", commandName)
+		os.Exit(2)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	namespace := *clientOpts.namespace
+	check, err := checkNodeRemoval(dynClient, namespace, fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printNodeRemovalCheck(check, namespace)
+	if !check.Safe() {
+		os.Exit(exitRemovalUnsafe)
+	}
+}
+
+// checkNodeRemoval analyses the volumes depending on a node and whether the rest of the cluster can absorb them
+func checkNodeRemoval(dynClient dynamic.Interface, namespace, nodeName string) (NodeRemovalCheck, error) {
+	check := NodeRemovalCheck{NodeName: nodeName}
+
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return check, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	found := false
+	for _, node := range nodes.Items {
+		if node.GetName() != nodeName {
+			continue
+		}
+		found = true
+		allowScheduling, ok, _ := unstructured.NestedBool(node.Object, "spec", "allowScheduling")
+		check.AllowScheduling = !ok || allowScheduling
+		check.EvictionRequested, _, _ = unstructured.NestedBool(node.Object, "spec", "evictionRequested")
+	}
+	if !found {
+		return check, fmt.Errorf("Longhorn node %s not found", nodeName)
+	}
+
+	capacities, err := getSchedulingCapacity(dynClient, namespace, longhornGVR(longhornNodes))
+	if err != nil {
+		return check, err
+	}
+	softAntiAffinity, err := getLonghornSetting(dynClient, namespace, replicaSoftAntiAffinitySetting)
+	if err != nil {
+		return check, err
+	}
+
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return check, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	volumeSizes := make(map[string]ByteSize)
+	for _, volume := range volumes.Items {
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseFloat(sizeStr, 64)
+		volumeSizes[volume.GetName()] = ByteSize(size)
+
+		if currentNode, _, _ := unstructured.NestedString(volume.Object, "status", "currentNodeID"); currentNode == nodeName {
+			check.Attached = append(check.Attached, volume.GetName())
+		}
+	}
+	sort.Strings(check.Attached)
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return check, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	// Volume -> nodes hosting one of its replicas, replicas on the node and healthy replicas elsewhere
+	volumeNodes := make(map[string]map[string]bool)
+	onNode := make(map[string]int)
+	healthyElsewhere := make(map[string]int)
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		if volumeNodes[volumeName] == nil {
+			volumeNodes[volumeName] = make(map[string]bool)
+		}
+		volumeNodes[volumeName][nodeID] = true
+
+		if nodeID == nodeName {
+			onNode[volumeName]++
+			continue
+		}
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if state == "running" && failedAt == "" {
+			healthyElsewhere[volumeName]++
+		}
+	}
+
+	for _, capacity := range capacities {
+		if capacity.NodeName != nodeName && capacity.Unschedulable == "" {
+			check.SpareCapacity += capacity.Remaining
+		}
+	}
+
+	for volumeName, count := range onNode {
+		impact := NodeVolumeImpact{
+			VolumeName:   volumeName,
+			Size:         volumeSizes[volumeName],
+			Replicas:     count,
+			OtherHealthy: healthyElsewhere[volumeName],
+		}
+		check.MoveSize += impact.Size * ByteSize(count)
+
+		// A candidate is a schedulable node with a disk large enough that, unless soft anti-affinity
+		// is enabled, does not host a replica of the volume already
+		for _, capacity := range capacities {
+			if capacity.NodeName == nodeName || capacity.Unschedulable != "" || capacity.LargestVolume < impact.Size {
+				continue
+			}
+			if softAntiAffinity != "true" && volumeNodes[volumeName][capacity.NodeName] {
+				continue
+			}
+			impact.Candidates++
+		}
+
+		switch {
+		case impact.Candidates < impact.Replicas:
+			impact.Problem = fmt.Sprintf("only %d node(s) can take its %d replica(s)", impact.Candidates, impact.Replicas)
+		case impact.OtherHealthy == 0:
+			impact.Problem = "only healthy copy is on this node, evict before draining"
+		}
+		check.Volumes = append(check.Volumes, impact)
+	}
+
+	sort.Slice(check.Volumes, func(i, j int) bool {
+		return check.Volumes[i].VolumeName < check.Volumes[j].VolumeName
+	})
+
+	return check, nil
+}
+
+// printNodeRemovalCheck prints the dependent volumes, the verdict and the decommissioning procedure
+func printNodeRemovalCheck(check NodeRemovalCheck, namespace string) {
+	printSectionHeader(Section{
+		Title:       "NODE REMOVAL CHECK",
+		Description: fmt.Sprintf("Whether node %s can be decommissioned safely", check.NodeName),
+		Color:       Cyan,
+	})
+
+	scheduling := colorize("enabled", Yellow)
+	if !check.AllowScheduling {
+		scheduling = colorize("disabled", Green)
+	}
+	eviction := colorize("not requested", Yellow)
+	if check.EvictionRequested {
+		eviction = colorize("requested", Green)
+		if len(check.Volumes) > 0 {
+			eviction = colorize("in progress", Yellow)
+		}
+	}
+	fmt.Printf("Scheduling:     %s\n", scheduling)
+	fmt.Printf("Eviction:       %s\n", eviction)
+	fmt.Printf("Attached:       %d volume(s)\n", len(check.Attached))
+	fmt.Printf("To move:        %s\n", check.MoveSize)
+	fmt.Printf("Spare capacity: %s\n\n", check.SpareCapacity)
+
+	if len(check.Volumes) > 0 {
+		w := newTableWriter()
+
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tREPLICAS HERE\tHEALTHY ELSEWHERE\tCANDIDATE NODES\tPROBLEM%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "VOLUME\tSIZE\tREPLICAS HERE\tHEALTHY ELSEWHERE\tCANDIDATE NODES\tPROBLEM")
+		}
+
+		fmt.Fprintln(w, "──────\t────\t─────────────\t─────────────────\t───────────────\t───────")
+
+		for _, volume := range check.Volumes {
+			problemColor := Yellow
+			if volume.Candidates < volume.Replicas {
+				problemColor = Red
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n",
+				colorize(volume.VolumeName, Blue),
+				volume.Size,
+				volume.Replicas,
+				volume.OtherHealthy,
+				volume.Candidates,
+				colorize(volume.Problem, problemColor),
+			)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	if len(check.Attached) > 0 {
+		fmt.Println("Volumes attached to this node, their workloads move when the node is drained:")
+		for _, volume := range check.Attached {
+			fmt.Printf("  %s\n", colorize(volume, Blue))
+		}
+		fmt.Println()
+	}
+
+	blockers := check.Blockers()
+	switch {
+	case check.Safe():
+		fmt.Println(colorize("SAFE: no volumes depend on this node", Green+Bold))
+	case len(blockers) == 0:
+		fmt.Println(colorize("SAFE AFTER EVICTION: the remaining nodes can absorb every replica", Yellow+Bold))
+	default:
+		fmt.Println(colorize("BLOCKED: the remaining nodes cannot absorb the replicas on this node", Red+Bold))
+		for _, blocker := range blockers {
+			fmt.Printf("  - %s\n", blocker)
+		}
+		fmt.Println("Add nodes or disks, or lower the replica count of the listed volumes, before decommissioning.")
+	}
+
+	// Print the decommissioning procedure, marking the steps that are already done
+	fmt.Println("\nSteps to decommission the node:")
+	patch := fmt.Sprintf("kubectl -n %s patch nodes.longhorn.io %s --type merge -p", namespace, check.NodeName)
+	steps := []struct {
+		text    string
+		command string
+		done    bool
+	}{
+		{"Disable replica scheduling on the node",
+			fmt.Sprintf(`%s '{"spec":{"allowScheduling":false}}'`, patch),
+			!check.AllowScheduling},
+		{"Request eviction of its replicas",
+			fmt.Sprintf(`%s '{"spec":{"evictionRequested":true}}'`, patch),
+			check.EvictionRequested},
+		{"Wait until all replicas have been rebuilt elsewhere",
+			fmt.Sprintf("%s node can-remove %s -namespace %s", commandName, check.NodeName, namespace),
+			len(check.Volumes) == 0},
+		{"Drain the Kubernetes node",
+			fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data", check.NodeName),
+			check.Safe()},
+		{"Remove the node from Longhorn",
+			fmt.Sprintf("kubectl -n %s delete nodes.longhorn.io %s", namespace, check.NodeName),
+			false},
+		{"Remove the node from Kubernetes", fmt.Sprintf("kubectl delete node %s", check.NodeName), false},
+	}
+
+	for i, step := range steps {
+		marker := "[ ]"
+		if step.done {
+			marker = colorize("[x]", Green)
+		}
+		fmt.Printf("  %d. %s %s\n", i+1, marker, step.text)
+		if !step.done {
+			if useColors {
+				fmt.Printf("     %s%s%s\n", Bold+Cyan, step.command, Reset)
+			} else {
+				fmt.Printf("     %s\n", step.command)
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// notification is a newly detected or resolved issue sent to the configured notifiers
+type notification struct {
+	Resolved   bool
+	ID         string
+	Object     string // kind/name of the object with the issue
+	Issue      string
+	Severity   severity
+	At         time.Time
+	Runbook    string // Runbook URL configured for the issue category, empty when none
+	Collection string // Collection ID of the run that detected the transition
+}
+
+// summary renders the notification as a single line
+func (n notification) summary() string {
+	event := "ISSUE"
+	if n.Resolved {
+		event = "RESOLVED"
+	}
+	issue := n.Issue
+	if n.Runbook != "" {
+		issue += " (runbook: " + n.Runbook + ")"
+	}
+	id := n.ID
+	if n.Collection != "" {
+		id += ", collection " + n.Collection
+	}
+	return fmt.Sprintf("[%s] %s %s: %s (%s)", strings.ToUpper(n.Severity.String()), event, n.Object, issue, id)
+}
+
+// payload returns the JSON form of the notification
+func (n notification) payload() notificationPayload {
+	event := "issue"
+	if n.Resolved {
+		event = "resolved"
+	}
+	return notificationPayload{
+		Event:      event,
+		ID:         n.ID,
+		Object:     n.Object,
+		Issue:      n.Issue,
+		Severity:   n.Severity.String(),
+		At:         n.At,
+		Runbook:    n.Runbook,
+		Collection: n.Collection,
+	}
+}
+
+// Notifier delivers notifications to an external system. Backends are created from a notifiers
+// entry of the configuration file by the factory registered for its type.
+type Notifier interface {
+	Notify(n notification) error
+}
+
+// notifierConfig is a notifiers entry of the configuration file. Each backend uses the fields
+// relevant to it.
+type notifierConfig struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`                  // slack, webhook or email
+	MinSeverity string            `json:"minSeverity,omitempty"` // Only notify issues of this severity or worse
+	URL         string            `json:"url,omitempty"`         // Slack incoming webhook or webhook endpoint
+	Headers     map[string]string `json:"headers,omitempty"`     // Extra webhook request headers
+	SMTPServer  string            `json:"smtpServer,omitempty"`  // host:port of the mail server
+	From        string            `json:"from,omitempty"`
+	To          []string          `json:"to,omitempty"`
+	Username    string            `json:"username,omitempty"`    // SMTP login, no authentication when empty
+	PasswordEnv string            `json:"passwordEnv,omitempty"` // Environment variable holding the SMTP password
+}
+
+// notifierFactory creates a notifier from its configuration
+type notifierFactory func(config notifierConfig) (Notifier, error)
+
+// notifierTypes are the notifier backends selectable with the type of a notifiers entry
+var notifierTypes = map[string]notifierFactory{
+	"slack":   newSlackNotifier,
+	"webhook": newWebhookNotifier,
+	"email":   newEmailNotifier,
+}
+
+// configuredNotifier is a notifier created from the configuration file
+type configuredNotifier struct {
+	name        string
+	minSeverity severity
+	notifier    Notifier
+}
+
+// notifiers are the notifiers from the configuration file and the hooks from the command line
+var notifiers []configuredNotifier
+
+// setNotifiers creates and installs the configured notifiers
+func setNotifiers(configs []notifierConfig) error {
+	var created []configuredNotifier
+	for i, config := range configs {
+		factory, found := notifierTypes[config.Type]
+		if !found {
+			return fmt.Errorf("notifier %d (%s): unknown type %q", i+1, config.Name, config.Type)
+		}
+		minSeverity, err := parseSeverity(config.MinSeverity)
+		if err != nil {
+			return fmt.Errorf("notifier %d (%s): %v", i+1, config.Name, err)
+		}
+		notifier, err := factory(config)
+		if err != nil {
+			return fmt.Errorf("notifier %d (%s): %v", i+1, config.Name, err)
+		}
+
+		name := config.Name
+		if name == "" {
+			name = config.Type
+		}
+		created = append(created, configuredNotifier{name: name, minSeverity: minSeverity, notifier: notifier})
+	}
+	notifiers = created
+	return nil
+}
+
+// notify sends a notification to every notifier whose minimum severity it meets. Delivery
+// failures are printed and do not stop the remaining notifiers.
+func notify(n notification) {
+	for _, configured := range notifiers {
+		if n.Severity < configured.minSeverity {
+			continue
+		}
+		if err := configured.notifier.Notify(n); err != nil {
+			fmt.Printf("Error: notifier %s: %v\n", configured.name, err)
+		}
+	}
+}
+
+// notifyClient bounds the time a notification may hold up the follow loop
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts a JSON body and treats any non-2xx response as an error
+func postJSON(url string, body interface{}, headers map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// slackNotifier posts to a Slack incoming webhook
+type slackNotifier struct {
+	url string
+}
+
+func newSlackNotifier(config notifierConfig) (Notifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("slack notifier needs the url of an incoming webhook")
+	}
+	return slackNotifier{url: config.URL}, nil
+}
+
+// Notify posts the notification summary as a message
+func (s slackNotifier) Notify(n notification) error {
+	return postJSON(s.url, map[string]string{"text": n.summary()}, nil)
+}
+
+// webhookNotifier posts each notification as a JSON object to a generic endpoint
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+}
+
+// notificationPayload is the JSON form of a notification posted by the webhook notifier and
+// passed to hooks on stdin
+type notificationPayload struct {
+	Event      string    `json:"event"` // issue or resolved
+	ID         string    `json:"id"`
+	Object     string    `json:"object"`
+	Issue      string    `json:"issue"`
+	Severity   string    `json:"severity"`
+	At         time.Time `json:"at"`
+	Runbook    string    `json:"runbook,omitempty"`
+	Collection string    `json:"collection"` // Matches the notification with the run's other output
+}
+
+func newWebhookNotifier(config notifierConfig) (Notifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook notifier needs a url")
+	}
+	return webhookNotifier{url: config.URL, headers: config.Headers}, nil
+}
+
+// Notify posts the notification payload
+func (w webhookNotifier) Notify(n notification) error {
+	return postJSON(w.url, n.payload(), w.headers)
+}
+
+// emailNotifier sends one mail per notification over SMTP
+type emailNotifier struct {
+	server string
+	from   string
+	to     []string
+	auth   smtp.Auth
+}
+
+func newEmailNotifier(config notifierConfig) (Notifier, error) {
+	if config.SMTPServer == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email notifier needs smtpServer, from and to")
+	}
+	notifier := emailNotifier{server: config.SMTPServer, from: config.From, to: config.To}
+	if config.Username != "" {
+		host := strings.Split(config.SMTPServer, ":")[0]
+		notifier.auth = smtp.PlainAuth("", config.Username, os.Getenv(config.PasswordEnv), host)
+	}
+	return notifier, nil
+}
+
+// Notify mails the notification with its summary as subject
+func (e emailNotifier) Notify(n notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: lhmon4: %s\r\n", n.summary())
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.At.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "\r\n")
+	fmt.Fprintf(&msg, "Object:   %s\r\nIssue:    %s\r\nSeverity: %s\r\nID:       %s\r\n", n.Object, n.Issue, n.Severity, n.ID)
+	return smtp.SendMail(e.server, e.auth, e.from, e.to, msg.Bytes())
+}
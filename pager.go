@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultPager is used when $PAGER is not set
+const defaultPager = "less -R"
+
+// pager buffers everything written to stdout and pages it when it does not fit on the screen
+type pager struct {
+	stdout *os.File // The real stdout, restored by finish
+	writer *os.File
+	done   chan struct{}
+	output bytes.Buffer
+}
+
+// startPager redirects stdout into a buffer when it is a terminal and paging is enabled.
+// It returns nil when output goes straight to stdout.
+func startPager(disabled bool) *pager {
+	if disabled || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil
+	}
+
+	p := &pager{stdout: os.Stdout, writer: writer, done: make(chan struct{})}
+	go func() {
+		io.Copy(&p.output, reader)
+		reader.Close()
+		close(p.done)
+	}()
+	os.Stdout = writer
+
+	return p
+}
+
+// finish restores stdout and shows the buffered output, through $PAGER if it exceeds the terminal height
+func (p *pager) finish() {
+	if p == nil {
+		return
+	}
+
+	p.writer.Close()
+	<-p.done
+	os.Stdout = p.stdout
+
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || bytes.Count(p.output.Bytes(), []byte("\n")) < height {
+		os.Stdout.Write(p.output.Bytes())
+		return
+	}
+
+	command := strings.Fields(os.Getenv("PAGER"))
+	if len(command) == 0 {
+		command = strings.Fields(defaultPager)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = &p.output
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Let less pass the color escape sequences through
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(cmd.Env, "LESS=R")
+	}
+
+	if err := cmd.Run(); err != nil {
+		// Pager not available, print the output directly
+		if _, isExit := err.(*exec.ExitError); !isExit {
+			os.Stdout.Write(p.output.Bytes())
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page layout of the PDF report, in points
+const (
+	pdfPageWidth    = 595.0
+	pdfPageHeight   = 842.0
+	pdfMargin       = 40.0
+	pdfFooterHeight = 20.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+	pdfRowHeight    = 13.0
+)
+
+// Fonts of the PDF report, the standard Type 1 fonts every PDF reader provides
+const (
+	pdfRegular = "F1" // Helvetica
+	pdfBold    = "F2" // Helvetica-Bold
+)
+
+// pdfColor is an RGB color with components between 0 and 1
+type pdfColor struct{ r, g, b float64 }
+
+// Colors of the PDF report, matching the thresholds of the terminal tables
+var (
+	pdfGreen  = pdfColor{0.18, 0.62, 0.30}
+	pdfYellow = pdfColor{0.90, 0.66, 0.10}
+	pdfRed    = pdfColor{0.80, 0.18, 0.18}
+	pdfGray   = pdfColor{0.90, 0.90, 0.90}
+	pdfBlack  = pdfColor{0, 0, 0}
+)
+
+// pdfColumn is a column of a PDF table, Width is a fraction of the content width
+type pdfColumn struct {
+	Header string
+	Width  float64
+	Right  bool // Right aligned, for numbers
+}
+
+// pdfWriter lays out headings, paragraphs, tables and bar charts on A4 pages and writes them as a
+// PDF without dependencies. Text is limited to the WinAnsi characters of the standard fonts.
+type pdfWriter struct {
+	pages []*bytes.Buffer // Content stream of every page
+	y     float64         // Top of the next element on the current page
+}
+
+// newPDFWriter returns a writer with an empty first page
+func newPDFWriter() *pdfWriter {
+	p := &pdfWriter{}
+	p.addPage()
+	return p
+}
+
+// addPage starts a new page
+func (p *pdfWriter) addPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// reserve starts a new page unless the given height fits above the footer of the current one
+func (p *pdfWriter) reserve(height float64) {
+	if p.y-height < pdfMargin+pdfFooterHeight {
+		p.addPage()
+	}
+}
+
+// pdfEscaper escapes the characters with a meaning in PDF strings
+var pdfEscaper = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+
+// pdfText converts text to the WinAnsi subset of the standard fonts, replacing other characters
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 32 && r < 127 {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return pdfEscaper.Replace(b.String())
+}
+
+// pdfTextWidth estimates the width of text in Helvetica, the average glyph is about half the size
+func pdfTextWidth(s string, size float64) float64 {
+	return float64(len(s)) * size * 0.52
+}
+
+// pdfFit shortens text to fit a width, ending it with ".." when cut
+func pdfFit(s string, width, size float64) string {
+	if pdfTextWidth(s, size) <= width {
+		return s
+	}
+	n := int(width/(size*0.52)) - 2
+	if n <= 0 {
+		return ""
+	}
+	return s[:n] + ".."
+}
+
+// text draws text with its baseline at y
+func (p *pdfWriter) text(x, y float64, font string, size float64, color pdfColor, s string) {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "BT %.3f %.3f %.3f rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		color.r, color.g, color.b, font, size, x, y, pdfText(s))
+}
+
+// rect fills a rectangle whose lower left corner is at x, y
+func (p *pdfWriter) rect(x, y, width, height float64, color pdfColor) {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		color.r, color.g, color.b, x, y, width, height)
+}
+
+// heading starts a section, on a new page when less than a few rows fit below it
+func (p *pdfWriter) heading(s string) {
+	p.reserve(24 + 4*pdfRowHeight)
+	p.y -= 16
+	p.text(pdfMargin, p.y, pdfBold, 14, pdfBlack, s)
+	p.y -= 10
+}
+
+// paragraph writes a line of regular text
+func (p *pdfWriter) paragraph(s string) {
+	p.reserve(pdfRowHeight)
+	p.y -= pdfRowHeight
+	p.text(pdfMargin, p.y+3, pdfRegular, 10, pdfBlack, pdfFit(s, pdfContentWidth, 10))
+}
+
+// space adds vertical space between elements
+func (p *pdfWriter) space(height float64) {
+	p.y -= height
+}
+
+// tableRow writes one row of a table, colors may be nil or hold a color per cell
+func (p *pdfWriter) tableRow(columns []pdfColumn, cells []string, font string, colors []pdfColor) {
+	p.y -= pdfRowHeight
+	x := pdfMargin
+	for i, column := range columns {
+		width := column.Width * pdfContentWidth
+		cell := ""
+		if i < len(cells) {
+			cell = pdfFit(cells[i], width-6, 9)
+		}
+		color := pdfBlack
+		if i < len(colors) && colors[i] != (pdfColor{}) {
+			color = colors[i]
+		}
+		cx := x + 2
+		if column.Right {
+			cx = x + width - 4 - pdfTextWidth(cell, 9)
+		}
+		p.text(cx, p.y+3.5, font, 9, color, cell)
+		x += width
+	}
+}
+
+// table writes a table, repeating the header on every page it continues on. cellColors may be nil.
+func (p *pdfWriter) table(columns []pdfColumn, rows [][]string, cellColors [][]pdfColor) {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	printHeader := func() {
+		p.rect(pdfMargin, p.y-pdfRowHeight, pdfContentWidth, pdfRowHeight, pdfGray)
+		p.tableRow(columns, header, pdfBold, nil)
+	}
+
+	p.reserve(2 * pdfRowHeight)
+	printHeader()
+	for i, row := range rows {
+		if p.y-pdfRowHeight < pdfMargin+pdfFooterHeight {
+			p.addPage()
+			printHeader()
+		}
+		var colors []pdfColor
+		if cellColors != nil {
+			colors = cellColors[i]
+		}
+		p.tableRow(columns, row, pdfRegular, colors)
+	}
+}
+
+// barChart draws a horizontal bar per label, fractions between 0 and 1 fill the bar
+func (p *pdfWriter) barChart(labels []string, fractions []float64, captions []string, colors []pdfColor) {
+	const labelWidth, captionWidth, barHeight = 110.0, 150.0, 10.0
+	barWidth := pdfContentWidth - labelWidth - captionWidth
+	for i, label := range labels {
+		p.reserve(pdfRowHeight + 3)
+		p.y -= pdfRowHeight + 3
+		p.text(pdfMargin, p.y+2, pdfRegular, 9, pdfBlack, pdfFit(label, labelWidth-6, 9))
+		p.rect(pdfMargin+labelWidth, p.y, barWidth, barHeight, pdfGray)
+		fraction := fractions[i]
+		if fraction > 1 {
+			fraction = 1
+		}
+		if fraction > 0 {
+			p.rect(pdfMargin+labelWidth, p.y, barWidth*fraction, barHeight, colors[i])
+		}
+		p.text(pdfMargin+labelWidth+barWidth+6, p.y+2, pdfRegular, 9, pdfBlack, captions[i])
+	}
+}
+
+// writeTo writes the pages as a PDF document, numbering them in the footer
+func (p *pdfWriter) writeTo(out io.Writer) error {
+	for i, page := range p.pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(p.pages))
+		fmt.Fprintf(page, "BT 0.4 0.4 0.4 rg /%s 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+			pdfRegular, pdfPageWidth-pdfMargin-pdfTextWidth(footer, 8), pdfMargin/2, footer)
+	}
+
+	w := &countingWriter{w: bufio.NewWriter(out)}
+	var offsets []int64
+	object := func(body string) {
+		offsets = append(offsets, w.n)
+		fmt.Fprintf(w, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	w.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4 are the catalog, the page tree and the fonts, every page adds a page and a content stream
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range p.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pdfRegular, pdfBold, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := w.n
+	fmt.Fprintf(w, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(w, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(w, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return w.w.Flush()
+}
+
+// countingWriter tracks the byte offset of the PDF objects for the cross-reference table
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+// Write writes and counts bytes
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteString writes and counts a string
+func (c *countingWriter) WriteString(s string) (int, error) {
+	return c.Write([]byte(s))
+}
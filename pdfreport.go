@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// outputPDF writes the capacity report as a paginated PDF, -o pdf
+const outputPDF = "pdf"
+
+// pdfReportPath is the file -o pdf writes to, set by --report-pdf. Standard output when empty.
+var pdfReportPath string
+
+// pdfUsageColor returns the color of a usage percentage, with the thresholds of the disk table
+func pdfUsageColor(percent float64) pdfColor {
+	switch {
+	case percent > 80:
+		return pdfRed
+	case percent > 60:
+		return pdfYellow
+	}
+	return pdfGreen
+}
+
+// severityPDFColor returns the color of an issue severity
+func severityPDFColor(name string) pdfColor {
+	switch name {
+	case severityCritical.String():
+		return pdfRed
+	case severityWarning.String():
+		return pdfYellow
+	}
+	return pdfBlack
+}
+
+// writePDFReport writes the cluster capacity, per-node utilization, disk and volume tables and
+// the issue summary of the report as a PDF for readers without cluster access
+func writePDFReport(out io.Writer, doc reportDocument) error {
+	p := newPDFWriter()
+
+	p.y -= 10
+	p.text(pdfMargin, p.y, pdfBold, 20, pdfBlack, "Longhorn capacity report")
+	p.y -= 8
+	p.paragraph("Generated " + formatTimestamp(time.Now()))
+	if doc.Collection != "" {
+		p.paragraph("Collection " + doc.Collection)
+	}
+
+	// Cluster totals
+	var maximum, available, scheduled, provisioned, actual ByteSize
+	type nodeUsage struct{ maximum, available ByteSize }
+	nodes := make(map[string]*nodeUsage)
+	for _, disk := range doc.Disks {
+		maximum += disk.StorageMaximum
+		available += disk.StorageAvailable
+		scheduled += disk.StorageScheduled
+		usage := nodes[disk.NodeName]
+		if usage == nil {
+			usage = &nodeUsage{}
+			nodes[disk.NodeName] = usage
+		}
+		usage.maximum += disk.StorageMaximum
+		usage.available += disk.StorageAvailable
+	}
+	for _, vol := range doc.Volumes {
+		provisioned += vol.Size
+		actual += vol.ActualSize
+	}
+	usedPercent := 0.0
+	if maximum > 0 {
+		usedPercent = float64(maximum-available) / float64(maximum) * 100
+	}
+
+	p.heading("Cluster capacity")
+	p.table([]pdfColumn{{Header: "Metric", Width: 0.5}, {Header: "Value", Width: 0.5, Right: true}}, [][]string{
+		{"Nodes", fmt.Sprint(len(nodes))},
+		{"Disks", fmt.Sprint(len(doc.Disks))},
+		{"Storage maximum", maximum.String()},
+		{"Storage available", available.String()},
+		{"Storage scheduled", scheduled.String()},
+		{"Used", formatNumber(usedPercent, 1) + "%"},
+		{"Volumes", fmt.Sprint(len(doc.Volumes))},
+		{"Provisioned size", provisioned.String()},
+		{"Actual size per replica", actual.String()},
+	}, nil)
+
+	// Per-node utilization chart
+	nodeNames := make([]string, 0, len(nodes))
+	for name := range nodes {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	fractions := make([]float64, len(nodeNames))
+	captions := make([]string, len(nodeNames))
+	colors := make([]pdfColor, len(nodeNames))
+	for i, name := range nodeNames {
+		usage := nodes[name]
+		percent := 0.0
+		if usage.maximum > 0 {
+			percent = float64(usage.maximum-usage.available) / float64(usage.maximum) * 100
+		}
+		fractions[i] = percent / 100
+		captions[i] = fmt.Sprintf("%s%% of %s", formatNumber(percent, 1), usage.maximum)
+		colors[i] = pdfUsageColor(percent)
+	}
+	p.heading("Utilization per node")
+	if len(nodeNames) == 0 {
+		p.paragraph("No disks found")
+	}
+	p.barChart(nodeNames, fractions, captions, colors)
+
+	// Disk capacity
+	p.heading("Disks")
+	diskRows := make([][]string, 0, len(doc.Disks))
+	diskColors := make([][]pdfColor, 0, len(doc.Disks))
+	for _, disk := range doc.Disks {
+		diskRows = append(diskRows, []string{
+			disk.NodeName,
+			disk.DiskName,
+			disk.StorageMaximum.String(),
+			disk.StorageAvailable.String(),
+			disk.StorageScheduled.String(),
+			formatNumber(disk.PercentUsed, 1) + "%",
+		})
+		diskColors = append(diskColors, []pdfColor{5: pdfUsageColor(disk.PercentUsed)})
+	}
+	p.table([]pdfColumn{
+		{Header: "Node", Width: 0.24},
+		{Header: "Disk", Width: 0.24},
+		{Header: "Maximum", Width: 0.13, Right: true},
+		{Header: "Available", Width: 0.13, Right: true},
+		{Header: "Scheduled", Width: 0.13, Right: true},
+		{Header: "Used", Width: 0.13, Right: true},
+	}, diskRows, diskColors)
+	if len(doc.Disks) == 0 {
+		p.paragraph("No disks found")
+	}
+
+	// Volumes, largest first
+	volumes := append([]VolumeInfo(nil), doc.Volumes...)
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return volumes[i].Size > volumes[j].Size
+	})
+	p.heading("Volumes")
+	volumeRows := make([][]string, 0, len(volumes))
+	volumeColors := make([][]pdfColor, 0, len(volumes))
+	for _, vol := range volumes {
+		volumeRows = append(volumeRows, []string{
+			vol.Name,
+			vol.Size.String(),
+			vol.ActualSize.String(),
+			fmt.Sprintf("%d/%d", vol.ReplicaCount, vol.DesiredReplicas),
+			vol.State,
+			vol.Robustness,
+		})
+		robustness := pdfGreen
+		switch vol.Robustness {
+		case "degraded":
+			robustness = pdfYellow
+		case "faulted", "unknown":
+			robustness = pdfRed
+		}
+		volumeColors = append(volumeColors, []pdfColor{5: robustness})
+	}
+	p.table([]pdfColumn{
+		{Header: "Volume", Width: 0.40},
+		{Header: "Size", Width: 0.12, Right: true},
+		{Header: "Actual", Width: 0.12, Right: true},
+		{Header: "Replicas", Width: 0.10, Right: true},
+		{Header: "State", Width: 0.13},
+		{Header: "Robustness", Width: 0.13},
+	}, volumeRows, volumeColors)
+	if len(volumes) == 0 {
+		p.paragraph("No volumes found")
+	}
+
+	// Issue summary, the records are sorted worst first
+	p.heading("Issues")
+	counts := make(map[string]int)
+	issueRows := make([][]string, 0, len(doc.Issues))
+	issueColors := make([][]pdfColor, 0, len(doc.Issues))
+	for _, issue := range doc.Issues {
+		if issue.Acknowledged {
+			counts["acknowledged"]++
+			continue
+		}
+		counts[issue.Severity]++
+		issueRows = append(issueRows, []string{issue.Severity, issue.Object, issue.Issue})
+		issueColors = append(issueColors, []pdfColor{severityPDFColor(issue.Severity)})
+	}
+	p.paragraph(fmt.Sprintf("%d critical, %d warning, %d info, %d acknowledged",
+		counts[severityCritical.String()], counts[severityWarning.String()], counts[severityInfo.String()], counts["acknowledged"]))
+	p.space(6)
+	if len(issueRows) > 0 {
+		p.table([]pdfColumn{
+			{Header: "Severity", Width: 0.12},
+			{Header: "Object", Width: 0.30},
+			{Header: "Issue", Width: 0.58},
+		}, issueRows, issueColors)
+	}
+
+	return p.writeTo(out)
+}
+
+// renderPDF writes the PDF report to --report-pdf or standard output
+func renderPDF(doc reportDocument) error {
+	if pdfReportPath == "" {
+		return writePDFReport(os.Stdout, doc)
+	}
+
+	f, err := os.Create(pdfReportPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", pdfReportPath, err)
+	}
+	err = writePDFReport(f, doc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", pdfReportPath, err)
+	}
+	fmt.Printf("Wrote %s\n", pdfReportPath)
+	return nil
+}
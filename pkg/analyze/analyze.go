@@ -0,0 +1,119 @@
+// Package analyze evaluates the health checks of lhmon4 against Longhorn resources: disk and volume
+// issues and whether a volume can be deleted safely.
+package analyze
+
+import (
+	"fmt"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Severity ranks detected issues
+type Severity int
+
+const (
+	SeverityNone Severity = iota // No issue found
+	SeverityInfo
+	SeverityWarning
+	SeverityCritical
+)
+
+// DiskIssue is a problem detected on a Longhorn disk
+type DiskIssue struct {
+	DiskName string
+	Issue    string
+	Severity Severity
+}
+
+// DiskIssues returns the problems detected on the disks of a Longhorn node
+func DiskIssues(node unstructured.Unstructured) []DiskIssue {
+	disksMap, found, err := unstructured.NestedMap(node.Object, "spec", "disks")
+	if err != nil || !found {
+		return nil
+	}
+	diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
+	if err != nil || !found {
+		return nil
+	}
+
+	var issues []DiskIssue
+	for diskName, diskSpec := range disksMap {
+		diskSpecMap, ok := diskSpec.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if tags, found := diskSpecMap["tags"]; !found || tags == nil {
+			issues = append(issues, DiskIssue{DiskName: diskName, Issue: "No tags defined", Severity: SeverityInfo})
+			continue
+		}
+
+		diskStatus, found := diskStatusMap[diskName].(map[string]interface{})
+		if !found {
+			issues = append(issues, DiskIssue{DiskName: diskName, Issue: "No disk status available", Severity: SeverityWarning})
+			continue
+		}
+
+		for _, condition := range longhorn.Conditions(diskStatus) {
+			if condition.Status == "False" && condition.Type != "" {
+				// A disk that is not ready fails its replicas, an unschedulable one only blocks new ones
+				severity := SeverityWarning
+				if condition.Type == "Ready" {
+					severity = SeverityCritical
+				}
+				issues = append(issues, DiskIssue{DiskName: diskName, Issue: fmt.Sprintf("%s: %s", condition.Type, condition.Reason), Severity: severity})
+			}
+		}
+	}
+	return issues
+}
+
+// VolumeIssues reports whether a volume has issues, along with the failed conditions describing them
+func VolumeIssues(volume unstructured.Unstructured) (bool, []longhorn.Condition) {
+	state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+	robustness, _, _ := unstructured.NestedString(volume.Object, "status", "robustness")
+
+	// Attached volumes with unhealthy robustness, detached or errored volumes. Standby volumes are
+	// expected to sit detached between restores.
+	hasIssue := false
+	if state == "attached" && (robustness == "degraded" || robustness == "faulted" || robustness == "unknown") {
+		hasIssue = true
+	}
+	if (state == "detached" && !longhorn.IsStandby(volume)) || state == "error" {
+		hasIssue = true
+	}
+
+	failedConditions := make([]longhorn.Condition, 0)
+	status, _, _ := unstructured.NestedMap(volume.Object, "status")
+	for _, condition := range longhorn.Conditions(status) {
+		// Skip condition types that don't indicate problems
+		if condition.Type == "Restore" || condition.Type == "WaitForBackingImage" {
+			continue
+		}
+		if condition.Status == "False" && condition.Message != "" {
+			condition.Timestamp = ""
+			failedConditions = append(failedConditions, condition)
+		}
+	}
+
+	if len(failedConditions) > 0 {
+		hasIssue = true
+	}
+	return hasIssue, failedConditions
+}
+
+// SafeToDelete reports whether a volume can be deleted, with the reason. pvPhase is the phase of
+// the PersistentVolume bound to the volume, empty when the volume has no PV.
+func SafeToDelete(volume longhorn.Volume, pvPhase string) (bool, string) {
+	switch {
+	case pvPhase == "Released":
+		return true, "PV is in Released state and no longer used by any pod"
+	case pvPhase == "Failed":
+		return true, "PV is in Failed state"
+	case pvPhase == "" && volume.State == "detached" && !volume.Standby:
+		// Standby volumes are never bound to a PV but are still needed for disaster recovery
+		return true, "Volume is detached and not bound to any PV"
+	}
+	return false, ""
+}
@@ -0,0 +1,92 @@
+package longhorn
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Disk is a disk of a Longhorn node. Sizes are in bytes.
+type Disk struct {
+	NodeName         string      `json:"nodeName"`
+	DiskName         string      `json:"diskName"`
+	Path             string      `json:"path"`
+	Tags             []string    `json:"tags"`
+	Type             string      `json:"type"`
+	StorageMaximum   float64     `json:"storageMaximum"`
+	StorageReserved  float64     `json:"storageReserved"`
+	StorageScheduled float64     `json:"storageScheduled"`
+	StorageAvailable float64     `json:"storageAvailable"`
+	PercentUsed      float64     `json:"percentUsed"`
+	Conditions       []Condition `json:"conditions"`
+}
+
+// NodeDisks returns the disks of a Longhorn node sorted by name. Disks without a status, e.g. just
+// added to the node, are left out.
+func NodeDisks(node unstructured.Unstructured) []Disk {
+	disksMap, found, err := unstructured.NestedMap(node.Object, "spec", "disks")
+	if err != nil || !found || disksMap == nil {
+		return nil
+	}
+	diskStatusMap, found, err := unstructured.NestedMap(node.Object, "status", "diskStatus")
+	if err != nil || !found || diskStatusMap == nil {
+		return nil
+	}
+
+	var disks []Disk
+	for diskName, diskSpec := range disksMap {
+		diskSpecMap, ok := diskSpec.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		diskStatus, ok := diskStatusMap[diskName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		disk := Disk{NodeName: node.GetName(), DiskName: diskName, Conditions: Conditions(diskStatus)}
+		disk.Path, _ = diskSpecMap["path"].(string)
+		disk.Type, _ = diskSpecMap["diskType"].(string)
+		if tags, ok := diskSpecMap["tags"].([]interface{}); ok {
+			for _, t := range tags {
+				if tag, ok := t.(string); ok {
+					disk.Tags = append(disk.Tags, tag)
+				}
+			}
+		}
+
+		disk.StorageMaximum, _ = Float64(diskStatus, "storageMaximum")
+		disk.StorageReserved, _ = Float64(diskStatus, "storageReserved")
+		disk.StorageScheduled, _ = Float64(diskStatus, "storageScheduled")
+		disk.StorageAvailable, _ = Float64(diskStatus, "storageAvailable")
+		if disk.StorageMaximum > 0 {
+			disk.PercentUsed = 100.0 * (disk.StorageMaximum - disk.StorageAvailable) / disk.StorageMaximum
+		}
+
+		disks = append(disks, disk)
+	}
+
+	sort.Slice(disks, func(i, j int) bool {
+		return disks[i].DiskName < disks[j].DiskName
+	})
+	return disks
+}
+
+// ListDisks returns the disks of all Longhorn nodes, sorted by node and disk name
+func ListDisks(ctx context.Context, client dynamic.Interface, namespace string) ([]Disk, error) {
+	nodes, err := List(ctx, client, Nodes, namespace)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].GetName() < nodes[j].GetName()
+	})
+
+	var disks []Disk
+	for _, node := range nodes {
+		disks = append(disks, NodeDisks(node)...)
+	}
+	return disks, nil
+}
@@ -0,0 +1,120 @@
+// Package longhorn reads Longhorn custom resources into plain Go structures. The parsers work on
+// objects listed by any client, e.g. the dynamic client or the informers of an operator.
+package longhorn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// API group, version and resources of the Longhorn CRDs
+const (
+	Group         = "longhorn.io"
+	Version       = "v1beta2"
+	Nodes         = "nodes"
+	Volumes       = "volumes"
+	Replicas      = "replicas"
+	Settings      = "settings"
+	Instances     = "instancemanagers"
+	Engines       = "engines"
+	BackupTargets = "backuptargets"
+	BackupVolumes = "backupvolumes"
+	Backups       = "backups"
+	RecurringJobs = "recurringjobs"
+	BackingImages = "backingimages"
+	Orphans       = "orphans"
+	Attachments   = "volumeattachments"
+	EngineImages  = "engineimages"
+	Snapshots     = "snapshots"
+)
+
+// DefaultNamespace is the namespace Longhorn is installed in by default
+const DefaultNamespace = "longhorn-system"
+
+// GVR returns the GroupVersionResource of a Longhorn CRD
+func GVR(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: Group, Version: Version, Resource: resource}
+}
+
+// PageSize is the number of objects List requests per call, 0 lists a resource in a single call
+var PageSize int64 = 500
+
+// List lists all objects of a Longhorn resource in a namespace, one page of PageSize objects at a
+// time so large clusters do not load the API server with a single huge response
+func List(ctx context.Context, client dynamic.Interface, resource, namespace string) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	opts := metav1.ListOptions{Limit: PageSize}
+	for {
+		list, err := client.Resource(GVR(resource)).Namespace(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Longhorn %s: %v", resource, err)
+		}
+		items = append(items, list.Items...)
+
+		opts.Continue = list.GetContinue()
+		if opts.Continue == "" {
+			return items, nil
+		}
+	}
+}
+
+// Condition is a condition of a Longhorn node, disk or volume
+type Condition struct {
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Conditions parses the conditions of a Longhorn status
+func Conditions(status map[string]interface{}) []Condition {
+	var conditions []Condition
+	list, _ := status["conditions"].([]interface{})
+	for _, c := range list {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		info := Condition{}
+		info.Type, _ = condition["type"].(string)
+		info.Status, _ = condition["status"].(string)
+		info.Reason, _ = condition["reason"].(string)
+		info.Message, _ = condition["message"].(string)
+		info.Timestamp, _ = condition["lastTransitionTime"].(string)
+		conditions = append(conditions, info)
+	}
+	return conditions
+}
+
+// Float64 extracts a number from a map, accepting the integer, float and string encodings found in
+// Longhorn statuses
+func Float64(m map[string]interface{}, key string) (float64, bool) {
+	v, found := m[key]
+	if !found {
+		return 0, false
+	}
+
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,72 @@
+package longhorn
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Replica is a replica of a Longhorn volume. The size is in bytes.
+type Replica struct {
+	Name       string    `json:"name"`
+	VolumeName string    `json:"volumeName"`
+	InstanceID string    `json:"instanceID"`
+	NodeID     string    `json:"nodeID"`
+	DiskID     string    `json:"diskID"`
+	DiskPath   string    `json:"diskPath"`
+	DataPath   string    `json:"dataPath"`
+	State      string    `json:"state"`
+	FailedAt   string    `json:"failedAt"`
+	Size       float64   `json:"size"`
+	Mode       string    `json:"mode"`
+	Healthy    bool      `json:"healthy"`
+	Created    time.Time `json:"created"`
+}
+
+// ParseReplica reads a Longhorn replica. Replicas in error or that failed before are unhealthy.
+func ParseReplica(replica unstructured.Unstructured) Replica {
+	info := Replica{Name: replica.GetName(), Created: replica.GetCreationTimestamp().Time}
+	info.VolumeName, _, _ = unstructured.NestedString(replica.Object, "spec", "volumeName")
+	info.InstanceID, _, _ = unstructured.NestedString(replica.Object, "status", "instanceID")
+	info.NodeID, _, _ = unstructured.NestedString(replica.Object, "spec", "nodeID")
+	info.DiskID, _, _ = unstructured.NestedString(replica.Object, "spec", "diskID")
+	info.DiskPath, _, _ = unstructured.NestedString(replica.Object, "spec", "diskPath")
+	info.DataPath, _, _ = unstructured.NestedString(replica.Object, "status", "currentReplicaAddressMap", "dataPath")
+	info.FailedAt, _, _ = unstructured.NestedString(replica.Object, "status", "failedAt")
+	info.State, _, _ = unstructured.NestedString(replica.Object, "status", "state")
+	info.Mode, _, _ = unstructured.NestedString(replica.Object, "spec", "mode")
+
+	sizeStr, _, _ := unstructured.NestedString(replica.Object, "spec", "size")
+	info.Size, _ = strconv.ParseFloat(sizeStr, 64)
+
+	info.Healthy = info.State != "ERR" && info.State != "FAILED" && info.FailedAt == ""
+	return info
+}
+
+// ListReplicas returns all Longhorn replicas, sorted by volume, node and name
+func ListReplicas(ctx context.Context, client dynamic.Interface, namespace string) ([]Replica, error) {
+	items, err := List(ctx, client, Replicas, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]Replica, 0, len(items))
+	for _, item := range items {
+		replicas = append(replicas, ParseReplica(item))
+	}
+	sort.Slice(replicas, func(i, j int) bool {
+		a, b := replicas[i], replicas[j]
+		if a.VolumeName != b.VolumeName {
+			return a.VolumeName < b.VolumeName
+		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Name < b.Name
+	})
+	return replicas, nil
+}
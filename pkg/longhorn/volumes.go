@@ -0,0 +1,100 @@
+package longhorn
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Volume is a Longhorn volume. Sizes are in bytes.
+type Volume struct {
+	Name            string            `json:"name"`
+	Size            float64           `json:"size"`
+	ActualSize      float64           `json:"actualSize"`
+	State           string            `json:"state"`
+	Robustness      string            `json:"robustness"`
+	Node            string            `json:"node"`
+	ReplicaCount    int               `json:"replicaCount"`
+	DesiredReplicas int               `json:"desiredReplicas"`
+	Scheduled       bool              `json:"scheduled"`
+	Message         string            `json:"message"` // Reason the volume cannot be scheduled
+	DiskSelector    []string          `json:"diskSelector"`
+	NodeSelector    []string          `json:"nodeSelector"`
+	Conditions      []Condition       `json:"conditions"`
+	AccessMode      string            `json:"accessMode"`    // rwo or rwx
+	ShareEndpoint   string            `json:"shareEndpoint"` // NFS endpoint of the share manager (RWX only)
+	ShareState      string            `json:"shareState"`    // State of the share manager (RWX only)
+	Standby         bool              `json:"standby"`       // True for DR volumes restoring from a backup target
+	Labels          map[string]string `json:"labels"`
+	Created         time.Time         `json:"created"`
+}
+
+// IsStandby reports whether a volume is a disaster recovery volume restoring from a backup target
+func IsStandby(volume unstructured.Unstructured) bool {
+	standby, _, _ := unstructured.NestedBool(volume.Object, "spec", "Standby")
+	return standby
+}
+
+// ParseVolume reads a Longhorn volume
+func ParseVolume(volume unstructured.Unstructured) Volume {
+	info := Volume{
+		Name:      volume.GetName(),
+		Scheduled: true,
+		Standby:   IsStandby(volume),
+		Labels:    volume.GetLabels(),
+		Created:   volume.GetCreationTimestamp().Time,
+	}
+
+	sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+	info.Size, _ = strconv.ParseFloat(sizeStr, 64)
+	actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+	info.ActualSize = float64(actualSize)
+
+	info.State, _, _ = unstructured.NestedString(volume.Object, "status", "state")
+	info.Robustness, _, _ = unstructured.NestedString(volume.Object, "status", "robustness")
+	info.Node, _, _ = unstructured.NestedString(volume.Object, "status", "currentNodeID")
+	desiredReplicas, _, _ := unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
+	info.DesiredReplicas = int(desiredReplicas)
+	info.DiskSelector, _, _ = unstructured.NestedStringSlice(volume.Object, "spec", "diskSelector")
+	info.NodeSelector, _, _ = unstructured.NestedStringSlice(volume.Object, "spec", "nodeSelector")
+
+	if status, found, _ := unstructured.NestedMap(volume.Object, "status"); found {
+		info.Conditions = Conditions(status)
+	}
+	for _, condition := range info.Conditions {
+		if condition.Type == "Scheduled" && condition.Status == "False" {
+			info.Scheduled = false
+			info.Message = condition.Message
+		}
+	}
+
+	replicas, _, _ := unstructured.NestedMap(volume.Object, "status", "replicas")
+	info.ReplicaCount = len(replicas)
+
+	info.AccessMode, _, _ = unstructured.NestedString(volume.Object, "spec", "accessMode")
+	info.ShareEndpoint, _, _ = unstructured.NestedString(volume.Object, "status", "shareEndpoint")
+	info.ShareState, _, _ = unstructured.NestedString(volume.Object, "status", "shareState")
+
+	return info
+}
+
+// ListVolumes returns all Longhorn volumes, sorted by name
+func ListVolumes(ctx context.Context, client dynamic.Interface, namespace string) ([]Volume, error) {
+	items, err := List(ctx, client, Volumes, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]Volume, 0, len(items))
+	for _, item := range items {
+		volumes = append(volumes, ParseVolume(item))
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	return volumes, nil
+}
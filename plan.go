@@ -0,0 +1,398 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// exitPlanDoesNotFit is the exit status of "lhmon4 plan" when the volumes cannot all be placed
+const exitPlanDoesNotFit = 6
+
+// maxPlanProbe bounds how many additional volumes are simulated to find the binding constraint
+const maxPlanProbe = 10000
+
+// volumeBatch is a number of volumes of one size, parsed from --add-volumes
+type volumeBatch struct {
+	Count int
+	Size  ByteSize
+}
+
+// parseVolumeBatches parses a comma-separated list like "20x100Gi,5x1Ti"
+func parseVolumeBatches(value string) ([]volumeBatch, error) {
+	var batches []volumeBatch
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		countStr, sizeStr, ok := strings.Cut(part, "x")
+		if !ok {
+			return nil, fmt.Errorf("invalid volumes %q, expected <count>x<size>, e.g. 20x100Gi", part)
+		}
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid volume count in %q", part)
+		}
+		quantity, err := resource.ParseQuantity(sizeStr)
+		if err != nil || quantity.Value() <= 0 {
+			return nil, fmt.Errorf("invalid volume size in %q", part)
+		}
+		batches = append(batches, volumeBatch{Count: count, Size: ByteSize(quantity.Value())})
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("no volumes to plan, use --add-volumes <count>x<size>")
+	}
+	return batches, nil
+}
+
+// planDisk is a schedulable disk whose scheduled storage grows as replicas are placed
+type planDisk struct {
+	NodeName  string
+	DiskName  string
+	Tags      []string
+	Maximum   ByteSize
+	Reserved  ByteSize
+	Scheduled ByteSize
+	Available ByteSize
+	Placed    int
+}
+
+// capacityPlan simulates replica placement with the scheduling settings of the cluster
+type capacityPlan struct {
+	Disks            []*planDisk
+	Replicas         int
+	OverProvisioning int
+	MinimalAvailable int
+	SoftAntiAffinity bool
+	NodeSelector     []string
+	DiskSelector     []string
+}
+
+// schedulable returns the largest replica the disk can accept and the constraint limiting it
+func (p *capacityPlan) schedulable(disk *planDisk) (ByteSize, string) {
+	size := diskSchedulableSize(disk.Maximum, disk.Reserved, disk.Scheduled, disk.Available, p.OverProvisioning, p.MinimalAvailable)
+	if !diskAboveMinimalAvailable(disk.Maximum, disk.Available, p.MinimalAvailable) {
+		return size, fmt.Sprintf("free space at or below the minimal available space (%s %d%%)", minimalAvailableSetting, p.MinimalAvailable)
+	}
+	return size, fmt.Sprintf("over-provisioning limit (%s %d%%)", overProvisioningSetting, p.OverProvisioning)
+}
+
+// place schedules the replicas of one volume, returning the binding constraint when it does not fit.
+// Like Longhorn, replicas of a volume never share a disk and only share a node with soft anti-affinity.
+func (p *capacityPlan) place(size ByteSize) (bool, string) {
+	usedNodes := make(map[string]bool)
+	usedDisks := make(map[*planDisk]bool)
+	var placed []*planDisk
+
+	for r := 0; r < p.Replicas; r++ {
+		// The disk with the most room takes the replica, the constraint of that disk binds when none fits
+		var best *planDisk
+		var bestSize ByteSize
+		var constraint string
+		for _, disk := range p.Disks {
+			if usedDisks[disk] || (!p.SoftAntiAffinity && usedNodes[disk.NodeName]) {
+				continue
+			}
+			fits, limit := p.schedulable(disk)
+			if constraint == "" || fits > bestSize {
+				bestSize, constraint = fits, limit
+				best = disk
+			}
+		}
+		if bestSize < size {
+			best = nil
+		}
+
+		if best == nil {
+			// Undo the replicas already placed for this volume
+			for _, disk := range placed {
+				disk.Scheduled -= size
+				disk.Placed--
+			}
+			switch {
+			case len(p.Disks) == 0:
+				return false, "no schedulable disks match the selectors"
+			case constraint == "":
+				return false, fmt.Sprintf("replica anti-affinity: %d replica(s) need separate nodes with matching disks, only %d available", p.Replicas, len(p.nodes()))
+			}
+			return false, constraint
+		}
+
+		best.Scheduled += size
+		best.Placed++
+		usedNodes[best.NodeName] = true
+		usedDisks[best] = true
+		placed = append(placed, best)
+	}
+	return true, ""
+}
+
+// nodes returns the names of the nodes with disks eligible for the plan
+func (p *capacityPlan) nodes() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, disk := range p.Disks {
+		if !seen[disk.NodeName] {
+			seen[disk.NodeName] = true
+			names = append(names, disk.NodeName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// headroom returns the schedulable capacity left per node
+func (p *capacityPlan) headroom() map[string]ByteSize {
+	headroom := make(map[string]ByteSize)
+	for _, disk := range p.Disks {
+		size, _ := p.schedulable(disk)
+		headroom[disk.NodeName] += size
+	}
+	return headroom
+}
+
+// hasTags returns true if all wanted tags are present
+func hasTags(tags, wanted []string) bool {
+	for _, tag := range wanted {
+		if !contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// newCapacityPlan collects the disks matching the selectors that accept new replicas
+func newCapacityPlan(dynClient dynamic.Interface, namespace string, replicas int, nodeSelector, diskSelector []string) (*capacityPlan, error) {
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+
+	plan := &capacityPlan{Replicas: replicas, NodeSelector: nodeSelector, DiskSelector: diskSelector}
+	if plan.OverProvisioning, plan.MinimalAvailable, err = getSchedulingSettings(dynClient, namespace); err != nil {
+		return nil, err
+	}
+	softAntiAffinity, err := getLonghornSetting(dynClient, namespace, replicaSoftAntiAffinitySetting)
+	if err != nil {
+		return nil, err
+	}
+	plan.SoftAntiAffinity = softAntiAffinity == "true"
+
+	for _, node := range nodes.Items {
+		nodeTags, _, _ := unstructured.NestedStringSlice(node.Object, "spec", "tags")
+		if nodeUnschedulable(node) != "" || !hasTags(nodeTags, nodeSelector) {
+			continue
+		}
+
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		for diskName, d := range disksMap {
+			diskSpec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+			diskTags, _, _ := unstructured.NestedStringSlice(diskSpec, "tags")
+			if !diskAcceptsReplicas(diskSpec, diskStatus) || !hasTags(diskTags, diskSelector) {
+				continue
+			}
+
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+			plan.Disks = append(plan.Disks, &planDisk{
+				NodeName:  node.GetName(),
+				DiskName:  diskName,
+				Tags:      diskTags,
+				Maximum:   ByteSize(maximum),
+				Reserved:  ByteSize(reserved),
+				Scheduled: ByteSize(scheduled),
+				Available: ByteSize(available),
+			})
+		}
+	}
+
+	// Deterministic placement when disks have equal room
+	sort.Slice(plan.Disks, func(i, j int) bool {
+		if plan.Disks[i].NodeName != plan.Disks[j].NodeName {
+			return plan.Disks[i].NodeName < plan.Disks[j].NodeName
+		}
+		return plan.Disks[i].DiskName < plan.Disks[j].DiskName
+	})
+
+	return plan, nil
+}
+
+// splitTags splits a comma-separated selector
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// runPlan implements the "lhmon4 plan" subcommand
+func runPlan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	addVolumes := fs.String("add-volumes", "", "volumes to provision as <count>x<size>, comma-separated, e.g. 20x100Gi,5x1Ti")
+	replicas := fs.Int("replicas", 3, "number of replicas per volume")
+	diskSelector := fs.String("disk-selector", "", "comma-separated disk tags the volumes require (optional)")
+	nodeSelector := fs.String("node-selector", "", "comma-separated node tags the volumes require (optional)")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	batches, err := parseVolumeBatches(*addVolumes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *replicas <= 0 {
+		fmt.Println("Error: --replicas must be at least 1")
+		os.Exit(2)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	plan, err := newCapacityPlan(dynClient, *clientOpts.namespace, *replicas, splitTags(*nodeSelector), splitTags(*diskSelector))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !printCapacityPlan(plan, batches) {
+		os.Exit(exitPlanDoesNotFit)
+	}
+}
+
+// printCapacityPlan simulates the batches and prints the verdict, the per-node headroom and the
+// constraint that binds first. It returns true if every volume fits.
+func printCapacityPlan(plan *capacityPlan, batches []volumeBatch) bool {
+	var requested []string
+	total := 0
+	for _, batch := range batches {
+		requested = append(requested, fmt.Sprintf("%d x %s", batch.Count, batch.Size))
+		total += batch.Count
+	}
+	description := fmt.Sprintf("Provisioning %s with %d replica(s)", strings.Join(requested, ", "), plan.Replicas)
+	if len(plan.DiskSelector) > 0 {
+		description += " on disks tagged " + strings.Join(plan.DiskSelector, ",")
+	}
+	if len(plan.NodeSelector) > 0 {
+		description += " on nodes tagged " + strings.Join(plan.NodeSelector, ",")
+	}
+	printSectionHeader(Section{
+		Title:       "CAPACITY PLAN",
+		Description: description,
+		Color:       Cyan,
+	})
+
+	antiAffinity := "hard"
+	if plan.SoftAntiAffinity {
+		antiAffinity = "soft"
+	}
+	fmt.Printf("Over-provisioning: %d%%, minimal available: %d%%, node anti-affinity: %s\n",
+		plan.OverProvisioning, plan.MinimalAvailable, antiAffinity)
+	fmt.Printf("Eligible: %d disk(s) on %d node(s)\n\n", len(plan.Disks), len(plan.nodes()))
+
+	before := plan.headroom()
+
+	placedVolumes := 0
+	binding := ""
+	var failedSize ByteSize
+	for _, batch := range batches {
+		for i := 0; i < batch.Count && binding == ""; i++ {
+			ok, constraint := plan.place(batch.Size)
+			if !ok {
+				binding, failedSize = constraint, batch.Size
+				break
+			}
+			placedVolumes++
+		}
+	}
+	after := plan.headroom()
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sNODE\tDISKS\tNEW REPLICAS\tHEADROOM BEFORE\tHEADROOM AFTER%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "NODE\tDISKS\tNEW REPLICAS\tHEADROOM BEFORE\tHEADROOM AFTER")
+	}
+
+	fmt.Fprintln(w, "────\t─────\t────────────\t───────────────\t──────────────")
+
+	for _, nodeName := range plan.nodes() {
+		disks, placed := 0, 0
+		for _, disk := range plan.Disks {
+			if disk.NodeName == nodeName {
+				disks++
+				placed += disk.Placed
+			}
+		}
+		afterColor := Green
+		if after[nodeName] < before[nodeName]/10 {
+			afterColor = Red
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n",
+			nodeName,
+			disks,
+			placed,
+			before[nodeName],
+			colorize(after[nodeName].String(), afterColor),
+		)
+	}
+
+	if len(plan.Disks) == 0 {
+		fmt.Fprintln(w, "No schedulable disks match the selectors")
+	}
+
+	w.Flush()
+	fmt.Println()
+
+	if binding != "" {
+		fmt.Println(colorize(fmt.Sprintf("DOES NOT FIT: %d of %d volume(s) can be placed", placedVolumes, total), Red+Bold))
+		fmt.Printf("Binding constraint: %s, reached placing a %s volume\n", binding, failedSize)
+		return false
+	}
+
+	// Keep adding volumes of the last size to find the constraint that binds first
+	size := batches[len(batches)-1].Size
+	extra := 0
+	for extra < maxPlanProbe {
+		ok, constraint := plan.place(size)
+		if !ok {
+			binding = constraint
+			break
+		}
+		extra++
+	}
+
+	fmt.Println(colorize(fmt.Sprintf("FITS: all %d volume(s) can be placed", total), Green+Bold))
+	if binding != "" {
+		fmt.Printf("Room for %d more %s volume(s), then the binding constraint is: %s\n", extra, size, binding)
+	} else {
+		fmt.Printf("Room for more than %d more %s volume(s)\n", maxPlanProbe, size)
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commandName is the command the user typed, "kubectl lhmon" when the binary runs as a kubectl plugin
+var commandName = pluginCommandName(os.Args[0])
+
+// pluginCommandName returns the command kubectl runs a binary named kubectl-<plugin> for, where
+// underscores in the plugin name stand for dashes, or lhmon4 when the binary is not a plugin
+func pluginCommandName(binary string) string {
+	name := strings.TrimSuffix(filepath.Base(binary), ".exe")
+	if plugin, found := strings.CutPrefix(name, "kubectl-"); found {
+		return "kubectl " + strings.ReplaceAll(plugin, "_", "-")
+	}
+	return "lhmon4"
+}
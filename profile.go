@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output profiles of the disk, volume and replica tables
+const (
+	profileNarrow = "narrow" // Four columns for small terminals
+	profileNormal = "normal"
+	profileWide   = "wide" // Adds node, data path, condition and age columns
+)
+
+// outputProfile is the profile selected with --output-profile
+var outputProfile = profileNormal
+
+// setOutputProfile validates and installs an output profile
+func setOutputProfile(name string) error {
+	switch name {
+	case profileNarrow, profileNormal, profileWide:
+		outputProfile = name
+		return nil
+	}
+	return fmt.Errorf("invalid output profile %q, expected narrow, normal or wide", name)
+}
+
+// formatConditions lists the conditions not in their healthy status, "ok" when there are none.
+// Ready, Schedulable and Scheduled are healthy when true, every other condition when false.
+func formatConditions(conditions []ConditionInfo) (string, string) {
+	var unhealthy []string
+	for _, condition := range conditions {
+		healthy := "False"
+		switch condition.Type {
+		case "Ready", "Schedulable", "Scheduled":
+			healthy = "True"
+		}
+		if condition.Status != healthy {
+			unhealthy = append(unhealthy, condition.Type+"="+condition.Status)
+		}
+	}
+	if len(unhealthy) == 0 {
+		return "ok", Green
+	}
+	return strings.Join(unhealthy, ","), Red
+}
+
+// printNarrowHeader prints the header of a narrow table
+func printNarrowHeader(w io.Writer, columns ...string) {
+	if useColors {
+		fmt.Fprintf(w, "%s%s%s%s\n", Bold, Yellow, strings.Join(columns, "\t"), Reset)
+	} else {
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+	separators := make([]string, len(columns))
+	for i, column := range columns {
+		separators[i] = strings.Repeat("─", len(column))
+	}
+	fmt.Fprintln(w, strings.Join(separators, "\t"))
+}
+
+// printDisksNarrow prints the minimal disk table
+func printDisksNarrow(w io.Writer, disks []DiskInfo) {
+	printNarrowHeader(w, "NODE", "DISK", "AVAILABLE", "USED%")
+	for _, disk := range disks {
+		usageColor := Green
+		if disk.PercentUsed > 80 {
+			usageColor = Red
+		} else if disk.PercentUsed > 60 {
+			usageColor = Yellow
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			disk.NodeName,
+			disk.DiskName,
+			disk.StorageAvailable,
+			colorize(formatNumber(disk.PercentUsed, 1)+"%", usageColor),
+		)
+	}
+}
+
+// printVolumesNarrow prints the minimal volume table
+func printVolumesNarrow(w io.Writer, volumes []VolumeInfo) {
+	printNarrowHeader(w, "VOLUME", "SIZE", "STATE", "ROBUSTNESS")
+	for _, vol := range volumes {
+		robustnessColor := Green
+		if vol.Robustness == "degraded" {
+			robustnessColor = Yellow
+		} else if vol.Robustness == "faulted" || vol.Robustness == "unknown" {
+			robustnessColor = Red
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			vol.Name+newBadge("volume", vol.Name),
+			vol.Size,
+			vol.State,
+			colorize(vol.Robustness, robustnessColor),
+		)
+	}
+}
+
+// printReplicasNarrow prints the minimal replica table
+func printReplicasNarrow(w io.Writer, replicas []ReplicaInfo) {
+	printNarrowHeader(w, "VOLUME", "NODE", "STATE", "HEALTHY")
+	for _, replica := range replicas {
+		healthStatus := colorize("Yes", Green)
+		if !replica.Healthy {
+			healthStatus = colorize("No", Red)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			replica.VolumeName,
+			replica.NodeID,
+			replica.State,
+			healthStatus,
+		)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// recurringJobSourceLabel marks a PVC as the source of its volume's recurring job labels
+const recurringJobSourceLabel = "recurring-job-source.longhorn.io"
+
+// pvcPolicyAnnotationKeys are PVC annotations that influence how the Longhorn volume is provisioned or kept
+var pvcPolicyAnnotationKeys = []string{
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+	"helm.sh/resource-policy",
+}
+
+// pvcPolicy returns the labels, annotations and finalizers of a PVC that carry policy for its Longhorn volume
+func pvcPolicy(pvc corev1.PersistentVolumeClaim) []string {
+	var policy []string
+
+	// Recurring job selectors, only synced to the volume when the PVC is marked as the source
+	for key, value := range pvc.Labels {
+		if key == recurringJobSourceLabel ||
+			strings.HasPrefix(key, recurringJobLabelPrefix) ||
+			strings.HasPrefix(key, recurringJobGroupLabelPrefix) {
+			policy = append(policy, key+"="+value)
+		}
+	}
+
+	// Provisioner hints and Longhorn specific annotations
+	for key, value := range pvc.Annotations {
+		if contains(pvcPolicyAnnotationKeys, key) || strings.Contains(key, "longhorn.io/") {
+			policy = append(policy, key+"="+value)
+		}
+	}
+	sort.Strings(policy)
+
+	// Protection finalizers keep the PVC around while it is in use
+	for _, finalizer := range pvc.Finalizers {
+		if strings.Contains(finalizer, "protection") {
+			policy = append(policy, "finalizer:"+finalizer)
+		}
+	}
+
+	return policy
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// readOnlyTransport rejects every request that could modify the cluster. The get, list and watch
+// verbs are all GET requests, everything else is refused before it leaves the process.
+type readOnlyTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends GET and HEAD requests and refuses the others
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, fmt.Errorf("refusing %s %s in read-only mode, only the get, list and watch verbs are allowed", req.Method, req.URL.Path)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// requireWritable fails when a command that modifies the cluster runs in read-only mode
+func (cf clientFlags) requireWritable(action string) error {
+	if *cf.readOnly {
+		return fmt.Errorf("%s modifies the cluster and is disabled in read-only mode, run it with --read-only=false", action)
+	}
+	return nil
+}
+
+// readOnlyRule is a rule of the read-only ClusterRole printed by "lhmon4 rbac"
+type readOnlyRule struct {
+	group     string
+	resources []string
+}
+
+// readOnlyRules lists every resource lhmon4 reads. Secrets are read to check the credentials of
+// the backup target, nodes/proxy to scrape node-exporter through the API server.
+var readOnlyRules = []readOnlyRule{
+	{longhornGroup, []string{
+		longhornNodes, longhornVolumes, longhornReplicas, longhornSettings, longhornInstances, longhornEngines,
+		longhornBackupTargets, longhornBackupVolumes, longhornBackups, longhornRecurringJobs, longhornBackingImages,
+		longhornOrphans, longhornAttachments, longhornEngineImages, longhornSnapshots,
+	}},
+	{"", []string{"nodes", "nodes/proxy", "pods", "persistentvolumes", "persistentvolumeclaims", "events", "secrets"}},
+	{"storage.k8s.io", []string{"storageclasses"}},
+	{"snapshot.storage.k8s.io", []string{"volumesnapshots", "volumesnapshotcontents", "volumesnapshotclasses"}},
+	{"k8s.cni.cncf.io", []string{"network-attachment-definitions"}},
+}
+
+// runRBAC implements the "lhmon4 rbac" subcommand, printing the read-only ClusterRole
+func runRBAC(args []string) {
+	fs := flag.NewFlagSet("rbac", flag.ExitOnError)
+	name := fs.String("name", "lhmon4-read-only", "name of the ClusterRole")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 0 {
+		fmt.Printf("Usage: %s rbac [--name lhmon4-read-only]\n", commandName)
+		os.Exit(2)
+	}
+
+	fmt.Println("apiVersion: rbac.authorization.k8s.io/v1")
+	fmt.Println("kind: ClusterRole")
+	fmt.Println("metadata:")
+	fmt.Printf("  name: %s\n", *name)
+	fmt.Println("rules:")
+	for _, rule := range readOnlyRules {
+		fmt.Printf("- apiGroups: [%q]\n", rule.group)
+		fmt.Printf("  resources: [%s]\n", strings.Join(rule.resources, ", "))
+		fmt.Println(`  verbs: ["get", "list", "watch"]`)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Label prefixes used by Longhorn to assign recurring jobs and groups to volumes
+const (
+	recurringJobLabelPrefix      = "recurring-job.longhorn.io/"
+	recurringJobGroupLabelPrefix = "recurring-job-group.longhorn.io/"
+	defaultRecurringJobGroup     = "default"
+)
+
+// RecurringJobInfo stores information about a Longhorn recurring job
+type RecurringJobInfo struct {
+	Name        string
+	Task        string
+	Cron        string
+	Groups      []string
+	Retain      int64
+	Concurrency int64
+	Schedule    *cronSchedule // nil when the cron expression cannot be parsed
+	ParseError  string
+	Volumes     []string // Volumes the job applies to
+}
+
+// getRecurringJobs collects all recurring jobs and resolves the volumes they apply to
+func getRecurringJobs(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) ([]RecurringJobInfo, map[string][]string, error) {
+	jobList, err := listAll(dynClient, longhornGVR(longhornRecurringJobs), namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn recurring jobs: %v", err)
+	}
+
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	jobs := make([]RecurringJobInfo, 0, len(jobList.Items))
+	for _, item := range jobList.Items {
+		job := RecurringJobInfo{Name: item.GetName()}
+		job.Task, _, _ = unstructured.NestedString(item.Object, "spec", "task")
+		job.Cron, _, _ = unstructured.NestedString(item.Object, "spec", "cron")
+		job.Groups, _, _ = unstructured.NestedStringSlice(item.Object, "spec", "groups")
+		job.Retain, _, _ = unstructured.NestedInt64(item.Object, "spec", "retain")
+		job.Concurrency, _, _ = unstructured.NestedInt64(item.Object, "spec", "concurrency")
+
+		schedule, err := parseCron(job.Cron)
+		if err != nil {
+			job.ParseError = err.Error()
+		} else {
+			job.Schedule = schedule
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	// Resolve the jobs of every volume
+	volumeJobs := make(map[string][]string)
+	for _, volume := range volumes.Items {
+		names := volumeRecurringJobs(volume.GetLabels(), jobs)
+		volumeJobs[volume.GetName()] = names
+		for _, name := range names {
+			for i := range jobs {
+				if jobs[i].Name == name {
+					jobs[i].Volumes = append(jobs[i].Volumes, volume.GetName())
+				}
+			}
+		}
+	}
+
+	return jobs, volumeJobs, nil
+}
+
+// volumeRecurringJobs returns the names of the recurring jobs applying to a volume with the given labels.
+// Volumes without any recurring job labels belong to the default group.
+func volumeRecurringJobs(labels map[string]string, jobs []RecurringJobInfo) []string {
+	selected := make(map[string]bool)
+	groups := volumeGroupMembership(labels)
+
+	for key, value := range labels {
+		if value == "enabled" && strings.HasPrefix(key, recurringJobLabelPrefix) {
+			selected[strings.TrimPrefix(key, recurringJobLabelPrefix)] = true
+		}
+	}
+
+	for _, job := range jobs {
+		for _, group := range groups {
+			if contains(job.Groups, group) {
+				selected[job.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// volumeRecurringJobGroups returns the recurring job groups a volume is explicitly a member of
+func volumeRecurringJobGroups(labels map[string]string) []string {
+	var groups []string
+	for key, value := range labels {
+		if value == "enabled" && strings.HasPrefix(key, recurringJobGroupLabelPrefix) {
+			groups = append(groups, strings.TrimPrefix(key, recurringJobGroupLabelPrefix))
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// volumeGroupMembership returns the recurring job groups of a volume.
+// Volumes without any recurring job or group labels belong to the default group.
+func volumeGroupMembership(labels map[string]string) []string {
+	groups := volumeRecurringJobGroups(labels)
+	if len(groups) > 0 {
+		return groups
+	}
+	for key, value := range labels {
+		if value == "enabled" && strings.HasPrefix(key, recurringJobLabelPrefix) {
+			return nil
+		}
+	}
+	return []string{defaultRecurringJobGroup}
+}
+
+// volumeUserLabels returns the labels of a volume as sorted key=value pairs, leaving out recurring job labels
+func volumeUserLabels(labels map[string]string) []string {
+	var result []string
+	for key, value := range labels {
+		if strings.HasPrefix(key, recurringJobLabelPrefix) || strings.HasPrefix(key, recurringJobGroupLabelPrefix) {
+			continue
+		}
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// runRecurringJobs implements the "lhmon4 recurring-jobs" subcommand
+func runRecurringJobs(args []string) {
+	fs := flag.NewFlagSet("recurring-jobs", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	runs := fs.Int("runs", 3, "number of upcoming runs to show per job")
+	volumeName := fs.String("volume", "", "only show jobs applying to this volume")
+	herdThreshold := fs.Int("herd-threshold", 50, "warn when at least this many volumes start a backup at the same minute")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	tableStyleName := fs.String("table-style", "plain", "table style: plain, unicode, markdown or github")
+	noPager := fs.Bool("no-pager", false, "do not pipe output longer than the terminal through $PAGER")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if err := setTableStyle(*tableStyleName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	jobs, volumeJobs, err := getRecurringJobs(dynClient, *clientOpts.namespace, longhornGVR(longhornVolumes))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Longhorn creates the CronJobs without a time zone, so schedules run in the controller's UTC clock
+	now := time.Now().UTC()
+
+	output := startPager(*noPager)
+	defer output.finish()
+
+	printRecurringJobSchedule(jobs, volumeJobs, *volumeName, *runs, now)
+	printVolumeSchedule(jobs, volumeJobs, *volumeName, now)
+	printScheduleConflicts(jobs, now, *herdThreshold)
+}
+
+// printRecurringJobSchedule prints every recurring job with its next runs
+func printRecurringJobSchedule(jobs []RecurringJobInfo, volumeJobs map[string][]string, filterVolume string, runs int, now time.Time) {
+	printSectionHeader(Section{
+		Title:       "RECURRING JOBS",
+		Description: fmt.Sprintf("Schedules and next %d runs (UTC)", runs),
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sJOB\tTASK\tCRON\tGROUPS\tRETAIN\tCONCURRENCY\tVOLUMES\tNEXT RUNS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "JOB\tTASK\tCRON\tGROUPS\tRETAIN\tCONCURRENCY\tVOLUMES\tNEXT RUNS")
+	}
+
+	fmt.Fprintln(w, "───\t────\t────\t──────\t──────\t───────────\t───────\t─────────")
+
+	for _, job := range jobs {
+		if filterVolume != "" && !contains(volumeJobs[filterVolume], job.Name) {
+			continue
+		}
+
+		groups := "none"
+		if len(job.Groups) > 0 {
+			groups = strings.Join(job.Groups, ",")
+		}
+
+		nextRuns := colorize("invalid cron: "+job.ParseError, Red)
+		if job.Schedule != nil {
+			var formatted []string
+			for _, run := range job.Schedule.nextRuns(now, runs) {
+				formatted = append(formatted, formatSchedule(run))
+			}
+			nextRuns = strings.Join(formatted, ", ")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			colorize(job.Name, Blue),
+			job.Task,
+			job.Cron,
+			colorize(groups, Cyan),
+			job.Retain,
+			job.Concurrency,
+			len(job.Volumes),
+			nextRuns,
+		)
+	}
+
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "No recurring jobs found")
+	}
+
+	w.Flush()
+}
+
+// printVolumeSchedule prints the next scheduled job for every volume
+func printVolumeSchedule(jobs []RecurringJobInfo, volumeJobs map[string][]string, filterVolume string, now time.Time) {
+	printSectionHeader(Section{
+		Title:       "VOLUME SCHEDULES",
+		Description: "Recurring jobs applying to each volume and the next run (UTC)",
+		Color:       Magenta,
+	})
+
+	jobsByName := make(map[string]RecurringJobInfo)
+	for _, job := range jobs {
+		jobsByName[job.Name] = job
+	}
+
+	volumeNames := make([]string, 0, len(volumeJobs))
+	for name := range volumeJobs {
+		if filterVolume == "" || name == filterVolume {
+			volumeNames = append(volumeNames, name)
+		}
+	}
+	sort.Strings(volumeNames)
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tJOBS\tNEXT RUN\tNEXT JOB%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tJOBS\tNEXT RUN\tNEXT JOB")
+	}
+
+	fmt.Fprintln(w, "──────\t────\t────────\t────────")
+
+	for _, name := range volumeNames {
+		names := volumeJobs[name]
+		if len(names) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\n", name, colorize("none", Red))
+			continue
+		}
+
+		var nextRun time.Time
+		nextJob := ""
+		for _, jobName := range names {
+			job, found := jobsByName[jobName]
+			if !found || job.Schedule == nil {
+				continue
+			}
+			if run := job.Schedule.next(now); !run.IsZero() && (nextRun.IsZero() || run.Before(nextRun)) {
+				nextRun = run
+				nextJob = job.Name + " (" + job.Task + ")"
+			}
+		}
+
+		next := "-"
+		if !nextRun.IsZero() {
+			next = formatSchedule(nextRun)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, colorize(strings.Join(names, ","), Cyan), next, nextJob)
+	}
+	w.Flush()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Renderer writes a report in one output format. Most renderers only see the collected report
+// document, so adding an output format does not touch the print functions of the sections.
+type Renderer interface {
+	Render(run *reportRun) error
+}
+
+// RendererFunc adapts a function printing the report of a run to the Renderer interface, for
+// the table report and the jsonl stream, which query the snapshot while they print
+type RendererFunc func(run *reportRun) error
+
+// Render calls the function
+func (f RendererFunc) Render(run *reportRun) error {
+	return f(run)
+}
+
+// DocumentRendererFunc adapts a function writing the report document to the Renderer interface
+type DocumentRendererFunc func(doc reportDocument) error
+
+// Render collects the report document of the run and calls the function with it
+func (f DocumentRendererFunc) Render(run *reportRun) error {
+	doc, err := run.document()
+	if err != nil {
+		return err
+	}
+	if err := f(doc); err != nil {
+		return err
+	}
+	printStatsSummary()
+	return nil
+}
+
+// renderers holds the output formats selectable with -o
+var renderers = map[string]Renderer{
+	outputTable:         RendererFunc(printTableReport),
+	outputJSONLines:     RendererFunc(renderJSONLines),
+	outputJSON:          DocumentRendererFunc(renderJSON),
+	outputYAML:          DocumentRendererFunc(renderYAML),
+	outputCSV:           DocumentRendererFunc(renderCSV),
+	outputPrometheus:    DocumentRendererFunc(renderPrometheus),
+	outputHTML:          DocumentRendererFunc(renderHTML),
+	outputCustomColumns: DocumentRendererFunc(renderCustomColumns),
+	outputGoTemplate:    DocumentRendererFunc(renderGoTemplate),
+	outputJSONPath:      DocumentRendererFunc(renderJSONPath),
+	outputJUnit:         DocumentRendererFunc(renderJUnit),
+	outputPDF:           DocumentRendererFunc(renderPDF),
+}
+
+// lookupRenderer returns the renderer of an output format
+func lookupRenderer(name string) (Renderer, bool) {
+	renderer, found := renderers[name]
+	return renderer, found
+}
+
+// outputFormats lists every format accepted by -o
+func outputFormats() string {
+	var names []string
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// reportRun is the report of one invocation: the clients, the filters and the options of the
+// output format. The renderer sets exitCode to report policy violations and issues.
+type reportRun struct {
+	format    string
+	dynClient dynamic.Interface
+	clientset *kubernetes.Clientset
+	namespace string
+	filters   reportFilters
+	hardware  *hardwareScraper
+	highlight *diskHighlighter
+	config    *monitorConfig
+	failOn    severity
+
+	// Table report options
+	watch             bool
+	interval          time.Duration
+	verbose           bool
+	noPager           bool
+	fillWithin        time.Duration
+	tagWithin         time.Duration
+	growthPercent     float64
+	growthBytesPerDay ByteSize
+	bloatRatio        float64
+	chainThreshold    int
+
+	exitCode int
+}
+
+// collect takes the snapshot of the resources and writes the --textfile metrics from it
+func (run *reportRun) collect(resources []string) {
+	runStats.startSection("collection")
+	takeSnapshot(run.dynClient, run.namespace, resources)
+	if textfilePath != "" {
+		if err := writeTextfile(run.dynClient, run.clientset, run.namespace); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// document collects the report document with the sections the output format needs
+func (run *reportRun) document() (reportDocument, error) {
+	run.collect(snapshotResources)
+
+	filters := run.filters
+	switch run.format {
+	case outputHTML:
+		// The HTML report is a complete page of every section
+		filters.replicas, filters.relationships, filters.issues = true, true, true
+	case outputJSON, outputYAML, outputJUnit, outputPDF:
+		filters.issues = true
+	case outputCustomColumns:
+		filters.replicas = true
+	case outputGoTemplate, outputJSONPath:
+		filters.replicas, filters.relationships = true, true
+	}
+	return getReportDocument(run.dynClient, run.clientset, run.namespace, longhornGVR(longhornNodes), longhornGVR(longhornVolumes), longhornGVR(longhornReplicas), filters, run.hardware)
+}
+
+// outputExpressions parse the argument of the formats written as -o format=argument
+var outputExpressions = map[string]func(string) error{
+	outputCustomColumns: setDocumentColumns,
+	outputGoTemplate:    setOutputTemplate,
+	outputJSONPath:      setOutputPath,
+}
+
+// parseOutputExpression installs the argument of -o custom-columns=, go-template= or jsonpath=
+// and returns the bare format name. Other formats are returned unchanged.
+func parseOutputExpression(value string) (string, error) {
+	name, argument, found := strings.Cut(value, "=")
+	set, takesArgument := outputExpressions[name]
+	switch {
+	case !takesArgument:
+		return value, nil
+	case !found || argument == "":
+		return name, fmt.Errorf("-o %s needs an argument, e.g. -o %s=...", name, name)
+	}
+	return name, set(argument)
+}
+
+// renderJSON writes the report as an indented JSON document
+func renderJSON(doc reportDocument) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// renderYAML writes the report as a YAML document with the JSON field names
+func renderYAML(doc reportDocument) error {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// renderCSV writes one CSV file per section to the --csv-dir directory
+func renderCSV(doc reportDocument) error {
+	paths, err := writeCSVReport(csvOutputDir, doc)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", strings.Join(paths, ", "))
+	return nil
+}
+
+// renderPrometheus writes the capacity of the report in the Prometheus text exposition format
+func renderPrometheus(doc reportDocument) error {
+	return writeMetrics(os.Stdout, reportMetrics(doc))
+}
+
+// reportMetrics converts the disks and volumes of the report into the metric families of the
+// exporter, with the same names and labels
+func reportMetrics(doc reportDocument) []*metricFamily {
+	disks := newDiskMetrics()
+	for _, disk := range doc.Disks {
+		disks.add(disk.NodeName, disk.DiskName, disk.Tags, disk.NodeTags, float64(disk.StorageMaximum), float64(disk.StorageAvailable), float64(disk.StorageScheduled), float64(disk.StorageReserved))
+	}
+
+	volumes := newVolumeMetrics()
+	for _, volume := range doc.Volumes {
+		volumes.add(volume.Name, volume.StorageClass, volume.DiskSelector, float64(volume.Size), float64(volume.ActualSize))
+	}
+
+	return append(disks.families(), volumes.families()...)
+}
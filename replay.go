@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// replayHost is the API server the clients talk to in --replay mode, it is never contacted
+const replayHost = "http://replay.invalid"
+
+// replayTransport answers the API requests of a run from the fixtures written by "dev dump-fixtures",
+// so a report can be reproduced without the cluster it was dumped from. Longhorn resources without
+// a fixture are missing like on a release without the CRD; core resources such as pods and
+// persistent volumes are not dumped and are empty.
+type replayTransport struct {
+	lists map[string]*unstructured.UnstructuredList // Keyed by resource
+}
+
+// newReplayConfig returns a client config served from the fixtures in a directory
+func newReplayConfig(dir string) (*rest.Config, error) {
+	transport := &replayTransport{lists: make(map[string]*unstructured.UnstructuredList)}
+	for _, resource := range fixtureResources {
+		data, err := os.ReadFile(filepath.Join(dir, resource+".json"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read fixtures: %v", err)
+		}
+
+		// Older dumps carry no list kind, so decode the items rather than the list
+		var fixture struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to decode %s fixtures: %v", resource, err)
+		}
+		list := &unstructured.UnstructuredList{}
+		for _, item := range fixture.Items {
+			list.Items = append(list.Items, unstructured.Unstructured{Object: item})
+		}
+		transport.lists[resource] = list
+	}
+	if len(transport.lists) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	return &rest.Config{Host: replayHost, Transport: transport}, nil
+}
+
+// RoundTrip serves a get or list from the fixtures, every other request is refused
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	gvr, namespace, name, ok := parseAPIPath(req.URL.Path)
+	if !ok {
+		return replayResponse(req, apierrors.NewNotFound(schema.GroupResource{}, req.URL.Path))
+	}
+	if req.Method != http.MethodGet || req.URL.Query().Get("watch") == "true" {
+		return replayResponse(req, apierrors.NewMethodNotSupported(gvr.GroupResource(), req.Method))
+	}
+
+	list := t.lists[gvr.Resource]
+	if gvr.Group != longhornGroup {
+		// Core resources are not part of the fixtures
+		list = &unstructured.UnstructuredList{}
+	} else if list == nil {
+		return replayResponse(req, apierrors.NewNotFound(gvr.GroupResource(), name))
+	}
+
+	selector, err := labels.Parse(req.URL.Query().Get("labelSelector"))
+	if err != nil {
+		return replayResponse(req, apierrors.NewBadRequest(err.Error()))
+	}
+
+	result := &unstructured.UnstructuredList{Object: map[string]interface{}{"metadata": map[string]interface{}{}}}
+	result.SetAPIVersion(gvr.GroupVersion().String())
+	result.SetKind(replayListKind(gvr))
+	for _, item := range list.Items {
+		if namespace != "" && item.GetNamespace() != namespace {
+			continue
+		}
+		if name != "" && item.GetName() == name {
+			return replayJSON(req, http.StatusOK, &item)
+		}
+		if name == "" && selector.Matches(labels.Set(item.GetLabels())) {
+			result.Items = append(result.Items, item)
+		}
+	}
+	if name != "" {
+		return replayResponse(req, apierrors.NewNotFound(gvr.GroupResource(), name))
+	}
+	return replayJSON(req, http.StatusOK, result)
+}
+
+// replayListKind returns the kind of a list of the resource, the typed clients only decode lists of
+// the kind they asked for. Resources unknown to client-go, such as the Longhorn CRDs, are read by
+// the dynamic client, which accepts any kind.
+func replayListKind(gvr schema.GroupVersionResource) string {
+	for kind := range scheme.Scheme.KnownTypes(gvr.GroupVersion()) {
+		if plural, _ := meta.UnsafeGuessKindToResource(gvr.GroupVersion().WithKind(kind)); plural == gvr {
+			return kind + "List"
+		}
+	}
+	return "List"
+}
+
+// parseAPIPath splits /api/v1/... and /apis/<group>/<version>/... paths into the resource,
+// namespace and name, ok is false for subresources and anything else
+func parseAPIPath(path string) (gvr schema.GroupVersionResource, namespace, name string, ok bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		gvr.Version, parts = parts[1], parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		gvr.Group, gvr.Version, parts = parts[1], parts[2], parts[3:]
+	default:
+		return gvr, "", "", false
+	}
+
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		namespace, parts = parts[1], parts[2:]
+	}
+	switch len(parts) {
+	case 1:
+		gvr.Resource = parts[0]
+	case 2:
+		gvr.Resource, name = parts[0], parts[1]
+	default:
+		return gvr, "", "", false
+	}
+	return gvr, namespace, name, true
+}
+
+// replayResponse answers with the status of an API error, which the clients turn back into the error
+func replayResponse(req *http.Request, err *apierrors.StatusError) (*http.Response, error) {
+	status := err.ErrStatus
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	return replayJSON(req, int(status.Code), &status)
+}
+
+// replayJSON answers with an object encoded as JSON
+func replayJSON(req *http.Request, code int, obj interface{}) (*http.Response, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode:    code,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/dynamic"
+)
+
+// TestReplayIssues replays the fixtures in testdata/fixtures through a regular collection and
+// checks the issues found on their nodes and volumes
+func TestReplayIssues(t *testing.T) {
+	stateFile = filepath.Join(t.TempDir(), "state.json")
+
+	config, err := newReplayConfig(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.QPS = -1 // The fixtures are local, no need for client-side throttling
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		node   string
+		volume string
+		want   []string // object: issue (severity), worst first
+	}{
+		{
+			name: "all",
+			want: []string{
+				"node/node-2: disk d1: Ready: DiskNotReady (critical)",
+				"node/node-2: disk d1: Schedulable: DiskPressure (warning)",
+				"volume/pvc-degraded: state attached, robustness degraded (warning)",
+				"volume/pvc-unscheduled: Scheduled: insufficient storage (warning)",
+				"node/node-1: disk d2: No tags defined (info)",
+			},
+		},
+		{
+			name: "healthy node",
+			node: "node-1",
+			want: []string{
+				"volume/pvc-degraded: state attached, robustness degraded (warning)",
+				"volume/pvc-unscheduled: Scheduled: insufficient storage (warning)",
+				"node/node-1: disk d2: No tags defined (info)",
+			},
+		},
+		{
+			name:   "healthy volume",
+			node:   "node-2",
+			volume: "pvc-healthy",
+			want: []string{
+				"node/node-2: disk d1: Ready: DiskNotReady (critical)",
+				"node/node-2: disk d1: Schedulable: DiskPressure (warning)",
+			},
+		},
+		{
+			name:   "standby volume",
+			node:   "node-3",
+			volume: "pvc-standby",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			takeSnapshot(dynClient, "longhorn-system", snapshotResources)
+			records, err := getIssueRecords(dynClient, "longhorn-system", tt.node, tt.volume)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Object+": "+record.Issue+" ("+record.Severity+")")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
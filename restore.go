@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// RestoreInfo stores information about a volume with an active restore
+type RestoreInfo struct {
+	VolumeName string
+	FromBackup string
+	Condition  string // Reason of the Restore condition
+	Replicas   []RestoreStatusInfo
+}
+
+// getActiveRestores collects non-standby volumes that are restoring from a backup
+func getActiveRestores(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, filterVolume string) ([]RestoreInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	var engines map[string]unstructured.Unstructured
+	var restores []RestoreInfo
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		// Skip if we're filtering by volume name and this isn't the right one
+		if filterVolume != "" && volumeName != filterVolume {
+			continue
+		}
+
+		// Standby volumes restore continuously and are reported in the DR section
+		if longhorn.IsStandby(volume) {
+			continue
+		}
+
+		restoreRequired, _, _ := unstructured.NestedBool(volume.Object, "status", "restoreRequired")
+		restoreInitiated, _, _ := unstructured.NestedBool(volume.Object, "status", "restoreInitiated")
+
+		conditionReason := ""
+		restoreCondition := false
+		conditions, _, _ := unstructured.NestedSlice(volume.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			condType, _ := condition["type"].(string)
+			status, _ := condition["status"].(string)
+			reason, _ := condition["reason"].(string)
+			if condType == "Restore" && status == "True" {
+				restoreCondition = true
+				conditionReason = reason
+			}
+		}
+
+		if !restoreRequired && !restoreInitiated && !restoreCondition {
+			continue
+		}
+
+		// Engines are only needed once a restore has been found
+		if engines == nil {
+			engines, err = listEnginesByVolume(dynClient, namespace)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		info := RestoreInfo{VolumeName: volumeName, Condition: conditionReason}
+		info.FromBackup, _, _ = unstructured.NestedString(volume.Object, "spec", "fromBackup")
+		if engine, found := engines[volumeName]; found {
+			info.Replicas = engineRestoreStatus(engine)
+
+			// Restore status is keyed by replica address, show replica names instead
+			addressMap, _, _ := unstructured.NestedStringMap(engine.Object, "spec", "replicaAddressMap")
+			names := make(map[string]string)
+			for name, address := range addressMap {
+				names["tcp://"+address] = name
+				names[address] = name
+			}
+			for i := range info.Replicas {
+				if name, found := names[info.Replicas[i].Replica]; found {
+					info.Replicas[i].Replica = name
+				}
+			}
+		}
+
+		restores = append(restores, info)
+	}
+
+	sort.Slice(restores, func(i, j int) bool {
+		return restores[i].VolumeName < restores[j].VolumeName
+	})
+
+	return restores, nil
+}
+
+// printRestoreProgress prints per-replica progress of active restores
+func printRestoreProgress(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, filterVolume string) error {
+	restores, err := getActiveRestores(dynClient, namespace, volumesGVR, filterVolume)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to show when no restore is running
+	if len(restores) == 0 {
+		return nil
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "RESTORES IN PROGRESS",
+		Description: "Per-replica progress of volumes restoring from a backup",
+		Color:       Blue,
+	})
+
+	// Setup tabwriter
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tREPLICA\tPROGRESS\tSTATE\tBACKUP\tERROR%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tREPLICA\tPROGRESS\tSTATE\tBACKUP\tERROR")
+	}
+
+	fmt.Fprintln(w, "──────\t───────\t────────\t─────\t──────\t─────")
+
+	for _, restore := range restores {
+		if len(restore.Replicas) == 0 {
+			state := restore.Condition
+			if state == "" {
+				state = "pending"
+			}
+			fmt.Fprintf(w, "%s\t-\t%s\t%s\t%s\t-\n", colorize(restore.VolumeName, Blue), progressBar(0, 20), state, restore.FromBackup)
+			continue
+		}
+
+		for _, replica := range restore.Replicas {
+			backup := replica.CurrentBackup
+			if backup == "" {
+				backup = restore.FromBackup
+			}
+
+			errText := "-"
+			progressColor := Yellow
+			if replica.Error != "" {
+				errText = colorize(replica.Error, Red)
+				progressColor = Red
+			} else if replica.Progress >= 100 {
+				progressColor = Green
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				colorize(restore.VolumeName, Blue),
+				replica.Replica,
+				colorize(progressBar(replica.Progress, 20), progressColor),
+				replica.State,
+				backup,
+				errText,
+			)
+		}
+	}
+	w.Flush()
+
+	return nil
+}
+
+// progressBar renders a percentage as a fixed-width text bar
+func progressBar(percent int64, width int) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := int(percent) * width / 100
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), percent)
+}
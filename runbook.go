@@ -0,0 +1,41 @@
+package main
+
+import "strings"
+
+// runbooks maps issue categories to runbook URLs, set from the config file. Besides the condition
+// categories and knowledge base issues, "disk", "mount", "availability" and "engine-upgrade" link
+// the disk, mount, availability policy and stuck engine upgrade checks.
+var runbooks map[string]string
+
+// runbookFor returns the runbook URL of the first key with one configured
+func runbookFor(keys ...string) string {
+	for _, key := range keys {
+		if url := runbooks[key]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// withRunbook appends the runbook URL of the first configured key to a problem description
+func withRunbook(text string, keys ...string) string {
+	if url := runbookFor(keys...); url != "" {
+		return text + " (runbook: " + url + ")"
+	}
+	return text
+}
+
+// issueRunbook returns the runbook URL of an issue detected on a node or volume, see objectIssues.
+// Node issues are disk issues, volume conditions are looked up by their issue category, classified
+// from the condition reason and message. The reason is empty when it is no longer known.
+func issueRunbook(object, issue, reason string) string {
+	kind, _, _ := strings.Cut(object, "/")
+	switch kind {
+	case "node":
+		return runbookFor("disk")
+	case "volume":
+		_, message, _ := strings.Cut(issue, ": ")
+		return runbookFor(issue, classifyCondition(reason, message))
+	}
+	return runbookFor(issue)
+}
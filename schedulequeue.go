@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UnschedulableInfo describes a volume or PVC waiting to be scheduled
+type UnschedulableInfo struct {
+	Kind       string
+	Name       string
+	Constraint string
+	Message    string
+	Since      time.Time // When scheduling started failing
+}
+
+// getUnschedulableQueue returns the volumes whose Scheduled condition is False and the Longhorn PVCs
+// still waiting for a volume, longest stuck first
+func getUnschedulableQueue(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource) ([]UnschedulableInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	var queue []UnschedulableInfo
+	volumePVCs := make(map[string]bool) // namespace/name of PVCs that already have a volume
+	for _, volume := range volumes.Items {
+		pvcNamespace, _, _ := unstructured.NestedString(volume.Object, "status", "kubernetesStatus", "namespace")
+		pvcName, _, _ := unstructured.NestedString(volume.Object, "status", "kubernetesStatus", "pvcName")
+		if pvcName != "" {
+			volumePVCs[pvcNamespace+"/"+pvcName] = true
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(volume.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			condType, _ := condition["type"].(string)
+			status, _ := condition["status"].(string)
+			if condType != "Scheduled" || status != "False" {
+				continue
+			}
+
+			reason, _ := condition["reason"].(string)
+			message, _ := condition["message"].(string)
+			since := volume.GetCreationTimestamp().Time
+			if ts, _ := condition["lastTransitionTime"].(string); ts != "" {
+				if t, err := time.Parse(time.RFC3339, ts); err == nil {
+					since = t
+				}
+			}
+
+			queue = append(queue, UnschedulableInfo{
+				Kind:       "volume",
+				Name:       volume.GetName(),
+				Constraint: classifyCondition(reason, message),
+				Message:    message,
+				Since:      since,
+			})
+		}
+	}
+
+	// Pending PVCs without a volume never got past provisioning
+	longhornClasses, err := listLonghornStorageClasses(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list StorageClasses: %v", err)
+	}
+	pvcs, err := listPersistentVolumeClaims(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumeClaims: %v", err)
+	}
+	for _, pvc := range pvcs.Items {
+		key := pvc.Namespace + "/" + pvc.Name
+		if pvc.Status.Phase != corev1.ClaimPending || !longhornClasses[pvcStorageClass(pvc)] || volumePVCs[key] {
+			continue
+		}
+		queue = append(queue, UnschedulableInfo{
+			Kind:       "pvc",
+			Name:       key,
+			Constraint: "provisioning",
+			Message:    "Waiting for Longhorn to create the volume",
+			Since:      pvc.CreationTimestamp.Time,
+		})
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if !queue[i].Since.Equal(queue[j].Since) {
+			return queue[i].Since.Before(queue[j].Since)
+		}
+		return queue[i].Name < queue[j].Name
+	})
+
+	return queue, nil
+}
+
+// printUnschedulableQueue prints the volumes and PVCs failing to schedule, longest stuck first
+func printUnschedulableQueue(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource) {
+	queue, err := getUnschedulableQueue(dynClient, clientset, namespace, volumesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "UNSCHEDULABLE QUEUE",
+		Description: "Volumes and PVCs failing to schedule, longest stuck first",
+		Color:       Red,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%s#\tKIND\tNAME\tSTUCK FOR\tCONSTRAINT\tMESSAGE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "#\tKIND\tNAME\tSTUCK FOR\tCONSTRAINT\tMESSAGE")
+	}
+
+	fmt.Fprintln(w, "─\t────\t────\t─────────\t──────────\t───────")
+
+	for i, item := range queue {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			i+1,
+			item.Kind,
+			item.Name,
+			formatAge(item.Since),
+			colorize(item.Constraint, Red),
+			withRunbook(item.Message, item.Constraint),
+		)
+	}
+
+	if len(queue) == 0 {
+		fmt.Fprintln(w, "No volumes or PVCs waiting to be scheduled")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reportSections lists the sections of the table report, in the order they are printed
+var reportSections = []string{
+	"summary", "disks", "volumes", "dr-volumes", "restores", "replicas", "relationships",
+	"attachment-history", "locality", "statefulsets", "lineage", "deletion", "disk-issues",
+	"disk-conflicts", "cordoned", "fill-rate", "scheduling", "node-instances", "mounts",
+	"storage-network", "size-mismatches", "drift", "backing-images", "csi-snapshots", "growth",
+	"snapshot-bloat", "snapshot-chains", "unschedulable", "volume-issues", "availability",
+	"engine-upgrades", "engine-images", "disk-tags", "tag-forecast",
+}
+
+// hiddenSections holds the sections left out with --hide-sections
+var hiddenSections = make(map[string]bool)
+
+// hideSections adds a comma-separated list of sections to the hidden sections
+func hideSections(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !contains(reportSections, name) {
+			return fmt.Errorf("unknown section %q, expected one of %s", name, strings.Join(reportSections, ", "))
+		}
+		hiddenSections[name] = true
+	}
+	return nil
+}
+
+// showSection returns true unless the section was hidden, attributing the --stats figures from now on
+// to the section. Hidden issue sections do not count for --fail-on.
+func showSection(name string) bool {
+	if hiddenSections[name] {
+		return false
+	}
+	runStats.startSection(name)
+	return true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exitIssueSeverity is the exit code when an issue at or above the --fail-on severity is found
+const exitIssueSeverity = 4
+
+// severity ranks detected issues
+type severity int
+
+const (
+	severityNone severity = iota // No issue found
+	severityInfo
+	severityWarning
+	severityCritical
+)
+
+// String returns the name of the severity as used in output and flags
+func (s severity) String() string {
+	switch s {
+	case severityInfo:
+		return "info"
+	case severityWarning:
+		return "warning"
+	case severityCritical:
+		return "critical"
+	}
+	return "none"
+}
+
+// color returns the color the severity is rendered in
+func (s severity) color() string {
+	switch s {
+	case severityInfo:
+		return Cyan
+	case severityWarning:
+		return Yellow
+	}
+	return Red
+}
+
+// formatSeverity renders a severity as a fixed width colored label for line oriented output
+func formatSeverity(s severity) string {
+	return colorize(fmt.Sprintf("%-8s", strings.ToUpper(s.String())), s.color())
+}
+
+// parseSeverity parses a severity name, an empty string yields severityNone
+func parseSeverity(value string) (severity, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return severityNone, nil
+	case "info":
+		return severityInfo, nil
+	case "warning":
+		return severityWarning, nil
+	case "critical":
+		return severityCritical, nil
+	}
+	return severityNone, fmt.Errorf("invalid severity %q, expected info, warning or critical", value)
+}
+
+// volumeSeverity ranks the issues of a volume by its state and robustness. Failed conditions
+// are at least warnings, see volumeConditionSeverity.
+func volumeSeverity(state, robustness string) severity {
+	switch {
+	case state == "error" || robustness == "faulted":
+		return severityCritical
+	case robustness == "degraded" || robustness == "unknown":
+		return severityWarning
+	}
+	return severityInfo
+}
+
+// volumeConditionSeverity ranks a failed condition of a volume
+func volumeConditionSeverity(state, robustness string) severity {
+	if s := volumeSeverity(state, robustness); s > severityWarning {
+		return s
+	}
+	return severityWarning
+}
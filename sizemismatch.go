@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// SizeMismatch describes a disagreement between the Longhorn volume size, the PV capacity and the PVC
+type SizeMismatch struct {
+	VolumeName   string
+	LonghornSize ByteSize
+	PVCapacity   ByteSize
+	PVCRequest   ByteSize
+	PVCCapacity  ByteSize
+	Issue        string
+}
+
+// findSizeMismatches compares each Longhorn volume's spec.size with its PV capacity and PVC request
+func findSizeMismatches(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) ([]SizeMismatch, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	var mismatches []SizeMismatch
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		pvInfo, exists := pvInfoMap[volumeName]
+		if !exists {
+			continue
+		}
+
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseFloat(sizeStr, 64)
+
+		mismatch := SizeMismatch{
+			VolumeName:   volumeName,
+			LonghornSize: ByteSize(size),
+			PVCapacity:   ByteSize(pvInfo.CapacityBytes),
+			PVCRequest:   ByteSize(pvInfo.PVCRequestBytes),
+			PVCCapacity:  ByteSize(pvInfo.PVCCapacityBytes),
+		}
+
+		switch {
+		case mismatch.LonghornSize > mismatch.PVCapacity:
+			mismatch.Issue = "Longhorn volume is larger than the PV, CSI expansion did not update the PV capacity"
+		case mismatch.LonghornSize < mismatch.PVCapacity:
+			mismatch.Issue = "PV capacity exceeds the Longhorn volume, expansion never reached Longhorn or spec.size was edited manually"
+		case pvInfo.PVCRequestBytes > pvInfo.CapacityBytes:
+			mismatch.Issue = "PVC requests more than the PV capacity, expansion is pending or failed"
+		case pvInfo.PVCCapacityBytes > 0 && pvInfo.PVCCapacityBytes < pvInfo.CapacityBytes:
+			mismatch.Issue = "PVC status capacity is below the PV capacity, filesystem resize has not completed on the node"
+		default:
+			continue
+		}
+
+		mismatches = append(mismatches, mismatch)
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].VolumeName < mismatches[j].VolumeName
+	})
+
+	return mismatches, nil
+}
+
+// printSizeMismatches prints volumes whose Longhorn, PV and PVC sizes disagree
+func printSizeMismatches(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	mismatches, err := findSizeMismatches(dynClient, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error listing volumes: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "SIZE MISMATCHES",
+		Description: "Longhorn volume size versus PV capacity and PVC request",
+		Color:       Red,
+	})
+
+	// Setup tabwriter
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tLONGHORN SIZE\tPV CAPACITY\tPVC REQUEST\tPVC CAPACITY\tISSUE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tLONGHORN SIZE\tPV CAPACITY\tPVC REQUEST\tPVC CAPACITY\tISSUE")
+	}
+
+	fmt.Fprintln(w, "──────\t─────────────\t───────────\t───────────\t────────────\t─────")
+
+	for _, mismatch := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			mismatch.VolumeName,
+			colorize(mismatch.LonghornSize.String(), Blue),
+			mismatch.PVCapacity,
+			mismatch.PVCRequest,
+			mismatch.PVCCapacity,
+			colorize(mismatch.Issue, Red),
+		)
+	}
+
+	if len(mismatches) == 0 {
+		fmt.Fprintln(w, "No size mismatches found")
+	}
+
+	w.Flush()
+}
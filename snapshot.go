@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// snapshotResources are the Longhorn resources collected before a report renders
+var snapshotResources = []string{
+	longhornNodes,
+	longhornVolumes,
+	longhornReplicas,
+	longhornEngines,
+	longhornBackingImages,
+	longhornOrphans,
+	longhornAttachments,
+	longhornBackupTargets,
+	longhornBackupVolumes,
+	longhornBackups,
+	longhornRecurringJobs,
+	longhornEngineImages,
+}
+
+// sectionResources are the Longhorn resources collected for the sections of the table report that
+// use them. The nodes, volumes and replicas are needed by almost every section and always collected.
+var sectionResources = map[string][]string{
+	"summary":            {longhornEngines, longhornOrphans},
+	"dr-volumes":         {longhornEngines},
+	"restores":           {longhornEngines},
+	"attachment-history": {longhornAttachments},
+	"node-instances":     {longhornEngines},
+	"backing-images":     {longhornBackingImages},
+	"csi-snapshots":      {longhornEngines, longhornBackups},
+	"snapshot-bloat":     {longhornEngines},
+	"snapshot-chains":    {longhornEngines},
+	"engine-upgrades":    {longhornEngines},
+	"engine-images":      {longhornEngineImages},
+}
+
+// reportResources returns the snapshot resources used by the sections not hidden with
+// --hide-sections, so a report never lists expensive resources such as backups it does not show
+func reportResources() []string {
+	needed := map[string]bool{longhornNodes: true, longhornVolumes: true, longhornReplicas: true}
+	for section, resources := range sectionResources {
+		if hiddenSections[section] {
+			continue
+		}
+		for _, resource := range resources {
+			needed[resource] = true
+		}
+	}
+
+	var resources []string
+	for _, resource := range snapshotResources {
+		if needed[resource] {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// currentSnapshot serves the Longhorn lists of the report being rendered, nil outside a report
+var currentSnapshot *clusterSnapshot
+
+// clusterSnapshot holds the Longhorn resources of one namespace as collected at a single point in
+// time, so every section of a report renders from the same state. Without it the volume and
+// replica tables list at different times and can disagree.
+type clusterSnapshot struct {
+	id        string // Collection ID stamped on every output rendered from the snapshot
+	namespace string
+	takenAt   time.Time
+	lists     map[string]*unstructured.UnstructuredList // Keyed by resource
+	errs      map[string]error
+}
+
+// takeSnapshot collects the resources in parallel and installs the snapshot for listAll. Resources
+// left out are listed directly when needed. Errors are kept and returned to the sections listing
+// the resource, like a direct list would.
+func takeSnapshot(dynClient dynamic.Interface, namespace string, resources []string) *clusterSnapshot {
+	// Collect from the API server or the watch cache, never from the previous snapshot
+	currentSnapshot = nil
+
+	takenAt := time.Now()
+	snapshot := &clusterSnapshot{
+		id:        newCollectionID(takenAt),
+		namespace: namespace,
+		takenAt:   takenAt,
+		lists:     make(map[string]*unstructured.UnstructuredList),
+		errs:      make(map[string]error),
+	}
+
+	var mu sync.Mutex
+	forEachConcurrently(resources, func(resource string) {
+		list, err := listAll(dynClient, longhornGVR(resource), namespace)
+		mu.Lock()
+		snapshot.lists[resource] = list
+		snapshot.errs[resource] = err
+		mu.Unlock()
+	})
+
+	currentSnapshot = snapshot
+	return snapshot
+}
+
+// list returns a copy of a collected list, found is false if the resource is not part of the snapshot
+func (s *clusterSnapshot) list(gvr schema.GroupVersionResource, namespace string) (list *unstructured.UnstructuredList, found bool, err error) {
+	if gvr.Group != longhornGroup || gvr.Version != longhornVersion || namespace != s.namespace {
+		return nil, false, nil
+	}
+	if err, found := s.errs[gvr.Resource]; !found {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, true, err
+	}
+
+	// Sections may sort or filter the items, the snapshot itself stays untouched
+	collected := s.lists[gvr.Resource]
+	list = &unstructured.UnstructuredList{Object: collected.Object}
+	list.Items = append([]unstructured.Unstructured(nil), collected.Items...)
+	return list, true, nil
+}
+
+// newCollectionID returns an ID that sorts by collection time and is unique across concurrent runs,
+// e.g. 20240102T150405Z-9f86d081
+func newCollectionID(t time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return t.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// stamp describes the collection for matching up the artifacts of one run
+func (s *clusterSnapshot) stamp() string {
+	return fmt.Sprintf("Collection %s at %s", s.id, formatTimestamp(s.takenAt))
+}
+
+// printCollectionStamp prints the stamp of the current snapshot, if any
+func printCollectionStamp() {
+	if currentSnapshot != nil {
+		fmt.Println(colorize(currentSnapshot.stamp(), Cyan))
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// snapshotMaxCountSetting limits the number of snapshots per volume, Longhorn defaults it to 250
+const (
+	snapshotMaxCountSetting = "snapshot-max-count"
+	defaultSnapshotMaxCount = 250
+)
+
+// SnapshotChainInfo describes a volume with a long snapshot chain
+type SnapshotChainInfo struct {
+	VolumeName string
+	Snapshots  int
+	MaxCount   int // Snapshot limit of the volume, from the volume spec or the global setting
+	Threshold  int // Warning threshold that was exceeded
+}
+
+// findLongSnapshotChains finds volumes whose snapshot chain reaches the threshold. A threshold of 0
+// warns when the chain reaches the volume's snapshot limit.
+func findLongSnapshotChains(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, threshold int) ([]SnapshotChainInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	maxCount := defaultSnapshotMaxCount
+	value, err := getLonghornSetting(dynClient, namespace, snapshotMaxCountSetting)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		maxCount = n
+	}
+
+	var chains []SnapshotChainInfo
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		engine, found := volumeEngines[volumeName]
+		if !found {
+			continue
+		}
+
+		// The volume head is not a snapshot
+		count := 0
+		for _, snapshot := range engineSnapshots(engine) {
+			if snapshot.Name != volumeHeadSnapshot {
+				count++
+			}
+		}
+
+		// Volumes may override the global limit
+		volumeMax := maxCount
+		if n, _, _ := unstructured.NestedInt64(volume.Object, "spec", "snapshotMaxCount"); n > 0 {
+			volumeMax = int(n)
+		}
+
+		limit := threshold
+		if limit <= 0 {
+			limit = volumeMax
+		}
+		if count < limit {
+			continue
+		}
+
+		chains = append(chains, SnapshotChainInfo{
+			VolumeName: volumeName,
+			Snapshots:  count,
+			MaxCount:   volumeMax,
+			Threshold:  limit,
+		})
+	}
+
+	// Longest chains first
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Snapshots > chains[j].Snapshots
+	})
+
+	return chains, nil
+}
+
+// printSnapshotChains prints volumes whose snapshot chains are long enough to hurt performance
+func printSnapshotChains(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, threshold int) {
+	chains, err := findLongSnapshotChains(dynClient, namespace, volumesGVR, threshold)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	description := "Volumes reaching their snapshot-max-count limit"
+	if threshold > 0 {
+		description = fmt.Sprintf("Volumes with at least %d snapshots", threshold)
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "SNAPSHOT CHAINS",
+		Description: description,
+		Color:       Yellow,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSNAPSHOTS\tMAX COUNT\tRECOMMENDATION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSNAPSHOTS\tMAX COUNT\tRECOMMENDATION")
+	}
+
+	fmt.Fprintln(w, "──────\t─────────\t─────────\t──────────────")
+
+	for _, chain := range chains {
+		countColor := Yellow
+		recommendation := "Long chains slow down reads and rebuilds: lower the retain count of snapshot jobs or delete old snapshots"
+		if chain.Snapshots >= chain.MaxCount {
+			countColor = Red
+			recommendation = "Limit reached: new snapshots and backups will fail until snapshots are deleted"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+			chain.VolumeName,
+			colorize(strconv.Itoa(chain.Snapshots), countColor),
+			chain.MaxCount,
+			recommendation,
+		)
+	}
+
+	if len(chains) == 0 {
+		fmt.Fprintln(w, "No long snapshot chains found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// volumeHeadSnapshot is the name of the writable head in an engine's snapshot chain
+const volumeHeadSnapshot = "volume-head"
+
+// SnapshotInfo stores information about a snapshot in an engine's snapshot chain
+type SnapshotInfo struct {
+	Name        string
+	Parent      string
+	Size        ByteSize
+	Created     string
+	Removed     bool
+	UserCreated bool
+}
+
+// SnapshotBloatInfo describes a volume whose replicas hold much more data than the volume size
+type SnapshotBloatInfo struct {
+	VolumeName       string
+	Size             ByteSize
+	ActualSize       ByteSize
+	Replicas         int
+	Snapshots        int
+	RemovedSnapshots int
+	ReplicaUsage     ByteSize  // Sum of the on-disk usage of all replicas
+	Reclaimable      ByteSize  // Estimated space freed by purging snapshots, across all replicas
+	OldestSnapshot   time.Time // Creation time of the oldest snapshot in the chain
+}
+
+// engineSnapshots returns the snapshot chain reported in an engine's status, including the volume head
+func engineSnapshots(engine unstructured.Unstructured) []SnapshotInfo {
+	snapshotsMap, found, _ := unstructured.NestedMap(engine.Object, "status", "snapshots")
+	if !found {
+		return nil
+	}
+
+	var snapshots []SnapshotInfo
+	for name, s := range snapshotsMap {
+		snapshot, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		size, _ := longhorn.Float64(snapshot, "size")
+		parent, _ := snapshot["parent"].(string)
+		created, _ := snapshot["created"].(string)
+		removed, _ := snapshot["removed"].(bool)
+		userCreated, _ := snapshot["usercreated"].(bool)
+
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:        name,
+			Parent:      parent,
+			Size:        ByteSize(size),
+			Created:     created,
+			Removed:     removed,
+			UserCreated: userCreated,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created < snapshots[j].Created
+	})
+
+	return snapshots
+}
+
+// engineReplicaCount returns the number of replicas in RW mode according to an engine's status
+func engineReplicaCount(engine unstructured.Unstructured) int {
+	modeMap, found, _ := unstructured.NestedStringMap(engine.Object, "status", "replicaModeMap")
+	if !found {
+		return 0
+	}
+	count := 0
+	for _, mode := range modeMap {
+		if mode == "RW" {
+			count++
+		}
+	}
+	return count
+}
+
+// findSnapshotBloat detects volumes whose per-replica usage exceeds the volume size by more than the given ratio,
+// or which still carry removed snapshots waiting to be purged
+func findSnapshotBloat(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, ratio float64) ([]SnapshotBloatInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	volumeEngines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var bloated []SnapshotBloatInfo
+	for _, volume := range volumes.Items {
+		volumeName := volume.GetName()
+
+		sizeStr, _, _ := unstructured.NestedString(volume.Object, "spec", "size")
+		size, _ := strconv.ParseFloat(sizeStr, 64)
+		actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		desiredReplicas, _, _ := unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
+
+		engine, found := volumeEngines[volumeName]
+		if !found || size == 0 {
+			continue
+		}
+
+		snapshots := engineSnapshots(engine)
+		replicas := engineReplicaCount(engine)
+		if replicas == 0 {
+			replicas = int(desiredReplicas)
+		}
+
+		// Per-replica usage is the sum of the chain, falling back to the reported actual size
+		var perReplica, removedSize ByteSize
+		var oldest time.Time
+		snapshotCount, removedCount := 0, 0
+		for _, snapshot := range snapshots {
+			perReplica += snapshot.Size
+			if snapshot.Name == volumeHeadSnapshot {
+				continue
+			}
+			snapshotCount++
+			if created, err := time.Parse(time.RFC3339, snapshot.Created); err == nil && (oldest.IsZero() || created.Before(oldest)) {
+				oldest = created
+			}
+			if snapshot.Removed {
+				removedCount++
+				removedSize += snapshot.Size
+			}
+		}
+		if perReplica == 0 {
+			perReplica = ByteSize(actualSize)
+		}
+
+		if float64(perReplica) <= ratio*size && removedCount == 0 {
+			continue
+		}
+
+		// Without snapshots a replica never holds more than the volume size, so anything
+		// above it plus removed snapshots is space a purge can give back
+		reclaimable := removedSize
+		if excess := perReplica - removedSize - ByteSize(size); excess > 0 {
+			reclaimable += excess
+		}
+
+		bloated = append(bloated, SnapshotBloatInfo{
+			VolumeName:       volumeName,
+			Size:             ByteSize(size),
+			ActualSize:       ByteSize(actualSize),
+			Replicas:         replicas,
+			Snapshots:        snapshotCount,
+			RemovedSnapshots: removedCount,
+			ReplicaUsage:     perReplica * ByteSize(replicas),
+			Reclaimable:      reclaimable * ByteSize(replicas),
+			OldestSnapshot:   oldest,
+		})
+	}
+
+	// Largest savings first
+	sort.Slice(bloated, func(i, j int) bool {
+		return bloated[i].Reclaimable > bloated[j].Reclaimable
+	})
+
+	return bloated, nil
+}
+
+// printSnapshotBloat prints volumes whose snapshot chains consume excessive space
+func printSnapshotBloat(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, ratio float64) {
+	bloated, err := findSnapshotBloat(dynClient, namespace, volumesGVR, ratio)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "SNAPSHOT BLOAT",
+		Description: fmt.Sprintf("Volumes whose replicas use more than %.1fx the volume size", ratio),
+		Color:       Yellow,
+	})
+
+	// Setup tabwriter
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sVOLUME\tSIZE\tACTUAL SIZE\tSNAPSHOTS\tOLDEST SNAPSHOT\tREPLICA USAGE\tRECLAIMABLE\tRECOMMENDATION%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "VOLUME\tSIZE\tACTUAL SIZE\tSNAPSHOTS\tOLDEST SNAPSHOT\tREPLICA USAGE\tRECLAIMABLE\tRECOMMENDATION")
+	}
+
+	fmt.Fprintln(w, "──────\t────\t───────────\t─────────\t───────────────\t─────────────\t───────────\t──────────────")
+
+	var total ByteSize
+	for _, info := range bloated {
+		recommendation := "Delete unneeded snapshots and purge the snapshot chain, then trim the filesystem"
+		if info.RemovedSnapshots > 0 {
+			recommendation = fmt.Sprintf("Purge %d removed snapshot(s), then trim the filesystem", info.RemovedSnapshots)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			info.VolumeName,
+			colorize(info.Size.String(), Blue),
+			info.ActualSize,
+			info.Snapshots,
+			formatAge(info.OldestSnapshot),
+			colorize(fmt.Sprintf("%s (%d replicas)", info.ReplicaUsage, info.Replicas), Yellow),
+			colorize(info.Reclaimable.String(), Green),
+			recommendation,
+		)
+		total += info.Reclaimable
+	}
+
+	if len(bloated) == 0 {
+		fmt.Fprintln(w, "No snapshot bloat found")
+	}
+
+	w.Flush()
+
+	if len(bloated) > 0 {
+		fmt.Printf("Estimated reclaimable space: %s\n", colorize(total.String(), Bold+Green))
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// stateFile overrides the location of the state recorded between runs
+var stateFile string
+
+// monitorState is what lhmon4 remembers between runs and watch refreshes
+type monitorState struct {
+	Disks   map[string]diskState    `json:"disks"`             // Keyed by node/disk
+	Volumes map[string]volumeState  `json:"volumes,omitempty"` // Keyed by volume name
+	Tags    map[string]tagState     `json:"tags,omitempty"`    // Keyed by disk tag, empty for untagged disks
+	Acks    map[string]time.Time    `json:"acks,omitempty"`    // Acknowledged issue IDs and when the acknowledgement expires
+	Alerted map[string]alertedIssue `json:"alerted,omitempty"` // Open issues already reported, keyed by issue ID
+
+	Incidents []incident `json:"incidents,omitempty"` // Resolved issues, oldest first
+
+	MaintenanceUntil time.Time `json:"maintenanceUntil,omitempty"` // End of the maintenance window started with "lhmon4 ack --silence"
+
+	base    *monitorState // The state file as last read or written, telling our changes from other runs'
+	modTime time.Time     // Modification time of the state file when it was last read or written
+}
+
+// diskState is the recorded state of a single disk
+type diskState struct {
+	StorageMaximum ByteSize     `json:"storageMaximum"`
+	ExpandedAt     time.Time    `json:"expandedAt,omitempty"`    // Last time storageMaximum was seen growing
+	Samples        []sizeSample `json:"samples,omitempty"`       // Available space history, oldest first
+	CordonedSince  time.Time    `json:"cordonedSince,omitempty"` // First time the disk was seen closed to new replicas
+}
+
+// alertedIssue is an open issue that has already been reported
+type alertedIssue struct {
+	Object   string    `json:"object"` // kind/name of the object with the issue
+	Issue    string    `json:"issue"`
+	Severity severity  `json:"severity"`
+	Since    time.Time `json:"since"`
+
+	InMaintenance bool `json:"inMaintenance,omitempty"` // Detected during a maintenance window
+	External      bool `json:"external,omitempty"`      // Received from Alertmanager instead of detected
+}
+
+// incident is an issue that was detected and has been resolved since
+type incident struct {
+	ID         string    `json:"id"`
+	Object     string    `json:"object"`
+	Issue      string    `json:"issue"`
+	Severity   severity  `json:"severity"`
+	Since      time.Time `json:"since"`
+	Resolved   time.Time `json:"resolved"`
+	Resolution string    `json:"resolution"` // How the issue ended, see the incident resolutions
+
+	InMaintenance bool `json:"inMaintenance,omitempty"` // Detected during a maintenance window
+}
+
+// Resolutions of an incident
+const (
+	resolutionCleared = "cleared" // The object no longer has the issue
+	resolutionDeleted = "deleted" // The object was deleted
+)
+
+// incidentRetention is how long resolved issues are kept for "lhmon4 report incidents"
+const incidentRetention = 90 * 24 * time.Hour
+
+// recordIncident moves a resolved issue into the incident history, dropping expired incidents
+func (s *monitorState) recordIncident(id string, alerted alertedIssue, resolved time.Time, resolution string) {
+	s.Incidents = append(s.Incidents, incident{
+		ID:            id,
+		Object:        alerted.Object,
+		Issue:         alerted.Issue,
+		Severity:      alerted.Severity,
+		Since:         alerted.Since,
+		Resolved:      resolved,
+		Resolution:    resolution,
+		InMaintenance: alerted.InMaintenance,
+	})
+
+	first := 0
+	for first < len(s.Incidents) && resolved.Sub(s.Incidents[first].Resolved) > incidentRetention {
+		first++
+	}
+	s.Incidents = s.Incidents[first:]
+}
+
+// volumeState is the recorded state of a single volume
+type volumeState struct {
+	Samples []sizeSample `json:"samples,omitempty"` // Actual size history, oldest first
+}
+
+// tagState is the recorded capacity of the disks sharing a disk tag
+type tagState struct {
+	Available   []sizeSample `json:"available,omitempty"`   // Available space history, oldest first
+	Schedulable []sizeSample `json:"schedulable,omitempty"` // Schedulable space history, oldest first
+}
+
+// sizeSample is a size observed at a point in time
+type sizeSample struct {
+	At   time.Time `json:"at"`
+	Size ByteSize  `json:"size"`
+}
+
+// Sample history kept in the state file
+const (
+	sampleInterval   = 10 * time.Minute   // Minimum time between recorded samples
+	sampleRetention  = 7 * 24 * time.Hour // Samples older than this are dropped
+	minSampledPeriod = time.Hour          // Minimum period a rate is computed over
+)
+
+// recordSample appends a sample unless the previous one is too recent, dropping expired samples
+func recordSample(samples []sizeSample, at time.Time, size ByteSize) []sizeSample {
+	if n := len(samples); n == 0 || at.Sub(samples[n-1].At) >= sampleInterval {
+		samples = append(samples, sizeSample{At: at, Size: size})
+	}
+
+	first := 0
+	for first < len(samples) && at.Sub(samples[first].At) > sampleRetention {
+		first++
+	}
+	return samples[first:]
+}
+
+// sampleRate returns the change per day between the oldest sample and the given current size,
+// along with the period it was measured over. ok is false when the history is too short.
+func sampleRate(samples []sizeSample, now time.Time, current ByteSize) (perDay float64, period time.Duration, ok bool) {
+	if len(samples) == 0 {
+		return 0, 0, false
+	}
+	period = now.Sub(samples[0].At)
+	if period < minSampledPeriod {
+		return 0, period, false
+	}
+	return float64(current-samples[0].Size) / period.Hours() * 24, period, true
+}
+
+// statePath returns the path of the state file, defaulting to the user's cache directory
+func statePath() (string, error) {
+	if stateFile != "" {
+		return stateFile, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %v", err)
+	}
+	return filepath.Join(dir, "lhmon4", "state.json"), nil
+}
+
+// loadState reads the recorded state, returning an empty state on the first run
+func loadState() (*monitorState, error) {
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+
+	state, err := decodeState(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+	}
+	// A second copy records the file as read, the first one is changed by the run
+	if state.base, err = decodeState(data); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil {
+		state.modTime = info.ModTime()
+	}
+	return state, nil
+}
+
+// decodeState decodes a state file, empty data giving an empty state
+func decodeState(data []byte) (*monitorState, error) {
+	state := &monitorState{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, err
+		}
+	}
+
+	if state.Disks == nil {
+		state.Disks = make(map[string]diskState)
+	}
+	if state.Volumes == nil {
+		state.Volumes = make(map[string]volumeState)
+	}
+	if state.Tags == nil {
+		state.Tags = make(map[string]tagState)
+	}
+	if state.Acks == nil {
+		state.Acks = make(map[string]time.Time)
+	}
+	if state.Alerted == nil {
+		state.Alerted = make(map[string]alertedIssue)
+	}
+	return state, nil
+}
+
+// currentState is the state shared by every feature of a run, so one feature saving it does not
+// overwrite what another recorded
+var currentState *monitorState
+
+// sharedState loads the recorded state on first use and returns the shared copy afterwards
+func sharedState() (*monitorState, error) {
+	if currentState != nil {
+		return currentState, nil
+	}
+	state, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	currentState = state
+	return currentState, nil
+}
+
+// freshState returns the shared state with the changes other runs saved since it was read, such
+// as acknowledgements and silences added with "lhmon4 ack" while issues are followed
+func freshState() (*monitorState, error) {
+	state, err := sharedState()
+	if err != nil {
+		return nil, err
+	}
+	if err := state.refresh(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// refresh merges the state file into the state when another run has written it since
+func (s *monitorState) refresh() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		// Without a file there is nothing newer to merge
+		return nil
+	}
+
+	disk, err := loadState()
+	if err != nil {
+		return err
+	}
+	s.merge(disk)
+	s.base, s.modTime = disk.base, disk.modTime
+	return nil
+}
+
+// merge takes what other runs changed from the state file and keeps what this run changed.
+// Acknowledgements are merged one by one, everything else by section.
+func (s *monitorState) merge(disk *monitorState) {
+	base := s.base
+	if base == nil {
+		base, _ = decodeState(nil)
+	}
+
+	acks := make(map[string]time.Time, len(disk.Acks))
+	for id, until := range disk.Acks {
+		acks[id] = until
+	}
+	for id := range base.Acks {
+		if _, kept := s.Acks[id]; !kept {
+			delete(acks, id)
+		}
+	}
+	for id, until := range s.Acks {
+		if was, found := base.Acks[id]; !found || !was.Equal(until) {
+			acks[id] = until
+		}
+	}
+	s.Acks = acks
+
+	if s.MaintenanceUntil.Equal(base.MaintenanceUntil) {
+		s.MaintenanceUntil = disk.MaintenanceUntil
+	}
+	if reflect.DeepEqual(s.Disks, base.Disks) {
+		s.Disks = disk.Disks
+	}
+	if reflect.DeepEqual(s.Volumes, base.Volumes) {
+		s.Volumes = disk.Volumes
+	}
+	if reflect.DeepEqual(s.Tags, base.Tags) {
+		s.Tags = disk.Tags
+	}
+	if reflect.DeepEqual(s.Alerted, base.Alerted) {
+		s.Alerted = disk.Alerted
+	}
+	if reflect.DeepEqual(s.Incidents, base.Incidents) {
+		s.Incidents = disk.Incidents
+	}
+}
+
+// save merges the changes other runs saved since the state was read, then writes it back to disk
+func (s *monitorState) save() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	// Rewriting the file as read at startup would drop the acknowledgements and silences added since
+	disk, err := loadState()
+	if err != nil {
+		return err
+	}
+	s.merge(disk)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a truncated state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+
+	if s.base, err = decodeState(data); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
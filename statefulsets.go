@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatefulSetPinningInfo describes whether the volume of one StatefulSet ordinal is placed where
+// its pod may run
+type StatefulSetPinningInfo struct {
+	StatefulSet  string // namespace/name
+	Ordinal      int
+	PodName      string
+	Claim        string
+	VolumeName   string
+	AccessMode   string
+	PodNode      string
+	PodZone      string
+	AllowedZones []string // Zones the pod is restricted to by its node selector or affinity, empty when unrestricted
+	AttachedNode string
+	ReplicaZones []string // Zones of the healthy replicas
+	Problem      string
+	Severity     severity
+}
+
+// nodeZone returns the topology zone of a Kubernetes node, including the legacy beta label
+func nodeZone(node corev1.Node) string {
+	if zone := node.Labels[corev1.LabelTopologyZone]; zone != "" {
+		return zone
+	}
+	return node.Labels[corev1.LabelFailureDomainBetaZone]
+}
+
+// isZoneLabel returns true for the labels node zones are selected by
+func isZoneLabel(key string) bool {
+	return key == corev1.LabelTopologyZone || key == corev1.LabelFailureDomainBetaZone
+}
+
+// podAllowedZones returns the zones a pod may be scheduled to by its node selector and required
+// node affinity, nil when it may run in any zone. Node selector terms are alternatives, so a single
+// term without a zone requirement lifts the restriction.
+func podAllowedZones(pod corev1.Pod) []string {
+	var selected []string
+	for key, value := range pod.Spec.NodeSelector {
+		if isZoneLabel(key) {
+			selected = []string{value}
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return selected
+	}
+
+	allowed := make(map[string]bool)
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		restricted := false
+		for _, expr := range term.MatchExpressions {
+			if isZoneLabel(expr.Key) && expr.Operator == corev1.NodeSelectorOpIn {
+				restricted = true
+				for _, value := range expr.Values {
+					allowed[value] = true
+				}
+			}
+		}
+		if !restricted {
+			return selected
+		}
+	}
+
+	// Both the node selector and the affinity must match
+	if selected != nil {
+		if allowed[selected[0]] {
+			return selected
+		}
+		return []string{}
+	}
+	return sortedKeys(allowed)
+}
+
+// getStatefulSetPinning compares the replica zones and attachment node of each volume claimed by
+// a StatefulSet pod with the node and topology constraints of the pod
+func getStatefulSetPinning(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) ([]StatefulSetPinningInfo, error) {
+	// Claims of StatefulSet pods and their namespaces
+	claimVolumes := make(map[string]string) // namespace/claim -> volume
+	podNamespaces := make(map[string]bool)
+	for volumeName, pvInfo := range pvInfoMap {
+		for _, pod := range pvInfo.ConsumerPods {
+			if strings.HasPrefix(pod.Workload, "StatefulSet/") {
+				claimVolumes[pvInfo.PVCNamespace+"/"+pvInfo.PVCName] = volumeName
+				podNamespaces[pod.Namespace] = true
+			}
+		}
+	}
+	if len(claimVolumes) == 0 {
+		return nil, nil
+	}
+
+	k8sNodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	zones := make(map[string]string)
+	for _, node := range k8sNodes.Items {
+		zones[node.Name] = nodeZone(node)
+	}
+
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	volumesByName := make(map[string]unstructured.Unstructured)
+	for _, volume := range volumes.Items {
+		volumesByName[volume.GetName()] = volume
+	}
+
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	replicaNodes := make(map[string]map[string]bool) // volume -> nodes with a healthy replica
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if nodeID == "" || failedAt != "" {
+			continue
+		}
+		if replicaNodes[volumeName] == nil {
+			replicaNodes[volumeName] = make(map[string]bool)
+		}
+		replicaNodes[volumeName][nodeID] = true
+	}
+
+	var infos []StatefulSetPinningInfo
+	for _, podNamespace := range sortedKeys(podNamespaces) {
+		pods, err := listPods(clientset, podNamespace, activePodsFieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %v", podNamespace, err)
+		}
+
+		for _, pod := range pods.Items {
+			workload := podWorkload(pod)
+			if !strings.HasPrefix(workload, "StatefulSet/") {
+				continue
+			}
+			setName := strings.TrimPrefix(workload, "StatefulSet/")
+			ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, setName+"-"))
+			if err != nil {
+				ordinal = -1
+			}
+
+			for _, podVolume := range pod.Spec.Volumes {
+				if podVolume.PersistentVolumeClaim == nil {
+					continue
+				}
+				claim := podVolume.PersistentVolumeClaim.ClaimName
+				volumeName, found := claimVolumes[pod.Namespace+"/"+claim]
+				if !found {
+					continue
+				}
+
+				info := StatefulSetPinningInfo{
+					StatefulSet:  pod.Namespace + "/" + setName,
+					Ordinal:      ordinal,
+					PodName:      pod.Name,
+					Claim:        claim,
+					VolumeName:   volumeName,
+					PodNode:      pod.Spec.NodeName,
+					PodZone:      zones[pod.Spec.NodeName],
+					AllowedZones: podAllowedZones(pod),
+				}
+				volume := volumesByName[volumeName]
+				info.AccessMode, _, _ = unstructured.NestedString(volume.Object, "spec", "accessMode")
+				info.AttachedNode, _, _ = unstructured.NestedString(volume.Object, "status", "currentNodeID")
+
+				replicaZones := make(map[string]bool)
+				for node := range replicaNodes[volumeName] {
+					if zone := zones[node]; zone != "" {
+						replicaZones[zone] = true
+					}
+				}
+				info.ReplicaZones = sortedKeys(replicaZones)
+				info.Problem, info.Severity = pinningProblem(info, replicaZones, len(replicaNodes[volumeName]))
+
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	// Problems first, then by StatefulSet and ordinal
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Severity != infos[j].Severity {
+			return infos[i].Severity > infos[j].Severity
+		}
+		if infos[i].StatefulSet != infos[j].StatefulSet {
+			return infos[i].StatefulSet < infos[j].StatefulSet
+		}
+		if infos[i].Ordinal != infos[j].Ordinal {
+			return infos[i].Ordinal < infos[j].Ordinal
+		}
+		return infos[i].Claim < infos[j].Claim
+	})
+
+	return infos, nil
+}
+
+// pinningProblem explains why the volume of an ordinal does not align with its pod, empty when it does
+func pinningProblem(info StatefulSetPinningInfo, replicaZones map[string]bool, healthyReplicas int) (string, severity) {
+	switch {
+	case healthyReplicas == 0:
+		return "no healthy replica", severityCritical
+
+	case info.PodNode == "":
+		// A pending pod restricted to zones without a replica cannot start once it is scheduled
+		if info.AllowedZones != nil && len(replicaZones) > 0 {
+			for _, zone := range info.AllowedZones {
+				if replicaZones[zone] {
+					return "pod pending", severityWarning
+				}
+			}
+			return "pod pending, restricted to zones without a replica", severityCritical
+		}
+		return "pod pending", severityWarning
+
+	case info.AccessMode != "rwx" && info.AttachedNode != "" && info.AttachedNode != info.PodNode:
+		return fmt.Sprintf("attached to %s instead of the pod's node", info.AttachedNode), severityCritical
+
+	case info.PodZone != "" && len(replicaZones) > 0 && !replicaZones[info.PodZone]:
+		return fmt.Sprintf("no replica in zone %s of the pod", info.PodZone), severityWarning
+	}
+	return "", severityNone
+}
+
+// printStatefulSetPinning prints whether the volume of each StatefulSet ordinal is placed where its pod runs
+func printStatefulSetPinning(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	infos, err := getStatefulSetPinning(dynClient, clientset, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "STATEFULSET VOLUME PINNING",
+		Description: "Whether the volume of each StatefulSet ordinal is placed where its pod may run",
+		Color:       Cyan,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sSTATEFULSET\tORDINAL\tPVC\tVOLUME\tPOD NODE\tALLOWED ZONES\tATTACHED TO\tREPLICA ZONES\tSTATUS%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "STATEFULSET\tORDINAL\tPVC\tVOLUME\tPOD NODE\tALLOWED ZONES\tATTACHED TO\tREPLICA ZONES\tSTATUS")
+	}
+
+	fmt.Fprintln(w, "───────────\t───────\t───\t──────\t────────\t─────────────\t───────────\t─────────────\t──────")
+
+	for _, info := range infos {
+		ordinal := strconv.Itoa(info.Ordinal)
+		if info.Ordinal < 0 {
+			ordinal = "-"
+		}
+		podNode := info.PodNode
+		switch {
+		case podNode == "":
+			podNode = "-"
+		case info.PodZone != "":
+			podNode = fmt.Sprintf("%s (%s)", podNode, info.PodZone)
+		}
+		allowed := "any"
+		if info.AllowedZones != nil {
+			allowed = strings.Join(info.AllowedZones, ", ")
+		}
+		attached := info.AttachedNode
+		if attached == "" {
+			attached = "-"
+		}
+		replicaZones := strings.Join(info.ReplicaZones, ", ")
+		if replicaZones == "" {
+			replicaZones = "-"
+		}
+
+		status := colorize("aligned", Green)
+		if info.Problem != "" {
+			status = colorize(info.Problem, info.Severity.color())
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			info.StatefulSet,
+			ordinal,
+			info.Claim,
+			info.VolumeName,
+			podNode,
+			allowed,
+			attached,
+			replicaZones,
+			status,
+		)
+	}
+
+	if len(infos) == 0 {
+		fmt.Fprintln(w, "No StatefulSet volumes found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runStats collects the API statistics printed with --stats, nil when they are not collected
+var runStats *requestStats
+
+// requestStats counts the API requests of a run and the work done by each report section
+type requestStats struct {
+	requests atomic.Int64
+	objects  atomic.Int64
+	bytes    atomic.Int64
+
+	mu       sync.Mutex
+	sections []sectionStats
+}
+
+// sectionStats is the share of one report section in the statistics of a run
+type sectionStats struct {
+	Name     string
+	Started  time.Time
+	Duration time.Duration
+	Requests int64
+	Objects  int64
+	Bytes    int64
+}
+
+// startSection ends the current section and attributes everything from now on to the named one
+func (s *requestStats) startSection(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endSection()
+	s.sections = append(s.sections, sectionStats{
+		Name:     name,
+		Started:  time.Now(),
+		Requests: s.requests.Load(),
+		Objects:  s.objects.Load(),
+		Bytes:    s.bytes.Load(),
+	})
+}
+
+// endSection turns the counters recorded at the start of the current section into its share
+func (s *requestStats) endSection() {
+	n := len(s.sections)
+	if n == 0 || s.sections[n-1].Duration != 0 {
+		return
+	}
+	current := &s.sections[n-1]
+	current.Duration = time.Since(current.Started)
+	current.Requests = s.requests.Load() - current.Requests
+	current.Objects = s.objects.Load() - current.Objects
+	current.Bytes = s.bytes.Load() - current.Bytes
+}
+
+// addObjects counts objects returned by list calls, nil safe so callers need not check --stats
+func (s *requestStats) addObjects(n int) {
+	if s == nil {
+		return
+	}
+	s.objects.Add(int64(n))
+}
+
+// statsTransport counts the requests sent to the API server and the bytes of their bodies
+type statsTransport struct {
+	next  http.RoundTripper
+	stats *requestStats
+}
+
+// RoundTrip counts the request and wraps the response body to count the bytes read from it
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.requests.Add(1)
+	if req.ContentLength > 0 {
+		t.stats.bytes.Add(req.ContentLength)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, bytes: &t.stats.bytes}
+	return resp, nil
+}
+
+// countingBody adds the bytes read from a response body to a counter
+type countingBody struct {
+	io.ReadCloser
+	bytes *atomic.Int64
+}
+
+// Read reads from the body and counts the bytes read
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes.Add(int64(n))
+	return n, err
+}
+
+// takeSections ends the current section and returns the sections of the run, so the next run of
+// watch mode starts afresh
+func (s *requestStats) takeSections() []sectionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endSection()
+	sections := s.sections
+	s.sections = nil
+	return sections
+}
+
+// sumSections adds up the sections of a run
+func sumSections(sections []sectionStats) sectionStats {
+	total := sectionStats{Name: "total"}
+	for _, section := range sections {
+		total.Duration += section.Duration
+		total.Requests += section.Requests
+		total.Objects += section.Objects
+		total.Bytes += section.Bytes
+	}
+	return total
+}
+
+// printStats prints the requests, objects, bytes and time of every section as the report footer
+func printStats() {
+	if runStats == nil {
+		return
+	}
+	sections := runStats.takeSections()
+
+	printSectionHeader(Section{
+		Title:       "RUN STATISTICS",
+		Description: "API requests, objects processed, bytes transferred and time per section",
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sSECTION\tTIME\tREQUESTS\tOBJECTS\tBYTES%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "SECTION\tTIME\tREQUESTS\tOBJECTS\tBYTES")
+	}
+
+	fmt.Fprintln(w, "───────\t────\t────────\t───────\t─────")
+
+	for _, section := range append(sections, sumSections(sections)) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+			section.Name,
+			section.Duration.Round(time.Millisecond),
+			section.Requests,
+			section.Objects,
+			ByteSize(section.Bytes),
+		)
+	}
+
+	w.Flush()
+}
+
+// printStatsSummary prints the totals of the run on stderr, keeping structured output parseable
+func printStatsSummary() {
+	if runStats == nil {
+		return
+	}
+	total := sumSections(runStats.takeSections())
+	fmt.Fprintf(os.Stderr, "Stats: %d API requests, %d objects processed, %s transferred in %s\n",
+		total.Requests, total.Objects, ByteSize(total.Bytes), total.Duration.Round(time.Millisecond))
+}
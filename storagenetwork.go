@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Constants for the storage network check
+const (
+	storageNetworkSetting    = "storage-network"
+	storageNetworkInterface  = "lhnet1"
+	multusNetworkStatusAnnot = "k8s.v1.cni.cncf.io/network-status"
+	multusNetworksAnnot      = "k8s.v1.cni.cncf.io/networks"
+	instanceManagerPodLabel  = "longhorn.io/component=instance-manager"
+)
+
+// networkAttachmentDefinitionsGVR is the Multus NetworkAttachmentDefinition resource
+var networkAttachmentDefinitionsGVR = schema.GroupVersionResource{Group: "k8s.cni.cncf.io", Version: "v1", Resource: "network-attachment-definitions"}
+
+// StorageNetworkIssue describes a mismatch between the storage-network setting and the cluster
+type StorageNetworkIssue struct {
+	Pod   string
+	Node  string
+	Issue string
+}
+
+// multusNetworkStatus is a single entry of the Multus network-status pod annotation
+type multusNetworkStatus struct {
+	Name      string   `json:"name"`
+	Interface string   `json:"interface"`
+	IPs       []string `json:"ips"`
+}
+
+// checkStorageNetwork validates the storage-network setting against its NetworkAttachmentDefinition
+// and the interfaces attached to the instance-manager pods. It returns the configured network
+// ("" when the storage network is not configured) and the detected issues.
+func checkStorageNetwork(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) (string, []StorageNetworkIssue, error) {
+	network, err := getLonghornSetting(dynClient, namespace, storageNetworkSetting)
+	if err != nil {
+		return "", nil, err
+	}
+	if network == "" {
+		return "", nil, nil
+	}
+
+	var issues []StorageNetworkIssue
+
+	// The setting has the form <namespace>/<name>
+	parts := strings.Split(network, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		issues = append(issues, StorageNetworkIssue{Issue: fmt.Sprintf("Setting %s has invalid value %q, expected <namespace>/<name>", storageNetworkSetting, network)})
+		return network, issues, nil
+	}
+
+	// Verify the referenced NetworkAttachmentDefinition exists and has a valid CNI config
+	nad, err := dynClient.Resource(networkAttachmentDefinitionsGVR).Namespace(parts[0]).Get(context.TODO(), parts[1], metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			issues = append(issues, StorageNetworkIssue{Issue: fmt.Sprintf("NetworkAttachmentDefinition %s not found", network)})
+		} else {
+			issues = append(issues, StorageNetworkIssue{Issue: fmt.Sprintf("Unable to read NetworkAttachmentDefinition %s: %v", network, err)})
+		}
+	} else {
+		cniConfig, _, _ := unstructured.NestedString(nad.Object, "spec", "config")
+		var parsed map[string]interface{}
+		if cniConfig == "" {
+			issues = append(issues, StorageNetworkIssue{Issue: fmt.Sprintf("NetworkAttachmentDefinition %s has an empty CNI config", network)})
+		} else if err := json.Unmarshal([]byte(cniConfig), &parsed); err != nil {
+			issues = append(issues, StorageNetworkIssue{Issue: fmt.Sprintf("NetworkAttachmentDefinition %s has an invalid CNI config: %v", network, err)})
+		}
+	}
+
+	// Check that every instance-manager pod has the secondary interface
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: instanceManagerPodLabel})
+	if err != nil {
+		return network, issues, fmt.Errorf("failed to list instance-manager pods: %v", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+
+		requested := pod.Annotations[multusNetworksAnnot]
+		if !strings.Contains(requested, parts[1]) {
+			issues = append(issues, StorageNetworkIssue{
+				Pod:   pod.Name,
+				Node:  pod.Spec.NodeName,
+				Issue: fmt.Sprintf("Pod does not request network %s (restart the instance manager to apply the setting)", network),
+			})
+			continue
+		}
+
+		var statuses []multusNetworkStatus
+		if err := json.Unmarshal([]byte(pod.Annotations[multusNetworkStatusAnnot]), &statuses); err != nil {
+			issues = append(issues, StorageNetworkIssue{
+				Pod:   pod.Name,
+				Node:  pod.Spec.NodeName,
+				Issue: "Missing or unreadable Multus network-status annotation",
+			})
+			continue
+		}
+
+		attached := false
+		for _, status := range statuses {
+			if status.Name != network {
+				continue
+			}
+			attached = true
+			if status.Interface != storageNetworkInterface {
+				issues = append(issues, StorageNetworkIssue{
+					Pod:   pod.Name,
+					Node:  pod.Spec.NodeName,
+					Issue: fmt.Sprintf("Network %s attached as %s instead of %s", network, status.Interface, storageNetworkInterface),
+				})
+			} else if len(status.IPs) == 0 {
+				issues = append(issues, StorageNetworkIssue{
+					Pod:   pod.Name,
+					Node:  pod.Spec.NodeName,
+					Issue: fmt.Sprintf("Interface %s has no IP address", storageNetworkInterface),
+				})
+			}
+		}
+
+		if !attached {
+			issues = append(issues, StorageNetworkIssue{
+				Pod:   pod.Name,
+				Node:  pod.Spec.NodeName,
+				Issue: fmt.Sprintf("Secondary interface for %s not present, replication traffic uses the pod network", network),
+			})
+		}
+	}
+
+	return network, issues, nil
+}
+
+// printStorageNetworkCheck prints the result of the storage network check
+func printStorageNetworkCheck(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) {
+	network, issues, err := checkStorageNetwork(dynClient, clientset, namespace)
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "STORAGE NETWORK",
+		Description: "Storage network setting versus Multus attachments on instance managers",
+		Color:       Red,
+	})
+
+	if err != nil {
+		fmt.Printf("Error checking storage network: %v\n", err)
+	}
+
+	if network == "" {
+		if err == nil {
+			fmt.Println("Storage network not configured, replication uses the pod network")
+		}
+		return
+	}
+
+	fmt.Printf("Configured network: %s\n", colorize(network, Cyan))
+
+	// Setup tabwriter
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sPOD\tNODE\tISSUE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "POD\tNODE\tISSUE")
+	}
+
+	fmt.Fprintln(w, "───\t────\t─────")
+
+	for _, issue := range issues {
+		pod := issue.Pod
+		if pod == "" {
+			pod = "-"
+		}
+		node := issue.Node
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", pod, node, colorize(issue.Issue, Red))
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No storage network issues found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// reclaimableCategories are the summary lines, in display order
+var reclaimableCategories = []struct {
+	category string
+	label    string
+}{
+	{"volume", "Released or failed volumes"},
+	{"detached", "Detached volumes"},
+	{"replica", "Failed replicas"},
+	{"orphan", "Orphaned replica data"},
+	{"snapshot", "Prunable snapshots"},
+}
+
+// getReclaimableSpace estimates the disk space held by released, failed and detached volumes,
+// failed replicas, orphans and removed snapshots, per category
+func getReclaimableSpace(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) (map[string]ByteSize, map[string]int, error) {
+	items, err := collectHousekeeping(dynClient, namespace, pvInfoMap, housekeepingOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sizes := make(map[string]ByteSize)
+	counts := make(map[string]int)
+	listed := make(map[string]bool)
+	for _, item := range items {
+		sizes[item.Category] += item.Reclaimable
+		counts[item.Category]++
+		if item.Category == "volume" {
+			listed[item.Name] = true
+		}
+	}
+
+	// Detached volumes still hold their data on every replica, standby volumes are detached by design
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	replicaCount := make(map[string]int)
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		replicaCount[volumeName]++
+	}
+	for _, volume := range volumes.Items {
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		if state != "detached" || longhorn.IsStandby(volume) || listed[volume.GetName()] {
+			continue
+		}
+		actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+		sizes["detached"] += ByteSize(actualSize) * ByteSize(replicaCount[volume.GetName()])
+		counts["detached"]++
+	}
+
+	return sizes, counts, nil
+}
+
+// printSummary prints the overall reclaimable space figure
+func printSummary(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource, pvInfoMap map[string]PersistentVolumeInfo) {
+	sizes, counts, err := getReclaimableSpace(dynClient, namespace, volumesGVR, pvInfoMap)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "SUMMARY",
+		Description: "Estimated reclaimable disk space, across all replicas",
+		Color:       Green,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sSOURCE\tITEMS\tRECLAIMABLE%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "SOURCE\tITEMS\tRECLAIMABLE")
+	}
+
+	fmt.Fprintln(w, "──────\t─────\t───────────")
+
+	var total ByteSize
+	for _, c := range reclaimableCategories {
+		total += sizes[c.category]
+		fmt.Fprintf(w, "%s\t%d\t%s\n", c.label, counts[c.category], sizes[c.category])
+	}
+	fmt.Fprintf(w, "%s\t\t%s\n", colorize("Total", Bold), colorize(total.String(), Bold+Green))
+
+	w.Flush()
+
+	if counts["orphan"] > 0 {
+		fmt.Println("Orphaned replica data is not sized by Longhorn and is not included in the total")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// Table styles selectable with --table-style
+const (
+	tableStylePlain    = "plain"
+	tableStyleUnicode  = "unicode"
+	tableStyleMarkdown = "markdown"
+	tableStyleGithub   = "github"
+)
+
+// tableStyle is the style every table is rendered in
+var tableStyle = tableStylePlain
+
+// ansiEscape matches the color escape sequences written into table cells
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// setTableStyle validates and selects the table style
+func setTableStyle(style string) error {
+	switch style {
+	case tableStylePlain, tableStyleUnicode, tableStyleMarkdown, tableStyleGithub:
+		tableStyle = style
+		return nil
+	}
+	return fmt.Errorf("unknown table style %q, expected plain, unicode, markdown or github", style)
+}
+
+// tableWriter collects tab-separated rows and renders them in the selected table style on Flush.
+// A row made of "─" runs marks the row before it as the header.
+type tableWriter struct {
+	buf bytes.Buffer
+}
+
+// newTableWriter creates a table writer printing to stdout
+func newTableWriter() *tableWriter {
+	return &tableWriter{}
+}
+
+// Write buffers table rows until Flush
+func (t *tableWriter) Write(p []byte) (int, error) {
+	return t.buf.Write(p)
+}
+
+// Flush renders the buffered rows and resets the writer
+func (t *tableWriter) Flush() error {
+	defer t.buf.Reset()
+
+	if tableStyle == tableStylePlain {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.TabIndent)
+		w.Write(t.buf.Bytes())
+		return w.Flush()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(t.buf.String(), "\n"), "\n")
+
+	// Split the rows into tables at every header, other lines are printed as they are
+	var header []string
+	var rows [][]string
+	var notes []string
+	for i, line := range lines {
+		if isTableSeparator(line) {
+			continue
+		}
+		if i+1 < len(lines) && isTableSeparator(lines[i+1]) {
+			renderTable(header, rows, notes)
+			header, rows, notes = strings.Split(line, "\t"), nil, nil
+			continue
+		}
+		if header != nil && !strings.Contains(line, "\t") && len(header) > 1 {
+			notes = append(notes, line)
+			continue
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	renderTable(header, rows, notes)
+
+	return nil
+}
+
+// isTableSeparator returns true for the "────" rows printed below table headers
+func isTableSeparator(line string) bool {
+	line = strings.ReplaceAll(line, "\t", "")
+	return line != "" && strings.Trim(line, "─") == ""
+}
+
+// renderTable prints a single table in the selected style followed by its free-form notes
+func renderTable(header []string, rows [][]string, notes []string) {
+	if header == nil && len(rows) == 0 && len(notes) == 0 {
+		return
+	}
+
+	markdown := tableStyle == tableStyleMarkdown || tableStyle == tableStyleGithub
+
+	// Header colors are reapplied per cell, Markdown is meant to be pasted and carries no colors
+	clean := func(cell string) string {
+		if markdown {
+			return strings.ReplaceAll(ansiEscape.ReplaceAllString(cell, ""), "|", "\\|")
+		}
+		return cell
+	}
+	for i := range header {
+		header[i] = ansiEscape.ReplaceAllString(header[i], "")
+	}
+
+	columns := len(header)
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	cell := func(row []string, i int) string {
+		if i < len(row) {
+			return clean(row[i])
+		}
+		return ""
+	}
+
+	if columns == 0 {
+		for _, note := range notes {
+			fmt.Println(clean(note))
+		}
+		return
+	}
+
+	widths := make([]int, columns)
+	for i := 0; i < columns; i++ {
+		widths[i] = visibleWidth(cell(header, i))
+		for _, row := range rows {
+			if width := visibleWidth(cell(row, i)); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	pad := func(text string, width int) string {
+		return text + strings.Repeat(" ", width-visibleWidth(text))
+	}
+
+	switch tableStyle {
+	case tableStyleUnicode:
+		border := func(left, middle, right string) {
+			parts := make([]string, columns)
+			for i, width := range widths {
+				parts[i] = strings.Repeat("─", width+2)
+			}
+			fmt.Println(left + strings.Join(parts, middle) + right)
+		}
+		line := func(row []string, color string) {
+			parts := make([]string, columns)
+			for i, width := range widths {
+				text := cell(row, i)
+				parts[i] = " " + colorize(text, color) + strings.Repeat(" ", width-visibleWidth(text)) + " "
+			}
+			fmt.Println("│" + strings.Join(parts, "│") + "│")
+		}
+
+		border("┌", "┬", "┐")
+		if header != nil {
+			line(header, Bold+Yellow)
+			border("├", "┼", "┤")
+		}
+		for _, row := range rows {
+			line(row, "")
+		}
+		border("└", "┴", "┘")
+
+	case tableStyleMarkdown, tableStyleGithub:
+		// GitHub style is compact, Markdown style aligns the columns for reading as plain text
+		aligned := tableStyle == tableStyleMarkdown
+		line := func(row []string) {
+			parts := make([]string, columns)
+			for i, width := range widths {
+				parts[i] = cell(row, i)
+				if aligned {
+					parts[i] = pad(parts[i], width)
+				}
+			}
+			fmt.Println("| " + strings.Join(parts, " | ") + " |")
+		}
+
+		if header == nil {
+			header = make([]string, columns)
+		}
+		line(header)
+		separator := make([]string, columns)
+		for i, width := range widths {
+			separator[i] = "---"
+			if aligned && width > 3 {
+				separator[i] = strings.Repeat("-", width)
+			}
+		}
+		fmt.Println("| " + strings.Join(separator, " | ") + " |")
+		for _, row := range rows {
+			line(row)
+		}
+
+		// A blank line ends the Markdown table, otherwise notes would be read as rows
+		if len(notes) > 0 {
+			fmt.Println()
+		}
+	}
+
+	for _, note := range notes {
+		fmt.Println(clean(note))
+	}
+}
+
+// visibleWidth returns the number of characters of a cell as shown on screen, ignoring color codes
+func visibleWidth(text string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(text, ""))
+}
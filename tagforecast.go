@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pascal71/lhmon4/pkg/longhorn"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// TagForecast is the capacity behind one disk tag and when it runs out at its recent rate
+type TagForecast struct {
+	Tag         string // Empty for untagged disks
+	Disks       int
+	Volumes     int      // Volumes whose disk selector requires the tag
+	VolumeSize  ByteSize // Total size of those volumes
+	Maximum     ByteSize
+	Available   ByteSize
+	Schedulable ByteSize // Room left for new replicas under the over-provisioning settings
+
+	FullIn          time.Duration // Zero when available space is not shrinking
+	UnschedulableIn time.Duration // Zero when schedulable space is not shrinking
+	Period          time.Duration
+	Measured        bool // False while the history is too short for a rate
+}
+
+// forecastRunOut returns when space runs out at the rate measured over its samples, zero when it
+// is not shrinking
+func forecastRunOut(samples []sizeSample, now time.Time, current ByteSize) (time.Duration, time.Duration, bool) {
+	rate, period, ok := sampleRate(samples, now, current)
+	if !ok || rate >= 0 {
+		return 0, period, ok
+	}
+	return time.Duration(float64(current) / -rate * float64(24*time.Hour)), period, true
+}
+
+// forecastTagCapacity sums the capacity of the disks by disk tag, records it in the history store
+// and forecasts when each tag runs out of available and schedulable space
+func forecastTagCapacity(dynClient dynamic.Interface, namespace string) ([]TagForecast, error) {
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	overProvisioning, minimalAvailable, err := getSchedulingSettings(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := make(map[string]*TagForecast)
+	tier := func(tag string) *TagForecast {
+		if tiers[tag] == nil {
+			tiers[tag] = &TagForecast{Tag: tag}
+		}
+		return tiers[tag]
+	}
+
+	for _, node := range nodes.Items {
+		unschedulable := nodeUnschedulable(node) != ""
+		disksMap, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		for diskName, d := range disksMap {
+			diskSpec, _ := d.(map[string]interface{})
+			diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus", diskName)
+			diskTags, _, _ := unstructured.NestedStringSlice(diskSpec, "tags")
+
+			maximum, _ := longhorn.Float64(diskStatus, "storageMaximum")
+			available, _ := longhorn.Float64(diskStatus, "storageAvailable")
+			scheduled, _ := longhorn.Float64(diskStatus, "storageScheduled")
+			reserved, _ := longhorn.Float64(diskSpec, "storageReserved")
+			var schedulable ByteSize
+			if !unschedulable && diskAcceptsReplicas(diskSpec, diskStatus) {
+				schedulable = diskSchedulableSize(ByteSize(maximum), ByteSize(reserved), ByteSize(scheduled), ByteSize(available), overProvisioning, minimalAvailable)
+			}
+
+			// Untagged capacity is reported with an empty tag
+			if len(diskTags) == 0 {
+				diskTags = []string{""}
+			}
+			for _, tag := range diskTags {
+				t := tier(tag)
+				t.Disks++
+				t.Maximum += ByteSize(maximum)
+				t.Available += ByteSize(available)
+				t.Schedulable += schedulable
+			}
+		}
+	}
+
+	// Volumes depending on a tag, including tags no disk carries
+	for _, item := range volumes.Items {
+		volume := longhorn.ParseVolume(item)
+		for _, tag := range volume.DiskSelector {
+			t := tier(tag)
+			t.Volumes++
+			t.VolumeSize += ByteSize(volume.Size)
+		}
+	}
+
+	state, err := sharedState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	forecasts := make([]TagForecast, 0, len(tiers))
+	for tag, t := range tiers {
+		recorded := state.Tags[tag]
+		t.FullIn, t.Period, t.Measured = forecastRunOut(recorded.Available, now, t.Available)
+		t.UnschedulableIn, _, _ = forecastRunOut(recorded.Schedulable, now, t.Schedulable)
+		recorded.Available = recordSample(recorded.Available, now, t.Available)
+		recorded.Schedulable = recordSample(recorded.Schedulable, now, t.Schedulable)
+		state.Tags[tag] = recorded
+		forecasts = append(forecasts, *t)
+	}
+
+	if err := state.save(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(forecasts, func(i, j int) bool {
+		return forecasts[i].Tag < forecasts[j].Tag
+	})
+	return forecasts, nil
+}
+
+// formatRunOut formats a forecast, red when it falls within the horizon
+func formatRunOut(d time.Duration, measured bool, horizon time.Duration) string {
+	switch {
+	case !measured:
+		return "collecting"
+	case d == 0:
+		return "-"
+	case d <= horizon:
+		return colorize(formatDuration(d), Red)
+	}
+	return formatDuration(d)
+}
+
+// printTagForecast prints the capacity of every disk tag and when it runs out, for procurement per tier
+func printTagForecast(dynClient dynamic.Interface, namespace string, horizon time.Duration) {
+	forecasts, err := forecastTagCapacity(dynClient, namespace)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "DISK TAG CAPACITY FORECAST",
+		Description: fmt.Sprintf("Capacity behind each disk tag and when it runs out at its recent rate, red within %s", formatDuration(horizon)),
+		Color:       Blue,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sTAG\tDISKS\tVOLUMES\tVOLUME SIZE\tMAX\tAVAILABLE\tSCHEDULABLE\tFULL IN\tUNSCHEDULABLE IN\tMEASURED OVER%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "TAG\tDISKS\tVOLUMES\tVOLUME SIZE\tMAX\tAVAILABLE\tSCHEDULABLE\tFULL IN\tUNSCHEDULABLE IN\tMEASURED OVER")
+	}
+
+	fmt.Fprintln(w, "───\t─────\t───────\t───────────\t───\t─────────\t───────────\t───────\t────────────────\t─────────────")
+
+	for _, f := range forecasts {
+		tag := f.Tag
+		if tag == "" {
+			tag = "(untagged)"
+		}
+		disks := fmt.Sprintf("%d", f.Disks)
+		if f.Disks == 0 {
+			// Volumes requiring a tag no disk carries cannot be scheduled at all
+			disks = colorize(disks, Red)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			tag,
+			disks,
+			f.Volumes,
+			f.VolumeSize,
+			f.Maximum,
+			f.Available,
+			f.Schedulable,
+			formatRunOut(f.FullIn, f.Measured, horizon),
+			formatRunOut(f.UnschedulableIn, f.Measured, horizon),
+			f.Period.Round(time.Minute),
+		)
+	}
+
+	if len(forecasts) == 0 {
+		fmt.Fprintln(w, "No disks found")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Output formats taking an expression, -o go-template='{{...}}' and -o jsonpath='{...}'
+const (
+	outputGoTemplate = "go-template"
+	outputJSONPath   = "jsonpath"
+)
+
+// Expressions of -o go-template and -o jsonpath
+var (
+	outputTemplate *template.Template
+	outputPath     *jsonpath.JSONPath
+)
+
+// templateNumber converts a field of the -o json form to a number
+func templateNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
+
+// templateResult returns integral sums as integers, so byte counts print without an exponent
+func templateResult(sum float64) interface{} {
+	if sum == float64(int64(sum)) {
+		return int64(sum)
+	}
+	return sum
+}
+
+// templateFuncs are the functions available to -o go-template besides the built-in ones
+var templateFuncs = template.FuncMap{
+	// add sums numbers, e.g. {{$free = add $free .storageAvailable}}
+	"add": func(values ...interface{}) (interface{}, error) {
+		var sum float64
+		for _, value := range values {
+			n, err := templateNumber(value)
+			if err != nil {
+				return nil, err
+			}
+			sum += n
+		}
+		return templateResult(sum), nil
+	},
+	// has reports whether a list contains a value, e.g. {{if has .tags "nvme"}}
+	"has": func(list interface{}, value interface{}) bool {
+		items, _ := list.([]interface{}) // Empty lists are null in the -o json form
+		for _, item := range items {
+			if item == value {
+				return true
+			}
+		}
+		return false
+	},
+	// bytes renders a byte count like the tables, e.g. {{bytes .storageAvailable}}
+	"bytes": func(value interface{}) (string, error) {
+		n, err := templateNumber(value)
+		if err != nil {
+			return "", err
+		}
+		return ByteSize(n).String(), nil
+	},
+}
+
+// setOutputTemplate parses the template of -o go-template=
+func setOutputTemplate(text string) error {
+	tmpl, err := template.New(outputGoTemplate).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid go-template: %v", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// setOutputPath parses the expression of -o jsonpath=. Missing keys render as nothing, like kubectl.
+func setOutputPath(expression string) error {
+	path := jsonpath.New(outputJSONPath).AllowMissingKeys(true)
+	if err := path.Parse(expression); err != nil {
+		return fmt.Errorf("invalid jsonpath: %v", err)
+	}
+	outputPath = path
+	return nil
+}
+
+// documentData returns the -o json form of the report the expressions are evaluated against
+func documentData(doc reportDocument) (interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// renderGoTemplate executes the -o go-template template against the report
+func renderGoTemplate(doc reportDocument) error {
+	data, err := documentData(doc)
+	if err != nil {
+		return err
+	}
+	return outputTemplate.Execute(os.Stdout, data)
+}
+
+// renderJSONPath evaluates the -o jsonpath expression against the report
+func renderJSONPath(doc reportDocument) error {
+	data, err := documentData(doc)
+	if err != nil {
+		return err
+	}
+	return outputPath.Execute(os.Stdout, data)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+// enableANSI reports whether the terminal interprets ANSI escape sequences, which Unix terminals always do
+func enableANSI() bool {
+	return true
+}
+
+// clearScreenFallback is never used on Unix, where clearing relies on ANSI escape sequences
+func clearScreenFallback() {}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableANSI turns on virtual terminal processing so the console interprets ANSI escape sequences.
+// It returns false on consoles that predate Windows 10 and cannot process them.
+func enableANSI() bool {
+	handle := windows.Handle(os.Stdout.Fd())
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		// Not a console, e.g. output redirected to a file or a mintty pipe that handles ANSI itself
+		return true
+	}
+
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// clearScreenFallback clears the console on terminals without ANSI support
+func clearScreenFallback() {
+	cmd := exec.Command("cmd", "/c", "cls")
+	cmd.Stdout = os.Stdout
+	cmd.Run()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// textfilePath is the file written with --textfile for the node_exporter textfile collector
+var textfilePath string
+
+// writeOpenMetrics renders metric families in the OpenMetrics text format. Unlike the Prometheus
+// format, counter families are named without their _total suffix and the exposition ends with # EOF.
+func writeOpenMetrics(out io.Writer, families []*metricFamily) error {
+	w := bufio.NewWriter(out)
+	for _, family := range families {
+		name := family.Name
+		if family.Type == "counter" {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, family.Type)
+		fmt.Fprintf(w, "# HELP %s %s\n", name, family.Help)
+		for _, sample := range family.Samples {
+			w.WriteString(family.Name)
+			if len(sample.Labels) > 0 {
+				w.WriteString("{")
+				for i := 0; i+1 < len(sample.Labels); i += 2 {
+					if i > 0 {
+						w.WriteString(",")
+					}
+					fmt.Fprintf(w, `%s="%s"`, sample.Labels[i], labelEscaper.Replace(sample.Labels[i+1]))
+				}
+				w.WriteString("}")
+			}
+			fmt.Fprintf(w, " %s\n", strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		}
+	}
+	w.WriteString("# EOF\n")
+	return w.Flush()
+}
+
+// writeTextfile writes the capacity metrics of the exporter to --textfile. The file is written
+// next to its destination and renamed into place, so the collector never reads a partial file.
+func writeTextfile(dynClient dynamic.Interface, clientset *kubernetes.Clientset, namespace string) error {
+	families, err := collectMetrics(dynClient, clientset, namespace)
+	if err != nil {
+		return err
+	}
+	lastRun := &metricFamily{Name: "lhmon_textfile_last_run_timestamp_seconds", Help: "Time lhmon4 last wrote this file.", Type: "gauge"}
+	lastRun.add(float64(time.Now().Unix()))
+	families = append(families, lastRun)
+
+	// The collector only reads *.prom files, so the temporary file is not picked up half written
+	tmp, err := os.CreateTemp(filepath.Dir(textfilePath), "."+filepath.Base(textfilePath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write textfile: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeOpenMetrics(tmp, families); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write textfile: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write textfile: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write textfile: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), textfilePath); err != nil {
+		return fmt.Errorf("failed to write textfile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// robustnessTransition is a change of volume robustness, e.g. healthy to degraded
+type robustnessTransition struct {
+	Volume, From, To string
+}
+
+// transitionCounter counts volume robustness transitions and completed rebuilds seen on the watch
+// stream, so alerts can fire on rates of degradation instead of only on the current state
+type transitionCounter struct {
+	mu          sync.Mutex
+	robustness  map[string]string            // Last seen robustness per volume
+	modes       map[string]map[string]string // Last seen replica modes per engine
+	transitions map[robustnessTransition]float64
+	rebuilds    map[string]float64 // Completed rebuilds per volume
+}
+
+// newTransitionCounter returns an empty counter
+func newTransitionCounter() *transitionCounter {
+	return &transitionCounter{
+		robustness:  make(map[string]string),
+		modes:       make(map[string]map[string]string),
+		transitions: make(map[robustnessTransition]float64),
+		rebuilds:    make(map[string]float64),
+	}
+}
+
+// follow consumes volume and engine events until the channel closes
+func (c *transitionCounter) follow(events <-chan followEvent) {
+	for event := range events {
+		c.observe(event)
+	}
+}
+
+// observe records a volume or engine event. The first sighting of an object only sets the
+// baseline, so restarting the exporter or re-establishing a watch counts nothing.
+func (c *transitionCounter) observe(event followEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := event.Object.GetName()
+	switch event.Kind {
+	case "volume":
+		if event.Type == watch.Deleted {
+			delete(c.robustness, name)
+			return
+		}
+		robustness, _, _ := unstructured.NestedString(event.Object.Object, "status", "robustness")
+		if previous, seen := c.robustness[name]; seen && previous != robustness {
+			c.transitions[robustnessTransition{Volume: name, From: previous, To: robustness}]++
+		}
+		c.robustness[name] = robustness
+
+	case "engine":
+		if event.Type == watch.Deleted {
+			delete(c.modes, name)
+			return
+		}
+		modes, _, _ := unstructured.NestedStringMap(event.Object.Object, "status", "replicaModeMap")
+		if previous, seen := c.modes[name]; seen {
+			volumeName, _, _ := unstructured.NestedString(event.Object.Object, "spec", "volumeName")
+			// A rebuilding replica is write-only until it has caught up
+			for replica, mode := range modes {
+				if mode == "RW" && previous[replica] == "WO" {
+					c.rebuilds[volumeName]++
+				}
+			}
+		}
+		c.modes[name] = modes
+	}
+}
+
+// families returns the counters as metric families
+func (c *transitionCounter) families() []*metricFamily {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	transitions := &metricFamily{Name: "lhmon_volume_robustness_transitions_total", Help: "Robustness changes of a Longhorn volume since the exporter started.", Type: "counter"}
+	keys := make([]robustnessTransition, 0, len(c.transitions))
+	for key := range c.transitions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Volume != keys[j].Volume {
+			return keys[i].Volume < keys[j].Volume
+		}
+		if keys[i].From != keys[j].From {
+			return keys[i].From < keys[j].From
+		}
+		return keys[i].To < keys[j].To
+	})
+	for _, key := range keys {
+		transitions.add(c.transitions[key], "volume", key.Volume, "from", key.From, "to", key.To)
+	}
+
+	rebuilds := &metricFamily{Name: "lhmon_volume_rebuilds_completed_total", Help: "Replica rebuilds of a Longhorn volume completed since the exporter started.", Type: "counter"}
+	volumes := make([]string, 0, len(c.rebuilds))
+	for volume := range c.rebuilds {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	for _, volume := range volumes {
+		rebuilds.add(c.rebuilds[volume], "volume", volume)
+	}
+
+	return []*metricFamily{transitions, rebuilds}
+}
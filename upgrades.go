@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// EngineUpgradeInfo stores the state of a volume whose engine image is being upgraded
+type EngineUpgradeInfo struct {
+	VolumeName     string
+	State          string
+	FromImage      string
+	ToImage        string
+	EngineUpgraded bool
+	Replicas       int
+	Upgraded       int    // Replicas running the target image
+	StuckReason    string // Empty while the upgrade is progressing
+}
+
+// Progress returns the share of the engine and replicas running the target image
+func (u EngineUpgradeInfo) Progress() int64 {
+	done := u.Upgraded
+	if u.EngineUpgraded {
+		done++
+	}
+	return int64(100 * done / (u.Replicas + 1))
+}
+
+// volumeImages returns the desired and current engine image of a volume
+func volumeImages(volume unstructured.Unstructured) (string, string) {
+	// Longhorn renamed spec.engineImage to spec.image
+	image, _, _ := unstructured.NestedString(volume.Object, "spec", "image")
+	if image == "" {
+		image, _, _ = unstructured.NestedString(volume.Object, "spec", "engineImage")
+	}
+	current, _, _ := unstructured.NestedString(volume.Object, "status", "currentImage")
+	return image, current
+}
+
+// getEngineUpgrades finds volumes whose current engine image differs from the desired one
+func getEngineUpgrades(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) ([]EngineUpgradeInfo, error) {
+	volumes, err := listAll(dynClient, volumesGVR, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+
+	var upgrading []unstructured.Unstructured
+	for _, volume := range volumes.Items {
+		image, current := volumeImages(volume)
+		if image != "" && current != "" && image != current {
+			upgrading = append(upgrading, volume)
+		}
+	}
+
+	// Engines and replicas are only needed once an upgrade has been found
+	if len(upgrading) == 0 {
+		return nil, nil
+	}
+
+	engines, err := listEnginesByVolume(dynClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	replicaList, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+	replicas := make(map[string][]unstructured.Unstructured)
+	for _, replica := range replicaList.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		replicas[volumeName] = append(replicas[volumeName], replica)
+	}
+
+	var upgrades []EngineUpgradeInfo
+	for _, volume := range upgrading {
+		volumeName := volume.GetName()
+		image, current := volumeImages(volume)
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		robustness, _, _ := unstructured.NestedString(volume.Object, "status", "robustness")
+
+		info := EngineUpgradeInfo{
+			VolumeName: volumeName,
+			State:      state,
+			FromImage:  current,
+			ToImage:    image,
+		}
+
+		var stuck []string
+		if engine, found := engines[volumeName]; found {
+			engineImage, _, _ := unstructured.NestedString(engine.Object, "status", "currentImage")
+			info.EngineUpgraded = engineImage == image
+
+			engineState, _, _ := unstructured.NestedString(engine.Object, "status", "currentState")
+			if state == "attached" && engineState != "running" {
+				stuck = append(stuck, "engine is "+engineState)
+			}
+		}
+
+		for _, replica := range replicas[volumeName] {
+			info.Replicas++
+			replicaImage, _, _ := unstructured.NestedString(replica.Object, "status", "currentImage")
+			if replicaImage == image {
+				info.Upgraded++
+			}
+
+			replicaState, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+			failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+			if replicaState == "error" || failedAt != "" {
+				stuck = append(stuck, "replica "+replica.GetName()+" failed")
+			}
+		}
+
+		// A live upgrade needs every replica healthy, detached volumes upgrade on the next attach
+		if state == "attached" && robustness != "healthy" {
+			stuck = append(stuck, "volume is "+robustness)
+		}
+		if state == "detached" {
+			stuck = append(stuck, "volume is detached, attach it to finish the upgrade")
+		}
+
+		info.StuckReason = strings.Join(stuck, "; ")
+		upgrades = append(upgrades, info)
+	}
+
+	sort.Slice(upgrades, func(i, j int) bool {
+		return upgrades[i].VolumeName < upgrades[j].VolumeName
+	})
+
+	return upgrades, nil
+}
+
+// printEngineUpgrades prints progressing and stuck engine upgrades as separate tables
+func printEngineUpgrades(dynClient dynamic.Interface, namespace string, volumesGVR schema.GroupVersionResource) {
+	upgrades, err := getEngineUpgrades(dynClient, namespace, volumesGVR)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	var progressing, stuck []EngineUpgradeInfo
+	for _, upgrade := range upgrades {
+		if upgrade.StuckReason != "" {
+			stuck = append(stuck, upgrade)
+		} else {
+			progressing = append(progressing, upgrade)
+		}
+	}
+
+	// Print section header
+	printSectionHeader(Section{
+		Title:       "ENGINE UPGRADES",
+		Description: "Volumes whose engine image differs from the desired image",
+		Color:       Blue,
+	})
+
+	if len(upgrades) == 0 {
+		fmt.Println(colorize("No engine upgrades in progress", Green))
+		return
+	}
+
+	w := newTableWriter()
+
+	if len(progressing) > 0 {
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sVOLUME\tFROM\tTO\tENGINE\tREPLICAS\tPROGRESS%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "VOLUME\tFROM\tTO\tENGINE\tREPLICAS\tPROGRESS")
+		}
+
+		fmt.Fprintln(w, "──────\t────\t──\t──────\t────────\t────────")
+
+		for _, upgrade := range progressing {
+			engine := "pending"
+			if upgrade.EngineUpgraded {
+				engine = "upgraded"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+				colorize(upgrade.VolumeName, Blue),
+				upgrade.FromImage,
+				upgrade.ToImage,
+				engine,
+				upgrade.Upgraded,
+				upgrade.Replicas,
+				colorize(progressBar(upgrade.Progress(), 20), Yellow),
+			)
+		}
+		w.Flush()
+	}
+
+	if len(stuck) > 0 {
+		if len(progressing) > 0 {
+			fmt.Println()
+		}
+
+		// Print header
+		if useColors {
+			fmt.Fprintf(w, "%s%sSTUCK VOLUME\tSTATE\tTO\tREPLICAS\tPROGRESS\tREASON%s\n", Bold, Yellow, Reset)
+		} else {
+			fmt.Fprintln(w, "STUCK VOLUME\tSTATE\tTO\tREPLICAS\tPROGRESS\tREASON")
+		}
+
+		fmt.Fprintln(w, "────────────\t─────\t──\t────────\t────────\t──────")
+
+		for _, upgrade := range stuck {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\t%s\n",
+				colorize(upgrade.VolumeName, Red),
+				upgrade.State,
+				upgrade.ToImage,
+				upgrade.Upgraded,
+				upgrade.Replicas,
+				colorize(progressBar(upgrade.Progress(), 20), Red),
+				colorize(withRunbook(upgrade.StuckReason, "engine-upgrade"), Red),
+			)
+		}
+		w.Flush()
+	}
+}
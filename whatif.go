@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Components a rolling restart can be estimated for
+const (
+	restartManager         = "longhorn-manager"
+	restartInstanceManager = "instance-manager"
+)
+
+// Impact of restarting a component on a node on one volume
+const (
+	impactPause    = "I/O pause"
+	impactRebuild  = "replica rebuild"
+	impactFaulted  = "faulted"
+	impactStalled  = "operation delayed"
+	impactNoEffect = "none"
+)
+
+// restartVolumeImpact is what restarting a component on one node means for a volume
+type restartVolumeImpact struct {
+	VolumeName string
+	Size       ByteSize // Data copied by a rebuild, the actual size of the volume
+	Impacts    []string
+}
+
+// restartStep is the restart of the component on one node
+type restartStep struct {
+	NodeName string
+	Volumes  []restartVolumeImpact
+}
+
+// rebuildSize returns the data rebuilt after the step
+func (s restartStep) rebuildSize() ByteSize {
+	var size ByteSize
+	for _, volume := range s.Volumes {
+		for _, impact := range volume.Impacts {
+			if impact == impactRebuild {
+				size += volume.Size
+			}
+		}
+	}
+	return size
+}
+
+// restartComponent maps the accepted --rolling-restart targets to a component
+func restartComponent(target string) (string, error) {
+	switch strings.ToLower(target) {
+	case "daemonset/longhorn-manager", "ds/longhorn-manager", "longhorn-manager":
+		return restartManager, nil
+	case "instance-manager", "instance-managers", "instancemanager", "instancemanagers", "instancemanagers.longhorn.io":
+		return restartInstanceManager, nil
+	}
+	return "", fmt.Errorf("unsupported restart target %q, expected daemonset/longhorn-manager or instance-managers", target)
+}
+
+// runWhatIf implements the "lhmon4 whatif" subcommand
+func runWhatIf(args []string) {
+	fs := flag.NewFlagSet("whatif", flag.ExitOnError)
+	clientOpts := registerClientFlags(fs)
+	registerFormatFlags(fs)
+	rollingRestart := fs.String("rolling-restart", "", "estimate the impact of restarting daemonset/longhorn-manager or the instance-managers one node at a time")
+	order := fs.String("order", "", "comma separated node order of the restart (default all Longhorn nodes by name)")
+	nocolor := fs.Bool("nocolor", false, "disable color output")
+	parseFlags(fs, args)
+
+	useColors = !*nocolor && ansiSupported
+
+	if *rollingRestart == "" {
+		fmt.Printf("Usage: %s whatif --rolling-restart daemonset/longhorn-manager|instance-managers [--order node1,node2,...] [flags]\n", commandName)
+		os.Exit(2)
+	}
+	component, err := restartComponent(*rollingRestart)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dynClient, _, err := clientOpts.newClients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var nodeOrder []string
+	if *order != "" {
+		nodeOrder = splitTags(*order)
+	}
+	steps, err := estimateRollingRestart(dynClient, *clientOpts.namespace, component, nodeOrder)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printRollingRestart(component, steps)
+}
+
+// estimateRollingRestart estimates the impact of restarting a component on each node in turn.
+// Engines and replicas run in the instance managers, so restarting them interrupts the volumes
+// attached to the node and rebuilds the replicas it hosts. Restarting longhorn-manager leaves the
+// data path alone and only delays attach, detach and rebuild operations it drives on the node.
+func estimateRollingRestart(dynClient dynamic.Interface, namespace, component string, nodeOrder []string) ([]restartStep, error) {
+	nodes, err := listAll(dynClient, longhornGVR(longhornNodes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn nodes: %v", err)
+	}
+	known := make(map[string]bool)
+	for _, node := range nodes.Items {
+		known[node.GetName()] = true
+	}
+	if nodeOrder == nil {
+		nodeOrder = sortedKeys(known)
+	}
+	for _, nodeName := range nodeOrder {
+		if !known[nodeName] {
+			return nil, fmt.Errorf("Longhorn node %s not found", nodeName)
+		}
+	}
+
+	volumes, err := listAll(dynClient, longhornGVR(longhornVolumes), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %v", err)
+	}
+	replicas, err := listAll(dynClient, longhornGVR(longhornReplicas), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn replicas: %v", err)
+	}
+
+	// Healthy replicas per volume and node
+	healthy := make(map[string]map[string]int)
+	replicaNodes := make(map[string]string)
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		nodeID, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		replicaNodes[replica.GetName()] = nodeID
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if state != "running" || failedAt != "" {
+			continue
+		}
+		if healthy[volumeName] == nil {
+			healthy[volumeName] = make(map[string]int)
+		}
+		healthy[volumeName][nodeID]++
+	}
+
+	// Replicas being rebuilt per node, their rebuild restarts when longhorn-manager does
+	engines, err := listAll(dynClient, longhornGVR(longhornEngines), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn engines: %v", err)
+	}
+	rebuilding := make(map[string]bool) // volume/node
+	for _, engine := range engines.Items {
+		volumeName, _, _ := unstructured.NestedString(engine.Object, "spec", "volumeName")
+		modes, _, _ := unstructured.NestedStringMap(engine.Object, "status", "replicaModeMap")
+		for replica, mode := range modes {
+			if mode == "WO" {
+				rebuilding[volumeName+"/"+replicaNodes[replica]] = true
+			}
+		}
+	}
+
+	steps := make([]restartStep, 0, len(nodeOrder))
+	for _, nodeName := range nodeOrder {
+		step := restartStep{NodeName: nodeName}
+		for _, volume := range volumes.Items {
+			volumeName := volume.GetName()
+			state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+			currentNode, _, _ := unstructured.NestedString(volume.Object, "status", "currentNodeID")
+			actualSize, _, _ := unstructured.NestedInt64(volume.Object, "status", "actualSize")
+
+			impact := restartVolumeImpact{VolumeName: volumeName, Size: ByteSize(actualSize)}
+			switch component {
+			case restartInstanceManager:
+				// Replicas of detached volumes are not running, restarting their instance manager changes nothing
+				if state != "attached" {
+					continue
+				}
+				if currentNode == nodeName {
+					impact.Impacts = append(impact.Impacts, impactPause)
+				}
+				if here := healthy[volumeName][nodeName]; here > 0 {
+					elsewhere := 0
+					for node, count := range healthy[volumeName] {
+						if node != nodeName {
+							elsewhere += count
+						}
+					}
+					if elsewhere == 0 {
+						impact.Impacts = append(impact.Impacts, impactFaulted)
+					} else {
+						impact.Impacts = append(impact.Impacts, impactRebuild)
+					}
+				}
+
+			case restartManager:
+				transient := state == "attaching" || state == "detaching"
+				if (transient && currentNode == nodeName) || rebuilding[volumeName+"/"+nodeName] {
+					impact.Impacts = append(impact.Impacts, impactStalled)
+				}
+			}
+			if len(impact.Impacts) > 0 {
+				step.Volumes = append(step.Volumes, impact)
+			}
+		}
+		sort.Slice(step.Volumes, func(i, j int) bool {
+			return step.Volumes[i].VolumeName < step.Volumes[j].VolumeName
+		})
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// printRollingRestart prints the impact of each restart step in node order
+func printRollingRestart(component string, steps []restartStep) {
+	printSectionHeader(Section{
+		Title:       "ROLLING RESTART IMPACT",
+		Description: fmt.Sprintf("Volumes affected when %s restarts one node at a time", component),
+		Color:       Cyan,
+	})
+
+	w := newTableWriter()
+
+	// Print header
+	if useColors {
+		fmt.Fprintf(w, "%s%sSTEP\tNODE\tVOLUME\tIMPACT\tREBUILD DATA%s\n", Bold, Yellow, Reset)
+	} else {
+		fmt.Fprintln(w, "STEP\tNODE\tVOLUME\tIMPACT\tREBUILD DATA")
+	}
+
+	fmt.Fprintln(w, "────\t────\t──────\t──────\t────────────")
+
+	var paused, rebuilt, faulted int
+	var rebuildTotal ByteSize
+	for i, step := range steps {
+		if len(step.Volumes) == 0 {
+			fmt.Fprintf(w, "%d\t%s\t-\t%s\t-\n", i+1, step.NodeName, colorize(impactNoEffect, Green))
+			continue
+		}
+		for _, volume := range step.Volumes {
+			impacts := make([]string, len(volume.Impacts))
+			rebuild := "-"
+			for j, impact := range volume.Impacts {
+				color := Yellow
+				switch impact {
+				case impactPause:
+					paused++
+				case impactRebuild:
+					rebuilt++
+					rebuild = volume.Size.String()
+				case impactFaulted:
+					faulted++
+					color = Red
+				}
+				impacts[j] = colorize(impact, color)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				i+1,
+				step.NodeName,
+				colorize(volume.VolumeName, Blue),
+				strings.Join(impacts, ", "),
+				rebuild,
+			)
+		}
+		rebuildTotal += step.rebuildSize()
+	}
+
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "No nodes found")
+	}
+
+	w.Flush()
+	fmt.Println()
+
+	switch component {
+	case restartInstanceManager:
+		fmt.Printf("I/O pauses:       %d\n", paused)
+		fmt.Printf("Replica rebuilds: %d (%s)\n", rebuilt, rebuildTotal)
+		if faulted > 0 {
+			fmt.Println(colorize(fmt.Sprintf("%d volume(s) have their only healthy replica on the restarted node and fault, add replicas or detach them first", faulted), Red+Bold))
+		}
+		fmt.Println("Wait until every volume is healthy again before restarting the next node, or rebuilds of the same volume overlap.")
+	case restartManager:
+		fmt.Println("Engines and replicas keep running while longhorn-manager restarts, attached volumes see no I/O pause.")
+	}
+}